@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/lyall/gosei/internal/api"
+	"github.com/lyall/gosei/internal/systemd"
+)
+
+// runAgent runs gosei in agent mode: the JSON API of this host, protected by
+// a bearer token, for a main gosei server to manage remotely
+func runAgent(args []string) {
+	fs := flag.NewFlagSet("gosei agent", flag.ExitOnError)
+	host := fs.String("host", getEnv("GOSEI_HOST", "0.0.0.0"), "Host to bind to")
+	port := fs.String("port", getEnv("GOSEI_PORT", "8081"), "Port to listen on")
+	projectsDir := fs.String("projects-dir", getEnv("GOSEI_PROJECTS_DIR", "."), "Directory containing compose projects")
+	token := fs.String("token", getEnv("GOSEI_AGENT_TOKEN", ""), "Shared token the main server must present")
+	tlsCert := fs.String("tls-cert", getEnv("GOSEI_TLS_CERT", ""), "TLS certificate to serve the agent API with")
+	tlsKey := fs.String("tls-key", getEnv("GOSEI_TLS_KEY", ""), "TLS key to serve the agent API with")
+	df := addDockerFlags(fs)
+	lf := addLogFlags(fs)
+	fs.Parse(args)
+	lf.setup()
+
+	if *token == "" {
+		fatal("Agent mode requires --token (or GOSEI_AGENT_TOKEN)")
+	}
+	if *tlsCert == "" {
+		slog.Warn("Agent API is served without TLS; the token is sent in plain text")
+	}
+
+	slog.Info("Starting Gosei agent", "version", Version)
+
+	a := setup(df, *projectsDir)
+	defer a.close()
+
+	router := api.NewAgentRouter(&api.Config{
+		DockerClient:  a.docker,
+		ComposeClient: a.compose,
+		Scanner:       a.scanner,
+		SSEBroker:     a.broker,
+		Build:         buildInfo(),
+		AutoUpdater:   a.updater,
+		Maintenance:   a.windows,
+		EventLog:      a.events,
+		LogArchive:    a.logs,
+		MaxLogStreams: *df.logStreams,
+		QuietLogPaths: splitList(*lf.quiet),
+
+		OperationTimeout: *df.opTimeout,
+		Mock:             a.mock,
+	}, *token)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go systemd.Watchdog(ctx, a.healthy)
+
+	ln, err := listen(fmt.Sprintf("%s:%s", *host, *port), "", 0)
+	if err != nil {
+		fatal("Failed to listen", "error", err)
+	}
+
+	serve(ln, router, *tlsCert, *tlsKey)
+}
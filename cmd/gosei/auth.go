@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/lyall/gosei/internal/auth"
+)
+
+// authFlags holds the login configuration flags
+type authFlags struct {
+	issuer       *string
+	clientID     *string
+	clientSecret *string
+	redirectURL  *string
+	groupsClaim  *string
+	roles        *string
+	sessionTTL   *time.Duration
+}
+
+func addAuthFlags(fs *flag.FlagSet) *authFlags {
+	return &authFlags{
+		issuer:       fs.String("oidc-issuer", getEnv("GOSEI_OIDC_ISSUER", ""), "OpenID Connect issuer URL; enables login when set"),
+		clientID:     fs.String("oidc-client-id", getEnv("GOSEI_OIDC_CLIENT_ID", ""), "OpenID Connect client ID"),
+		clientSecret: fs.String("oidc-client-secret", "", "OpenID Connect client secret (prefer GOSEI_OIDC_CLIENT_SECRET)"),
+		redirectURL:  fs.String("oidc-redirect-url", getEnv("GOSEI_OIDC_REDIRECT_URL", ""), "Public URL of /auth/callback registered with the issuer"),
+		groupsClaim:  fs.String("oidc-groups-claim", getEnv("GOSEI_OIDC_GROUPS_CLAIM", "groups"), "ID token claim listing the user's groups"),
+		roles:        fs.String("oidc-roles", getEnv("GOSEI_OIDC_ROLES", ""), "Comma-separated group=role mappings (admin, operator, viewer; * matches everyone)"),
+		sessionTTL:   fs.Duration("session-ttl", getEnvDuration("GOSEI_SESSION_TTL", 12*time.Hour), "How long a login lasts"),
+	}
+}
+
+// setup returns the auth manager, or nil when login is not configured
+func (f *authFlags) setup() *auth.Manager {
+	if *f.issuer == "" {
+		return nil
+	}
+
+	secret := *f.clientSecret
+	if secret == "" {
+		// Read from the environment by default so the secret stays out of ps output
+		secret = os.Getenv("GOSEI_OIDC_CLIENT_SECRET")
+	}
+
+	roleMap, err := auth.ParseRoleMap(*f.roles)
+	if err != nil {
+		fatal("Invalid --oidc-roles", "error", err)
+	}
+	if len(roleMap) == 0 {
+		slog.Warn("No --oidc-roles configured; every user who can log in at the issuer is an admin")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	provider, err := auth.NewOIDCProvider(ctx, auth.OIDCConfig{
+		IssuerURL:    *f.issuer,
+		ClientID:     *f.clientID,
+		ClientSecret: secret,
+		RedirectURL:  *f.redirectURL,
+		GroupsClaim:  *f.groupsClaim,
+		RoleMap:      roleMap,
+	})
+	if err != nil {
+		fatal("Failed to configure OpenID Connect", "error", err)
+	}
+
+	slog.Info("OpenID Connect login enabled", "issuer", *f.issuer)
+	return auth.NewManager(provider, auth.NewSessionStore(*f.sessionTTL))
+}
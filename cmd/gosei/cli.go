@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lyall/gosei/pkg/client"
+)
+
+// clientCommands are the subcommands that call the API of a running gosei
+// instance instead of serving one, for scripting from a terminal
+var clientCommands = map[string]func(args []string) error{
+	"ls":      runLs,
+	"ps":      runPs,
+	"up":      runUp,
+	"down":    projectOperation("down", (*client.Client).Down),
+	"start":   projectOperation("start", (*client.Client).Start),
+	"stop":    projectOperation("stop", (*client.Client).Stop),
+	"restart": projectOperation("restart", (*client.Client).Restart),
+	"pull":    projectOperation("pull", (*client.Client).Pull),
+	"logs":    runLogs,
+	"events":  runEvents,
+}
+
+// runClientCommand runs a client subcommand and exits with its outcome
+func runClientCommand(name string, args []string) {
+	if err := clientCommands[name](args); err != nil {
+		fmt.Fprintf(os.Stderr, "gosei %s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// clientFlags are the flags of a client subcommand, including those
+// locating the server
+type clientFlags struct {
+	*flag.FlagSet
+	server *string
+	token  *string
+}
+
+func newClientFlags(name, usage string) *clientFlags {
+	fs := flag.NewFlagSet("gosei "+name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gosei %s [flags] %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return &clientFlags{
+		FlagSet: fs,
+		server:  fs.String("server", getEnv("GOSEI_SERVER", "http://127.0.0.1:8080"), "URL of the gosei server or agent"),
+		token:   fs.String("token", getEnv("GOSEI_TOKEN", ""), "Bearer token, for an agent (prefer GOSEI_TOKEN)"),
+	}
+}
+
+// parse parses the arguments, of which nargs must be left after the flags
+// (-1 for any number), and returns a client for the server
+func (f *clientFlags) parse(args []string, nargs int) *client.Client {
+	f.Parse(args)
+	if nargs >= 0 && f.NArg() != nargs {
+		f.Usage()
+		os.Exit(2)
+	}
+	c, err := client.New(*f.server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", f.Name(), err)
+		os.Exit(2)
+	}
+	c.Token = *f.token
+	return c
+}
+
+// interruptible returns a context cancelled by Ctrl-C or SIGTERM
+func interruptible() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// runLs lists compose projects, like docker compose ls
+func runLs(args []string) error {
+	fs := newClientFlags("ls", "")
+	c := fs.parse(args, 0)
+	ctx, cancel := interruptible()
+	defer cancel()
+
+	projects, err := c.ListProjects(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSTATUS\tRUNNING\tPATH")
+	for _, p := range projects {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d/%d\t%s\n", p.ID, p.Name, p.Status, p.Running, p.Total, p.Path)
+	}
+	return tw.Flush()
+}
+
+// runPs lists containers, of one project when given
+func runPs(args []string) error {
+	fs := newClientFlags("ps", "[project]")
+	all := fs.Bool("a", false, "Show stopped containers too")
+	c := fs.parse(args, -1)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	ctx, cancel := interruptible()
+	defer cancel()
+
+	var containers []client.Container
+	if name := fs.Arg(0); name != "" {
+		p, err := c.GetProject(ctx, name)
+		if err != nil {
+			return err
+		}
+		containers = p.Containers
+	} else {
+		var err error
+		if containers, err = c.ListContainers(ctx); err != nil {
+			return err
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tPROJECT\tSERVICE\tSTATUS\tPORTS")
+	for _, ct := range containers {
+		if !*all && ct.State != "running" {
+			continue
+		}
+		status := ct.Status
+		if ct.Health != "" && ct.Health != "none" {
+			status += " (" + ct.Health + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", ct.Name, ct.ProjectName, ct.ServiceName, status, formatPorts(ct.Ports))
+	}
+	return tw.Flush()
+}
+
+// formatPorts formats published ports like docker ps
+func formatPorts(ports []client.Port) string {
+	var s []string
+	for _, p := range ports {
+		if p.HostPort == "" {
+			s = append(s, p.ContainerPort+"/"+p.Protocol)
+			continue
+		}
+		host := p.HostIP
+		if host == "" {
+			host = "0.0.0.0"
+		}
+		s = append(s, fmt.Sprintf("%s:%s->%s/%s", host, p.HostPort, p.ContainerPort, p.Protocol))
+	}
+	return strings.Join(s, ", ")
+}
+
+// runUp runs docker compose up for a project on the server
+func runUp(args []string) error {
+	fs := newClientFlags("up", "<project>")
+	pull := fs.String("pull", "", "Pull images before starting (always, missing, never or build)")
+	build := fs.Bool("build", false, "Build images before starting")
+	recreate := fs.Bool("force-recreate", false, "Recreate containers even if unchanged")
+	detach := fs.Bool("d", false, "Return once started instead of following the output")
+	c := fs.parse(args, 1)
+
+	opts := client.UpOptions{Pull: *pull, Build: *build, ForceRecreate: *recreate}
+	return runOperation(c, fs.Arg(0), "up", *detach, func(ctx context.Context, c *client.Client, id string) (*client.Operation, error) {
+		return c.Up(ctx, id, opts)
+	})
+}
+
+// projectOperation returns a subcommand running a compose operation
+// without options on a project
+func projectOperation(name string, start func(*client.Client, context.Context, string) (*client.Operation, error)) func([]string) error {
+	return func(args []string) error {
+		fs := newClientFlags(name, "<project>")
+		detach := fs.Bool("d", false, "Return once started instead of following the output")
+		c := fs.parse(args, 1)
+		return runOperation(c, fs.Arg(0), name, *detach, func(ctx context.Context, c *client.Client, id string) (*client.Operation, error) {
+			return start(c, ctx, id)
+		})
+	}
+}
+
+// runOperation starts a compose operation and, unless detached, prints its
+// output until it completes, failing if the operation does. Interrupting
+// it cancels the operation on the server, as interrupting docker compose
+// would; a second interrupt stops waiting.
+func runOperation(c *client.Client, id, name string, detach bool, start func(context.Context, *client.Client, string) (*client.Operation, error)) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if detach {
+		op, err := start(ctx, c, id)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s %s\n", op.Operation, op.Status)
+		return nil
+	}
+
+	// Subscribed before starting so the completion can't be missed
+	events, err := c.StreamEvents(ctx, client.EventComposeOutput, client.EventComposeComplete)
+	if err != nil {
+		return err
+	}
+	defer events.Close()
+
+	op, err := start(ctx, c, id)
+	if err != nil {
+		return err
+	}
+
+	interrupts := make(chan os.Signal, 2)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupts)
+	go func() {
+		<-interrupts
+		fmt.Fprintf(os.Stderr, "Cancelling %s...\n", name)
+		if err := c.CancelOperation(ctx, op.ProjectID); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to cancel: %v\n", err)
+		}
+		<-interrupts
+		cancel()
+	}()
+
+	for events.Next() {
+		event := events.Event()
+		switch event.Type {
+		case client.EventComposeOutput:
+			var out client.ComposeOutputEvent
+			if event.Decode(&out) != nil || out.ProjectID != op.ProjectID || out.Operation != name {
+				continue
+			}
+			if out.Stream == "stderr" {
+				fmt.Fprintln(os.Stderr, out.Line)
+			} else {
+				fmt.Println(out.Line)
+			}
+		case client.EventComposeComplete:
+			var done client.ComposeCompleteEvent
+			if event.Decode(&done) != nil || done.ProjectID != op.ProjectID || done.Operation != name {
+				continue
+			}
+			if !done.Success {
+				return fmt.Errorf("%s %s: %s", name, done.Status, done.Message)
+			}
+			return nil
+		}
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("stopped waiting; %s continues on the server", name)
+	}
+	if err := events.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("event stream ended before %s completed", name)
+}
+
+// runLogs prints a container's logs, following them with -f
+func runLogs(args []string) error {
+	fs := newClientFlags("logs", "<container>")
+	follow := fs.Bool("f", false, "Follow log output")
+	tail := fs.String("tail", "", `Number of lines to show from the end, or "all" (default 100)`)
+	since := fs.String("since", "", "Show lines after a time (RFC 3339) or a duration ago (e.g. 10m)")
+	timestamps := fs.Bool("t", false, "Show timestamps")
+	c := fs.parse(args, 1)
+
+	opts := client.LogOptions{Tail: *tail, ANSI: "keep"}
+	if *since != "" {
+		t, err := parseSinceFlag(*since)
+		if err != nil {
+			return err
+		}
+		opts.Since = t
+	}
+	ctx, cancel := interruptible()
+	defer cancel()
+
+	show := func(l client.LogLine) {
+		out := os.Stdout
+		if l.Stream == "stderr" {
+			out = os.Stderr
+		}
+		if *timestamps {
+			fmt.Fprintf(out, "%s %s\n", l.Timestamp.Format(time.RFC3339Nano), l.Message)
+		} else {
+			fmt.Fprintln(out, l.Message)
+		}
+	}
+
+	if !*follow {
+		lines, err := c.Logs(ctx, fs.Arg(0), opts)
+		for _, l := range lines {
+			show(l)
+		}
+		return err
+	}
+
+	stream, err := c.FollowLogs(ctx, fs.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	for stream.Next() {
+		show(stream.Line())
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return stream.Err()
+}
+
+// parseSinceFlag parses a time or a duration ago
+func parseSinceFlag(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q: expected an RFC 3339 time or a duration", s)
+	}
+	return time.Now().Add(-d), nil
+}
+
+// runEvents prints server events as they happen, one JSON object a line
+func runEvents(args []string) error {
+	fs := newClientFlags("events", "")
+	types := fs.String("type", "", "Comma-separated event types to show, e.g. project:status,compose:complete (default all)")
+	c := fs.parse(args, 0)
+	ctx, cancel := interruptible()
+	defer cancel()
+
+	events, err := c.StreamEvents(ctx, splitList(*types)...)
+	if err != nil {
+		return err
+	}
+	defer events.Close()
+
+	enc := json.NewEncoder(os.Stdout)
+	for events.Next() {
+		event := events.Event()
+		enc.Encode(struct {
+			Time time.Time       `json:"time"`
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}{time.Now(), event.Type, event.Data})
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return events.Err()
+}
@@ -4,91 +4,829 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/lyall/gosei/internal/agent"
 	"github.com/lyall/gosei/internal/api"
+	"github.com/lyall/gosei/internal/autoupdate"
 	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/logarchive"
+	"github.com/lyall/gosei/internal/logging"
+	"github.com/lyall/gosei/internal/maintenance"
+	"github.com/lyall/gosei/internal/monitor"
+	"github.com/lyall/gosei/internal/notify"
+	"github.com/lyall/gosei/internal/probe"
 	"github.com/lyall/gosei/internal/project"
+	"github.com/lyall/gosei/internal/release"
+	"github.com/lyall/gosei/internal/scheduler"
+	"github.com/lyall/gosei/internal/settings"
 	"github.com/lyall/gosei/internal/sse"
+	"github.com/lyall/gosei/internal/store"
+	"github.com/lyall/gosei/internal/systemd"
+	"github.com/lyall/gosei/internal/vuln"
+	"github.com/lyall/gosei/internal/webhook"
 )
 
+// Build metadata, set at build time with -ldflags "-X main.Version=..."
 var (
-	// Version is set at build time
-	Version = "0.1.0"
+	Version   = "0.1.0"
+	Commit    = "" // from the Go toolchain's VCS stamp when not set
+	BuildDate = "" // RFC 3339
 )
 
 func main() {
-	// Parse flags
-	host := flag.String("host", getEnv("GOSEI_HOST", "127.0.0.1"), "Host to bind to")
-	port := flag.String("port", getEnv("GOSEI_PORT", "8080"), "Port to listen on")
-	projectsDir := flag.String("projects-dir", getEnv("GOSEI_PROJECTS_DIR", "."), "Directory containing compose projects")
-	mockMode := flag.Bool("mock", getEnvBool("GOSEI_MOCK", false), "Run with mock Docker client (no Docker required)")
-	flag.Parse()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "agent":
+			runAgent(os.Args[2:])
+			return
+		case "serve":
+			runServer(os.Args[2:])
+			return
+		case "restore":
+			runRestore(os.Args[2:])
+			return
+		}
+		if _, ok := clientCommands[os.Args[1]]; ok {
+			runClientCommand(os.Args[1], os.Args[2:])
+			return
+		}
+	}
+	runServer(os.Args[1:])
+}
 
-	// Validate projects directory
-	if _, err := os.Stat(*projectsDir); os.IsNotExist(err) {
-		log.Fatalf("Projects directory does not exist: %s", *projectsDir)
+// defaultAlertInterval is how often stats are sampled for alerts or the
+// stats history when --stats-interval is not set
+const defaultAlertInterval = 30 * time.Second
+
+// runServer runs the dashboard server
+func runServer(args []string) {
+	fs := flag.NewFlagSet("gosei", flag.ExitOnError)
+	host := fs.String("host", getEnv("GOSEI_HOST", "127.0.0.1"), "Host to bind to")
+	port := fs.String("port", getEnv("GOSEI_PORT", "8080"), "Port to listen on")
+	projectsDir := fs.String("projects-dir", getEnv("GOSEI_PROJECTS_DIR", "."), "Directory containing compose projects")
+	agents := fs.String("agents", getEnv("GOSEI_AGENTS", ""), "Comma-separated remote agents to manage (name=url)")
+	agentToken := fs.String("agent-token", getEnv("GOSEI_AGENT_TOKEN", ""), "Token used to authenticate to agents listed in --agents")
+	socketPath := fs.String("listen-socket", getEnv("GOSEI_LISTEN_SOCKET", ""), "Listen on a Unix domain socket instead of host:port")
+	socketMode := fs.String("listen-socket-mode", getEnv("GOSEI_LISTEN_SOCKET_MODE", "0660"), "File mode for the Unix domain socket")
+	rateLimit := fs.Float64("rate-limit", getEnvFloat("GOSEI_RATE_LIMIT", 2), "Mutating API requests allowed per second per client IP (0 disables)")
+	rateBurst := fs.Int("rate-burst", getEnvInt("GOSEI_RATE_BURST", 10), "Burst size for --rate-limit")
+	maxBody := fs.Int64("max-body-size", int64(getEnvInt("GOSEI_MAX_BODY_SIZE", 1<<20)), "Maximum API request body size in bytes")
+	maxUpload := fs.Int64("max-upload-size", int64(getEnvInt("GOSEI_MAX_UPLOAD_SIZE", 1<<30)), "Maximum size in bytes of container file uploads and project imports (0 for no limit)")
+	trustedProxies := fs.String("trusted-proxies", getEnv("GOSEI_TRUSTED_PROXIES", ""), "Comma-separated IPs and CIDR ranges of reverse proxies whose X-Forwarded-For headers give the client address")
+	corsOrigins := fs.String("cors-origins", getEnv("GOSEI_CORS_ORIGINS", ""), "Comma-separated origins allowed to call the API cross-origin (* for any)")
+	noCSRF := fs.Bool("disable-csrf", getEnvBool("GOSEI_DISABLE_CSRF", false), "Disable CSRF token checks on browser requests")
+	noCompress := fs.Bool("disable-compression", getEnvBool("GOSEI_DISABLE_COMPRESSION", false), "Disable brotli/gzip compression of responses")
+	vulnScanner := fs.String("vuln-scanner", getEnv("GOSEI_VULN_SCANNER", ""), "Image vulnerability scanner to enable (trivy)")
+	trivyPath := fs.String("trivy-path", getEnv("GOSEI_TRIVY_PATH", "trivy"), "Path to the trivy binary")
+	alertRules := fs.String("alerts", getEnv("GOSEI_ALERTS", ""), "Comma-separated alert rules, e.g. memory>90%:5m,cpu>150%:10m,restarts>3:1h")
+	webhooksFile := fs.String("webhooks-file", getEnv("GOSEI_WEBHOOKS_FILE", ""), "YAML file of outgoing webhooks receiving signed event payloads")
+	probesFile := fs.String("probes-file", getEnv("GOSEI_PROBES_FILE", ""), "File to keep health URLs set through the API in across restarts")
+	ssePolicy := fs.String("sse-policy", getEnv("GOSEI_SSE_POLICY", string(sse.PolicyDropNewest)), "What to do with events for browsers that fall behind (drop-newest, drop-oldest, coalesce, disconnect)")
+	statsInterval := fs.Duration("stats-interval", getEnvDuration("GOSEI_STATS_INTERVAL", 0), "Push container stats to browsers over SSE at this interval instead of browsers polling (0 disables)")
+	statsHistory := fs.Duration("stats-history", getEnvDuration("GOSEI_STATS_HISTORY", 0), "Keep container stats samples for this long, for Grafana at /api/grafana (0 disables)")
+	updateCheck := fs.Duration("update-check-interval", getEnvDuration("GOSEI_UPDATE_CHECK_INTERVAL", 0), "Check GitHub for newer gosei releases at this interval, at least 1h (0 disables)")
+	updateRepo := fs.String("update-check-repo", getEnv("GOSEI_UPDATE_CHECK_REPO", release.DefaultRepo), "GitHub repository (owner/name) whose releases are checked")
+	debug := fs.Bool("debug", getEnvBool("GOSEI_DEBUG", false), "Expose /debug/pprof and /api/system/runtime diagnostics")
+	demo := fs.Bool("demo", getEnvBool("GOSEI_DEMO", false), "Run a public demo: mock mode, only lifecycle operations allowed, state reset periodically")
+	demoReset := fs.Duration("demo-reset", getEnvDuration("GOSEI_DEMO_RESET", time.Hour), "How often --demo resets the mock containers to their initial state")
+	df := addDockerFlags(fs)
+	lf := addLogFlags(fs)
+	af := addAuthFlags(fs)
+	mf := addMQTTFlags(fs)
+	nf := addNotifyFlags(fs)
+	fs.Parse(args)
+	lf.setup()
+
+	slog.Info("Starting Gosei", "version", Version)
+
+	authManager := af.setup()
+
+	policy, err := sse.ParsePolicy(*ssePolicy)
+	if err != nil {
+		fatal("Invalid --sse-policy", "error", err)
+	}
+
+	if *demo {
+		df.mock.enabled = true
+	}
+	a := setup(df, *projectsDir)
+	defer a.close()
+	a.broker.SetPolicy(policy)
+
+	registry := agent.NewRegistry()
+	specs, err := agent.ParseSpecs(*agents)
+	if err != nil {
+		fatal("Invalid --agents", "error", err)
+	}
+	for name, url := range specs {
+		if _, err := registry.Register(name, url, *agentToken); err != nil {
+			fatal("Failed to register agent", "agent", name, "error", err)
+		}
+		slog.Info("Registered agent", "agent", name, "url", url)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go registry.Monitor(ctx, 30*time.Second)
+	go systemd.Watchdog(ctx, a.healthy)
+	if *demo {
+		slog.Warn("Running in DEMO MODE - only lifecycle operations are allowed", "reset", *demoReset)
+		if *demoReset > 0 {
+			go a.resetDemo(ctx, *demoReset)
+		}
+	}
+
+	rules, err := monitor.ParseRules(*alertRules)
+	if err != nil {
+		fatal("Invalid --alerts", "error", err)
+	}
+	targets, notifyRules := nf.setup()
+	dispatcher := notify.NewDispatcher()
+	dispatcher.SetRules(notifyRules)
+	dispatcher.SetMaintenance(a.windows)
+	// Without rules only alerts are notified, as before rules existed
+	if len(notifyRules) > 0 {
+		go monitor.NewEventNotifier(a.broker, a.docker, dispatcher).Run(ctx)
+		slog.Info("Notification rules enabled", "rules", len(notifyRules))
+	}
+
+	// Idle until the stats interval is set, unless alerts or the history
+	// need samples
+	sampler := monitor.NewSampler(a.docker, a.broker, 0)
+	var history *monitor.History
+	if *statsHistory > 0 {
+		history = monitor.NewHistory(*statsHistory)
+		sampler.SetHistory(history)
+		slog.Info("Stats history enabled", "retention", *statsHistory)
+	}
+	if len(rules) > 0 {
+		alerter := monitor.NewAlerter(rules, a.broker, dispatcher)
+		alerter.SetMaintenance(a.windows)
+		sampler.SetAlerter(alerter)
+		go alerter.Run(ctx)
+		slog.Info("Alerts enabled", "rules", len(rules))
+	}
+	go sampler.Run(ctx)
+
+	releases := release.New(buildInfo(), *updateRepo)
+	go releases.Run(ctx)
+
+	// Settings changed through the API take effect here, without a restart
+	apply := func(s settings.Settings) error {
+		notifiers, err := s.Notify.Notifiers()
+		if err != nil {
+			return err
+		}
+		dispatcher.SetNotifiers(notifiers)
+		if a.masker != nil {
+			a.masker.SetEnvMasker(docker.NewEnvMasker(s.EnvMask))
+		}
+		interval := s.Stats()
+		if interval <= 0 && (len(rules) > 0 || history != nil) {
+			interval = defaultAlertInterval
+		}
+		sampler.SetInterval(interval)
+		releases.SetInterval(s.UpdateCheck())
+		slog.Info("Applied settings", "notifiers", len(notifiers), "statsInterval", interval, "updateCheckInterval", s.UpdateCheck())
+		return nil
+	}
+	defaults := settings.Settings{
+		StatsInterval:       statsInterval.String(),
+		UpdateCheckInterval: updateCheck.String(),
+		EnvMask:             splitList(*df.envMask),
+		Notify:              targets,
+	}
+	runtimeSettings, err := settings.New(defaults, stateDoc(a.db, "settings", ""), apply)
+	if err != nil {
+		fatal("Invalid settings", "error", err)
+	}
+
+	var webhooks *webhook.Sink
+	if *webhooksFile != "" {
+		endpoints, err := webhook.LoadFile(*webhooksFile)
+		if err != nil {
+			fatal("Invalid --webhooks-file", "error", err)
+		}
+		webhooks = webhook.NewSink(endpoints, a.broker, a.docker)
+		go webhooks.Run(ctx)
+		slog.Info("Outgoing webhooks enabled", "webhooks", len(endpoints))
+	}
+
+	// Services opt in with gosei.healthcheck.url, so this is idle otherwise
+	prober, err := probe.New(a.docker, a.scanner, a.broker, dispatcher, stateDoc(a.db, "probes", *probesFile))
+	if err != nil {
+		fatal("Failed to load health URLs", "error", err)
+	}
+	go prober.Run(ctx)
+
+	jobs := scheduler.New()
+	jobs.SetMaintenance(a.windows)
+	go jobs.Run(ctx)
+	go scheduler.NewRestarts(jobs, a.docker, a.scanner).Run(ctx, time.Minute)
+
+	if publisher := mf.setup(a.broker, a.docker, a.scanner); publisher != nil {
+		go publisher.Run(ctx)
+	}
+
+	var scanner vuln.Scanner
+	switch *vulnScanner {
+	case "":
+	case "trivy":
+		if _, err := exec.LookPath(*trivyPath); err != nil {
+			fatal("Vulnerability scanner not found", "path", *trivyPath, "error", err)
+		}
+		scanner = vuln.NewTrivy(*trivyPath, *df.host)
+		slog.Info("Image vulnerability scanning enabled", "scanner", "trivy")
+	default:
+		fatal("Unknown --vuln-scanner", "scanner", *vulnScanner)
+	}
+
+	if *debug {
+		slog.Warn("Debug endpoints enabled at /debug/pprof and /api/system/runtime")
+	}
+
+	proxies, err := api.ParseTrustedProxies(*trustedProxies)
+	if err != nil {
+		fatal("Invalid --trusted-proxies", "error", err)
+	}
+
+	router := api.NewRouter(&api.Config{
+		DockerClient:  a.docker,
+		ComposeClient: a.compose,
+		Scanner:       a.scanner,
+		SSEBroker:     a.broker,
+		Agents:        registry,
+		Build:         buildInfo(),
+		Debug:         *debug,
+		RateLimit:     *rateLimit,
+		RateBurst:     *rateBurst,
+		MaxBodyBytes:  *maxBody,
+		CORSOrigins:   splitList(*corsOrigins),
+		DisableCSRF:   *noCSRF,
+		Auth:          authManager,
+		VulnScanner:   scanner,
+		VulnReports:   a.reports,
+		AutoUpdater:   a.updater,
+		EventLog:      a.events,
+		StatsHistory:  history,
+		LogArchive:    a.logs,
+		Webhooks:      webhooks,
+		Prober:        prober,
+		Scheduler:     jobs,
+		Maintenance:   a.windows,
+		Settings:      runtimeSettings,
+		Store:         a.db,
+		Releases:      releases,
+		MaxLogStreams: *df.logStreams,
+		QuietLogPaths: splitList(*lf.quiet),
+
+		TrustedProxies:     proxies,
+		MaxUploadBytes:     *maxUpload,
+		OperationTimeout:   *df.opTimeout,
+		Demo:               *demo,
+		DisableCompression: *noCompress,
+		Mock:               a.mock,
+	})
+
+	mode, err := parseFileMode(*socketMode)
+	if err != nil {
+		fatal("Invalid --listen-socket-mode", "error", err)
+	}
+	ln, err := listen(fmt.Sprintf("%s:%s", *host, *port), *socketPath, mode)
+	if err != nil {
+		fatal("Failed to listen", "error", err)
+	}
+
+	serve(ln, router, "", "")
+}
+
+// listenURL describes a listener for logging
+func listenURL(ln net.Listener, scheme string) string {
+	if ln.Addr().Network() == "unix" {
+		return "unix://" + ln.Addr().String()
+	}
+	return scheme + "://" + ln.Addr().String()
+}
+
+// dockerFlags holds the flags that select and configure the Docker connection
+type dockerFlags struct {
+	mock       *mockFlag
+	host       *string
+	tlsCA      *string
+	tlsCert    *string
+	tlsKey     *string
+	tlsVerify  *bool
+	context    *string
+	envMask    *string
+	publicHost *string
+	volumePull *bool
+	compose    *string
+	maxLine    *int
+	opTimeout  *time.Duration
+	eventLog   *int
+	stateFile  *string
+	logStreams *int
+
+	dataDir         *string
+	maintenanceFile *string
+
+	logArchive         *string
+	logArchiveFileSize *int64
+	logArchiveMaxSize  *int64
+	logArchiveMaxAge   *time.Duration
+
+	scenario *docker.MockScenario // loaded by setup
+}
+
+func addDockerFlags(fs *flag.FlagSet) *dockerFlags {
+	return &dockerFlags{
+		mock:       newMockFlag(fs, getEnv("GOSEI_MOCK", "")),
+		host:       fs.String("docker-host", getEnv("GOSEI_DOCKER_HOST", ""), "Docker daemon address (unix://, tcp:// or ssh://user@host)"),
+		tlsCA:      fs.String("docker-tls-ca", getEnv("GOSEI_DOCKER_TLS_CA", ""), "Path to CA certificate for a TLS Docker daemon"),
+		tlsCert:    fs.String("docker-tls-cert", getEnv("GOSEI_DOCKER_TLS_CERT", ""), "Path to client certificate for a TLS Docker daemon"),
+		tlsKey:     fs.String("docker-tls-key", getEnv("GOSEI_DOCKER_TLS_KEY", ""), "Path to client key for a TLS Docker daemon"),
+		tlsVerify:  fs.Bool("docker-tls-verify", getEnvBool("GOSEI_DOCKER_TLS_VERIFY", true), "Verify the Docker daemon's TLS certificate"),
+		context:    fs.String("docker-context", getEnv("GOSEI_DOCKER_CONTEXT", ""), "Docker CLI context to connect through (from ~/.docker/contexts)"),
+		publicHost: fs.String("public-host", getEnv("GOSEI_PUBLIC_HOST", ""), "Host for published port URLs, optionally with a scheme (default: the Docker host)"),
+		volumePull: fs.Bool("volume-browser-pull", getEnvBool("GOSEI_VOLUME_BROWSER_PULL", false), "Pull the image used to browse volumes when it is missing, instead of failing"),
+		compose:    fs.String("compose-binary", getEnv("GOSEI_COMPOSE_BINARY", ""), "Standalone compose binary (e.g. docker-compose) to use when the docker compose plugin is missing"),
+		maxLine:    fs.Int("compose-max-line-size", getEnvInt("GOSEI_COMPOSE_MAX_LINE_SIZE", docker.DefaultComposeMaxLineSize), "Longest line of compose output streamed as one line; longer lines are split"),
+		opTimeout:  fs.Duration("operation-timeout", getEnvDuration("GOSEI_OPERATION_TIMEOUT", time.Hour), "Maximum duration of a compose operation started from the API (0 for none)"),
+		eventLog:   fs.Int("event-history", getEnvInt("GOSEI_EVENT_HISTORY", 500), "Number of recent Docker events kept for /api/events/history"),
+		stateFile:  fs.String("state-file", getEnv("GOSEI_STATE_FILE", ""), "File to keep last-known project statuses and operations in across restarts"),
+		logStreams: fs.Int("max-log-streams", getEnvInt("GOSEI_MAX_LOG_STREAMS", 50), "Maximum number of containers whose logs are followed at once for browsers (0 for no limit)"),
+		envMask:    fs.String("env-mask", getEnv("GOSEI_ENV_MASK", strings.Join(docker.DefaultEnvMask, ",")), "Comma-separated name patterns of container env vars to mask (empty shows all)"),
+
+		dataDir:         fs.String("data-dir", getEnv("GOSEI_DATA_DIR", ""), "Directory for gosei's database of its own state (empty keeps it in memory or the --*-file flags' files)"),
+		maintenanceFile: fs.String("maintenance-file", getEnv("GOSEI_MAINTENANCE_FILE", ""), "File to keep maintenance windows in across restarts"),
+
+		logArchive:         fs.String("log-archive-dir", getEnv("GOSEI_LOG_ARCHIVE_DIR", ""), "Directory to capture container logs to, so they survive container recreation (empty disables)"),
+		logArchiveFileSize: fs.Int64("log-archive-file-size", int64(getEnvInt("GOSEI_LOG_ARCHIVE_FILE_SIZE", logarchive.DefaultFileSize)), "Size in bytes at which a container's archived log file is rotated"),
+		logArchiveMaxSize:  fs.Int64("log-archive-max-size", int64(getEnvInt("GOSEI_LOG_ARCHIVE_MAX_SIZE", logarchive.DefaultMaxSize)), "Archived log bytes kept per container; the oldest files are removed first (0 for no limit)"),
+		logArchiveMaxAge:   fs.Duration("log-archive-max-age", getEnvDuration("GOSEI_LOG_ARCHIVE_MAX_AGE", 7*24*time.Hour), "How long archived log files are kept after their last line (0 for no limit)"),
+	}
+}
+
+// mockFlag is --mock, which is either a plain switch or, as
+// --mock=scenario.yaml, the scenario the mock client starts with
+type mockFlag struct {
+	enabled  bool
+	scenario string
+}
+
+func newMockFlag(fs *flag.FlagSet, value string) *mockFlag {
+	f := &mockFlag{}
+	if value != "" {
+		f.Set(value)
 	}
+	fs.Var(f, "mock", "Run with mock Docker client (no Docker required), optionally --mock=scenario.yaml defining its projects, containers and events")
+	return f
+}
 
-	log.Printf("Starting Gosei v%s", Version)
-	log.Printf("Projects directory: %s", *projectsDir)
+func (f *mockFlag) Set(value string) error {
+	switch value {
+	case "true", "1", "yes":
+		f.enabled, f.scenario = true, ""
+	case "false", "0", "no":
+		f.enabled, f.scenario = false, ""
+	default:
+		f.enabled, f.scenario = true, value
+	}
+	return nil
+}
 
-	// Initialize Docker client (real or mock)
-	var dockerClient docker.DockerClient
-	var composeClient docker.ComposeExecutor
+func (f *mockFlag) String() string {
+	if f == nil || !f.enabled {
+		return ""
+	}
+	if f.scenario != "" {
+		return f.scenario
+	}
+	return "true"
+}
 
-	if *mockMode {
-		log.Println("Running in MOCK MODE - no Docker connection required")
+// IsBoolFlag lets --mock be given without a value
+func (f *mockFlag) IsBoolFlag() bool { return true }
+
+func (f *dockerFlags) envMasker() *docker.EnvMasker {
+	return docker.NewEnvMasker(strings.Split(*f.envMask, ","))
+}
+
+// connect creates the Docker and Compose clients (real or mock)
+func (f *dockerFlags) connect() (docker.DockerClient, docker.ComposeExecutor) {
+	if f.mock.enabled {
+		slog.Warn("Running in MOCK MODE - no Docker connection required")
 		mockDocker := docker.NewMockClient()
-		dockerClient = mockDocker
-		composeClient = docker.NewMockComposeClient(mockDocker)
+		if f.scenario != nil {
+			var err error
+			if mockDocker, err = docker.NewMockClientFromScenario(f.scenario); err != nil {
+				fatal("Failed to load mock scenario", "path", f.mock.scenario, "error", err)
+			}
+		}
+		mockDocker.SetEnvMasker(f.envMasker())
+		mockDocker.SetPublicHost(*f.publicHost)
+		return mockDocker, docker.NewMockComposeClient(mockDocker)
+	}
+
+	opts := docker.ClientOptions{
+		Host:      *f.host,
+		TLSCACert: *f.tlsCA,
+		TLSCert:   *f.tlsCert,
+		TLSKey:    *f.tlsKey,
+		TLSVerify: *f.tlsVerify,
+	}
+	if *f.context != "" {
+		if *f.host != "" {
+			fatal("--docker-context and --docker-host cannot be used together")
+		}
+		dc, err := docker.LoadContext(*f.context)
+		if err != nil {
+			fatal("Failed to load Docker context", "context", *f.context, "error", err)
+		}
+		opts = dc.ClientOptions()
+		slog.Info("Using Docker context", "context", dc.Name)
+	}
+
+	realClient, err := docker.NewClient(opts)
+	if err != nil {
+		fatal("Failed to create Docker client", "error", err)
+	}
+	realClient.SetEnvMasker(f.envMasker())
+	realClient.SetPublicHost(*f.publicHost)
+	realClient.SetVolumeBrowserPull(*f.volumePull)
+	go removeStaleVolumeBrowsers(realClient)
+	if opts.Host != "" {
+		slog.Info("Connected to Docker host", "host", opts.Host)
+	}
+	info := docker.DetectCompose(context.Background(), *f.compose)
+	if info.Error != "" {
+		slog.Warn("Docker Compose not found; project operations will fail", "error", info.Error)
 	} else {
-		realClient, err := docker.NewClient()
+		slog.Info("Using Docker Compose", "command", info.Command, "version", info.Version)
+	}
+	composeClient := docker.NewComposeClient(realClient, info)
+	composeClient.SetMaxLineSize(*f.maxLine)
+	return realClient, composeClient
+}
+
+// app holds the components shared by server and agent mode
+type app struct {
+	docker  docker.DockerClient
+	masker  envMasked // the Docker client under the cache
+	compose docker.ComposeExecutor
+	scanner *project.Scanner
+	broker  *sse.Broker
+	updater *autoupdate.Updater
+	windows *maintenance.Manager
+	db      *store.Store // when there is a data directory
+	reports *vuln.Store
+	events  *docker.EventLog
+	logs    *logarchive.Archiver // when capturing logs to disk
+	cancel  context.CancelFunc
+
+	state       store.Doc          // where project state is saved, if anywhere
+	mock        *docker.MockClient // in mock mode
+	scenarioDir string             // temporary projects directory of a mock scenario
+}
+
+// envMasked is a Docker client whose env masking can change while running
+type envMasked interface {
+	SetEnvMasker(m *docker.EnvMasker)
+}
+
+// stateSaveInterval is how often changed project state is written to the
+// state file
+const stateSaveInterval = 10 * time.Second
+
+// logArchiveInterval is how often the log archiver looks for containers
+// that started or stopped
+const logArchiveInterval = 10 * time.Second
+
+// removeStaleVolumeBrowsers removes volume browser containers left over
+// from a previous run. Ones created in the last minute are kept, as they may
+// belong to another gosei on the same engine.
+func removeStaleVolumeBrowsers(client *docker.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	n, err := client.RemoveStaleVolumeBrowsers(ctx, time.Minute)
+	if err != nil {
+		slog.Warn("Failed to remove leftover volume browsers", "error", err)
+	} else if n > 0 {
+		slog.Info("Removed leftover volume browsers", "count", n)
+	}
+}
+
+// setup connects to Docker, scans projects and starts the event watcher
+func setup(df *dockerFlags, projectsDir string) *app {
+	// A mock scenario brings its own projects, written to a temporary
+	// projects directory in place of the given one
+	var scenarioDir string
+	if df.mock.scenario != "" {
+		scenario, err := docker.LoadMockScenario(df.mock.scenario)
 		if err != nil {
-			log.Fatalf("Failed to create Docker client: %v", err)
+			fatal("Failed to load mock scenario", "path", df.mock.scenario, "error", err)
+		}
+		df.scenario = scenario
+		if len(scenario.Projects) > 0 {
+			if scenarioDir, err = os.MkdirTemp("", "gosei-scenario-"); err != nil {
+				fatal("Failed to create scenario projects directory", "error", err)
+			}
+			if err := scenario.WriteProjects(scenarioDir); err != nil {
+				fatal("Failed to write scenario projects", "error", err)
+			}
+			projectsDir = scenarioDir
 		}
-		dockerClient = realClient
-		composeClient = docker.NewComposeClient(realClient)
 	}
-	defer dockerClient.Close()
+
+	// Validate projects directory
+	if _, err := os.Stat(projectsDir); os.IsNotExist(err) {
+		fatal("Projects directory does not exist", "dir", projectsDir)
+	}
+	slog.Info("Using projects directory", "dir", projectsDir)
+
+	dockerClient, composeClient := df.connect()
+	mockDocker, _ := dockerClient.(*docker.MockClient)
+	masker, _ := dockerClient.(envMasked)
+	// Share container listings between the many per-project status lookups
+	dockerClient = docker.NewCachingClient(dockerClient, docker.DefaultContainerCacheTTL)
 
 	// Initialize project scanner
-	scanner := project.NewScanner(*projectsDir)
+	scanner := project.NewScanner(projectsDir)
 
 	// Initial scan
 	projects, err := scanner.Scan(context.Background())
 	if err != nil {
-		log.Printf("Warning: Failed to scan projects: %v", err)
+		slog.Warn("Failed to scan projects", "error", err)
 	} else {
-		log.Printf("Found %d projects", len(projects))
+		slog.Info("Scanned projects", "count", len(projects))
 	}
 
 	// Initialize SSE broker
 	broker := sse.NewBroker()
-	defer broker.Close()
+
+	var db *store.Store
+	if *df.dataDir != "" {
+		if db, err = store.Open(*df.dataDir); err != nil {
+			fatal("Failed to open database", "dir", *df.dataDir, "error", err)
+		}
+		slog.Info("Using database", "path", db.Path())
+	}
+
+	// The last-known statuses are kept if Docker can't be asked in time
+	ctx, cancel := context.WithCancel(context.Background())
+	state := stateDoc(db, "projects", *df.stateFile)
+	if state != nil {
+		if err := scanner.LoadState(state); err != nil {
+			slog.Warn("Failed to load project state", "error", err)
+		}
+		go scanner.PersistState(ctx, state, stateSaveInterval)
+	}
+
+	// Serve real statuses from the first request rather than unknown until
+	// each project is looked at
+	reconcileCtx, reconcileCancel := context.WithTimeout(ctx, reconcileTimeout)
+	if err := reconcileStatuses(reconcileCtx, dockerClient, scanner, broker); err != nil {
+		slog.Warn("Failed to compute project statuses", "error", err)
+	}
+	reconcileCancel()
+
+	if df.scenario != nil {
+		mockDocker.PlayScenario(ctx, df.scenario)
+	}
 
 	// Start watching Docker events
-	go watchDockerEvents(dockerClient, broker, scanner)
+	reports := vuln.NewStore()
+	events := docker.NewEventLog(*df.eventLog)
+	go watchDockerEvents(dockerClient, broker, scanner, reports, events)
 
-	// Create router
-	router := api.NewRouter(&api.Config{
-		DockerClient:  dockerClient,
-		ComposeClient: composeClient,
-		Scanner:       scanner,
-		SSEBroker:     broker,
-		Version:       Version,
-	})
+	windows, err := maintenance.New(stateDoc(db, "maintenance", *df.maintenanceFile))
+	if err != nil {
+		fatal("Failed to load maintenance windows", "error", err)
+	}
+
+	// Apply gosei.auto-update policies in the background
+	updater := autoupdate.New(dockerClient, composeClient, scanner, broker)
+	updater.SetMaintenance(windows)
+	if db != nil {
+		if err := updater.SetStore(db.Doc("autoupdate")); err != nil {
+			slog.Warn("Failed to load auto-update history", "error", err)
+		}
+	}
+	go updater.Run(ctx, time.Minute)
+
+	var logs *logarchive.Archiver
+	if *df.logArchive != "" {
+		logs, err = logarchive.New(dockerClient, logarchive.Options{
+			Dir:      *df.logArchive,
+			FileSize: *df.logArchiveFileSize,
+			MaxSize:  *df.logArchiveMaxSize,
+			MaxAge:   *df.logArchiveMaxAge,
+		})
+		if err != nil {
+			fatal("Failed to set up log archive", "dir", *df.logArchive, "error", err)
+		}
+		go logs.Run(ctx, logArchiveInterval)
+		slog.Info("Archiving container logs", "dir", *df.logArchive)
+	}
+
+	return &app{
+		docker:  dockerClient,
+		masker:  masker,
+		compose: composeClient,
+		scanner: scanner,
+		broker:  broker,
+		updater: updater,
+		windows: windows,
+		db:      db,
+		reports: reports,
+		events:  events,
+		logs:    logs,
+		cancel:  cancel,
+
+		state:       state,
+		mock:        mockDocker,
+		scenarioDir: scenarioDir,
+	}
+}
+
+// buildInfo describes the running gosei, falling back to the commit the Go
+// toolchain stamps on builds from a git checkout
+func buildInfo() release.Build {
+	b := release.Build{Version: Version, Commit: Commit, Date: BuildDate}
+	if info, ok := debug.ReadBuildInfo(); ok && b.Commit == "" {
+		dirty := false
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				b.Commit = s.Value
+			case "vcs.modified":
+				dirty = s.Value == "true"
+			}
+		}
+		if dirty && b.Commit != "" {
+			b.Commit += "-dirty"
+		}
+	}
+	return b
+}
+
+// stateDoc returns where a piece of state is kept: with a database, the
+// document called name, into which file is imported if the document is
+// new; otherwise file, if given
+func stateDoc(db *store.Store, name, file string) store.Doc {
+	if db == nil {
+		return store.File(file)
+	}
+	if imported, err := db.Import(name, file); err != nil {
+		slog.Warn("Failed to import state file", "path", file, "error", err)
+	} else if imported {
+		slog.Info("Imported state file into database", "path", file, "name", name)
+	}
+	return db.Doc(name)
+}
+
+// reconcileTimeout bounds the status sweep at startup, so a slow Docker
+// daemon doesn't hold up the server
+const reconcileTimeout = 10 * time.Second
+
+// reconcileStatuses updates every project's status from one listing of all
+// containers and broadcasts each, for when statuses may be stale as a
+// whole: at startup and after missing events while disconnected
+func reconcileStatuses(ctx context.Context, client docker.DockerClient, scanner *project.Scanner, broker *sse.Broker) error {
+	all, err := client.ListContainers(ctx, "")
+	if err != nil {
+		return err
+	}
+	scanner.UpdateStatuses(docker.RunningByProject(all))
+
+	byProject := docker.GroupByProject(all)
+	for _, p := range scanner.ListProjects() {
+		broker.Publish(sse.ProjectStatusEvent{
+			ID:      p.ID,
+			Name:    p.Name,
+			Status:  p.Status,
+			Running: p.Running,
+			Total:   p.Total,
+			Failed:  docker.FailedServices(ctx, client, byProject[p.Name]),
+		})
+	}
+	return nil
+}
+
+// resetDemo returns the mock containers to their initial state every
+// interval, undoing what demo visitors changed
+func (a *app) resetDemo(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		a.mock.Reset()
+		if cache, ok := a.docker.(*docker.CachingClient); ok {
+			cache.Invalidate()
+		}
+		if err := reconcileStatuses(ctx, a.docker, a.scanner, a.broker); err != nil {
+			slog.Warn("Failed to compute project statuses", "error", err)
+		}
+		slog.Info("Reset demo state")
+	}
+}
+
+// healthy reports whether gosei itself is functioning, for the systemd
+// watchdog. Docker being unreachable is not a reason to restart gosei.
+func (a *app) healthy(ctx context.Context) error {
+	if backlog, capacity := a.broker.Backlog(); backlog >= capacity {
+		return fmt.Errorf("SSE broker is not draining events")
+	}
+	return nil
+}
+
+func (a *app) close() {
+	a.cancel()
+	if a.state != nil {
+		if err := a.scanner.SaveState(a.state); err != nil {
+			slog.Warn("Failed to save project state", "error", err)
+		}
+	}
+	if a.db != nil {
+		a.db.Close()
+	}
+	a.broker.Close()
+	a.docker.Close()
+	if a.scenarioDir != "" {
+		os.RemoveAll(a.scenarioDir)
+	}
+}
+
+// listen opens the server's listener: the socket passed by systemd socket
+// activation, a Unix domain socket when socketPath is set, otherwise TCP on addr
+func listen(addr, socketPath string, socketMode os.FileMode) (net.Listener, error) {
+	activated, err := systemd.Listeners()
+	if err != nil {
+		return nil, err
+	}
+	if len(activated) > 0 {
+		slog.Info("Using systemd socket activation", "sockets", len(activated))
+		for _, extra := range activated[1:] {
+			extra.Close()
+		}
+		return activated[0], nil
+	}
+
+	if socketPath == "" {
+		return net.Listen("tcp", addr)
+	}
+
+	// A socket left behind by an unclean shutdown would make Listen fail
+	if info, err := os.Stat(socketPath); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(socketPath); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socketPath, socketMode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set socket mode: %w", err)
+	}
+	return ln, nil
+}
 
-	// Create HTTP server
-	addr := fmt.Sprintf("%s:%s", *host, *port)
+// parseFileMode parses an octal file mode such as "0660"
+func parseFileMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid file mode %q", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// serve runs an HTTP server on ln until SIGINT/SIGTERM, then shuts down
+// gracefully. TLS is used when both certFile and keyFile are set.
+func serve(ln net.Listener, handler http.Handler, certFile, keyFile string) {
 	server := &http.Server{
-		Addr:         addr,
-		Handler:      router,
+		Handler:      handler,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -96,28 +834,71 @@ func main() {
 
 	// Start server in goroutine
 	go func() {
-		log.Printf("Server listening on http://%s", addr)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed: %v", err)
+		var err error
+		if certFile != "" && keyFile != "" {
+			slog.Info("Server listening", "url", listenURL(ln, "https"))
+			err = server.ServeTLS(ln, certFile, keyFile)
+		} else {
+			slog.Info("Server listening", "url", listenURL(ln, "http"))
+			err = server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			fatal("Server failed", "error", err)
 		}
 	}()
 
+	if _, err := systemd.Notify("READY=1"); err != nil {
+		slog.Warn("Failed to notify systemd", "error", err)
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
+	systemd.Notify("STOPPING=1")
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		fatal("Server forced to shutdown", "error", err)
+	}
+
+	slog.Info("Server stopped")
+}
+
+// logFlags holds the logging configuration flags
+type logFlags struct {
+	level  *string
+	format *string
+	quiet  *string
+}
+
+func addLogFlags(fs *flag.FlagSet) *logFlags {
+	return &logFlags{
+		level:  fs.String("log-level", getEnv("GOSEI_LOG_LEVEL", "info"), "Log level (debug, info, warn, error)"),
+		format: fs.String("log-format", getEnv("GOSEI_LOG_FORMAT", "text"), "Log format (text or json)"),
+		quiet:  fs.String("log-quiet-paths", getEnv("GOSEI_LOG_QUIET_PATHS", ""), "Comma-separated path prefixes whose requests are only logged at debug level (e.g. /partials/,/api/v1/events)"),
+	}
+}
+
+// setup installs the configured logger as the slog default
+func (f *logFlags) setup() {
+	logger, err := logging.New(os.Stderr, *f.level, *f.format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
+	slog.SetDefault(logger)
+}
 
-	log.Println("Server stopped")
+// fatal logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
 
 // getEnv returns an environment variable value or a default
@@ -137,33 +918,88 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return value == "true" || value == "1" || value == "yes"
 }
 
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvInt returns an environment variable as int or a default
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvFloat returns an environment variable as float64 or a default
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvDuration returns an environment variable as a duration or a default
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 // watchDockerEvents watches for Docker events and broadcasts them via SSE
-func watchDockerEvents(client docker.DockerClient, broker *sse.Broker, scanner *project.Scanner) {
+func watchDockerEvents(client docker.DockerClient, broker *sse.Broker, scanner *project.Scanner, reports *vuln.Store, history *docker.EventLog) {
 	ctx := context.Background()
+	statuses := newStatusDebouncer(projectStatusDelay, func(name string) {
+		updateProjectStatus(ctx, client, scanner, broker, name)
+	})
 
-	for {
+	for reconnected := false; ; reconnected = true {
 		events, errs := client.WatchEvents(ctx)
 
+		// Containers may have changed while no events were received
+		if reconnected {
+			if err := reconcileStatuses(ctx, client, scanner, broker); err != nil {
+				slog.Warn("Failed to compute project statuses", "error", err)
+			}
+		}
+
 		for {
 			select {
 			case event, ok := <-events:
 				if !ok {
 					goto reconnect
 				}
+				history.Add(event)
+
+				if event.Type != "container" {
+					handleResourceEvent(event, broker, reports)
+					continue
+				}
 
 				// Broadcast container status change
-				broker.BroadcastJSON("container:status", sse.ContainerStatusEvent{
+				broker.Publish(sse.ContainerStatusEvent{
 					ID:      event.ID[:12],
 					Name:    event.Name,
 					Status:  event.Action,
 					State:   mapActionToState(event.Action),
 					Project: event.Project,
 					Service: event.Service,
+					Time:    event.Timestamp,
 				})
 
 				// Update project status if this is a compose container
 				if event.Project != "" {
-					updateProjectStatus(ctx, client, scanner, broker, event.Project)
+					statuses.schedule(event.Project)
 				}
 
 			case err, ok := <-errs:
@@ -171,18 +1007,74 @@ func watchDockerEvents(client docker.DockerClient, broker *sse.Broker, scanner *
 					goto reconnect
 				}
 				if err != nil {
-					log.Printf("Docker events error: %v", err)
+					slog.Error("Docker events error", "error", err)
 					goto reconnect
 				}
 			}
 		}
 
 	reconnect:
-		log.Println("Docker events disconnected, reconnecting in 5s...")
+		slog.Warn("Docker events disconnected, reconnecting", "delay", "5s")
 		time.Sleep(5 * time.Second)
 	}
 }
 
+// projectStatusDelay coalesces the burst of events from e.g. a compose up
+// of a large stack into one project status update
+const projectStatusDelay = 500 * time.Millisecond
+
+// statusDebouncer runs at most one status update per project per delay
+type statusDebouncer struct {
+	delay   time.Duration
+	update  func(project string)
+	pending map[string]bool
+	mu      sync.Mutex
+}
+
+func newStatusDebouncer(delay time.Duration, update func(project string)) *statusDebouncer {
+	return &statusDebouncer{delay: delay, update: update, pending: make(map[string]bool)}
+}
+
+// schedule updates a project's status after the delay, unless an update is
+// already pending. Events during the update schedule another, so the last
+// state is always seen.
+func (d *statusDebouncer) schedule(project string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.pending[project] {
+		return
+	}
+	d.pending[project] = true
+	time.AfterFunc(d.delay, func() {
+		d.mu.Lock()
+		delete(d.pending, project)
+		d.mu.Unlock()
+		d.update(project)
+	})
+}
+
+// handleResourceEvent broadcasts an image, volume or network event as
+// image:event, volume:event or network:event
+func handleResourceEvent(event docker.Event, broker *sse.Broker, reports *vuln.Store) {
+	// A pulled tag may now point at a different image, and a deleted image's
+	// report is of no use
+	if event.Type == "image" {
+		switch event.Action {
+		case "pull", "delete", "untag", "tag":
+			reports.Delete(event.ID)
+		}
+	}
+
+	broker.Publish(sse.ResourceEvent{
+		Kind:      event.Type,
+		ID:        event.ID,
+		Name:      event.Name,
+		Action:    event.Action,
+		Timestamp: event.Timestamp,
+	})
+}
+
 // mapActionToState maps Docker event actions to container states
 func mapActionToState(action string) string {
 	switch action {
@@ -223,28 +1115,17 @@ func updateProjectStatus(ctx context.Context, client docker.DockerClient, scanne
 		return
 	}
 
-	running := 0
-	for _, c := range containers {
-		if c.State == "running" {
-			running++
-		}
-	}
-
-	status := "stopped"
-	if running > 0 && running == proj.Total {
-		status = "running"
-	} else if running > 0 {
-		status = "partial"
-	}
-
-	scanner.UpdateProjectStatus(proj.ID, running, status)
+	running := docker.RunningByProject(containers)[projectName]
+	scanner.UpdateProjectStatus(proj.ID, running)
+	status := project.Status(running, proj.Total)
 
 	// Broadcast update
-	broker.BroadcastJSON("project:status", sse.ProjectStatusEvent{
+	broker.Publish(sse.ProjectStatusEvent{
 		ID:      proj.ID,
 		Name:    proj.Name,
 		Status:  status,
 		Running: running,
 		Total:   proj.Total,
+		Failed:  docker.FailedServices(ctx, client, containers),
 	})
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/mqtt"
+	"github.com/lyall/gosei/internal/project"
+	"github.com/lyall/gosei/internal/sse"
+)
+
+// mqttFlags holds the MQTT publishing flags
+type mqttFlags struct {
+	broker   *string
+	clientID *string
+	username *string
+	password *string
+	prefix   *string
+	qos      *int
+
+	discovery       *bool
+	discoveryPrefix *string
+}
+
+func addMQTTFlags(fs *flag.FlagSet) *mqttFlags {
+	return &mqttFlags{
+		broker:   fs.String("mqtt-broker", getEnv("GOSEI_MQTT_BROKER", ""), "MQTT broker to publish status to, e.g. tcp://homeassistant.lan:1883"),
+		clientID: fs.String("mqtt-client-id", getEnv("GOSEI_MQTT_CLIENT_ID", ""), "MQTT client ID (default gosei-<hostname>)"),
+		username: fs.String("mqtt-username", getEnv("GOSEI_MQTT_USERNAME", ""), "MQTT username"),
+		password: fs.String("mqtt-password", "", "MQTT password (prefer GOSEI_MQTT_PASSWORD)"),
+		prefix:   fs.String("mqtt-topic-prefix", getEnv("GOSEI_MQTT_TOPIC_PREFIX", "gosei"), "Prefix of the MQTT topics published to"),
+		qos:      fs.Int("mqtt-qos", getEnvInt("GOSEI_MQTT_QOS", 0), "MQTT quality of service (0, 1 or 2)"),
+
+		discovery:       fs.Bool("mqtt-discovery", getEnvBool("GOSEI_MQTT_DISCOVERY", true), "Announce projects to Home Assistant with MQTT discovery"),
+		discoveryPrefix: fs.String("mqtt-discovery-prefix", getEnv("GOSEI_MQTT_DISCOVERY_PREFIX", "homeassistant"), "Home Assistant MQTT discovery prefix"),
+	}
+}
+
+// setup returns the MQTT publisher, or nil when no broker is configured
+func (f *mqttFlags) setup(b *sse.Broker, dc docker.DockerClient, s *project.Scanner) *mqtt.Publisher {
+	if *f.broker == "" {
+		return nil
+	}
+
+	password := *f.password
+	if password == "" {
+		// Read from the environment by default so the password stays out of ps output
+		password = os.Getenv("GOSEI_MQTT_PASSWORD")
+	}
+	clientID := *f.clientID
+	if clientID == "" {
+		host, _ := os.Hostname()
+		clientID = fmt.Sprintf("gosei-%s", host)
+	}
+	if *f.qos < 0 || *f.qos > 2 {
+		fatal("Invalid --mqtt-qos", "qos", *f.qos)
+	}
+
+	opts := mqtt.Options{
+		Broker:      *f.broker,
+		ClientID:    clientID,
+		Username:    *f.username,
+		Password:    password,
+		TopicPrefix: *f.prefix,
+		QoS:         byte(*f.qos),
+	}
+	if *f.discovery {
+		opts.DiscoveryPrefix = *f.discoveryPrefix
+	}
+	if err := opts.Validate(); err != nil {
+		fatal("Invalid MQTT configuration", "error", err)
+	}
+
+	slog.Info("MQTT status publishing enabled", "broker", *f.broker, "prefix", *f.prefix)
+	return mqtt.New(opts, b, dc, s)
+}
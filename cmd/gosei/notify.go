@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/lyall/gosei/internal/notify"
+)
+
+// notifyFlags holds the flags for the channels alert notifications go to
+type notifyFlags struct {
+	webhooks  *string
+	rulesFile *string
+
+	gotifyURL   *string
+	gotifyToken *string
+
+	pushoverToken *string
+	pushoverUser  *string
+
+	telegramToken *string
+	telegramChat  *string
+}
+
+func addNotifyFlags(fs *flag.FlagSet) *notifyFlags {
+	return &notifyFlags{
+		webhooks:  fs.String("notify-webhooks", getEnv("GOSEI_NOTIFY_WEBHOOKS", ""), "Comma-separated URLs that alert notifications are posted to as JSON"),
+		rulesFile: fs.String("notify-rules-file", getEnv("GOSEI_NOTIFY_RULES_FILE", ""), "YAML file of rules choosing which notifications are sent where"),
+
+		gotifyURL:   fs.String("notify-gotify-url", getEnv("GOSEI_NOTIFY_GOTIFY_URL", ""), "Gotify server to push alert notifications to"),
+		gotifyToken: fs.String("notify-gotify-token", "", "Gotify application token (prefer GOSEI_NOTIFY_GOTIFY_TOKEN)"),
+
+		pushoverToken: fs.String("notify-pushover-token", "", "Pushover application token (prefer GOSEI_NOTIFY_PUSHOVER_TOKEN)"),
+		pushoverUser:  fs.String("notify-pushover-user", getEnv("GOSEI_NOTIFY_PUSHOVER_USER", ""), "Pushover user or group key to push alert notifications to"),
+
+		telegramToken: fs.String("notify-telegram-token", "", "Telegram bot token (prefer GOSEI_NOTIFY_TELEGRAM_TOKEN)"),
+		telegramChat:  fs.String("notify-telegram-chat", getEnv("GOSEI_NOTIFY_TELEGRAM_CHAT", ""), "Telegram chat ID or @channel the bot sends alert notifications to"),
+	}
+}
+
+// setup returns the notification targets and rules. A provider is enabled
+// by its destination flag; its token is then required.
+func (f *notifyFlags) setup() (notify.Targets, []notify.Rule) {
+	targets := notify.Targets{
+		Webhooks: splitList(*f.webhooks),
+		Gotify:   notify.GotifyTarget{URL: *f.gotifyURL, Token: secret(*f.gotifyToken, "GOSEI_NOTIFY_GOTIFY_TOKEN")},
+		Pushover: notify.PushoverTarget{User: *f.pushoverUser, Token: secret(*f.pushoverToken, "GOSEI_NOTIFY_PUSHOVER_TOKEN")},
+		Telegram: notify.TelegramTarget{Chat: *f.telegramChat, Token: secret(*f.telegramToken, "GOSEI_NOTIFY_TELEGRAM_TOKEN")},
+	}
+
+	var rules []notify.Rule
+	if *f.rulesFile != "" {
+		var err error
+		if rules, err = notify.LoadRules(*f.rulesFile); err != nil {
+			fatal("Invalid --notify-rules-file", "error", err)
+		}
+	}
+	return targets, rules
+}
+
+// secret returns a flag value, falling back to the environment so tokens can
+// stay out of ps output
+func secret(flagValue, env string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	return os.Getenv(env)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lyall/gosei/internal/store"
+)
+
+// runRestore replaces the database in the data directory with one from a
+// backup made by GET /api/system/backup, for moving gosei to another host
+func runRestore(args []string) {
+	fs := flag.NewFlagSet("gosei restore", flag.ExitOnError)
+	dataDir := fs.String("data-dir", getEnv("GOSEI_DATA_DIR", ""), "Data directory to restore into")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gosei restore [flags] <backup.tar.gz | ->\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 || *dataDir == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var r io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gosei restore: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	manifest, err := store.Restore(*dataDir, r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gosei restore: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Restored backup of gosei %s from %s into %s\n", manifest.Version, manifest.Created.Format("2006-01-02 15:04:05 MST"), *dataDir)
+}
@@ -3,8 +3,18 @@ module github.com/lyall/gosei
 go 1.22
 
 require (
+	github.com/andybalholm/brotli v1.2.5
+	github.com/coreos/go-oidc/v3 v3.11.0
 	github.com/docker/docker v27.0.3+incompatible
+	github.com/docker/go-connections v0.5.0
+	github.com/eclipse/paho.mqtt.golang v1.4.3
 	github.com/go-chi/chi/v5 v5.1.0
+	github.com/go-jose/go-jose/v4 v4.0.2
+	github.com/gorilla/websocket v1.5.0
+	github.com/robfig/cron/v3 v3.0.1
+	go.etcd.io/bbolt v1.3.11
+	golang.org/x/oauth2 v0.21.0
+	golang.org/x/time v0.5.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -12,7 +22,6 @@ require (
 	github.com/Microsoft/go-winio v0.6.2 // indirect
 	github.com/containerd/log v0.1.0 // indirect
 	github.com/distribution/reference v0.6.0 // indirect
-	github.com/docker/go-connections v0.5.0 // indirect
 	github.com/docker/go-units v0.5.0 // indirect
 	github.com/felixge/httpsnoop v1.0.4 // indirect
 	github.com/go-logr/logr v1.4.2 // indirect
@@ -30,7 +39,9 @@ require (
 	go.opentelemetry.io/otel/metric v1.28.0 // indirect
 	go.opentelemetry.io/otel/sdk v1.28.0 // indirect
 	go.opentelemetry.io/otel/trace v1.28.0 // indirect
+	golang.org/x/crypto v0.25.0 // indirect
+	golang.org/x/net v0.27.0 // indirect
+	golang.org/x/sync v0.5.0 // indirect
 	golang.org/x/sys v0.22.0 // indirect
-	golang.org/x/time v0.5.0 // indirect
 	gotest.tools/v3 v3.5.1 // indirect
 )
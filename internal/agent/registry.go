@@ -0,0 +1,224 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Agent is a remote gosei instance running in agent mode
+type Agent struct {
+	Name     string    `json:"name"`
+	URL      string    `json:"url"`
+	Status   string    `json:"status"` // "online", "offline", "unknown"
+	Version  string    `json:"version,omitempty"`
+	LastSeen time.Time `json:"lastSeen,omitempty"`
+	Error    string    `json:"error,omitempty"`
+
+	base  *url.URL
+	token string
+	proxy *httputil.ReverseProxy
+}
+
+// Registry tracks the agents the main server manages
+type Registry struct {
+	agents map[string]*Agent
+	client *http.Client
+	mu     sync.RWMutex
+}
+
+// NewRegistry creates an empty agent registry
+func NewRegistry() *Registry {
+	return &Registry{
+		agents: make(map[string]*Agent),
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// ParseSpecs parses a comma-separated list of name=url agent definitions
+func ParseSpecs(specs string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, spec := range strings.Split(specs, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		name, rawURL, ok := strings.Cut(spec, "=")
+		if !ok || name == "" || rawURL == "" {
+			return nil, fmt.Errorf("invalid agent definition %q, expected name=url", spec)
+		}
+		result[name] = rawURL
+	}
+	return result, nil
+}
+
+// Register adds or replaces an agent
+func (r *Registry) Register(name, rawURL, token string) (*Agent, error) {
+	if name == "" {
+		return nil, fmt.Errorf("agent name is required")
+	}
+	base, err := url.Parse(strings.TrimRight(rawURL, "/"))
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid agent URL: %s", rawURL)
+	}
+
+	a := &Agent{
+		Name:   name,
+		URL:    base.String(),
+		Status: "unknown",
+		base:   base,
+		token:  token,
+	}
+	a.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(base)
+			pr.Out.Host = base.Host
+			// The agent authenticates by token; the user's session and
+			// CSRF token are for this server only
+			pr.Out.Header.Del("Cookie")
+			pr.Out.Header.Del("X-CSRF-Token")
+			pr.Out.Header.Set("Authorization", "Bearer "+token)
+		},
+		// The agent's deprecation of its unversioned /api paths, which are
+		// proxied to, isn't the client's concern
+		ModifyResponse: func(resp *http.Response) error {
+			resp.Header.Del("Deprecation")
+			resp.Header.Del("Link")
+			return nil
+		},
+		// Flush immediately so SSE streams from the agent are not buffered
+		FlushInterval: -1,
+	}
+
+	r.mu.Lock()
+	r.agents[name] = a
+	r.mu.Unlock()
+
+	return a, nil
+}
+
+// Unregister removes an agent, reporting whether it existed
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.agents[name]; !ok {
+		return false
+	}
+	delete(r.agents, name)
+	return true
+}
+
+// List returns a snapshot of all registered agents sorted by name
+func (r *Registry) List() []Agent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	agents := make([]Agent, 0, len(r.agents))
+	for _, a := range r.agents {
+		agents = append(agents, *a)
+	}
+	sort.Slice(agents, func(i, j int) bool {
+		return agents[i].Name < agents[j].Name
+	})
+	return agents
+}
+
+// Get returns a snapshot of a single agent
+func (r *Registry) Get(name string) (Agent, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	a, ok := r.agents[name]
+	if !ok {
+		return Agent{}, false
+	}
+	return *a, true
+}
+
+// GetJSON issues an authenticated GET against an agent's API and decodes the response
+func (r *Registry) GetJSON(ctx context.Context, name, path string, v interface{}) error {
+	r.mu.RLock()
+	a, ok := r.agents[name]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("agent not found: %s", name)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("agent %s unreachable: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("agent %s returned %s", name, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Proxy forwards a request to an agent's API. path is relative to the
+// agent's /api prefix.
+func (r *Registry) Proxy(w http.ResponseWriter, req *http.Request, name, path string) {
+	r.mu.RLock()
+	a, ok := r.agents[name]
+	r.mu.RUnlock()
+	if !ok {
+		http.Error(w, "Agent not found", http.StatusNotFound)
+		return
+	}
+
+	out := req.Clone(req.Context())
+	out.URL.Path = "/api/" + strings.TrimPrefix(path, "/")
+	out.URL.RawPath = ""
+	a.proxy.ServeHTTP(w, out)
+}
+
+// Monitor periodically checks every agent's health until ctx is cancelled
+func (r *Registry) Monitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.checkAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Registry) checkAll(ctx context.Context) {
+	for _, a := range r.List() {
+		var version struct {
+			Version string `json:"version"`
+		}
+		err := r.GetJSON(ctx, a.Name, "/api/system/version", &version)
+
+		r.mu.Lock()
+		if live, ok := r.agents[a.Name]; ok {
+			if err != nil {
+				live.Status = "offline"
+				live.Error = err.Error()
+			} else {
+				live.Status = "online"
+				live.Error = ""
+				live.Version = version.Version
+				live.LastSeen = time.Now()
+			}
+		}
+		r.mu.Unlock()
+	}
+}
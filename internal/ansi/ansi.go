@@ -0,0 +1,229 @@
+// Package ansi handles the ANSI escape sequences colorized programs write
+// to their logs: stripping them for plain text, or turning their colors
+// into styled spans a browser can render.
+package ansi
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Mode is how log output with escape sequences is returned
+type Mode string
+
+const (
+	// ModeKeep passes escape sequences through untouched
+	ModeKeep Mode = "keep"
+	// ModeStrip removes escape sequences
+	ModeStrip Mode = "strip"
+	// ModeSpans removes escape sequences and describes the text's styling
+	// as spans
+	ModeSpans Mode = "spans"
+)
+
+// ParseMode parses a mode name, defaulting to keep
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case "":
+		return ModeKeep, nil
+	case ModeKeep, ModeStrip, ModeSpans:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid ansi mode %q: expected keep, strip or spans", s)
+}
+
+// escape matches CSI sequences (colors, cursor movement), OSC sequences
+// (titles, hyperlinks) and two-byte escapes
+var escape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// Strip removes escape sequences from s
+func Strip(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return escape.ReplaceAllString(s, "")
+}
+
+// Span is a run of text with one style. Colors are names such as "red" or
+// "bright-red" for the 16 standard colors, or "#rrggbb".
+type Span struct {
+	Text      string `json:"text"`
+	FG        string `json:"fg,omitempty"`
+	BG        string `json:"bg,omitempty"`
+	Bold      bool   `json:"bold,omitempty"`
+	Dim       bool   `json:"dim,omitempty"`
+	Italic    bool   `json:"italic,omitempty"`
+	Underline bool   `json:"underline,omitempty"`
+}
+
+// Spans splits s into styled spans by its SGR (color and style) sequences;
+// other escape sequences are dropped. It returns nil for text without
+// escape sequences, which is a single unstyled span.
+func Spans(s string) []Span {
+	if !strings.Contains(s, "\x1b") {
+		return nil
+	}
+
+	var spans []Span
+	var style Span
+	add := func(text string) {
+		if text == "" {
+			return
+		}
+		// Sequences that don't change the style don't split the text
+		if n := len(spans); n > 0 && sameStyle(spans[n-1], style) {
+			spans[n-1].Text += text
+			return
+		}
+		style.Text = text
+		spans = append(spans, style)
+	}
+
+	last := 0
+	for _, m := range escape.FindAllStringIndex(s, -1) {
+		add(s[last:m[0]])
+		last = m[1]
+		if seq := s[m[0]:m[1]]; strings.HasPrefix(seq, "\x1b[") && strings.HasSuffix(seq, "m") {
+			style = applySGR(style, seq[2:len(seq)-1])
+		}
+	}
+	add(s[last:])
+	return spans
+}
+
+func sameStyle(a, b Span) bool {
+	a.Text, b.Text = "", ""
+	return a == b
+}
+
+var colorNames = [8]string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// applySGR applies the parameters of a Select Graphic Rendition sequence
+func applySGR(style Span, params string) Span {
+	codes := strings.Split(params, ";")
+	for i := 0; i < len(codes); i++ {
+		code, err := strconv.Atoi(codes[i])
+		if err != nil && codes[i] != "" {
+			continue
+		}
+		switch {
+		case code == 0:
+			style = Span{}
+		case code == 1:
+			style.Bold = true
+		case code == 2:
+			style.Dim = true
+		case code == 3:
+			style.Italic = true
+		case code == 4:
+			style.Underline = true
+		case code == 22:
+			style.Bold, style.Dim = false, false
+		case code == 23:
+			style.Italic = false
+		case code == 24:
+			style.Underline = false
+		case code >= 30 && code <= 37:
+			style.FG = colorNames[code-30]
+		case code >= 90 && code <= 97:
+			style.FG = "bright-" + colorNames[code-90]
+		case code == 39:
+			style.FG = ""
+		case code >= 40 && code <= 47:
+			style.BG = colorNames[code-40]
+		case code >= 100 && code <= 107:
+			style.BG = "bright-" + colorNames[code-100]
+		case code == 49:
+			style.BG = ""
+		case code == 38 || code == 48:
+			color, n := extendedColor(codes[i+1:])
+			i += n
+			if code == 38 {
+				style.FG = color
+			} else {
+				style.BG = color
+			}
+		}
+	}
+	return style
+}
+
+// extendedColor parses the arguments of a 256-color (5;n) or truecolor
+// (2;r;g;b) code, returning the color and how many arguments it used
+func extendedColor(args []string) (string, int) {
+	arg := func(i int) int {
+		if i >= len(args) {
+			return 0
+		}
+		n, _ := strconv.Atoi(args[i])
+		return min(max(n, 0), 255)
+	}
+	if len(args) == 0 {
+		return "", 0
+	}
+
+	switch args[0] {
+	case "5":
+		n := arg(1)
+		switch {
+		case n < 8:
+			return colorNames[n], 2
+		case n < 16:
+			return "bright-" + colorNames[n-8], 2
+		case n < 232:
+			// 6x6x6 color cube
+			levels := [6]int{0, 95, 135, 175, 215, 255}
+			n -= 16
+			return hex(levels[n/36], levels[n/6%6], levels[n%6]), 2
+		default:
+			gray := 8 + (n-232)*10
+			return hex(gray, gray, gray), 2
+		}
+	case "2":
+		return hex(arg(1), arg(2), arg(3)), 4
+	}
+	return "", 1
+}
+
+func hex(r, g, b int) string {
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// Class returns the CSS classes for the span's named colors and styles
+func (s Span) Class() string {
+	var classes []string
+	if s.FG != "" && !strings.HasPrefix(s.FG, "#") {
+		classes = append(classes, "ansi-"+s.FG)
+	}
+	if s.BG != "" && !strings.HasPrefix(s.BG, "#") {
+		classes = append(classes, "ansi-bg-"+s.BG)
+	}
+	for _, c := range []struct {
+		on   bool
+		name string
+	}{{s.Bold, "ansi-bold"}, {s.Dim, "ansi-dim"}, {s.Italic, "ansi-italic"}, {s.Underline, "ansi-underline"}} {
+		if c.on {
+			classes = append(classes, c.name)
+		}
+	}
+	return strings.Join(classes, " ")
+}
+
+// FGHex returns the foreground color if it is a hex color, for an inline
+// style
+func (s Span) FGHex() string {
+	if strings.HasPrefix(s.FG, "#") {
+		return s.FG
+	}
+	return ""
+}
+
+// BGHex returns the background color if it is a hex color
+func (s Span) BGHex() string {
+	if strings.HasPrefix(s.BG, "#") {
+		return s.BG
+	}
+	return ""
+}
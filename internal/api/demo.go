@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"path"
+)
+
+// demoAllowed lists the state-changing requests a public demo permits:
+// lifecycle operations, which only change the mock client's containers.
+// Everything else, such as importing projects, uploading files, switching
+// Docker contexts or registering agents, could reach beyond the mock.
+var demoAllowed = []string{
+	"/api/projects/*/up",
+	"/api/projects/*/down",
+	"/api/projects/*/start",
+	"/api/projects/*/stop",
+	"/api/projects/*/restart",
+	"/api/projects/*/pull",
+	"/api/projects/*/update",
+	"/api/projects/*/validate",
+	"/api/projects/*/operations/cancel",
+	"/api/projects/refresh",
+	"/api/compose/validate",
+	"/api/containers/*/start",
+	"/api/containers/*/stop",
+	"/api/containers/*/restart",
+	"/api/images/pull",
+	"/auth/logout",
+}
+
+// demoSandbox rejects state-changing requests outside demoAllowed, which
+// lists API paths without their version
+func demoSandbox(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isMutating(r.Method) && !demoPermits(r.URL.Path) {
+			http.Error(w, "Forbidden: disabled in demo mode", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func demoPermits(urlPath string) bool {
+	for _, pattern := range demoAllowed {
+		if ok, _ := path.Match(pattern, unversionedPath(urlPath)); ok {
+			return true
+		}
+	}
+	return false
+}
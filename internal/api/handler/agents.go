@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/agent"
+	"github.com/lyall/gosei/internal/project"
+)
+
+// LocalHost is the host name reported for projects managed by this instance
+const LocalHost = "local"
+
+// AgentHandler handles registration of and access to remote gosei agents
+type AgentHandler struct {
+	agents  *agent.Registry
+	scanner *project.Scanner
+}
+
+// NewAgentHandler creates a new agent handler
+func NewAgentHandler(reg *agent.Registry, s *project.Scanner) *AgentHandler {
+	return &AgentHandler{
+		agents:  reg,
+		scanner: s,
+	}
+}
+
+// List returns all registered agents
+func (h *AgentHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.agents.List())
+}
+
+// Register adds an agent at runtime
+func (h *AgentHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name  string `json:"name"`
+		URL   string `json:"url"`
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if req.Name == LocalHost {
+		writeError(w, http.StatusBadRequest, "Agent name is reserved: "+LocalHost)
+		return
+	}
+
+	a, err := h.agents.Register(req.Name, req.URL, req.Token)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, a)
+}
+
+// Unregister removes an agent
+func (h *AgentHandler) Unregister(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	if !h.agents.Unregister(name) {
+		writeError(w, http.StatusNotFound, "Agent not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "removed", "name": name})
+}
+
+// Projects returns projects from this instance and every reachable agent
+func (h *AgentHandler) Projects(w http.ResponseWriter, r *http.Request) {
+	var responses []ProjectResponse
+	for _, p := range h.scanner.ListProjects() {
+		resp := projectToResponse(p)
+		resp.Host = LocalHost
+		responses = append(responses, resp)
+	}
+
+	for _, a := range h.agents.List() {
+		var remote []ProjectResponse
+		if err := h.agents.GetJSON(r.Context(), a.Name, "/api/projects", &remote); err != nil {
+			slog.WarnContext(r.Context(), "Failed to list projects from agent", "agent", a.Name, "error", err)
+			continue
+		}
+		for i := range remote {
+			remote[i].Host = a.Name
+		}
+		responses = append(responses, remote...)
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+// Proxy forwards any API request to the named agent
+func (h *AgentHandler) Proxy(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Accept") == "text/event-stream" {
+		// Proxied SSE streams are long-lived like local ones
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	}
+	// The agent answers in the version negotiated here, and says so itself
+	r.Header.Set(APIVersionHeader, strconv.Itoa(APIVersion(r.Context())))
+	w.Header().Del(APIVersionHeader)
+	h.agents.Proxy(w, r, chi.URLParam(r, "name"), chi.URLParam(r, "*"))
+}
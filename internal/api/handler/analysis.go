@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/project"
+)
+
+// AnalysisHandler finds problems across projects before compose runs into
+// them
+type AnalysisHandler struct {
+	docker  docker.DockerClient
+	scanner *project.Scanner
+}
+
+// NewAnalysisHandler creates a new analysis handler
+func NewAnalysisHandler(dc docker.DockerClient, s *project.Scanner) *AnalysisHandler {
+	return &AnalysisHandler{docker: dc, scanner: s}
+}
+
+// PortConflicts lists host ports that services of the scanned projects, or
+// running containers, would publish on overlapping addresses
+func (h *AnalysisHandler) PortConflicts(w http.ResponseWriter, r *http.Request) {
+	containers, err := h.docker.ListContainers(r.Context(), "")
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "Failed to list containers: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, project.PortConflicts(h.scanner.ListProjects(), containers))
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/lyall/gosei/internal/auth"
+)
+
+// AuthHandler handles login, logout and identity requests
+type AuthHandler struct {
+	auth *auth.Manager
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(m *auth.Manager) *AuthHandler {
+	return &AuthHandler{auth: m}
+}
+
+// Login redirects the browser to the OpenID Connect issuer
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	returnTo := auth.SafeReturnTo(r.URL.Query().Get("return_to"))
+	http.Redirect(w, r, h.auth.BeginLogin(w, r, returnTo), http.StatusFound)
+}
+
+// Callback completes a login when the issuer redirects back
+func (h *AuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if errCode := q.Get("error"); errCode != "" {
+		slog.WarnContext(r.Context(), "OIDC login rejected by issuer", "error", errCode, "description", q.Get("error_description"))
+		http.Error(w, "Login failed: "+errCode, http.StatusUnauthorized)
+		return
+	}
+
+	user, returnTo, err := h.auth.FinishLogin(r.Context(), w, r, q.Get("state"), q.Get("code"))
+	if err != nil {
+		slog.WarnContext(r.Context(), "OIDC login failed", "error", err)
+		http.Error(w, "Login failed: "+err.Error(), http.StatusForbidden)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "User logged in", "user", user.Name, "role", user.Role, "provider", user.Provider)
+	h.auth.StartSession(w, r, *user)
+	http.Redirect(w, r, auth.SafeReturnTo(returnTo), http.StatusFound)
+}
+
+// Logout ends the session
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	h.auth.EndSession(w, r)
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// Me returns the logged-in user
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Not logged in")
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/autoupdate"
+	"github.com/lyall/gosei/internal/project"
+)
+
+// AutoUpdateHandler exposes the automatic update policy of projects
+type AutoUpdateHandler struct {
+	updater *autoupdate.Updater
+	scanner *project.Scanner
+}
+
+// NewAutoUpdateHandler creates a new auto-update handler
+func NewAutoUpdateHandler(u *autoupdate.Updater, s *project.Scanner) *AutoUpdateHandler {
+	return &AutoUpdateHandler{
+		updater: u,
+		scanner: s,
+	}
+}
+
+// Status returns a project's update policy, next run and recent results
+func (h *AutoUpdateHandler) Status(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.scanner.GetProject(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.updater.Status(p))
+}
+
+// Run starts an automatic update immediately; the result is broadcast as an
+// autoupdate:complete event
+func (h *AutoUpdateHandler) Run(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.scanner.GetProject(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	// Use background context since this runs after the HTTP response is sent
+	go h.updater.Update(context.Background(), p)
+
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"status":    "started",
+		"projectId": p.ID,
+	})
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/lyall/gosei/internal/store"
+)
+
+// BackupHandler serves backups of gosei's database
+type BackupHandler struct {
+	store   *store.Store
+	version string
+}
+
+// NewBackupHandler creates a new backup handler
+func NewBackupHandler(s *store.Store, version string) *BackupHandler {
+	return &BackupHandler{store: s, version: version}
+}
+
+// Backup streams a gzipped tar archive of the database, for `gosei restore`
+// on another host
+func (h *BackupHandler) Backup(w http.ResponseWriter, r *http.Request) {
+	name := fmt.Sprintf("gosei-backup-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	// A large database takes longer than the server's write timeout to
+	// send, which would cut the archive short
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	// Headers are sent with the first bytes, so a failure can only end the
+	// download short
+	if err := h.store.Backup(w, h.version); err != nil {
+		slog.Error("Backup failed", "error", err)
+	}
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lyall/gosei/internal/store"
+)
+
+// TestBackupOutlastsWriteTimeout checks that a backup still sent after the
+// server's write timeout arrives as a complete, restorable archive
+func TestBackupOutlastsWriteTimeout(t *testing.T) {
+	s, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	const writeTimeout = 50 * time.Millisecond
+	h := NewBackupHandler(s, "test")
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Stands in for a database too large to send within the timeout
+		time.Sleep(2 * writeTimeout)
+		h.Backup(w, r)
+	}))
+	srv.Config.WriteTimeout = writeTimeout
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	manifest, err := store.Restore(t.TempDir(), resp.Body)
+	if err != nil {
+		t.Fatalf("backup is incomplete: %v", err)
+	}
+	if manifest.Version != "test" {
+		t.Errorf("manifest version = %q, want test", manifest.Version)
+	}
+}
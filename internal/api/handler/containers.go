@@ -1,33 +1,56 @@
 package handler
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/ansi"
+	"github.com/lyall/gosei/internal/auth"
 	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/project"
 	"github.com/lyall/gosei/internal/sse"
+	"github.com/lyall/gosei/internal/terminal"
 )
 
 // ContainerHandler handles container-related API requests
 type ContainerHandler struct {
-	docker docker.DockerClient
-	broker *sse.Broker
+	docker    docker.DockerClient
+	broker    *sse.Broker
+	logs      *docker.LogHub
+	terminals *terminal.Registry
+	scanner   *project.Scanner
 }
 
-// NewContainerHandler creates a new container handler
-func NewContainerHandler(dc docker.DockerClient, b *sse.Broker) *ContainerHandler {
+// NewContainerHandler creates a new container handler. Exec sessions are
+// tracked in terminals; the scanner's projects are what compose containers
+// are checked against.
+func NewContainerHandler(dc docker.DockerClient, b *sse.Broker, logs *docker.LogHub, terminals *terminal.Registry, s *project.Scanner) *ContainerHandler {
 	return &ContainerHandler{
-		docker: dc,
-		broker: b,
+		docker:    dc,
+		broker:    b,
+		logs:      logs,
+		terminals: terminals,
+		scanner:   s,
 	}
 }
 
+// ContainerDetail is a container with how its published ports compare to
+// those its compose service declares, for running containers of a scanned
+// project
+type ContainerDetail struct {
+	*docker.ContainerInfo
+	PortCheck *project.PortCheck `json:"portCheck,omitempty"`
+}
+
 // List returns all containers
 func (h *ContainerHandler) List(w http.ResponseWriter, r *http.Request) {
 	projectName := r.URL.Query().Get("project")
@@ -41,6 +64,54 @@ func (h *ContainerHandler) List(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, containers)
 }
 
+// Create creates and starts a one-off container from an image that has
+// already been pulled. Compose projects remain the way to run real stacks.
+func (h *ContainerHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var opts docker.CreateOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := opts.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := h.docker.CreateContainer(r.Context(), opts)
+	if errors.Is(err, docker.ErrImageNotPresent) {
+		writeError(w, http.StatusUnprocessableEntity, err.Error()+"; pull it first with POST /api/v1/images/pull")
+		return
+	}
+	if errors.Is(err, docker.ErrNameInUse) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	slog.Info("Container created", "id", id[:12], "image", opts.Image, "name", opts.Name)
+
+	container, _ := h.docker.GetContainer(r.Context(), id)
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"status":    "created",
+		"container": container,
+	})
+}
+
+// Standalone returns the synthetic group of containers outside any compose
+// project. Its containers support the same per-container operations.
+func (h *ContainerHandler) Standalone(w http.ResponseWriter, r *http.Request) {
+	containers, err := h.docker.ListContainers(r.Context(), "")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list containers: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, docker.Standalone(containers))
+}
+
 // Get returns a specific container
 func (h *ContainerHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -51,7 +122,15 @@ func (h *ContainerHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, container)
+	detail := ContainerDetail{ContainerInfo: container}
+	if p, ok := h.scanner.GetProjectByName(container.ProjectName); ok && container.State == "running" {
+		if svc, ok := p.Service(container.ServiceName); ok {
+			check := project.CheckPorts(svc, *container)
+			detail.PortCheck = &check
+		}
+	}
+
+	writeJSON(w, http.StatusOK, detail)
 }
 
 // Start starts a container
@@ -108,39 +187,273 @@ func (h *ContainerHandler) Restart(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Logs streams container logs
-func (h *ContainerHandler) Logs(w http.ResponseWriter, r *http.Request) {
+// Exec timeouts: how long a command is waited for by default, and at most
+const (
+	defaultExecTimeout = time.Minute
+	maxExecTimeout     = 10 * time.Minute
+)
+
+// Exec runs a command in a running container without a TTY and returns
+// its exit code and captured output, for one-off tasks such as migrations.
+// ?timeout= (a duration, default 1m, at most 10m) bounds the wait; a command
+// still running then is left running in the container.
+func (h *ContainerHandler) Exec(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var opts docker.ExecOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := opts.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	timeout := defaultExecTimeout
+	if s := r.URL.Query().Get("timeout"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 || d > maxExecTimeout {
+			writeError(w, http.StatusBadRequest, "Invalid timeout: expected a duration up to "+maxExecTimeout.String())
+			return
+		}
+		timeout = d
+	}
+
+	// The command may outlast the server's usual write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 10*time.Second))
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	session := terminal.Session{Kind: terminal.KindExec, ContainerID: id, Container: id, Command: opts.Cmd[0], RemoteAddr: r.RemoteAddr}
+	if c, err := h.docker.GetContainer(ctx, id); err == nil {
+		session.ContainerID, session.Container = c.ID, c.Name
+	}
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		session.User = user.Name
+	}
+	ctx, end := h.terminals.Start(ctx, session)
+	defer end()
+
+	result, err := h.docker.ExecContainer(ctx, id, opts)
+	switch {
+	case errors.Is(context.Cause(ctx), terminal.ErrTerminated):
+		writeError(w, http.StatusConflict, "Command session was terminated by an admin; it may still be running in the container")
+		return
+	case errors.Is(err, docker.ErrContainerNotRunning):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusGatewayTimeout, "Command did not finish within "+timeout.String()+"; it may still be running in the container")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "Failed to run command: "+err.Error())
+		return
+	}
+	// Only the executable is logged: arguments may hold secrets
+	slog.InfoContext(r.Context(), "Command run in container", "container", id, "cmd", opts.Cmd[0], "exitCode", result.ExitCode, "duration", result.Duration)
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Update changes a container's restart policy or resource limits without
+// recreating it, e.g. to throttle a runaway container. The change lasts until
+// compose recreates the container from its compose file.
+func (h *ContainerHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var res docker.ContainerResources
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := res.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	warnings, err := h.docker.UpdateContainer(r.Context(), id, res)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to update container: "+err.Error())
+		return
+	}
+
+	container, _ := h.docker.GetContainer(r.Context(), id)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":    "updated",
+		"container": container,
+		"warnings":  warnings,
+	})
+}
+
+// ConnectNetwork connects a container to a network from a JSON body of
+// docker.ConnectOptions
+func (h *ContainerHandler) ConnectNetwork(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	tail := r.URL.Query().Get("tail")
-	if tail == "" {
-		tail = "100"
+
+	var opts docker.ConnectOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := opts.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.docker.ConnectNetwork(r.Context(), id, opts); err != nil {
+		writeNetworkError(w, err)
+		return
+	}
+	slog.Info("Container connected to network", "container", id, "network", opts.Network)
+
+	container, _ := h.docker.GetContainer(r.Context(), id)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":    "connected",
+		"container": container,
+	})
+}
+
+// DisconnectNetwork disconnects a container from a network; ?force=true
+// removes the endpoint even if the container is unreachable
+func (h *ContainerHandler) DisconnectNetwork(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	network := chi.URLParam(r, "network")
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+
+	if err := h.docker.DisconnectNetwork(r.Context(), id, network, force); err != nil {
+		writeNetworkError(w, err)
+		return
 	}
+	slog.Info("Container disconnected from network", "container", id, "network", network)
+
+	container, _ := h.docker.GetContainer(r.Context(), id)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":    "disconnected",
+		"container": container,
+	})
+}
 
-	follow := r.URL.Query().Get("follow") == "true"
+// writeNetworkError writes the status for a failed connect or disconnect
+func writeNetworkError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, docker.ErrNetworkNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, docker.ErrNetworkConflict):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// Logs streams container logs, with the parameters of parseLogQuery
+func (h *ContainerHandler) Logs(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	q, err := parseLogQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// If following, use SSE
-	if follow {
-		h.streamLogs(w, r, id, tail)
+	if q.follow {
+		h.streamLogs(w, r, id, q)
 		return
 	}
 
 	// Otherwise, return logs as JSON
-	logs, err := h.docker.GetContainerLogs(r.Context(), id, tail, false)
+	logs, err := h.docker.GetContainerLogs(r.Context(), id, q.tail, q.since, false)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "Failed to get logs: "+err.Error())
 		return
 	}
 	defer logs.Close()
 
-	lines := parseLogLines(logs)
+	lines := parseLogLines(logs, q.mode, q.since)
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"containerId": id,
 		"lines":       lines,
 	})
 }
 
+// logQuery holds the query parameters of container and project logs
+type logQuery struct {
+	tail   string
+	since  time.Time
+	mode   ansi.Mode
+	follow bool
+}
+
+// parseLogQuery parses the parameters of a logs request: ?tail= is a number
+// of lines or "all" (default 100); ?since= starts after a time (RFC 3339,
+// Unix seconds or a duration ago); ?ansi= keeps (default), strips or turns
+// into spans the escape sequences of colorized output; ?follow=true streams
+// log SSE events, each with its timestamp as the event ID.
+//
+// An EventSource reconnecting sends the ID of the last line it received as
+// Last-Event-ID, which takes the place of ?since=. Given a cursor, the tail
+// defaults to all, and always is on reconnect, so lines written during the
+// gap aren't missed and those already received aren't sent again.
+func parseLogQuery(r *http.Request) (logQuery, error) {
+	q := logQuery{
+		tail:   r.URL.Query().Get("tail"),
+		follow: r.URL.Query().Get("follow") == "true",
+	}
+
+	cursor := r.URL.Query().Get("since")
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		cursor, q.tail = id, "all"
+	}
+	if cursor != "" {
+		var err error
+		if q.since, err = parseSince(cursor, time.Now()); err != nil {
+			return q, errors.New("Invalid since: expected an RFC 3339 time, Unix seconds or a duration")
+		}
+		if q.tail == "" {
+			q.tail = "all"
+		}
+	}
+
+	if q.tail == "" {
+		q.tail = "100"
+	}
+	if !validTail(q.tail) {
+		return q, errors.New(`Invalid tail: expected a number of lines or "all"`)
+	}
+
+	var err error
+	q.mode, err = ansi.ParseMode(r.URL.Query().Get("ansi"))
+	return q, err
+}
+
+// validTail reports whether tail is a line count or "all", which Docker
+// accepts; it errors on anything else only once the logs are read
+func validTail(tail string) bool {
+	if tail == "all" {
+		return true
+	}
+	n, err := strconv.Atoi(tail)
+	return err == nil && n >= 0
+}
+
 // streamLogs streams logs via SSE
-func (h *ContainerHandler) streamLogs(w http.ResponseWriter, r *http.Request, id string, tail string) {
+func (h *ContainerHandler) streamLogs(w http.ResponseWriter, r *http.Request, id string, q logQuery) {
+	// Get container name
+	container, _ := h.docker.GetContainer(r.Context(), id)
+	containerID, containerName := id, id
+	if container != nil {
+		containerID, containerName = container.ID, container.Name
+	}
+
+	logs, err := h.logs.Subscribe(containerID)
+	if err != nil {
+		writeError(w, logStreamStatus(err), "Failed to follow logs: "+err.Error())
+		return
+	}
+	defer logs.Close()
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -156,58 +469,89 @@ func (h *ContainerHandler) streamLogs(w http.ResponseWriter, r *http.Request, id
 		writeError(w, http.StatusInternalServerError, "SSE not supported")
 		return
 	}
+	flusher.Flush()
+
+	err = followLogs(r.Context(), h.docker, logs, containerID, q, func(l LogLine) bool {
+		writeLogEvent(w, sse.LogLineEvent{
+			ContainerID: id,
+			Container:   containerName,
+			Line:        l.Message,
+			Spans:       l.Spans,
+			Stream:      l.Stream,
+			Timestamp:   l.Timestamp,
+		})
+		flusher.Flush()
+		return true
+	})
+	if err != nil && r.Context().Err() == nil {
+		slog.ErrorContext(r.Context(), "Error reading logs", "container", id, "error", err)
+	}
+}
 
-	logs, err := h.docker.GetContainerLogs(r.Context(), id, tail, true)
+// errLogsBehind ends a log stream whose client fell too far behind the
+// shared Docker stream; it reconnects and resumes after its last line
+var errLogsBehind = errors.New("client fell behind the log stream")
+
+// followLogs passes send a container's lines from q's tail or since cursor,
+// then those it writes from now on, received through logs, until they end,
+// send returns false or ctx is cancelled. logs is subscribed to before the
+// earlier lines are read so that none are missed in between; lines read
+// both ways are skipped the second time.
+func followLogs(ctx context.Context, dc docker.DockerClient, logs *docker.LogSubscription, id string, q logQuery, send func(LogLine) bool) error {
+	history, err := dc.GetContainerLogs(ctx, id, q.tail, q.since, false)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Failed to get logs: "+err.Error())
-		return
+		return err
 	}
-	defer logs.Close()
+	defer history.Close()
 
-	// Get container name
-	container, _ := h.docker.GetContainer(r.Context(), id)
-	containerName := id
-	if container != nil {
-		containerName = container.Name
+	last := q.since
+	scanner := docker.NewLogScanner(history)
+	for scanner.Scan() {
+		l, ok := newLogLine(scanner.Line(), q.mode)
+		if !ok || !after(l.Timestamp, q.since) {
+			continue
+		}
+		if l.Timestamp.After(last) {
+			last = l.Timestamp
+		}
+		if !send(l) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
 	}
 
-	reader := bufio.NewReader(logs)
 	for {
 		select {
-		case <-r.Context().Done():
-			return
-		default:
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if err != io.EOF {
-					log.Printf("Error reading logs: %v", err)
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-logs.Lines:
+			if !ok {
+				if logs.Dropped() {
+					return errLogsBehind
 				}
-				return
+				return nil
 			}
-
-			// Parse log line (Docker adds 8-byte header for multiplexed streams)
-			logLine := parseDockerLogLine(line)
-			if logLine == "" {
+			l, ok := newLogLine(line, q.mode)
+			if !ok || !after(l.Timestamp, last) {
 				continue
 			}
-
-			event := sse.LogLineEvent{
-				ContainerID: id,
-				Container:   containerName,
-				Line:        logLine,
-				Stream:      "stdout",
-				Timestamp:   time.Now(),
+			if !send(l) {
+				return nil
 			}
-
-			data, _ := json.Marshal(event)
-			w.Write([]byte("event: log\ndata: "))
-			w.Write(data)
-			w.Write([]byte("\n\n"))
-			flusher.Flush()
 		}
 	}
 }
 
+// logStreamStatus is the status of a failure to follow logs
+func logStreamStatus(err error) int {
+	if errors.Is(err, docker.ErrTooManyLogStreams) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
 // Stats returns container stats
 func (h *ContainerHandler) Stats(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -221,73 +565,79 @@ func (h *ContainerHandler) Stats(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, stats)
 }
 
+// AllStats returns stats for every running container in one response,
+// gathered concurrently. ?project= limits it to a project and repeated ?id=
+// (an ID prefix or name) to specific containers.
+func (h *ContainerHandler) AllStats(w http.ResponseWriter, r *http.Request) {
+	containers, err := h.docker.ListContainers(r.Context(), r.URL.Query().Get("project"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list containers: "+err.Error())
+		return
+	}
+
+	if ids := r.URL.Query()["id"]; len(ids) > 0 {
+		selected := containers[:0]
+		for _, c := range containers {
+			for _, id := range ids {
+				if id != "" && (c.Name == id || strings.HasPrefix(c.ID, id)) {
+					selected = append(selected, c)
+					break
+				}
+			}
+		}
+		containers = selected
+	}
+
+	writeJSON(w, http.StatusOK, docker.CollectStats(r.Context(), h.docker, containers))
+}
+
 // LogLine represents a parsed log line
 type LogLine struct {
-	Timestamp time.Time `json:"timestamp"`
-	Stream    string    `json:"stream"`
-	Message   string    `json:"message"`
+	Timestamp time.Time   `json:"timestamp"`
+	Stream    string      `json:"stream"`
+	Message   string      `json:"message"`
+	Spans     []ansi.Span `json:"spans,omitempty"`     // with ?ansi=spans, for colorized lines
+	Container string      `json:"container,omitempty"` // in project logs
+	Service   string      `json:"service,omitempty"`
 }
 
-// parseLogLines parses Docker log output into structured lines
-func parseLogLines(r io.Reader) []LogLine {
+// parseLogLines parses Docker log output into structured lines, skipping
+// those not after since
+func parseLogLines(r io.Reader, mode ansi.Mode, since time.Time) []LogLine {
 	var lines []LogLine
-	reader := bufio.NewReader(r)
-
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			break
+	scanner := docker.NewLogScanner(r)
+	for scanner.Scan() {
+		if line, ok := newLogLine(scanner.Line(), mode); ok && after(line.Timestamp, since) {
+			lines = append(lines, line)
 		}
-
-		logLine := parseDockerLogLine(line)
-		if logLine == "" {
-			continue
-		}
-
-		// Try to parse timestamp from the line
-		parts := strings.SplitN(logLine, " ", 2)
-		var timestamp time.Time
-		var message string
-
-		if len(parts) == 2 {
-			if t, err := time.Parse(time.RFC3339Nano, parts[0]); err == nil {
-				timestamp = t
-				message = parts[1]
-			} else {
-				timestamp = time.Now()
-				message = logLine
-			}
-		} else {
-			timestamp = time.Now()
-			message = logLine
-		}
-
-		lines = append(lines, LogLine{
-			Timestamp: timestamp,
-			Stream:    "stdout",
-			Message:   strings.TrimSuffix(message, "\n"),
-		})
 	}
-
 	return lines
 }
 
-// parseDockerLogLine removes Docker's 8-byte header from multiplexed log output
-func parseDockerLogLine(line string) string {
-	if len(line) < 8 {
-		return strings.TrimSpace(line)
-	}
+// after reports whether a line's timestamp is after a since cursor. Docker
+// includes lines at the since time, which is the last line a client has.
+func after(t, since time.Time) bool {
+	return since.IsZero() || t.After(since)
+}
 
-	// Docker multiplexed log format has an 8-byte header
-	// First byte is stream type (1=stdout, 2=stderr)
-	// Bytes 4-7 are the frame size (big-endian)
-	header := []byte(line[:8])
+// writeLogEvent writes a log SSE event, identified by its timestamp so a
+// reconnecting EventSource resumes after it
+func writeLogEvent(w io.Writer, event sse.LogLineEvent) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "id: %s\nevent: log\ndata: %s\n\n", event.Timestamp.Format(time.RFC3339Nano), data)
+}
 
-	// Check if this looks like a Docker log header
-	// Stream type should be 0, 1, or 2
-	if header[0] <= 2 && header[1] == 0 && header[2] == 0 && header[3] == 0 {
-		return strings.TrimSpace(line[8:])
+// newLogLine parses a line of Docker log output, reporting false for blank
+// lines
+func newLogLine(line docker.LogLine, mode ansi.Mode) (LogLine, bool) {
+	timestamp, message := docker.SplitLogTimestamp(line.Text)
+	l := LogLine{Timestamp: timestamp, Stream: line.Stream, Message: message}
+	switch mode {
+	case ansi.ModeStrip:
+		l.Message = ansi.Strip(message)
+	case ansi.ModeSpans:
+		l.Message = ansi.Strip(message)
+		l.Spans = ansi.Spans(message)
 	}
-
-	return strings.TrimSpace(line)
+	return l, strings.TrimSpace(ansi.Strip(message)) != ""
 }
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lyall/gosei/internal/docker"
+)
+
+// EventHandler serves the history of Docker events
+type EventHandler struct {
+	log *docker.EventLog
+}
+
+// NewEventHandler creates a new event handler
+func NewEventHandler(log *docker.EventLog) *EventHandler {
+	return &EventHandler{log: log}
+}
+
+// History returns recent Docker events, oldest first. ?since= takes an
+// RFC 3339 time, Unix seconds or a duration such as 10m (ago), like docker
+// events --since; ?type= keeps one type (container, image, volume, network).
+func (h *EventHandler) History(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		var err error
+		if since, err = parseSince(s, time.Now()); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid since: expected an RFC 3339 time, Unix seconds or a duration")
+			return
+		}
+	}
+
+	events := h.log.Since(since)
+	if typ := r.URL.Query().Get("type"); typ != "" {
+		filtered := events[:0]
+		for _, e := range events {
+			if e.Type == typ {
+				filtered = append(filtered, e)
+			}
+		}
+		events = filtered
+	}
+
+	writeJSON(w, http.StatusOK, events)
+}
+
+// parseSince parses a since parameter relative to now
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.Add(-d), nil
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/docker"
+)
+
+// maxPreviewBytes caps how much of a file is returned for preview
+const maxPreviewBytes = 64 << 10
+
+// FSResponse describes a directory listing or a file preview
+type FSResponse struct {
+	docker.FileInfo
+	Entries   []docker.FileInfo `json:"entries,omitempty"`
+	Content   string            `json:"content,omitempty"`
+	Binary    bool              `json:"binary,omitempty"`
+	Truncated bool              `json:"truncated,omitempty"`
+}
+
+// Files lists a directory or previews a small text file inside a container
+func (h *ContainerHandler) Files(w http.ResponseWriter, r *http.Request) {
+	browseFiles(w, r, h.docker, chi.URLParam(r, "id"), "/", r.URL.Query().Get("path"))
+}
+
+// browseFiles serves a listing or preview of path p inside container id.
+// Paths are relative to root, which also confines symlinks, so a volume
+// mounted in a browser container reads like its own file system.
+func browseFiles(w http.ResponseWriter, r *http.Request, dc docker.DockerClient, id, root, p string) {
+	p = path.Clean("/" + p)
+
+	info, err := dc.StatContainerPath(r.Context(), id, path.Join(root, p))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	// Follow one level of symlink so links to config directories browse naturally
+	if info.LinkTarget != "" {
+		target := info.LinkTarget
+		if !path.IsAbs(target) {
+			target = path.Join(path.Dir(p), target)
+		}
+		target = path.Clean(target)
+		if resolved, err := dc.StatContainerPath(r.Context(), id, path.Join(root, target)); err == nil {
+			p, info = target, resolved
+		}
+	}
+	info.Path = p
+	info.Name = path.Base(p)
+
+	archive, _, err := dc.CopyFromContainer(r.Context(), id, path.Join(root, p))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// Closing early aborts the transfer of anything not read
+	defer archive.Close()
+
+	resp := FSResponse{FileInfo: *info}
+	if info.IsDir {
+		resp.Entries, resp.Truncated, err = docker.ListArchiveDir(archive, p)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to list directory: "+err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	data, truncated, err := docker.ReadArchiveFile(archive, maxPreviewBytes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp.Truncated = truncated
+	if docker.IsText(data) {
+		resp.Content = string(data)
+	} else {
+		resp.Binary = true
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Download streams a path out of a container. Regular files are sent as-is;
+// directories, or any path with ?format=tar, are sent as a tar archive.
+func (h *ContainerHandler) Download(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	p := path.Clean("/" + r.URL.Query().Get("path"))
+	asTar := r.URL.Query().Get("format") == "tar"
+
+	archive, info, err := h.docker.CopyFromContainer(r.Context(), id, p)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	defer archive.Close()
+
+	// Large files take longer than the server's write timeout to send
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	name := info.Name
+	if name == "/" {
+		name = "root"
+	}
+
+	if info.IsDir || info.LinkTarget != "" || asTar {
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ".tar"}))
+		if _, err := io.Copy(w, archive); err != nil {
+			slog.Warn("Container download interrupted", "container", id, "path", p, "error", err)
+		}
+		return
+	}
+
+	tr := tar.NewReader(archive)
+	hdr, err := tr.Next()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to read archive: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(hdr.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	if _, err := io.Copy(w, tr); err != nil {
+		slog.Warn("Container download interrupted", "container", id, "path", p, "error", err)
+	}
+}
+
+// Upload writes the multipart "file" field into a directory inside a
+// container, replacing any existing file of the same name. The request is
+// subject to the server's body size limit.
+func (h *ContainerHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	dir := path.Clean("/" + r.URL.Query().Get("path"))
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Missing file: "+err.Error())
+		return
+	}
+	defer file.Close()
+
+	name := path.Base(header.Filename)
+	if name == "." || name == "/" || name == ".." {
+		writeError(w, http.StatusBadRequest, "Invalid file name")
+		return
+	}
+
+	info, err := h.docker.StatContainerPath(r.Context(), id, dir)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if !info.IsDir {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("%s is not a directory", dir))
+		return
+	}
+
+	archive := docker.SingleFileArchive(name, 0o644, file, header.Size)
+	if err := h.docker.CopyToContainer(r.Context(), id, dir, archive); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	dst := path.Join(dir, name)
+	slog.Info("Uploaded file to container", "container", id, "path", dst, "size", header.Size)
+	writeJSON(w, http.StatusOK, map[string]string{"path": dst})
+}
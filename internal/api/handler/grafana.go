@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/monitor"
+)
+
+// grafanaMetrics are the series kept per container, as metric:container
+// targets, with the value of each
+var grafanaMetrics = map[string]func(monitor.Point) float64{
+	"cpu":            func(p monitor.Point) float64 { return p.CPUPercent },
+	"memory":         func(p monitor.Point) float64 { return float64(p.MemoryUsage) },
+	"memory_percent": func(p monitor.Point) float64 { return p.MemoryPercent },
+}
+
+// grafanaMetricNames lists grafanaMetrics in the order search offers them
+var grafanaMetricNames = []string{"cpu", "memory", "memory_percent"}
+
+// GrafanaHandler serves the stats history in the protocol of Grafana's JSON
+// datasources, so container usage can be graphed without an exporter
+type GrafanaHandler struct {
+	history *monitor.History
+	events  *docker.EventLog
+}
+
+// NewGrafanaHandler creates a new Grafana handler. Docker events from
+// events, if set, are offered as annotations.
+func NewGrafanaHandler(h *monitor.History, events *docker.EventLog) *GrafanaHandler {
+	return &GrafanaHandler{history: h, events: events}
+}
+
+// grafanaRange is the time range of a query or annotation request
+type grafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// Test answers the datasource's connection test
+func (h *GrafanaHandler) Test(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// Search lists the targets that can be queried, cpu, memory (bytes) and
+// memory_percent of each container with samples, as metric:container. The
+// request's target filters them by substring.
+func (h *GrafanaHandler) Search(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Target string `json:"target"`
+	}
+	// An empty body searches for everything
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	targets := []string{}
+	for _, name := range h.history.Containers() {
+		for _, metric := range grafanaMetricNames {
+			target := metric + ":" + name
+			if strings.Contains(target, req.Target) {
+				targets = append(targets, target)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, targets)
+}
+
+// grafanaSeries is a time series as Grafana expects it, with datapoints of
+// [value, Unix milliseconds]
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// Query returns a series for each target in the request's range, averaged
+// down to at most maxDataPoints points
+func (h *GrafanaHandler) Query(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Range         grafanaRange `json:"range"`
+		MaxDataPoints int          `json:"maxDataPoints"`
+		Targets       []struct {
+			Target string `json:"target"`
+		} `json:"targets"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	series := make([]grafanaSeries, 0, len(req.Targets))
+	for _, t := range req.Targets {
+		if t.Target == "" {
+			// A panel's query that hasn't been filled in yet
+			continue
+		}
+		metric, name, _ := strings.Cut(t.Target, ":")
+		value, ok := grafanaMetrics[metric]
+		if !ok || name == "" {
+			writeError(w, http.StatusBadRequest, "Unknown target "+t.Target+": expected cpu, memory or memory_percent:<container>")
+			return
+		}
+		points := h.history.Range(name, req.Range.From, req.Range.To)
+		series = append(series, grafanaSeries{Target: t.Target, Datapoints: datapoints(points, value, req.MaxDataPoints)})
+	}
+	writeJSON(w, http.StatusOK, series)
+}
+
+// datapoints converts samples to Grafana datapoints. With more samples than
+// limit, consecutive ones are averaged.
+func datapoints(points []monitor.Point, value func(monitor.Point) float64, limit int) [][2]float64 {
+	per := 1
+	if limit > 0 && len(points) > limit {
+		per = (len(points) + limit - 1) / limit
+	}
+	result := make([][2]float64, 0, (len(points)+per-1)/per)
+	for i := 0; i < len(points); i += per {
+		group := points[i:min(i+per, len(points))]
+		var sum float64
+		for _, p := range group {
+			sum += value(p)
+		}
+		last := group[len(group)-1].Time
+		result = append(result, [2]float64{sum / float64(len(group)), float64(last.UnixMilli())})
+	}
+	return result
+}
+
+// grafanaAnnotation marks a Docker event on graphs
+type grafanaAnnotation struct {
+	Annotation json.RawMessage `json:"annotation,omitempty"`
+	Time       int64           `json:"time"`
+	Title      string          `json:"title"`
+	Text       string          `json:"text"`
+	Tags       []string        `json:"tags"`
+}
+
+// Annotations returns the container events in the request's range whose
+// container, project or action contains the annotation's query, e.g. die
+// or a project name; an empty query matches all
+func (h *GrafanaHandler) Annotations(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Range      grafanaRange    `json:"range"`
+		Annotation json.RawMessage `json:"annotation"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	var query struct {
+		Query string `json:"query"`
+	}
+	json.Unmarshal(req.Annotation, &query)
+
+	annotations := []grafanaAnnotation{}
+	if h.events != nil {
+		for _, e := range h.events.Since(req.Range.From) {
+			if e.Type != "container" || e.Timestamp.After(req.Range.To) {
+				continue
+			}
+			if q := query.Query; q != "" && !strings.Contains(e.Name, q) && !strings.Contains(e.Project, q) && !strings.Contains(e.Action, q) {
+				continue
+			}
+			tags := []string{e.Action}
+			if e.Project != "" {
+				tags = append(tags, e.Project)
+			}
+			annotations = append(annotations, grafanaAnnotation{
+				Annotation: req.Annotation,
+				Time:       e.Timestamp.UnixMilli(),
+				Title:      e.Name + " " + e.Action,
+				Text:       e.Image,
+				Tags:       tags,
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, annotations)
+}
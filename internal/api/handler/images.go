@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/sse"
+	"github.com/lyall/gosei/internal/vuln"
+)
+
+// Pulls and scans outlive the HTTP request, so they get their own deadlines
+const (
+	imagePullTimeout = 30 * time.Minute
+	imageScanTimeout = 15 * time.Minute
+)
+
+// ImageHandler handles image-related API requests
+type ImageHandler struct {
+	docker  docker.DockerClient
+	broker  *sse.Broker
+	scanner vuln.Scanner
+	reports *vuln.Store
+}
+
+// NewImageHandler creates a new image handler. scanner may be nil when
+// vulnerability scanning is not configured.
+func NewImageHandler(dc docker.DockerClient, b *sse.Broker, scanner vuln.Scanner, reports *vuln.Store) *ImageHandler {
+	return &ImageHandler{
+		docker:  dc,
+		broker:  b,
+		scanner: scanner,
+		reports: reports,
+	}
+}
+
+// Pull starts pulling an image and streams per-layer progress via SSE
+// image:progress events, followed by image:complete
+func (h *ImageHandler) Pull(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Image string               `json:"image"`
+		Auth  *docker.RegistryAuth `json:"auth,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	req.Image = strings.TrimSpace(req.Image)
+	if req.Image == "" {
+		writeError(w, http.StatusBadRequest, "Image reference is required")
+		return
+	}
+
+	pullID := newPullID()
+
+	go func() {
+		// Use background context since this runs after the HTTP response is sent
+		ctx, cancel := context.WithTimeout(context.Background(), imagePullTimeout)
+		defer cancel()
+
+		err := h.pull(ctx, pullID, req.Image, req.Auth)
+
+		message := "Pulled " + req.Image
+		if err != nil {
+			message = err.Error()
+			slog.Error("Image pull failed", "image", req.Image, "error", err)
+		} else {
+			slog.Info("Image pulled", "image", req.Image)
+		}
+
+		h.broker.Publish(sse.ImagePullCompleteEvent{
+			PullID:  pullID,
+			Image:   req.Image,
+			Success: err == nil,
+			Message: message,
+		})
+	}()
+
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"status": "started",
+		"pullId": pullID,
+		"image":  req.Image,
+	})
+}
+
+func (h *ImageHandler) pull(ctx context.Context, pullID, ref string, auth *docker.RegistryAuth) error {
+	stream, err := h.docker.PullImage(ctx, ref, auth)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	throttle := newProgressThrottle()
+	return docker.ReadPullProgress(stream, func(p docker.LayerProgress) {
+		if !throttle.allow(p) {
+			return
+		}
+		h.broker.Publish(sse.ImageProgressEvent{
+			PullID:  pullID,
+			Image:   ref,
+			Layer:   p.Layer,
+			Status:  p.Status,
+			Percent: p.Percent,
+			Current: p.Current,
+			Total:   p.Total,
+		})
+	})
+}
+
+// Scan starts a vulnerability scan of an image, which must be present
+// locally. Reports are kept by image ID, which is what the scanner is given.
+// The finished report is broadcast as an image:scan event and served by
+// ScanReport.
+func (h *ImageHandler) Scan(w http.ResponseWriter, r *http.Request) {
+	if h.scanner == nil {
+		writeError(w, http.StatusNotImplemented, "Vulnerability scanning is not enabled (see --vuln-scanner)")
+		return
+	}
+
+	image, err := h.docker.ResolveImage(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, docker.ErrImageNotFound) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	if existing, ok := h.reports.Get(image); ok && existing.Status == "scanning" {
+		writeJSON(w, http.StatusConflict, existing)
+		return
+	}
+
+	report := &vuln.Report{
+		Image:     image,
+		Scanner:   h.scanner.Name(),
+		Status:    "scanning",
+		StartedAt: time.Now(),
+	}
+	h.reports.Put(image, report)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), imageScanTimeout)
+		defer cancel()
+
+		result, err := h.scanner.Scan(ctx, image)
+		if err != nil {
+			slog.Error("Image scan failed", "image", image, "error", err)
+			result = &vuln.Report{
+				Image:   image,
+				Scanner: h.scanner.Name(),
+				Status:  "failed",
+				Error:   err.Error(),
+			}
+		} else {
+			slog.Info("Image scanned", "image", image, "vulnerabilities", result.Counts.Total())
+		}
+		result.StartedAt = report.StartedAt
+
+		// Reports are replaced rather than updated so readers never see a
+		// half-written one
+		h.reports.Put(image, result)
+		h.broker.Publish(result)
+	}()
+
+	writeJSON(w, http.StatusAccepted, report)
+}
+
+// ScanReport returns the latest vulnerability report for an image, by ID
+// or by the name of an image still present
+func (h *ImageHandler) ScanReport(w http.ResponseWriter, r *http.Request) {
+	ref := chi.URLParam(r, "id")
+	report, ok := h.reports.Get(ref)
+	if !ok {
+		if id, err := h.docker.ResolveImage(r.Context(), ref); err == nil {
+			report, ok = h.reports.Get(id)
+		}
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "Image has not been scanned")
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// History returns an image's layers with their sizes and build commands
+func (h *ImageHandler) History(w http.ResponseWriter, r *http.Request) {
+	layers, err := h.docker.GetImageHistory(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Image not found: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, layers)
+}
+
+// progressThrottle drops layer updates that would not visibly change a
+// progress bar; the engine reports every few kilobytes, which would
+// overflow the SSE broadcast queue on large pulls
+type progressThrottle struct {
+	last map[string]docker.LayerProgress
+}
+
+func newProgressThrottle() *progressThrottle {
+	return &progressThrottle{last: make(map[string]docker.LayerProgress)}
+}
+
+func (t *progressThrottle) allow(p docker.LayerProgress) bool {
+	prev, seen := t.last[p.Layer]
+	if seen && prev.Status == p.Status && p.Percent-prev.Percent < 5 && p.Percent < 100 {
+		return false
+	}
+	t.last[p.Layer] = p
+	return true
+}
+
+func newPullID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
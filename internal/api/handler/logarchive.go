@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/logarchive"
+)
+
+// LogArchiveHandler serves container logs captured to disk
+type LogArchiveHandler struct {
+	archiver *logarchive.Archiver
+}
+
+// NewLogArchiveHandler creates a new log archive handler
+func NewLogArchiveHandler(archiver *logarchive.Archiver) *LogArchiveHandler {
+	return &LogArchiveHandler{archiver: archiver}
+}
+
+// List returns the containers with archived logs
+func (h *LogArchiveHandler) List(w http.ResponseWriter, r *http.Request) {
+	archives, err := h.archiver.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list log archives: "+err.Error())
+		return
+	}
+	if archives == nil {
+		archives = []logarchive.Archive{}
+	}
+	writeJSON(w, http.StatusOK, archives)
+}
+
+// Get returns a container's archived logs as text, oldest first, one
+// "timestamp stream text" line each
+func (h *LogArchiveHandler) Get(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	// Headers must be set before the first file is copied, so a missing
+	// archive is checked for first
+	if !h.archiver.Has(name) {
+		writeError(w, http.StatusNotFound, "No archived logs for container")
+		return
+	}
+
+	// A long archive takes longer than the server's write timeout to send
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if r.URL.Query().Get("download") == "true" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ".log"}))
+	}
+	if err := h.archiver.WriteTo(w, name); err != nil && !errors.Is(err, logarchive.ErrNotFound) {
+		slog.Warn("Failed to send archived logs", "container", name, "error", err)
+	}
+}
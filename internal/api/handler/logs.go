@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"container/heap"
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/sse"
+)
+
+// logMergeWindow is how long a followed line is held back waiting for
+// earlier lines from quieter containers. Docker delivers each container's
+// lines in order, so a line is only emitted early when every container
+// has a later one pending.
+const logMergeWindow = 250 * time.Millisecond
+
+// Logs returns the logs of all of a project's containers merged in Docker
+// timestamp order, each line tagged with its service and container. It
+// takes the same parameters as container logs, with the tail per container.
+func (h *ProjectHandler) Logs(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.scanner.GetProject(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	q, err := parseLogQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	containers, err := h.docker.ListContainers(r.Context(), p.Name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list containers: "+err.Error())
+		return
+	}
+
+	if q.follow {
+		h.streamProjectLogs(w, r, containers, q)
+		return
+	}
+
+	var lines []LogLine
+	for _, c := range containers {
+		logs, err := h.docker.GetContainerLogs(r.Context(), c.ID, q.tail, q.since, false)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to get logs of "+c.Name+": "+err.Error())
+			return
+		}
+		for _, l := range parseLogLines(logs, q.mode, q.since) {
+			l.Container, l.Service = c.Name, c.ServiceName
+			lines = append(lines, l)
+		}
+		logs.Close()
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Timestamp.Before(lines[j].Timestamp) })
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"projectId": p.ID,
+		"lines":     lines,
+	})
+}
+
+// streamProjectLogs follows the logs of containers over SSE, merging them
+// by timestamp
+func (h *ProjectHandler) streamProjectLogs(w http.ResponseWriter, r *http.Request, containers []docker.ContainerInfo, q logQuery) {
+	subs := make([]*docker.LogSubscription, 0, len(containers))
+	defer func() {
+		for _, sub := range subs {
+			sub.Close()
+		}
+	}()
+	for _, c := range containers {
+		sub, err := h.logs.Subscribe(c.ID)
+		if err != nil {
+			writeError(w, logStreamStatus(err), "Failed to follow logs of "+c.Name+": "+err.Error())
+			return
+		}
+		subs = append(subs, sub)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "SSE not supported")
+		return
+	}
+
+	ctx := r.Context()
+	lines := make(chan pendingLog, 256)
+	finished := make(chan int, len(containers))
+	for i, c := range containers {
+		go followContainerLogs(ctx, h.docker, subs[i], i, c, q, lines, finished)
+	}
+	flusher.Flush()
+
+	m := newLogMerger(len(containers))
+	ticker := time.NewTicker(logMergeWindow / 5)
+	defer ticker.Stop()
+
+	for m.open > 0 || m.Len() > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case l := <-lines:
+			m.add(l)
+		case source := <-finished:
+			m.finish(source)
+			// Its last lines were sent before it finished
+			for drained := false; !drained; {
+				select {
+				case l := <-lines:
+					m.add(l)
+				default:
+					drained = true
+				}
+			}
+		case <-ticker.C:
+		}
+
+		for _, event := range m.ready(time.Now()) {
+			writeLogEvent(w, event)
+		}
+		flusher.Flush()
+	}
+}
+
+// followContainerLogs sends the followed log lines of a container to lines,
+// then reports the container as finished
+func followContainerLogs(ctx context.Context, dc docker.DockerClient, logs *docker.LogSubscription, source int, c docker.ContainerInfo, q logQuery, lines chan<- pendingLog, finished chan<- int) {
+	defer func() { finished <- source }()
+
+	err := followLogs(ctx, dc, logs, c.ID, q, func(l LogLine) bool {
+		event := sse.LogLineEvent{
+			ContainerID: shortID(c.ID),
+			Container:   c.Name,
+			Service:     c.ServiceName,
+			Line:        l.Message,
+			Spans:       l.Spans,
+			Stream:      l.Stream,
+			Timestamp:   l.Timestamp,
+		}
+		select {
+		case lines <- pendingLog{event: event, source: source, arrived: time.Now()}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		slog.ErrorContext(ctx, "Error reading logs", "container", c.Name, "error", err)
+	}
+}
+
+func shortID(id string) string {
+	return id[:min(12, len(id))]
+}
+
+// pendingLog is a followed line waiting to be emitted in order
+type pendingLog struct {
+	event   sse.LogLineEvent
+	source  int
+	arrived time.Time
+}
+
+// logMerger orders lines from several sources, each in order itself, by
+// timestamp. It is a min-heap of pending lines.
+type logMerger struct {
+	pending []pendingLog
+	count   []int  // pending lines per source
+	done    []bool // sources that have finished
+	open    int
+}
+
+func newLogMerger(sources int) *logMerger {
+	return &logMerger{count: make([]int, sources), done: make([]bool, sources), open: sources}
+}
+
+func (m *logMerger) Len() int { return len(m.pending) }
+func (m *logMerger) Less(i, j int) bool {
+	return m.pending[i].event.Timestamp.Before(m.pending[j].event.Timestamp)
+}
+func (m *logMerger) Swap(i, j int) { m.pending[i], m.pending[j] = m.pending[j], m.pending[i] }
+func (m *logMerger) Push(x any)    { m.pending = append(m.pending, x.(pendingLog)) }
+func (m *logMerger) Pop() any {
+	l := m.pending[len(m.pending)-1]
+	m.pending = m.pending[:len(m.pending)-1]
+	return l
+}
+
+func (m *logMerger) add(l pendingLog) {
+	heap.Push(m, l)
+	m.count[l.source]++
+}
+
+func (m *logMerger) finish(source int) {
+	if !m.done[source] {
+		m.done[source] = true
+		m.open--
+	}
+}
+
+// ready pops the lines that can be emitted: the earliest line once no open
+// source can still deliver an earlier one, or once it has waited out the
+// merge window
+func (m *logMerger) ready(now time.Time) []sse.LogLineEvent {
+	var events []sse.LogLineEvent
+	for m.Len() > 0 {
+		if !m.complete() && now.Sub(m.pending[0].arrived) < logMergeWindow {
+			break
+		}
+		l := heap.Pop(m).(pendingLog)
+		m.count[l.source]--
+		events = append(events, l.event)
+	}
+	return events
+}
+
+// complete reports whether every open source has a line pending
+func (m *logMerger) complete() bool {
+	for source, n := range m.count {
+		if n == 0 && !m.done[source] {
+			return false
+		}
+	}
+	return true
+}
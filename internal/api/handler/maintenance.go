@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/auth"
+	"github.com/lyall/gosei/internal/maintenance"
+	"github.com/lyall/gosei/internal/project"
+)
+
+// MaintenanceHandler manages maintenance windows
+type MaintenanceHandler struct {
+	windows *maintenance.Manager
+	scanner *project.Scanner
+}
+
+// NewMaintenanceHandler creates a new maintenance handler
+func NewMaintenanceHandler(m *maintenance.Manager, s *project.Scanner) *MaintenanceHandler {
+	return &MaintenanceHandler{windows: m, scanner: s}
+}
+
+// windowRequest is the body of a request for a maintenance window. It ends
+// at end, or after duration.
+type windowRequest struct {
+	ProjectID string    `json:"projectId"` // empty for every project
+	Start     time.Time `json:"start"`     // now when omitted
+	End       time.Time `json:"end"`
+	Duration  string    `json:"duration"` // e.g. 2h
+	Reason    string    `json:"reason"`
+}
+
+// List returns the current and upcoming maintenance windows
+func (h *MaintenanceHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.windows.List())
+}
+
+// Create adds a maintenance window for one project or all of them
+func (h *MaintenanceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req windowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	window := maintenance.Window{Start: req.Start, End: req.End, Reason: req.Reason}
+	if window.Start.IsZero() {
+		window.Start = time.Now()
+	}
+	if req.Duration != "" {
+		if !req.End.IsZero() {
+			writeError(w, http.StatusBadRequest, "Set end or duration, not both")
+			return
+		}
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid duration: "+err.Error())
+			return
+		}
+		window.End = window.Start.Add(d)
+	}
+	if req.ProjectID != "" {
+		p, ok := h.scanner.GetProject(req.ProjectID)
+		if !ok {
+			writeError(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		window.ProjectID, window.Project = p.ID, p.Name
+	}
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		window.CreatedBy = user.Name
+	}
+
+	window, err := h.windows.Add(window)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, window)
+}
+
+// Delete ends a maintenance window early, or cancels an upcoming one
+func (h *MaintenanceHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	err := h.windows.Remove(chi.URLParam(r, "id"))
+	switch {
+	case errors.Is(err, maintenance.ErrNotFound):
+		writeError(w, http.StatusNotFound, "Maintenance window not found")
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
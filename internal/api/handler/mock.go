@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/lyall/gosei/internal/docker"
+)
+
+// MockHandler injects faults into the mock Docker client in mock mode
+type MockHandler struct {
+	mock *docker.MockClient
+}
+
+// NewMockHandler creates a new mock fault handler
+func NewMockHandler(mock *docker.MockClient) *MockHandler {
+	return &MockHandler{mock: mock}
+}
+
+// Faults returns the injected faults
+func (h *MockHandler) Faults(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.mock.Faults())
+}
+
+// SetFaults replaces the injected faults; an empty body clears them
+func (h *MockHandler) SetFaults(w http.ResponseWriter, r *http.Request) {
+	var faults docker.MockFaults
+	if err := json.NewDecoder(r.Body).Decode(&faults); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if faults.Slowdown < 0 {
+		writeError(w, http.StatusBadRequest, "slowdown must not be negative")
+		return
+	}
+
+	h.mock.SetFaults(faults)
+	writeJSON(w, http.StatusOK, h.mock.Faults())
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/project"
+)
+
+// ProjectNetwork is a network a project's services join, with every
+// container attached to it, including those of other projects
+type ProjectNetwork struct {
+	project.Resource
+	Subnets    []docker.NetworkSubnet `json:"subnets,omitempty"`
+	Containers []NetworkMember        `json:"containers"`
+}
+
+// NetworkMember is a container on a network, with the addresses and names
+// other containers on it reach it by
+type NetworkMember struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Project     string   `json:"project,omitempty"`
+	Service     string   `json:"service,omitempty"`
+	IPAddress   string   `json:"ipAddress,omitempty"`
+	IPv6Address string   `json:"ipv6Address,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+}
+
+// Networks returns the project's networks with the containers attached,
+// their addresses and aliases, for diagnosing service-to-service
+// connectivity
+func (h *ProjectHandler) Networks(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.scanner.GetProject(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	networks, err := projectNetworks(r.Context(), h.docker, p)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to inspect networks: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, networks)
+}
+
+// projectNetworks inspects the networks the project's services join. A
+// network that doesn't exist yet is returned without containers. Aliases
+// come from inspecting each attached container.
+func projectNetworks(ctx context.Context, dc docker.DockerClient, p *project.Project) ([]ProjectNetwork, error) {
+	containers := make(map[string]*docker.ContainerInfo) // by ID, inspected once across networks
+	networks := []ProjectNetwork{}
+	for _, res := range p.JoinedNetworks() {
+		pn := ProjectNetwork{Resource: res, Containers: []NetworkMember{}}
+		exists := true
+		detail, err := dc.InspectNetwork(ctx, res.Name)
+		if errors.Is(err, docker.ErrNetworkNotFound) {
+			exists = false
+		} else if err != nil {
+			return nil, err
+		}
+		pn.Exists = &exists
+
+		if detail != nil {
+			pn.Subnets = detail.Subnets
+			for _, ep := range detail.Containers {
+				member := NetworkMember{ID: ep.ID, Name: ep.Name, IPAddress: ep.IPAddress, IPv6Address: ep.IPv6Address}
+				c, seen := containers[ep.ID]
+				if !seen {
+					// A container that went away meanwhile is listed without names
+					c, _ = dc.GetContainer(ctx, ep.ID)
+					containers[ep.ID] = c
+				}
+				if c != nil {
+					member.Project, member.Service = c.ProjectName, c.ServiceName
+					for _, a := range c.Networks {
+						if a.Network == detail.Name {
+							member.Aliases = a.Aliases
+						}
+					}
+				}
+				pn.Containers = append(pn.Containers, member)
+			}
+		}
+		networks = append(networks, pn)
+	}
+	return networks, nil
+}
@@ -4,12 +4,20 @@ import (
 	"context"
 	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/ansi"
+	"github.com/lyall/gosei/internal/auth"
+	"github.com/lyall/gosei/internal/csrf"
 	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/probe"
 	"github.com/lyall/gosei/internal/project"
+	"github.com/lyall/gosei/internal/vuln"
 	"github.com/lyall/gosei/web"
 )
 
@@ -22,11 +30,12 @@ type PageHandler struct {
 }
 
 // NewPageHandler creates a new page handler
-func NewPageHandler(dc docker.DockerClient, s *project.Scanner, version string) *PageHandler {
+func NewPageHandler(dc docker.DockerClient, s *project.Scanner, reports *vuln.Store, prober *probe.Prober, version string) *PageHandler {
 	// Parse templates
-	tmpl, err := template.New("").Funcs(templateFuncs()).ParseFS(web.TemplatesFS(), "templates/**/*.html")
+	tmpl, err := template.New("").Funcs(templateFuncs(reports, prober)).ParseFS(web.TemplatesFS(), "templates/**/*.html")
 	if err != nil {
-		log.Fatalf("Failed to parse templates: %v", err)
+		slog.Error("Failed to parse templates", "error", err)
+		os.Exit(1)
 	}
 
 	return &PageHandler{
@@ -38,8 +47,13 @@ func NewPageHandler(dc docker.DockerClient, s *project.Scanner, version string)
 }
 
 // templateFuncs returns custom template functions
-func templateFuncs() template.FuncMap {
+func templateFuncs(reports *vuln.Store, prober *probe.Prober) template.FuncMap {
 	return template.FuncMap{
+		"vulnReport": func(imageID string) *vuln.Report {
+			r, _ := reports.Get(imageID)
+			return r
+		},
+		"probeResult": prober.Lookup,
 		"statusClass": func(status string) string {
 			switch status {
 			case "running":
@@ -78,68 +92,116 @@ func templateFuncs() template.FuncMap {
 				return "state-exited"
 			}
 		},
-		"formatBytes": func(bytes uint64) string {
-			const unit = 1024
-			if bytes < unit {
-				return fmt.Sprintf("%d B", bytes)
-			}
-			div, exp := uint64(unit), 0
-			for n := bytes / unit; n >= unit; n /= unit {
-				div *= unit
-				exp++
+		"formatBytes": formatBytes,
+		"formatSize": func(bytes int64) string {
+			return formatBytes(uint64(bytes))
+		},
+		"percentOf": func(part, total int64) float64 {
+			if total <= 0 {
+				return 0
 			}
-			return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+			return float64(part) * 100 / float64(total)
 		},
 		"formatPercent": func(percent float64) string {
 			return fmt.Sprintf("%.1f%%", percent)
 		},
+		"join": strings.Join,
 	}
 }
 
+func formatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := uint64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
 // PageData holds common page data
 type PageData struct {
-	Title      string
-	Version    string
-	Projects   []*project.Project
-	Project    *project.Project
-	Container  *docker.ContainerInfo
+	Title     string
+	Version   string
+	Projects  []*project.Project
+	Project   *project.Project
+	Container *docker.ContainerInfo
+	// Readme is the project's rendered README.md, already sanitized
+	Readme template.HTML
+	// Preflight lists what the project lacks to come up
+	Preflight []project.PreflightIssue
+	// Networks are the project's networks with the containers on them
+	Networks   []ProjectNetwork
 	Containers []docker.ContainerInfo
+	Standalone *docker.ContainerGroup
 	ShowLogs   bool
+	// ShowStandalone selects the standalone page; the dashboard also sets
+	// Standalone for its summary card
+	ShowStandalone bool
+	CSRFToken      string
+	User           *auth.User
 }
 
-func (h *PageHandler) updateProjectStatuses(ctx context.Context, projects []*project.Project) {
-	for _, p := range projects {
-		containers, err := h.docker.ListContainers(ctx, p.Name)
-		if err != nil {
-			continue
-		}
-		running := 0
-		for _, c := range containers {
-			if c.State == "running" {
-				running++
-			}
-		}
-		p.Running = running
-		switch {
-		case running == 0:
-			p.Status = "stopped"
-		case running == p.Total:
-			p.Status = "running"
-		default:
-			p.Status = "partial"
-		}
+// currentUser returns the logged-in user, or nil when auth is disabled
+func currentUser(r *http.Request) *auth.User {
+	user, _ := auth.UserFromContext(r.Context())
+	return user
+}
+
+// projectList returns the projects with fresh statuses and the standalone
+// group, all from one container listing
+func (h *PageHandler) projectList(ctx context.Context) ([]*project.Project, *docker.ContainerGroup) {
+	all, err := updateAllStatuses(ctx, h.docker, h.scanner)
+	projects := h.scanner.ListProjects()
+	if err != nil {
+		return projects, nil
+	}
+	return projects, docker.Standalone(all)
+}
+
+// standalone returns the group of containers outside compose projects, or
+// nil when it can't be listed
+func (h *PageHandler) standalone(ctx context.Context) *docker.ContainerGroup {
+	containers, err := h.docker.ListContainers(ctx, "")
+	if err != nil {
+		return nil
 	}
+	return docker.Standalone(containers)
 }
 
 // Dashboard renders the main dashboard
 func (h *PageHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
-	projects := h.scanner.ListProjects()
-	h.updateProjectStatuses(r.Context(), projects)
+	projects, standalone := h.projectList(r.Context())
+
+	h.render(w, "base.html", PageData{
+		Title:      "Dashboard",
+		Version:    h.version,
+		Projects:   projects,
+		Standalone: standalone,
+		CSRFToken:  csrf.Token(r.Context()),
+		User:       currentUser(r),
+	})
+}
+
+// Standalone renders the containers that no compose project manages
+func (h *PageHandler) Standalone(w http.ResponseWriter, r *http.Request) {
+	group := h.standalone(r.Context())
+	if group == nil {
+		http.Error(w, "Failed to list containers", http.StatusInternalServerError)
+		return
+	}
 
 	h.render(w, "base.html", PageData{
-		Title:    "Dashboard",
-		Version:  h.version,
-		Projects: projects,
+		Title:          group.Name,
+		Version:        h.version,
+		Standalone:     group,
+		Containers:     group.Containers,
+		ShowStandalone: true,
+		CSRFToken:      csrf.Token(r.Context()),
+		User:           currentUser(r),
 	})
 }
 
@@ -160,6 +222,17 @@ func (h *PageHandler) ProjectDetail(w http.ResponseWriter, r *http.Request) {
 		Version:    h.version,
 		Project:    p,
 		Containers: containers,
+		CSRFToken:  csrf.Token(r.Context()),
+		User:       currentUser(r),
+	}
+	if readme, err := project.ReadReadme(p); err == nil {
+		data.Readme = template.HTML(readme.HTML)
+	}
+	if networks, volumes, err := hostResources(r.Context(), h.docker); err == nil {
+		data.Preflight = project.Preflight(p, networks, volumes).Issues
+	}
+	if networks, err := projectNetworks(r.Context(), h.docker, p); err == nil {
+		data.Networks = networks
 	}
 
 	h.render(w, "base.html", data)
@@ -179,6 +252,8 @@ func (h *PageHandler) ContainerDetail(w http.ResponseWriter, r *http.Request) {
 		Title:     container.Name,
 		Version:   h.version,
 		Container: container,
+		CSRFToken: csrf.Token(r.Context()),
+		User:      currentUser(r),
 	}
 
 	h.render(w, "base.html", data)
@@ -199,6 +274,8 @@ func (h *PageHandler) ContainerLogs(w http.ResponseWriter, r *http.Request) {
 		Version:   h.version,
 		Container: container,
 		ShowLogs:  true,
+		CSRFToken: csrf.Token(r.Context()),
+		User:      currentUser(r),
 	}
 
 	h.render(w, "base.html", data)
@@ -206,9 +283,8 @@ func (h *PageHandler) ContainerLogs(w http.ResponseWriter, r *http.Request) {
 
 // ProjectsPartial renders just the projects list
 func (h *PageHandler) ProjectsPartial(w http.ResponseWriter, r *http.Request) {
-	projects := h.scanner.ListProjects()
-	h.updateProjectStatuses(r.Context(), projects)
-	h.renderPartial(w, "partials/project-list.html", PageData{Projects: projects})
+	projects, standalone := h.projectList(r.Context())
+	h.renderPartial(w, "partials/project-list.html", PageData{Projects: projects, Standalone: standalone})
 }
 
 // ProjectDetailPartial renders just the project detail
@@ -249,6 +325,18 @@ func (h *PageHandler) ProjectContainersPartial(w http.ResponseWriter, r *http.Re
 	})
 }
 
+// StandaloneContainersPartial renders the containers section of the
+// standalone page
+func (h *PageHandler) StandaloneContainersPartial(w http.ResponseWriter, r *http.Request) {
+	group := h.standalone(r.Context())
+	if group == nil {
+		http.Error(w, "Failed to list containers", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderPartial(w, "partials/containers-section.html", PageData{Containers: group.Containers})
+}
+
 // ContainerActionsPartial renders just the container actions
 func (h *PageHandler) ContainerActionsPartial(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -273,14 +361,14 @@ func (h *PageHandler) ContainerLogsContent(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Get last 100 lines
-	logs, err := h.docker.GetContainerLogs(r.Context(), id, "100", false)
+	logs, err := h.docker.GetContainerLogs(r.Context(), id, "100", time.Time{}, false)
 	if err != nil {
 		http.Error(w, "Failed to get logs", http.StatusInternalServerError)
 		return
 	}
 	defer logs.Close()
 
-	lines := parseLogLines(logs)
+	lines := parseLogLines(logs, ansi.ModeSpans, time.Time{})
 
 	data := struct {
 		Container *docker.ContainerInfo
@@ -293,10 +381,34 @@ func (h *PageHandler) ContainerLogsContent(w http.ResponseWriter, r *http.Reques
 	h.renderPartial(w, "partials/logs-content.html", data)
 }
 
+// VulnBadgePartial renders the vulnerability badge for an image
+func (h *PageHandler) VulnBadgePartial(w http.ResponseWriter, r *http.Request) {
+	h.renderPartial(w, "partials/vuln-badge.html", chi.URLParam(r, "id"))
+}
+
+// ImageHistoryPartial renders an image's layer breakdown
+func (h *PageHandler) ImageHistoryPartial(w http.ResponseWriter, r *http.Request) {
+	layers, err := h.docker.GetImageHistory(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+
+	var total int64
+	for _, l := range layers {
+		total += l.Size
+	}
+
+	h.renderPartial(w, "partials/image-history.html", struct {
+		Layers []docker.ImageLayer
+		Total  int64
+	}{layers, total})
+}
+
 func (h *PageHandler) render(w http.ResponseWriter, name string, data any) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := h.templates.ExecuteTemplate(w, name, data); err != nil {
-		log.Printf("Failed to render template %s: %v", name, err)
+		slog.Error("Failed to render template", "template", name, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/probe"
+	"github.com/lyall/gosei/internal/project"
+)
+
+// ProbeHandler exposes the health URL checks of services
+type ProbeHandler struct {
+	prober  *probe.Prober
+	scanner *project.Scanner
+}
+
+// NewProbeHandler creates a new probe handler
+func NewProbeHandler(p *probe.Prober, s *project.Scanner) *ProbeHandler {
+	return &ProbeHandler{prober: p, scanner: s}
+}
+
+// List returns the latest result of every service's health URL
+func (h *ProbeHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.prober.Results(""))
+}
+
+// Project returns the latest results for a project's services
+func (h *ProbeHandler) Project(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.scanner.GetProject(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.prober.Results(p.ID))
+}
+
+// Set sets a service's health URL from a JSON probe.Target, overriding its
+// gosei.healthcheck.url label
+func (h *ProbeHandler) Set(w http.ResponseWriter, r *http.Request) {
+	p, service, ok := h.service(w, r)
+	if !ok {
+		return
+	}
+	var t probe.Target
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := t.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.prober.SetTarget(p.ID, service, t); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// Remove removes a health URL set through the API
+func (h *ProbeHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	p, service, ok := h.service(w, r)
+	if !ok {
+		return
+	}
+	err := h.prober.RemoveTarget(p.ID, service)
+	switch {
+	case errors.Is(err, probe.ErrNotFound):
+		writeError(w, http.StatusNotFound, "No health URL set for this service")
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// service looks up the project and service of the request, writing a 404
+// if either doesn't exist
+func (h *ProbeHandler) service(w http.ResponseWriter, r *http.Request) (*project.Project, string, bool) {
+	p, ok := h.scanner.GetProject(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Project not found")
+		return nil, "", false
+	}
+	service := chi.URLParam(r, "service")
+	if !slices.ContainsFunc(p.Services, func(s project.ServiceInfo) bool { return s.Name == service }) {
+		writeError(w, http.StatusNotFound, "Service not found")
+		return nil, "", false
+	}
+	return p, service, true
+}
@@ -1,13 +1,22 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/maintenance"
 	"github.com/lyall/gosei/internal/project"
 	"github.com/lyall/gosei/internal/sse"
 )
@@ -18,28 +27,58 @@ type ProjectHandler struct {
 	compose docker.ComposeExecutor
 	scanner *project.Scanner
 	broker  *sse.Broker
+	timeout time.Duration
+	logs    *docker.LogHub
+	windows *maintenance.Manager
+
+	running map[string]*runningOperation // by project ID
+	mu      sync.Mutex
+}
+
+// runningOperation is a compose operation that can be cancelled
+type runningOperation struct {
+	operation string
+	started   time.Time
+	cancel    context.CancelFunc
 }
 
-// NewProjectHandler creates a new project handler
-func NewProjectHandler(dc docker.DockerClient, cc docker.ComposeExecutor, s *project.Scanner, b *sse.Broker) *ProjectHandler {
+// NewProjectHandler creates a new project handler. Compose operations are
+// cancelled after timeout; zero means no limit. Followed logs are shared
+// through logs. windows may be nil when maintenance windows aren't kept.
+func NewProjectHandler(dc docker.DockerClient, cc docker.ComposeExecutor, s *project.Scanner, b *sse.Broker, timeout time.Duration, logs *docker.LogHub, windows *maintenance.Manager) *ProjectHandler {
 	return &ProjectHandler{
 		docker:  dc,
 		compose: cc,
 		scanner: s,
 		broker:  b,
+		timeout: timeout,
+		logs:    logs,
+		windows: windows,
+		running: make(map[string]*runningOperation),
 	}
 }
 
 // ProjectResponse represents a project in API responses
 type ProjectResponse struct {
-	ID         string                 `json:"id"`
-	Name       string                 `json:"name"`
-	Path       string                 `json:"path"`
-	Status     string                 `json:"status"`
-	Running    int                    `json:"running"`
-	Total      int                    `json:"total"`
-	Services   []project.ServiceInfo  `json:"services"`
-	Containers []docker.ContainerInfo `json:"containers,omitempty"`
+	ID             string                   `json:"id"`
+	Name           string                   `json:"name"`
+	Host           string                   `json:"host,omitempty"`
+	Path           string                   `json:"path"`
+	Status         string                   `json:"status"`
+	Running        int                      `json:"running"`
+	Total          int                      `json:"total"`
+	Services       []project.ServiceInfo    `json:"services"`
+	Links          []project.Link           `json:"links,omitempty"`
+	Networks       []project.Resource       `json:"networks,omitempty"`
+	Volumes        []project.Resource       `json:"volumes,omitempty"`
+	Containers     []docker.ContainerInfo   `json:"containers,omitempty"`
+	Warnings       []project.Issue          `json:"warnings,omitempty"`
+	Conflicts      []project.Conflict       `json:"conflicts,omitempty"` // compose files ignored in the project directory
+	LastOperation  *project.Operation       `json:"lastOperation,omitempty"`
+	Failed         []docker.ServiceExit     `json:"failed,omitempty"`         // services whose container exited non-zero
+	PortMismatches []project.PortCheck      `json:"portMismatches,omitempty"` // running containers not publishing the declared ports
+	Preflight      []project.PreflightIssue `json:"preflight,omitempty"`      // what up would fail for lacking
+	Maintenance    *maintenance.Window      `json:"maintenance,omitempty"`    // the window the project is in now
 }
 
 // List returns all projects
@@ -47,13 +86,11 @@ func (h *ProjectHandler) List(w http.ResponseWriter, r *http.Request) {
 	projects := h.scanner.ListProjects()
 
 	// Update project status from running containers
-	for _, p := range projects {
-		h.updateProjectStatus(r.Context(), p)
-	}
-
+	failed := h.updateProjectStatuses(r.Context(), projects)
 	responses := make([]ProjectResponse, len(projects))
 	for i, p := range projects {
-		responses[i] = projectToResponse(p)
+		responses[i] = h.response(p)
+		responses[i].Failed = failed[i]
 	}
 
 	writeJSON(w, http.StatusOK, responses)
@@ -69,23 +106,79 @@ func (h *ProjectHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.updateProjectStatus(r.Context(), p)
+	failed := h.updateProjectStatus(r.Context(), p)
 
 	// Get containers for this project
 	containers, err := h.docker.ListContainers(r.Context(), p.Name)
 	if err != nil {
-		log.Printf("Failed to list containers for project %s: %v", p.Name, err)
+		slog.ErrorContext(r.Context(), "Failed to list containers", "project", p.Name, "error", err)
 	}
 
-	resp := projectToResponse(p)
+	resp := h.response(p)
 	resp.Containers = containers
+	resp.Failed = failed
+	resp.PortMismatches = project.PortMismatches(p, containers)
+	// Networks and volumes are left unchecked if Docker can't list them
+	if networks, volumes, err := hostResources(r.Context(), h.docker); err != nil {
+		slog.WarnContext(r.Context(), "Failed to list networks and volumes", "error", err)
+	} else {
+		resp.Networks = project.WithExistence(p.Networks, networks)
+		resp.Volumes = project.WithExistence(p.Volumes, volumes)
+		resp.Preflight = project.Preflight(p, networks, volumes).Issues
+	}
 
 	writeJSON(w, http.StatusOK, resp)
 }
 
-// Up runs docker compose up for a project
+// Up runs docker compose up for a project. An optional JSON body of
+// docker.UpOptions sets the pull policy, build and recreate flags.
 func (h *ProjectHandler) Up(w http.ResponseWriter, r *http.Request) {
-	h.runComposeOperation(w, r, "up", h.compose.Up)
+	var opts docker.UpOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := opts.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.compose.Info().CheckUp(opts); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.runComposeOperation(w, r, "up", func(ctx context.Context, projectDir string, outputCh chan<- docker.ComposeOutput) (*docker.ComposeResult, error) {
+		return h.compose.Up(ctx, projectDir, opts, outputCh)
+	})
+}
+
+// ProjectStats is the combined resource usage of a project's containers
+type ProjectStats struct {
+	ProjectID  string                  `json:"projectId"`
+	Total      docker.StatsSummary     `json:"total"`
+	Containers []docker.ContainerStats `json:"containers"`
+}
+
+// Stats sums CPU, memory and network usage across a project's running
+// containers, to see which stack is using the host
+func (h *ProjectHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.scanner.GetProject(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	containers, err := h.docker.ListContainers(r.Context(), p.Name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list containers: "+err.Error())
+		return
+	}
+
+	resp := ProjectStats{ProjectID: p.ID, Containers: docker.CollectStats(r.Context(), h.docker, containers)}
+	for i := range resp.Containers {
+		resp.Total.Add(&resp.Containers[i])
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // Down runs docker compose down for a project
@@ -93,6 +186,162 @@ func (h *ProjectHandler) Down(w http.ResponseWriter, r *http.Request) {
 	h.runComposeOperation(w, r, "down", h.compose.Down)
 }
 
+// Start runs docker compose start for a project
+func (h *ProjectHandler) Start(w http.ResponseWriter, r *http.Request) {
+	h.runComposeOperation(w, r, "start", h.compose.Start)
+}
+
+// Stop runs docker compose stop for a project
+func (h *ProjectHandler) Stop(w http.ResponseWriter, r *http.Request) {
+	h.runComposeOperation(w, r, "stop", h.compose.Stop)
+}
+
+// Run runs a one-off command for a service with docker compose run --rm,
+// e.g. a database migration. Output streams like other compose operations.
+func (h *ProjectHandler) Run(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.scanner.GetProject(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	var opts docker.RunOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if !hasService(p, opts.Service) {
+		writeError(w, http.StatusBadRequest, "Unknown service: "+opts.Service)
+		return
+	}
+	for k := range opts.Env {
+		if k == "" || strings.ContainsAny(k, "= ") {
+			writeError(w, http.StatusBadRequest, "Invalid environment variable name: "+k)
+			return
+		}
+	}
+
+	h.runComposeOperation(w, r, "run", func(ctx context.Context, projectDir string, outputCh chan<- docker.ComposeOutput) (*docker.ComposeResult, error) {
+		return h.compose.Run(ctx, projectDir, opts, outputCh)
+	})
+}
+
+// Readme returns the project's README.md as written and rendered to
+// sanitized HTML
+func (h *ProjectHandler) Readme(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.scanner.GetProject(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	readme, err := project.ReadReadme(p)
+	if errors.Is(err, project.ErrNoReadme) {
+		writeError(w, http.StatusNotFound, "Project has no README.md")
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to read README: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, readme)
+}
+
+// Config returns the resolved compose configuration as JSON, or as YAML with
+// ?format=yaml. Repeat ?profile= to enable compose profiles.
+func (h *ProjectHandler) Config(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.scanner.GetProject(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "yaml" {
+		writeError(w, http.StatusBadRequest, "Invalid format: expected json or yaml")
+		return
+	}
+
+	config, err := h.compose.Config(r.Context(), p.Path, r.URL.Query()["profile"], format)
+	if err != nil {
+		// Usually an invalid compose file or missing variable, not a server fault
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	if format == "yaml" {
+		w.Header().Set("Content-Type", "application/yaml")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Write(config)
+}
+
+// Preflight checks that the external networks and volumes and the env files
+// a project needs exist, predicting failures of docker compose up
+func (h *ProjectHandler) Preflight(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.scanner.GetProject(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	networks, volumes, err := hostResources(r.Context(), h.docker)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list networks and volumes: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, project.Preflight(p, networks, volumes))
+}
+
+// Validate lints a project's compose file. A request body is linted instead
+// of the file on disk, so editors can check unsaved changes; variables are
+// resolved against the project's .env and gosei's environment.
+func (h *ProjectHandler) Validate(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.scanner.GetProject(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Failed to read request body: "+err.Error())
+		return
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		if data, err = os.ReadFile(p.ComposeFile); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to read compose file: "+err.Error())
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, project.Lint(data, project.InterpolationEnv(p.Path)))
+}
+
+// ValidateYAML lints arbitrary compose YAML from the request body. Without a
+// project there is no .env, so variables are not checked.
+func (h *ProjectHandler) ValidateYAML(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Failed to read request body: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, project.Lint(data, nil))
+}
+
+func hasService(p *project.Project, name string) bool {
+	for _, svc := range p.Services {
+		if svc.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Pull runs docker compose pull for a project
 func (h *ProjectHandler) Pull(w http.ResponseWriter, r *http.Request) {
 	h.runComposeOperation(w, r, "pull", h.compose.Pull)
@@ -108,6 +357,29 @@ func (h *ProjectHandler) Update(w http.ResponseWriter, r *http.Request) {
 	h.runComposeOperation(w, r, "update", h.compose.Update)
 }
 
+// Cancel stops the project's running compose operation. Completion, with a
+// cancelled status, is reported over SSE like any other outcome.
+func (h *ProjectHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	h.mu.Lock()
+	op, ok := h.running[id]
+	h.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "No operation is running")
+		return
+	}
+
+	op.cancel()
+	slog.Info("Compose operation cancelled", "project", id, "operation", op.operation,
+		"elapsed", time.Since(op.started).Round(time.Second))
+
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"status":    "cancelling",
+		"operation": op.operation,
+	})
+}
+
 // Refresh rescans the projects directory
 func (h *ProjectHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	projects, err := h.scanner.Scan(r.Context())
@@ -116,10 +388,11 @@ func (h *ProjectHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	failed := h.updateProjectStatuses(r.Context(), projects)
 	responses := make([]ProjectResponse, len(projects))
 	for i, p := range projects {
-		h.updateProjectStatus(r.Context(), p)
-		responses[i] = projectToResponse(p)
+		responses[i] = h.response(p)
+		responses[i].Failed = failed[i]
 	}
 
 	writeJSON(w, http.StatusOK, responses)
@@ -138,18 +411,53 @@ func (h *ProjectHandler) runComposeOperation(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// The operation outlives the request, so its context derives from
+	// Background and is cancelled by timeout or the cancel endpoint
+	ctx, cancel := context.WithCancel(context.Background())
+	if h.timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), h.timeout)
+	}
+
+	// One operation per project: concurrent compose runs on the same
+	// project race each other and would leave cancel ambiguous
+	h.mu.Lock()
+	if cur, busy := h.running[id]; busy {
+		h.mu.Unlock()
+		cancel()
+		writeError(w, http.StatusConflict, "Operation "+cur.operation+" is already running")
+		return
+	}
+	h.running[id] = &runningOperation{operation: operation, started: time.Now(), cancel: cancel}
+	h.mu.Unlock()
+
 	// Create output channel
 	outputCh := make(chan docker.ComposeOutput, 100)
 
 	// Start streaming output to SSE
 	go func() {
+		tracker := docker.NewComposePullTracker()
+		last := make(map[string]docker.ServiceProgress)
 		for output := range outputCh {
-			h.broker.BroadcastJSON("compose:output", sse.ComposeOutputEvent{
+			h.broker.Publish(sse.ComposeOutputEvent{
 				ProjectID: id,
 				Operation: operation,
 				Line:      output.Line,
 				Stream:    output.Stream,
 			})
+
+			// Pull output also drives per-service progress bars
+			p, ok := tracker.Update(output.Line)
+			if prev, seen := last[p.Service]; !ok || (seen && prev.Stage == p.Stage && p.Percent-prev.Percent < 2) {
+				continue
+			}
+			last[p.Service] = p
+			h.broker.Publish(sse.ComposeProgressEvent{
+				ProjectID: id,
+				Operation: operation,
+				Service:   p.Service,
+				Stage:     p.Stage,
+				Percent:   p.Percent,
+			})
 		}
 	}()
 
@@ -157,36 +465,70 @@ func (h *ProjectHandler) runComposeOperation(w http.ResponseWriter, r *http.Requ
 	go func() {
 		defer close(outputCh)
 
-		// Use background context since this runs after the HTTP response is sent
-		result, err := op(context.Background(), p.Path, outputCh)
+		result, err := op(ctx, p.Path, outputCh)
+
+		// Whether the operation was cancelled, before cancel() releasing
+		// its context makes it look so
+		ctxErr := ctx.Err()
+		h.mu.Lock()
+		delete(h.running, id)
+		h.mu.Unlock()
+		cancel()
 
 		// Broadcast completion
 		success := err == nil && result != nil && result.Success
+		status := "success"
 		message := "Operation completed"
 		if err != nil {
 			message = err.Error()
 		} else if result != nil && !result.Success {
 			message = result.Message
 		}
+		if !success {
+			status = "failed"
+			// The command's own error only says it was killed
+			switch {
+			case errors.Is(ctxErr, context.DeadlineExceeded):
+				status, message = "timeout", "Operation timed out after "+h.timeout.String()
+			case errors.Is(ctxErr, context.Canceled):
+				status, message = "cancelled", "Operation cancelled"
+			}
+		}
+
+		logLevel := slog.LevelInfo
+		if !success {
+			logLevel = slog.LevelError
+		}
+		slog.Log(context.Background(), logLevel, "Compose operation finished",
+			"project", p.Name, "operation", operation, "status", status, "message", message)
+
+		h.scanner.RecordOperation(id, project.Operation{
+			Name:     operation,
+			Status:   status,
+			Message:  message,
+			Finished: time.Now(),
+		})
 
-		h.broker.BroadcastJSON("compose:complete", sse.ComposeCompleteEvent{
+		h.broker.Publish(sse.ComposeCompleteEvent{
 			ProjectID: id,
 			Operation: operation,
 			Success:   success,
+			Status:    status,
 			Message:   message,
 		})
 
 		// Update project status
 		if p, ok := h.scanner.GetProject(id); ok {
 			ctx := context.Background()
-			h.updateProjectStatus(ctx, p)
+			failed := h.updateProjectStatus(ctx, p)
 
-			h.broker.BroadcastJSON("project:status", sse.ProjectStatusEvent{
+			h.broker.Publish(sse.ProjectStatusEvent{
 				ID:      p.ID,
 				Name:    p.Name,
 				Status:  p.Status,
 				Running: p.Running,
 				Total:   p.Total,
+				Failed:  failed,
 			})
 		}
 	}()
@@ -198,43 +540,84 @@ func (h *ProjectHandler) runComposeOperation(w http.ResponseWriter, r *http.Requ
 	})
 }
 
-// updateProjectStatus updates a project's status based on running containers
-func (h *ProjectHandler) updateProjectStatus(ctx context.Context, p *project.Project) {
+// updateProjectStatus updates a project's status based on running
+// containers and returns the services that exited non-zero
+func (h *ProjectHandler) updateProjectStatus(ctx context.Context, p *project.Project) []docker.ServiceExit {
 	containers, err := h.docker.ListContainers(ctx, p.Name)
 	if err != nil {
 		p.Status = "unknown"
-		return
+		return nil
 	}
+	h.scanner.UpdateProjectStatus(p.ID, docker.RunningByProject(containers)[p.Name])
+	return docker.FailedServices(ctx, h.docker, containers)
+}
 
-	running := 0
-	for _, c := range containers {
-		if c.State == "running" {
-			running++
+// updateProjectStatuses updates every project from one listing of all
+// containers rather than one per project, returning each project's failed
+// services in the order of projects
+func (h *ProjectHandler) updateProjectStatuses(ctx context.Context, projects []*project.Project) [][]docker.ServiceExit {
+	failed := make([][]docker.ServiceExit, len(projects))
+	all, err := updateAllStatuses(ctx, h.docker, h.scanner)
+	if err != nil {
+		for _, p := range projects {
+			p.Status = "unknown"
 		}
+		return failed
 	}
 
-	p.Running = running
-	if running == 0 {
-		p.Status = "stopped"
-	} else if running == p.Total {
-		p.Status = "running"
-	} else {
-		p.Status = "partial"
+	byProject := docker.GroupByProject(all)
+	for i, p := range projects {
+		failed[i] = docker.FailedServices(ctx, h.docker, byProject[p.Name])
 	}
+	return failed
+}
+
+// response converts a project to an API response, with the maintenance
+// window it is in
+func (h *ProjectHandler) response(p *project.Project) ProjectResponse {
+	resp := projectToResponse(p)
+	resp.Maintenance = h.windows.Active(p.ID)
+	return resp
+}
 
-	h.scanner.UpdateProjectStatus(p.ID, running, p.Status)
+// hostResources returns the names of the networks and volumes on the host
+func hostResources(ctx context.Context, dc docker.DockerClient) (networks, volumes map[string]bool, err error) {
+	nl, err := dc.ListNetworks(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	vl, err := dc.ListVolumes(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	networks = make(map[string]bool, len(nl))
+	for _, n := range nl {
+		networks[n.Name] = true
+	}
+	volumes = make(map[string]bool, len(vl))
+	for _, v := range vl {
+		volumes[v.Name] = true
+	}
+	return networks, volumes, nil
 }
 
 // projectToResponse converts a project to an API response
 func projectToResponse(p *project.Project) ProjectResponse {
 	return ProjectResponse{
-		ID:       p.ID,
-		Name:     p.Name,
-		Path:     p.Path,
-		Status:   p.Status,
-		Running:  p.Running,
-		Total:    p.Total,
-		Services: p.Services,
+		ID:        p.ID,
+		Name:      p.Name,
+		Path:      p.Path,
+		Status:    p.Status,
+		Running:   p.Running,
+		Total:     p.Total,
+		Services:  p.Services,
+		Links:     p.Links,
+		Networks:  p.Networks,
+		Volumes:   p.Volumes,
+		Warnings:  p.Warnings,
+		Conflicts: p.Conflicts,
+
+		LastOperation: p.LastOperation,
 	}
 }
 
@@ -249,3 +632,57 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 func writeError(w http.ResponseWriter, status int, message string) {
 	writeJSON(w, status, map[string]string{"error": message})
 }
+
+// Export downloads a bundle of a project's compose and env files for
+// importing on another gosei host
+func (h *ProjectHandler) Export(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	p, ok := h.scanner.GetProject(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	// Buffer the bundle so a read error can still be reported as JSON
+	var buf bytes.Buffer
+	if err := project.Export(p, &buf); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to export project: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": p.Name + ".gosei.tar.gz"}))
+	w.Write(buf.Bytes())
+}
+
+// Import creates a project from an exported bundle, sent either as the
+// request body or as the multipart "bundle" field. ?name= renames it.
+func (h *ProjectHandler) Import(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("bundle")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Missing bundle: "+err.Error())
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	p, err := h.scanner.Import(body, r.URL.Query().Get("name"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, project.ErrProjectExists) {
+			status = http.StatusConflict
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+
+	slog.Info("Imported project", "project", p.Name, "path", p.Path)
+	failed := h.updateProjectStatus(r.Context(), p)
+	resp := h.response(p)
+	resp.Failed = failed
+	writeJSON(w, http.StatusCreated, resp)
+}
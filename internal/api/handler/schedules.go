@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/lyall/gosei/internal/scheduler"
+)
+
+// ScheduleHandler exposes scheduled jobs
+type ScheduleHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+// NewScheduleHandler creates a new schedule handler
+func NewScheduleHandler(s *scheduler.Scheduler) *ScheduleHandler {
+	return &ScheduleHandler{scheduler: s}
+}
+
+// List returns every scheduled job with its next and last run
+func (h *ScheduleHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.scheduler.Jobs())
+}
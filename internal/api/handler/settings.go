@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/lyall/gosei/internal/settings"
+)
+
+// SettingsHandler reads and changes the runtime settings
+type SettingsHandler struct {
+	settings *settings.Manager
+}
+
+// NewSettingsHandler creates a new settings handler
+func NewSettingsHandler(m *settings.Manager) *SettingsHandler {
+	return &SettingsHandler{settings: m}
+}
+
+// Get returns the settings in effect, tokens masked
+func (h *SettingsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.settings.Get())
+}
+
+// Update changes the settings given in the body and applies them at once
+func (h *SettingsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Failed to read request body: "+err.Error())
+		return
+	}
+	view, err := h.settings.Update(body)
+	switch {
+	case errors.Is(err, settings.ErrNotSaved):
+		writeError(w, http.StatusInternalServerError, err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, view)
+	}
+}
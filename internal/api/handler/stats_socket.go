@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/project"
+)
+
+const (
+	// defaultFrameInterval is how often frames are sent without ?interval=
+	defaultFrameInterval = 5 * time.Second
+	// minFrameInterval keeps a dashboard from having the daemon asked for
+	// stats continuously
+	minFrameInterval = time.Second
+	// frameWriteTimeout drops clients that stop reading
+	frameWriteTimeout = 10 * time.Second
+)
+
+// StatsFrame is the usage of every running container at one moment
+type StatsFrame struct {
+	Time       time.Time           `json:"time"`
+	Containers []FrameStats        `json:"containers"`
+	Total      docker.StatsSummary `json:"total"`
+}
+
+// FrameStats is one container's stats with the project it belongs to
+type FrameStats struct {
+	docker.ContainerStats
+	Project string `json:"project,omitempty"`
+	Service string `json:"service,omitempty"`
+}
+
+// StatsSocketHandler streams container stats over a WebSocket, for
+// dashboards that want all containers in one connection
+type StatsSocketHandler struct {
+	docker   docker.DockerClient
+	scanner  *project.Scanner
+	upgrader websocket.Upgrader
+}
+
+// NewStatsSocketHandler creates a new stats socket handler. Browsers may
+// connect from the server's own origin or one of origins, the CORS origins,
+// where "*" allows any; clients that send no Origin are always allowed.
+func NewStatsSocketHandler(dc docker.DockerClient, s *project.Scanner, origins []string) *StatsSocketHandler {
+	return &StatsSocketHandler{
+		docker:  dc,
+		scanner: s,
+		upgrader: websocket.Upgrader{
+			HandshakeTimeout: 10 * time.Second,
+			CheckOrigin:      allowOrigins(origins),
+		},
+	}
+}
+
+// allowOrigins checks a WebSocket handshake's origin. Browsers don't apply
+// CORS to WebSockets, so without this any page could read stats with the
+// user's session cookie.
+func allowOrigins(origins []string) func(r *http.Request) bool {
+	allowed := make(map[string]bool, len(origins))
+	for _, o := range origins {
+		allowed[strings.TrimRight(strings.TrimSpace(o), "/")] = true
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || allowed["*"] || allowed[origin] {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+// Stats sends a StatsFrame of all running containers every ?interval=
+// (default 5s, at least 1s), optionally only those of the project with ID
+// ?project=, until the client disconnects. Messages from the client are ignored.
+func (h *StatsSocketHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	interval := defaultFrameInterval
+	if v := r.URL.Query().Get("interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < minFrameInterval {
+			writeError(w, http.StatusBadRequest, "interval must be a duration of at least "+minFrameInterval.String())
+			return
+		}
+		interval = d
+	}
+	var composeName string
+	if id := r.URL.Query().Get("project"); id != "" {
+		p, ok := h.scanner.GetProject(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		composeName = p.Name
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has answered the request
+		return
+	}
+	defer conn.Close()
+
+	// The handshake is over, so the request's context no longer ends when
+	// the client goes away; reading does, and also answers pings
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		frame, err := h.frame(ctx, composeName)
+		if ctx.Err() != nil {
+			return
+		}
+		conn.SetWriteDeadline(time.Now().Add(frameWriteTimeout))
+		if err != nil {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "Failed to list containers"))
+			return
+		}
+		if err := conn.WriteJSON(frame); err != nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// frame collects the stats of the running containers, of one compose
+// project when composeName is set
+func (h *StatsSocketHandler) frame(ctx context.Context, composeName string) (StatsFrame, error) {
+	containers, err := h.docker.ListContainers(ctx, composeName)
+	if err != nil {
+		return StatsFrame{}, err
+	}
+	byID := make(map[string]docker.ContainerInfo, len(containers))
+	for _, c := range containers {
+		byID[c.ID] = c
+	}
+
+	frame := StatsFrame{Time: time.Now(), Containers: []FrameStats{}}
+	for _, st := range docker.CollectStats(ctx, h.docker, containers) {
+		frame.Total.Add(&st)
+		c := byID[st.ID]
+		frame.Containers = append(frame.Containers, FrameStats{ContainerStats: st, Project: c.ProjectName, Service: c.ServiceName})
+	}
+	return frame, nil
+}
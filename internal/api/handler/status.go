@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/project"
+)
+
+// updateAllStatuses lists every container once and updates all projects'
+// statuses from it, bucketed by compose project, instead of listing per
+// project. The listing is returned for callers that need more from it.
+func updateAllStatuses(ctx context.Context, dc docker.DockerClient, s *project.Scanner) ([]docker.ContainerInfo, error) {
+	all, err := dc.ListContainers(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	s.UpdateStatuses(docker.RunningByProject(all))
+	return all, nil
+}
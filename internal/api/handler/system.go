@@ -1,18 +1,43 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"os"
 	"runtime"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/csrf"
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/project"
+	"github.com/lyall/gosei/internal/release"
+	"github.com/lyall/gosei/internal/sse"
 )
 
 // SystemHandler handles system-related API requests
 type SystemHandler struct {
-	version string
+	docker    docker.DockerClient
+	compose   docker.ComposeExecutor
+	scanner   *project.Scanner
+	broker    *sse.Broker
+	build     release.Build
+	startTime time.Time
 }
 
 // NewSystemHandler creates a new system handler
-func NewSystemHandler(version string) *SystemHandler {
-	return &SystemHandler{version: version}
+func NewSystemHandler(dc docker.DockerClient, cc docker.ComposeExecutor, s *project.Scanner, b *sse.Broker, build release.Build) *SystemHandler {
+	return &SystemHandler{
+		docker:    dc,
+		compose:   cc,
+		scanner:   s,
+		broker:    b,
+		build:     build,
+		startTime: time.Now(),
+	}
 }
 
 // Health returns health status
@@ -22,12 +47,256 @@ func (h *SystemHandler) Health(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Version returns version information
+// CheckResult is the outcome of a single readiness check
+type CheckResult struct {
+	Status   string `json:"status"` // "ok", "warn", "fail"
+	Latency  string `json:"latency"`
+	Error    string `json:"error,omitempty"`
+	Backlog  *int   `json:"backlog,omitempty"`
+	Capacity *int   `json:"capacity,omitempty"`
+}
+
+// Ready checks the dependencies gosei needs to serve requests and returns
+// 503 if any of them fail, so load balancers get an honest answer
+func (h *SystemHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	checks := map[string]CheckResult{
+		"docker":      runCheck(func() error { return h.docker.Ping(ctx) }),
+		"projectsDir": runCheck(func() error { return checkDirReadable(h.scanner.BaseDir()) }),
+		"broker":      h.checkBroker(),
+		"compose":     h.checkCompose(),
+	}
+
+	status, code := "ready", http.StatusOK
+	for _, c := range checks {
+		if c.Status == "fail" {
+			status, code = "unavailable", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	writeJSON(w, code, map[string]interface{}{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+// runCheck times a check function and converts its error into a result
+func runCheck(check func() error) CheckResult {
+	start := time.Now()
+	err := check()
+	result := CheckResult{Status: "ok", Latency: time.Since(start).String()}
+	if err != nil {
+		result.Status = "fail"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// checkBroker warns when broadcast events are queuing up faster than they are delivered
+func (h *SystemHandler) checkBroker() CheckResult {
+	start := time.Now()
+	backlog, capacity := h.broker.Backlog()
+	result := CheckResult{
+		Status:   "ok",
+		Latency:  time.Since(start).String(),
+		Backlog:  &backlog,
+		Capacity: &capacity,
+	}
+	switch {
+	case backlog >= capacity:
+		result.Status = "fail"
+		result.Error = "broadcast queue is full, events are being dropped"
+	case backlog*5 >= capacity*4:
+		result.Status = "warn"
+		result.Error = "broadcast queue is over 80% full"
+	}
+	return result
+}
+
+// checkCompose warns when no compose implementation was found; container
+// operations still work without one
+func (h *SystemHandler) checkCompose() CheckResult {
+	result := CheckResult{Status: "ok", Latency: "0s"}
+	if info := h.compose.Info(); info.Error != "" {
+		result.Status = "warn"
+		result.Error = info.Error
+	}
+	return result
+}
+
+func checkDirReadable(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.ReadDir(1); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("projects directory is not readable: %w", err)
+	}
+	return nil
+}
+
+// Version returns version information for gosei and what it runs on, so a
+// bug report needs one call. An unreachable daemon is reported in
+// dockerError rather than failing the request.
 func (h *SystemHandler) Version(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]interface{}{
+		"version":     h.build.Version,
+		"commit":      h.build.Commit,
+		"buildDate":   h.build.Date,
+		"apiVersions": APIVersions,
+		"goVersion":   runtime.Version(),
+		"os":          runtime.GOOS,
+		"arch":        runtime.GOARCH,
+		"compose":     h.compose.Info(),
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	if daemon, err := h.docker.DaemonVersion(ctx); err != nil {
+		resp["dockerError"] = err.Error()
+	} else {
+		resp["docker"] = daemon
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// SystemStats is the Docker host's capacity alongside what containers use
+type SystemStats struct {
+	Host            docker.HostInfo     `json:"host"`
+	TotalContainers int                 `json:"totalContainers"`
+	Containers      docker.StatsSummary `json:"containers"` // running containers only
+}
+
+// Stats reports host capacity and load with the combined usage of all
+// running containers, for an overview next to per-container numbers
+func (h *SystemHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	host, err := h.docker.HostInfo(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	containers, err := h.docker.ListContainers(r.Context(), "")
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "Failed to list containers: "+err.Error())
+		return
+	}
+
+	resp := SystemStats{Host: *host, TotalContainers: len(containers)}
+	for _, st := range docker.CollectStats(r.Context(), h.docker, containers) {
+		resp.Containers.Add(&st)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RuntimeStats represents Go runtime diagnostics
+type RuntimeStats struct {
+	Uptime        string    `json:"uptime"`
+	Goroutines    int       `json:"goroutines"`
+	CPUs          int       `json:"cpus"`
+	HeapAlloc     uint64    `json:"heapAlloc"`
+	HeapInuse     uint64    `json:"heapInuse"`
+	HeapObjects   uint64    `json:"heapObjects"`
+	Sys           uint64    `json:"sys"`
+	NumGC         uint32    `json:"numGC"`
+	LastGC        time.Time `json:"lastGC"`
+	PauseTotalNs  uint64    `json:"pauseTotalNs"`
+	GCCPUFraction float64   `json:"gcCpuFraction"`
+	SSEClients    int       `json:"sseClients"`
+}
+
+// Runtime returns Go runtime and SSE diagnostics
+func (h *SystemHandler) Runtime(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := RuntimeStats{
+		Uptime:        time.Since(h.startTime).Round(time.Second).String(),
+		Goroutines:    runtime.NumGoroutine(),
+		CPUs:          runtime.NumCPU(),
+		HeapAlloc:     mem.HeapAlloc,
+		HeapInuse:     mem.HeapInuse,
+		HeapObjects:   mem.HeapObjects,
+		Sys:           mem.Sys,
+		NumGC:         mem.NumGC,
+		PauseTotalNs:  mem.PauseTotalNs,
+		GCCPUFraction: mem.GCCPUFraction,
+		SSEClients:    h.broker.ClientCount(),
+	}
+	if mem.LastGC > 0 {
+		stats.LastGC = time.Unix(0, int64(mem.LastGC))
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// SSE lists the connected SSE clients with their topics and how many events
+// each has dropped, plus the backpressure policy, for finding out why a
+// dashboard is stale
+func (h *SystemHandler) SSE(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.broker.Stats())
+}
+
+// DisconnectSSE ends an SSE client's stream, e.g. a dashboard stuck
+// behind a slow connection; its browser reconnects with fresh state
+func (h *SystemHandler) DisconnectSSE(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !h.broker.Disconnect(id) {
+		writeError(w, http.StatusNotFound, "SSE client not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "disconnected", "id": id})
+}
+
+// CSRFToken returns the caller's CSRF token, for frontends that cannot read it from a page
+func (h *SystemHandler) CSRFToken(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"token":  csrf.Token(r.Context()),
+		"header": csrf.HeaderName,
+	})
+}
+
+// Contexts lists the docker CLI contexts available to switch to
+func (h *SystemHandler) Contexts(w http.ResponseWriter, r *http.Request) {
+	switcher, ok := h.docker.(docker.ContextSwitcher)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "Docker contexts are not supported by this client")
+		return
+	}
+
+	contexts, err := docker.ListContexts()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list contexts: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"active":   switcher.CurrentContext(),
+		"contexts": contexts,
+	})
+}
+
+// UseContext reconnects to the Docker endpoint of another docker CLI context
+func (h *SystemHandler) UseContext(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	switcher, ok := h.docker.(docker.ContextSwitcher)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "Docker contexts are not supported by this client")
+		return
+	}
+
+	if err := switcher.UseContext(r.Context(), name); err != nil {
+		writeError(w, http.StatusBadGateway, "Failed to switch context: "+err.Error())
+		return
+	}
+
 	writeJSON(w, http.StatusOK, map[string]string{
-		"version":   h.version,
-		"goVersion": runtime.Version(),
-		"os":        runtime.GOOS,
-		"arch":      runtime.GOARCH,
+		"status": "switched",
+		"active": switcher.CurrentContext(),
 	})
 }
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/terminal"
+)
+
+// TerminalHandler lists and ends the command sessions users have open in
+// containers
+type TerminalHandler struct {
+	sessions *terminal.Registry
+}
+
+// NewTerminalHandler creates a new terminal handler
+func NewTerminalHandler(sessions *terminal.Registry) *TerminalHandler {
+	return &TerminalHandler{sessions: sessions}
+}
+
+// List returns the open sessions, oldest first
+func (h *TerminalHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.sessions.List())
+}
+
+// Terminate ends a session. Its client gets an error at once; Docker can't
+// kill an exec'd process, so a non-interactive command may still run to
+// completion in the container.
+func (h *TerminalHandler) Terminate(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !h.sessions.Terminate(id) {
+		writeError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+	slog.InfoContext(r.Context(), "Terminal session terminated", "session", id)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "terminated", "id": id})
+}
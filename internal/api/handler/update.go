@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/lyall/gosei/internal/release"
+)
+
+// UpdateHandler reports whether a newer gosei release is out
+type UpdateHandler struct {
+	releases *release.Checker
+}
+
+// NewUpdateHandler creates a new update handler
+func NewUpdateHandler(c *release.Checker) *UpdateHandler {
+	return &UpdateHandler{releases: c}
+}
+
+// Status returns the running build and the outcome of the last release
+// check; checks are off until updateCheckInterval is set
+func (h *UpdateHandler) Status(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.releases.Status())
+}
@@ -0,0 +1,29 @@
+package handler
+
+import "context"
+
+// APIVersionHeader asks for an API version on the unversioned /api prefix,
+// and tells clients which version answered on every API response
+const APIVersionHeader = "Gosei-API-Version"
+
+// APIVersions lists the API versions served, oldest first. A breaking
+// change, such as a new error envelope or paginated lists, is made in a new
+// version that handlers check with APIVersion, leaving existing clients on
+// the behaviour they were written against.
+var APIVersions = []int{1}
+
+type apiVersionKey struct{}
+
+// WithAPIVersion returns a context for a request served with version v
+func WithAPIVersion(ctx context.Context, v int) context.Context {
+	return context.WithValue(ctx, apiVersionKey{}, v)
+}
+
+// APIVersion returns the API version a request is served with, the oldest
+// when it wasn't negotiated
+func APIVersion(ctx context.Context) int {
+	if v, ok := ctx.Value(apiVersionKey{}).(int); ok {
+		return v
+	}
+	return APIVersions[0]
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/docker"
+)
+
+// volumeBrowserTimeout bounds creating a volume browser, including pulling
+// its image
+const volumeBrowserTimeout = 2 * time.Minute
+
+// VolumeHandler handles volume-related API requests
+type VolumeHandler struct {
+	docker docker.DockerClient
+}
+
+// NewVolumeHandler creates a new volume handler
+func NewVolumeHandler(dc docker.DockerClient) *VolumeHandler {
+	return &VolumeHandler{docker: dc}
+}
+
+// Files lists a directory or previews a small text file inside a named
+// volume, read through a temporary container that mounts it read-only
+func (h *VolumeHandler) Files(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	// Creating isn't cancelled with the request, so a container the engine
+	// creates is always returned to be removed below
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), volumeBrowserTimeout)
+	defer cancel()
+	id, err := h.docker.CreateVolumeBrowser(ctx, name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, docker.ErrVolumeNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, docker.ErrVolumeBrowserImage):
+			status = http.StatusServiceUnavailable
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	defer func() {
+		// The request context may already be cancelled; don't leak the container
+		if err := h.docker.RemoveVolumeBrowser(context.Background(), id); err != nil {
+			slog.Warn("Failed to remove volume browser", "volume", name, "error", err)
+		}
+	}()
+
+	browseFiles(w, r, h.docker, id, docker.VolumeMountPath, r.URL.Query().Get("path"))
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/webhook"
+)
+
+// WebhookHandler shows outgoing webhooks and their recent deliveries
+type WebhookHandler struct {
+	sink *webhook.Sink
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(sink *webhook.Sink) *WebhookHandler {
+	return &WebhookHandler{sink: sink}
+}
+
+// List returns the configured webhooks, without their URLs and secrets
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.sink.Endpoints())
+}
+
+// Deliveries returns recent deliveries, newest first, filtered by
+// ?webhook=, ?event= and ?state= (pending, delivered or failed)
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	writeJSON(w, http.StatusOK, h.sink.Deliveries(webhook.Filter{
+		Webhook: q.Get("webhook"),
+		Event:   q.Get("event"),
+		State:   q.Get("state"),
+	}))
+}
+
+// Delivery returns one delivery with its payload and attempts
+func (h *WebhookHandler) Delivery(w http.ResponseWriter, r *http.Request) {
+	d, err := h.sink.Delivery(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Delivery not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// Redeliver sends a delivery's payload again as a new delivery
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	d, err := h.sink.Redeliver(chi.URLParam(r, "id"))
+	switch {
+	case errors.Is(err, webhook.ErrNotFound):
+		writeError(w, http.StatusNotFound, "Delivery not found")
+	case err != nil:
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeJSON(w, http.StatusAccepted, d)
+	}
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/lyall/gosei/internal/auth"
+)
+
+// accessLogKey holds a request's *accessLog in its context
+type accessLogKey struct{}
+
+// accessLog collects what inner middleware learns about a request for its
+// access log entry
+type accessLog struct {
+	user string
+}
+
+// requestLogger logs each request through slog; the request ID is added by
+// the logging handler from the request context. Requests whose path starts
+// with one of quiet, such as polled partials or long-lived SSE streams, are
+// logged at debug level only. Query parameters that may carry secrets are
+// redacted.
+func requestLogger(quiet []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			entry := &accessLog{}
+			r = r.WithContext(context.WithValue(r.Context(), accessLogKey{}, entry))
+
+			defer func() {
+				level := slog.LevelInfo
+				for _, prefix := range quiet {
+					if strings.HasPrefix(r.URL.Path, prefix) {
+						level = slog.LevelDebug
+						break
+					}
+				}
+				if !slog.Default().Enabled(r.Context(), level) {
+					return
+				}
+
+				attrs := []slog.Attr{
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+				}
+				if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+					attrs = append(attrs, slog.String("route", rctx.RoutePattern()))
+				}
+				if r.URL.RawQuery != "" {
+					attrs = append(attrs, slog.String("query", redactQuery(r.URL.Query())))
+				}
+				attrs = append(attrs,
+					slog.Int("status", ww.Status()),
+					slog.Int("bytes", ww.BytesWritten()),
+					slog.Duration("duration", time.Since(start)),
+					slog.String("remote", r.RemoteAddr),
+				)
+				if entry.user != "" {
+					attrs = append(attrs, slog.String("user", entry.user))
+				}
+				slog.LogAttrs(r.Context(), level, "HTTP request", attrs...)
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
+// logUser adds the authenticated user to the request's access log entry;
+// it runs after authentication, inside requestLogger
+func logUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if entry, ok := r.Context().Value(accessLogKey{}).(*accessLog); ok {
+			if user, ok := auth.UserFromContext(r.Context()); ok {
+				entry.user = user.Name
+				if user.Email != "" {
+					entry.user = user.Email
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// secretParams are fragments of query parameter names whose values are
+// redacted from access logs: tokens, OIDC codes and the like
+var secretParams = []string{"token", "secret", "password", "passwd", "key", "auth", "code", "state", "signature", "credential"}
+
+// redactQuery encodes a query for logging with secret values replaced
+func redactQuery(q url.Values) string {
+	for name, values := range q {
+		lower := strings.ToLower(name)
+		for _, secret := range secretParams {
+			if strings.Contains(lower, secret) {
+				for i := range values {
+					values[i] = "REDACTED"
+				}
+				break
+			}
+		}
+	}
+	return q.Encode()
+}
+
+// compressedTypes are the responses worth compressing: API JSON, pages and
+// partials, static assets and plain-text logs. SSE streams are left out so
+// events aren't held back in a compressor's buffer.
+var compressedTypes = []string{
+	"application/json",
+	"text/html",
+	"text/css",
+	"text/plain",
+	"text/javascript",
+	"application/javascript",
+	"image/svg+xml",
+}
+
+// compressionLevel balances size against CPU for both gzip and brotli
+const compressionLevel = 5
+
+// compress compresses responses with brotli, gzip or deflate, preferring
+// brotli when the client accepts it
+func compress() func(http.Handler) http.Handler {
+	c := middleware.NewCompressor(compressionLevel, compressedTypes...)
+	c.SetEncoder("br", func(w io.Writer, level int) io.Writer {
+		return brotli.NewWriterLevel(w, level)
+	})
+	return c.Handler
+}
+
+// conditional gives successful responses an ETag, a hash of the body, and
+// answers 304 Not Modified when it matches the request's If-None-Match, so
+// clients polling a list don't transfer it again while it's unchanged. The
+// response is buffered, so it suits lists rather than streams. The tag is
+// weak, as compression changes the bytes sent.
+func conditional(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := &bufferedResponse{header: w.Header(), status: http.StatusOK}
+		next.ServeHTTP(buf, r)
+
+		if buf.status != http.StatusOK {
+			w.WriteHeader(buf.status)
+			w.Write(buf.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(buf.body.Bytes())
+		etag := `W/"` + hex.EncodeToString(sum[:12]) + `"`
+		w.Header().Set("ETag", etag)
+		// Browsers revalidate rather than reuse it unasked
+		w.Header().Set("Cache-Control", "no-cache")
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			// Without a content type the compressor leaves the empty body be
+			w.Header().Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.body.Bytes())
+	})
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing
+// weakly as RFC 9110 requires for If-None-Match
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedResponse holds a response until it is complete
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header         { return b.header }
+func (b *bufferedResponse) WriteHeader(status int)      { b.status = status }
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+// tokenAuth rejects requests that do not carry the shared bearer token
+func tokenAuth(token string) func(http.Handler) http.Handler {
+	expected := []byte(token)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), expected) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="gosei-agent"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// cors allows browsers on the given origins to call the API. A single "*"
+// allows any origin without credentials.
+func cors(origins []string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(origins))
+	wildcard := false
+	for _, o := range origins {
+		o = strings.TrimRight(strings.TrimSpace(o), "/")
+		if o == "*" {
+			wildcard = true
+		} else if o != "" {
+			allowed[o] = true
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Origin")
+			switch {
+			case allowed[origin]:
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			case wildcard:
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Preflight requests are answered here rather than reaching the router
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+				if reqHeaders := r.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+					w.Header().Set("Access-Control-Allow-Headers", reqHeaders)
+				}
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ipRateLimiter keeps a token bucket per client IP
+type ipRateLimiter struct {
+	limit    rate.Limit
+	burst    int
+	limiters map[string]*ipLimiter
+	mu       sync.Mutex
+}
+
+type ipLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newIPRateLimiter(perSecond float64, burst int) *ipRateLimiter {
+	l := &ipRateLimiter{
+		limit:    rate.Limit(perSecond),
+		burst:    burst,
+		limiters: make(map[string]*ipLimiter),
+	}
+	go l.cleanup(10 * time.Minute)
+	return l
+}
+
+// reserve takes a token for ip, returning how long the caller must wait if none is available
+func (l *ipRateLimiter) reserve(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	entry, ok := l.limiters[ip]
+	if !ok {
+		entry = &ipLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.limiters[ip] = entry
+	}
+	entry.lastSeen = time.Now()
+	l.mu.Unlock()
+
+	r := entry.limiter.Reserve()
+	if delay := r.Delay(); delay > 0 {
+		r.Cancel()
+		return false, delay
+	}
+	return true, 0
+}
+
+// cleanup forgets clients that have been idle for longer than maxIdle
+func (l *ipRateLimiter) cleanup(maxIdle time.Duration) {
+	ticker := time.NewTicker(maxIdle)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		l.mu.Lock()
+		for ip, entry := range l.limiters {
+			if time.Since(entry.lastSeen) > maxIdle {
+				delete(l.limiters, ip)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// rateLimitMutations limits state-changing requests per client IP. Reads are
+// not limited so dashboards polling for status are unaffected, including
+// Grafana's, which are POSTs.
+func rateLimitMutations(perSecond float64, burst int) func(http.Handler) http.Handler {
+	limiter := newIPRateLimiter(perSecond, burst)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isMutating(r.Method) || slices.Contains(readOnlyPosts, unversionedPath(r.URL.Path)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if ok, wait := limiter.reserve(clientIP(r)); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitBody caps request body size; handlers see an error once maxBytes is
+// exceeded. Uploads are left to the cap of their route (limitUpload).
+func limitBody(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isUpload(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.ContentLength > maxBytes {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitUpload caps the body of an upload route at maxBytes (0 for no cap)
+// instead of the API's usual limit, and lets reading the body and writing
+// the response take up to uploadTimeout rather than the server's timeouts
+func limitUpload(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rc := http.NewResponseController(w)
+			deadline := time.Now().Add(uploadTimeout)
+			rc.SetReadDeadline(deadline)
+			rc.SetWriteDeadline(deadline)
+
+			if maxBytes > 0 {
+				if r.ContentLength > maxBytes {
+					http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// uploadTimeout bounds how long an upload may take to arrive
+const uploadTimeout = time.Hour
+
+// isUpload reports whether an API path is one of the upload routes, which
+// apply limitUpload themselves: container file uploads and project imports
+func isUpload(path string) bool {
+	path = unversionedPath(path)
+	return path == "/api/projects/import" ||
+		strings.HasPrefix(path, "/api/containers/") && strings.HasSuffix(path, "/fs/upload")
+}
+
+// readOnlyPosts are API paths taking POST only because their queries don't
+// fit a URL; they change nothing
+var readOnlyPosts = []string{
+	"/api/grafana/search",
+	"/api/grafana/query",
+	"/api/grafana/annotations",
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// clientIP returns the request's IP without port; realIP has already
+// applied the X-Forwarded-For header of a trusted proxy
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -2,13 +2,30 @@ package api
 
 import (
 	"net/http"
+	"net/netip"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/lyall/gosei/internal/agent"
 	"github.com/lyall/gosei/internal/api/handler"
+	"github.com/lyall/gosei/internal/auth"
+	"github.com/lyall/gosei/internal/autoupdate"
+	"github.com/lyall/gosei/internal/csrf"
 	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/logarchive"
+	"github.com/lyall/gosei/internal/maintenance"
+	"github.com/lyall/gosei/internal/monitor"
+	"github.com/lyall/gosei/internal/probe"
 	"github.com/lyall/gosei/internal/project"
+	"github.com/lyall/gosei/internal/release"
+	"github.com/lyall/gosei/internal/scheduler"
+	"github.com/lyall/gosei/internal/settings"
 	"github.com/lyall/gosei/internal/sse"
+	"github.com/lyall/gosei/internal/store"
+	"github.com/lyall/gosei/internal/terminal"
+	"github.com/lyall/gosei/internal/vuln"
+	"github.com/lyall/gosei/internal/webhook"
 	"github.com/lyall/gosei/web"
 )
 
@@ -18,7 +35,94 @@ type Config struct {
 	ComposeClient docker.ComposeExecutor
 	Scanner       *project.Scanner
 	SSEBroker     *sse.Broker
-	Version       string
+	Agents        *agent.Registry
+	Build         release.Build // version, commit and build date of the running gosei
+	Debug         bool          // exposes pprof and runtime diagnostics
+
+	// RateLimit is the sustained number of mutating API requests allowed per
+	// client IP per second, with bursts up to RateBurst. Zero disables it.
+	RateLimit    float64
+	RateBurst    int
+	MaxBodyBytes int64
+
+	// MaxUploadBytes caps file uploads and project imports in place of
+	// MaxBodyBytes. Zero means no cap.
+	MaxUploadBytes int64
+
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For and
+	// X-Real-IP headers give the client address; other peers' are ignored
+	TrustedProxies []netip.Prefix
+
+	// CORSOrigins lists origins allowed to call the API from a browser
+	CORSOrigins []string
+
+	// DisableCSRF turns off CSRF token validation for browser requests
+	DisableCSRF bool
+
+	// DisableCompression turns off brotli/gzip compression of responses,
+	// e.g. behind a proxy that compresses them already
+	DisableCompression bool
+
+	// Auth requires users to log in when set
+	Auth *auth.Manager
+
+	// VulnScanner scans images for vulnerabilities when set; reports are
+	// kept in VulnReports
+	VulnScanner vuln.Scanner
+	VulnReports *vuln.Store
+
+	// AutoUpdater applies gosei.auto-update policies
+	AutoUpdater *autoupdate.Updater
+
+	// Prober checks gosei.healthcheck.url health URLs when set
+	Prober *probe.Prober
+
+	// Scheduler runs scheduled jobs such as gosei.restart.schedule restarts
+	Scheduler *scheduler.Scheduler
+
+	// Maintenance keeps the maintenance windows that hold off automation
+	Maintenance *maintenance.Manager
+
+	// Settings holds the options that can be changed at runtime
+	Settings *settings.Manager
+
+	// Store is gosei's database, when there is a data directory
+	Store *store.Store
+
+	// Releases checks for newer gosei releases
+	Releases *release.Checker
+
+	// StatsHistory keeps recent stats samples, served to Grafana when set
+	StatsHistory *monitor.History
+
+	// EventLog holds recent Docker events for /api/events/history
+	EventLog *docker.EventLog
+
+	// MaxLogStreams limits how many containers' logs are followed at once
+	// for browsers. Zero means no limit.
+	MaxLogStreams int
+
+	// LogArchive serves container logs captured to disk when set
+	LogArchive *logarchive.Archiver
+
+	// Webhooks delivers events to outgoing webhooks when set
+	Webhooks *webhook.Sink
+
+	// OperationTimeout cancels compose operations that run longer. Zero
+	// lets them run until they finish or are cancelled.
+	OperationTimeout time.Duration
+
+	// QuietLogPaths are path prefixes whose requests are only logged at
+	// debug level, e.g. /partials/ polling and /api/events streams
+	QuietLogPaths []string
+
+	// Demo rejects state-changing requests other than lifecycle operations
+	// on the mock client, for a public demo instance
+	Demo bool
+
+	// Mock is the mock Docker client in mock mode, whose faults can then be
+	// set through /api/mock/faults
+	Mock *docker.MockClient
 }
 
 // NewRouter creates a new HTTP router
@@ -26,63 +130,283 @@ func NewRouter(cfg *Config) http.Handler {
 	r := chi.NewRouter()
 
 	// Middleware
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.RealIP)
 	r.Use(middleware.RequestID)
+	r.Use(realIP(cfg.TrustedProxies))
+	r.Use(requestLogger(cfg.QuietLogPaths))
+	r.Use(middleware.Recoverer)
+	if !cfg.DisableCompression {
+		r.Use(compress())
+	}
+	if cfg.Demo {
+		r.Use(demoSandbox)
+	}
+	if !cfg.DisableCSRF {
+		r.Use(csrf.Middleware())
+	}
+	if cfg.Auth != nil {
+		r.Use(cfg.Auth.Middleware())
+		r.Use(logUser)
+	}
 
 	// Create handlers
-	projectHandler := handler.NewProjectHandler(cfg.DockerClient, cfg.ComposeClient, cfg.Scanner, cfg.SSEBroker)
-	containerHandler := handler.NewContainerHandler(cfg.DockerClient, cfg.SSEBroker)
-	systemHandler := handler.NewSystemHandler(cfg.Version)
-	pageHandler := handler.NewPageHandler(cfg.DockerClient, cfg.Scanner, cfg.Version)
+	pageHandler := handler.NewPageHandler(cfg.DockerClient, cfg.Scanner, cfg.VulnReports, cfg.Prober, cfg.Build.Version)
+
+	// Profiles hold heap contents and the command line
+	if cfg.Debug {
+		r.With(auth.RequireAdmin).Mount("/debug", middleware.Profiler())
+	}
 
 	// Static files
 	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.FS(web.StaticFS()))))
 
+	// Login
+	if cfg.Auth != nil {
+		authHandler := handler.NewAuthHandler(cfg.Auth)
+		r.Get("/auth/login", authHandler.Login)
+		r.Get("/auth/callback", authHandler.Callback)
+		r.Post("/auth/logout", authHandler.Logout)
+	}
+
 	// Page routes
 	r.Get("/", pageHandler.Dashboard)
 	r.Get("/projects/{id}", pageHandler.ProjectDetail)
+	r.Get("/standalone", pageHandler.Standalone)
 	r.Get("/containers/{id}", pageHandler.ContainerDetail)
 	r.Get("/containers/{id}/logs", pageHandler.ContainerLogs)
 
 	// API routes
-	r.Route("/api", func(r chi.Router) {
-		// Projects
-		r.Get("/projects", projectHandler.List)
-		r.Get("/projects/{id}", projectHandler.Get)
-		r.Post("/projects/{id}/up", projectHandler.Up)
-		r.Post("/projects/{id}/down", projectHandler.Down)
-		r.Post("/projects/{id}/pull", projectHandler.Pull)
-		r.Post("/projects/{id}/restart", projectHandler.Restart)
-		r.Post("/projects/{id}/update", projectHandler.Update)
-		r.Post("/projects/refresh", projectHandler.Refresh)
-
-		// Containers
-		r.Get("/containers", containerHandler.List)
-		r.Get("/containers/{id}", containerHandler.Get)
-		r.Post("/containers/{id}/start", containerHandler.Start)
-		r.Post("/containers/{id}/stop", containerHandler.Stop)
-		r.Post("/containers/{id}/restart", containerHandler.Restart)
-		r.Get("/containers/{id}/logs", containerHandler.Logs)
-		r.Get("/containers/{id}/stats", containerHandler.Stats)
-
-		// System
-		r.Get("/system/health", systemHandler.Health)
-		r.Get("/system/version", systemHandler.Version)
-
-		// SSE events
-		r.Get("/events", cfg.SSEBroker.ServeHTTP)
-	})
+	apiRouter := chi.NewRouter()
+	registerAPIRoutes(apiRouter, cfg)
+
+	if cfg.Auth != nil {
+		apiRouter.Get("/auth/me", handler.NewAuthHandler(cfg.Auth).Me)
+	}
+
+	// Agents; the proxy acts with the agent's token, which has every role
+	if cfg.Agents != nil {
+		agentHandler := handler.NewAgentHandler(cfg.Agents, cfg.Scanner)
+		apiRouter.Get("/agents", agentHandler.List)
+		apiRouter.With(auth.RequireAdmin).Post("/agents", agentHandler.Register)
+		apiRouter.Get("/agents/projects", agentHandler.Projects)
+		apiRouter.With(auth.RequireAdmin).Delete("/agents/{name}", agentHandler.Unregister)
+		apiRouter.With(auth.RequireAdmin).HandleFunc("/agents/{name}/*", agentHandler.Proxy)
+	}
+	mountAPI(r, apiRouter)
 
 	// HTMX partials
 	r.Route("/partials", func(r chi.Router) {
-		r.Get("/projects", pageHandler.ProjectsPartial)
+		r.With(conditional).Get("/projects", pageHandler.ProjectsPartial)
 		r.Get("/projects/{id}", pageHandler.ProjectDetailPartial)
 		r.Get("/projects/{id}/containers", pageHandler.ProjectContainersPartial)
+		r.Get("/standalone/containers", pageHandler.StandaloneContainersPartial)
 		r.Get("/containers/{id}/actions", pageHandler.ContainerActionsPartial)
 		r.Get("/containers/{id}/logs-content", pageHandler.ContainerLogsContent)
+		r.Get("/images/{id}/vuln-badge", pageHandler.VulnBadgePartial)
+		r.Get("/images/{id}/history", pageHandler.ImageHistoryPartial)
 	})
 
 	return r
 }
+
+// NewAgentRouter creates the router for agent mode: the JSON API only,
+// guarded by a shared bearer token
+func NewAgentRouter(cfg *Config, token string) http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(middleware.RequestID)
+	r.Use(realIP(cfg.TrustedProxies))
+	r.Use(requestLogger(cfg.QuietLogPaths))
+	r.Use(middleware.Recoverer)
+	if !cfg.DisableCompression {
+		r.Use(compress())
+	}
+	r.Use(tokenAuth(token))
+
+	apiRouter := chi.NewRouter()
+	registerAPIRoutes(apiRouter, cfg)
+	mountAPI(r, apiRouter)
+
+	return r
+}
+
+// registerAPIRoutes registers the JSON API shared by server and agent mode
+func registerAPIRoutes(r chi.Router, cfg *Config) {
+	if len(cfg.CORSOrigins) > 0 {
+		r.Use(cors(cfg.CORSOrigins))
+	}
+	if cfg.RateLimit > 0 {
+		r.Use(rateLimitMutations(cfg.RateLimit, cfg.RateBurst))
+	}
+	if cfg.MaxBodyBytes > 0 {
+		r.Use(limitBody(cfg.MaxBodyBytes))
+	}
+
+	// Browsers following the same container's logs share one Docker stream
+	logHub := docker.NewLogHub(cfg.DockerClient, cfg.MaxLogStreams)
+	terminals := terminal.NewRegistry()
+	projectHandler := handler.NewProjectHandler(cfg.DockerClient, cfg.ComposeClient, cfg.Scanner, cfg.SSEBroker, cfg.OperationTimeout, logHub, cfg.Maintenance)
+	containerHandler := handler.NewContainerHandler(cfg.DockerClient, cfg.SSEBroker, logHub, terminals, cfg.Scanner)
+	systemHandler := handler.NewSystemHandler(cfg.DockerClient, cfg.ComposeClient, cfg.Scanner, cfg.SSEBroker, cfg.Build)
+	imageHandler := handler.NewImageHandler(cfg.DockerClient, cfg.SSEBroker, cfg.VulnScanner, cfg.VulnReports)
+	volumeHandler := handler.NewVolumeHandler(cfg.DockerClient)
+
+	// Projects
+	r.With(conditional).Get("/projects", projectHandler.List)
+	r.Get("/projects/{id}", projectHandler.Get)
+	r.Post("/projects/{id}/up", projectHandler.Up)
+	r.Post("/projects/{id}/down", projectHandler.Down)
+	r.Post("/projects/{id}/start", projectHandler.Start)
+	r.Post("/projects/{id}/stop", projectHandler.Stop)
+	r.With(auth.RequireAdmin).Post("/projects/{id}/run", projectHandler.Run)
+	r.Get("/projects/{id}/stats", projectHandler.Stats)
+	r.Get("/projects/{id}/logs", projectHandler.Logs)
+	// Interpolated config holds env file values unmasked
+	r.With(auth.RequireAdmin).Get("/projects/{id}/config", projectHandler.Config)
+	r.Get("/projects/{id}/readme", projectHandler.Readme)
+	r.Get("/projects/{id}/preflight", projectHandler.Preflight)
+	r.Get("/projects/{id}/networks", projectHandler.Networks)
+	r.Post("/projects/{id}/validate", projectHandler.Validate)
+	r.Post("/compose/validate", projectHandler.ValidateYAML)
+	r.Post("/projects/{id}/pull", projectHandler.Pull)
+	r.Post("/projects/{id}/restart", projectHandler.Restart)
+	r.Post("/projects/{id}/update", projectHandler.Update)
+	r.Post("/projects/{id}/operations/cancel", projectHandler.Cancel)
+	r.Post("/projects/refresh", projectHandler.Refresh)
+	// Bundles carry env files, and imported compose files can mount the host
+	r.With(auth.RequireAdmin, limitUpload(cfg.MaxUploadBytes)).Post("/projects/import", projectHandler.Import)
+	r.With(auth.RequireAdmin).Get("/projects/{id}/export", projectHandler.Export)
+	r.Get("/analysis/port-conflicts", handler.NewAnalysisHandler(cfg.DockerClient, cfg.Scanner).PortConflicts)
+	if cfg.AutoUpdater != nil {
+		autoUpdateHandler := handler.NewAutoUpdateHandler(cfg.AutoUpdater, cfg.Scanner)
+		r.Get("/projects/{id}/auto-update", autoUpdateHandler.Status)
+		r.Post("/projects/{id}/auto-update", autoUpdateHandler.Run)
+	}
+	if cfg.Prober != nil {
+		probeHandler := handler.NewProbeHandler(cfg.Prober, cfg.Scanner)
+		r.Get("/probes", probeHandler.List)
+		r.Get("/projects/{id}/probes", probeHandler.Project)
+		r.Put("/projects/{id}/probes/{service}", probeHandler.Set)
+		r.Delete("/projects/{id}/probes/{service}", probeHandler.Remove)
+	}
+	if cfg.Scheduler != nil {
+		r.Get("/schedules", handler.NewScheduleHandler(cfg.Scheduler).List)
+	}
+	if cfg.Maintenance != nil {
+		maintenanceHandler := handler.NewMaintenanceHandler(cfg.Maintenance, cfg.Scanner)
+		r.Get("/maintenance", maintenanceHandler.List)
+		r.Post("/maintenance", maintenanceHandler.Create)
+		r.Delete("/maintenance/{id}", maintenanceHandler.Delete)
+	}
+
+	// Containers
+	r.With(conditional).Get("/containers", containerHandler.List)
+	r.With(auth.RequireAdmin).Post("/containers", containerHandler.Create)
+	r.Get("/containers/stats", containerHandler.AllStats)
+	r.Get("/containers/{id}", containerHandler.Get)
+	r.Patch("/containers/{id}", containerHandler.Update)
+	r.Get("/standalone", containerHandler.Standalone)
+	r.Post("/containers/{id}/start", containerHandler.Start)
+	r.Post("/containers/{id}/stop", containerHandler.Stop)
+	r.Post("/containers/{id}/restart", containerHandler.Restart)
+	r.With(auth.RequireAdmin).Post("/containers/{id}/exec", containerHandler.Exec)
+	r.With(auth.RequireAdmin).Post("/containers/{id}/networks", containerHandler.ConnectNetwork)
+	r.With(auth.RequireAdmin).Delete("/containers/{id}/networks/{network}", containerHandler.DisconnectNetwork)
+	r.Get("/containers/{id}/logs", containerHandler.Logs)
+	r.Get("/containers/{id}/stats", containerHandler.Stats)
+	// Previews and downloads can read any secret in the container, and
+	// uploads can replace its binaries
+	r.With(auth.RequireAdmin).Get("/containers/{id}/fs", containerHandler.Files)
+	r.With(auth.RequireAdmin).Get("/containers/{id}/fs/download", containerHandler.Download)
+	r.With(auth.RequireAdmin, limitUpload(cfg.MaxUploadBytes)).Post("/containers/{id}/fs/upload", containerHandler.Upload)
+
+	// Stats of all running containers over a WebSocket, for external dashboards
+	r.Get("/ws/stats", handler.NewStatsSocketHandler(cfg.DockerClient, cfg.Scanner, cfg.CORSOrigins).Stats)
+
+	// Command sessions in containers
+	terminalHandler := handler.NewTerminalHandler(terminals)
+	r.With(auth.RequireAdmin).Get("/terminals", terminalHandler.List)
+	r.With(auth.RequireAdmin).Delete("/terminals/{id}", terminalHandler.Terminate)
+
+	// Volumes
+	// Volumes hold data as sensitive as container files, and browsing one
+	// creates a container
+	r.With(auth.RequireAdmin).Get("/volumes/{name}/fs", volumeHandler.Files)
+
+	// Images
+	r.Post("/images/pull", imageHandler.Pull)
+	r.Get("/images/{id}/history", imageHandler.History)
+	r.Post("/images/{id}/scan", imageHandler.Scan)
+	r.Get("/images/{id}/scan", imageHandler.ScanReport)
+
+	// System
+	r.Get("/system/health", systemHandler.Health)
+	r.Get("/system/ready", systemHandler.Ready)
+	r.Get("/csrf", systemHandler.CSRFToken)
+	r.Get("/system/version", systemHandler.Version)
+	r.Get("/system/stats", systemHandler.Stats)
+	r.With(auth.RequireAdmin).Get("/system/sse", systemHandler.SSE)
+	r.With(auth.RequireAdmin).Delete("/system/sse/{id}", systemHandler.DisconnectSSE)
+	r.Get("/system/contexts", systemHandler.Contexts)
+	// Switching contexts repoints the whole server at another daemon
+	r.With(auth.RequireAdmin).Post("/system/contexts/{name}/use", systemHandler.UseContext)
+	if cfg.Debug {
+		r.Get("/system/runtime", systemHandler.Runtime)
+	}
+
+	// Mock mode fault injection
+	if cfg.Mock != nil {
+		mockHandler := handler.NewMockHandler(cfg.Mock)
+		r.Get("/mock/faults", mockHandler.Faults)
+		r.With(auth.RequireAdmin).Put("/mock/faults", mockHandler.SetFaults)
+	}
+
+	// SSE events
+	r.Get("/events", cfg.SSEBroker.ServeHTTP)
+	if cfg.EventLog != nil {
+		r.Get("/events/history", handler.NewEventHandler(cfg.EventLog).History)
+	}
+
+	// Grafana JSON datasource
+	if cfg.StatsHistory != nil {
+		grafanaHandler := handler.NewGrafanaHandler(cfg.StatsHistory, cfg.EventLog)
+		r.Get("/grafana", grafanaHandler.Test)
+		r.Get("/grafana/", grafanaHandler.Test)
+		r.Post("/grafana/search", grafanaHandler.Search)
+		r.Post("/grafana/query", grafanaHandler.Query)
+		r.Post("/grafana/annotations", grafanaHandler.Annotations)
+	}
+
+	// Archived container logs
+	if cfg.LogArchive != nil {
+		logArchiveHandler := handler.NewLogArchiveHandler(cfg.LogArchive)
+		r.Get("/logs/archive", logArchiveHandler.List)
+		r.Get("/logs/archive/{name}", logArchiveHandler.Get)
+	}
+
+	// Outgoing webhooks; deliveries hold event payloads
+	if cfg.Webhooks != nil {
+		webhookHandler := handler.NewWebhookHandler(cfg.Webhooks)
+		r.With(auth.RequireAdmin).Get("/webhooks", webhookHandler.List)
+		r.With(auth.RequireAdmin).Get("/webhooks/deliveries", webhookHandler.Deliveries)
+		r.With(auth.RequireAdmin).Get("/webhooks/deliveries/{id}", webhookHandler.Delivery)
+		r.With(auth.RequireAdmin).Post("/webhooks/deliveries/{id}/redeliver", webhookHandler.Redeliver)
+	}
+
+	// Runtime settings; notification targets hold tokens
+	if cfg.Settings != nil {
+		settingsHandler := handler.NewSettingsHandler(cfg.Settings)
+		r.With(auth.RequireAdmin).Get("/settings", settingsHandler.Get)
+		r.With(auth.RequireAdmin).Put("/settings", settingsHandler.Update)
+	}
+
+	if cfg.Releases != nil {
+		updateHandler := handler.NewUpdateHandler(cfg.Releases)
+		r.Get("/system/update", updateHandler.Status)
+	}
+
+	// Backups hold settings with their tokens
+	if cfg.Store != nil {
+		backupHandler := handler.NewBackupHandler(cfg.Store, cfg.Build.Version)
+		r.With(auth.RequireAdmin).Get("/system/backup", backupHandler.Backup)
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lyall/gosei/internal/api/handler"
+)
+
+// apiDeprecated is when the unversioned /api prefix was deprecated in favour
+// of /api/v1, sent in its Deprecation header
+var apiDeprecated = time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+// mountAPI serves the API under /api/v1, and under /api for scripts written
+// before it was versioned. The unversioned prefix serves the version asked
+// for in the Gosei-API-Version header, by default the oldest, and marks its
+// responses deprecated.
+func mountAPI(r chi.Router, api http.Handler) {
+	for _, v := range handler.APIVersions {
+		r.With(pinAPIVersion(v)).Mount(fmt.Sprintf("/api/v%d", v), api)
+	}
+	r.With(negotiateAPIVersion, deprecatedAPI).Mount("/api", api)
+}
+
+// pinAPIVersion serves requests with the version in their path
+func pinAPIVersion(v int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(handler.APIVersionHeader, strconv.Itoa(v))
+			next.ServeHTTP(w, r.WithContext(handler.WithAPIVersion(r.Context(), v)))
+		})
+	}
+}
+
+// negotiateAPIVersion serves requests with the version in their
+// Gosei-API-Version header, rejecting versions that aren't served
+func negotiateAPIVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := handler.APIVersions[0]
+		if h := r.Header.Get(handler.APIVersionHeader); h != "" {
+			n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(h), "v"))
+			if err != nil || !slices.Contains(handler.APIVersions, n) {
+				http.Error(w, fmt.Sprintf("Unsupported API version %q (supported: %s)", h, supportedVersions()), http.StatusBadRequest)
+				return
+			}
+			v = n
+		}
+		pinAPIVersion(v)(next).ServeHTTP(w, r)
+	})
+}
+
+// deprecatedAPI marks responses on the unversioned prefix deprecated
+// (RFC 9745), linking to the same resource under the current version
+func deprecatedAPI(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := handler.APIVersions[len(handler.APIVersions)-1]
+		successor := fmt.Sprintf("/api/v%d%s", current, strings.TrimPrefix(r.URL.Path, "/api"))
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", apiDeprecated.Unix()))
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unversionedPath strips the version from an API path, so path rules apply
+// to every version alike
+func unversionedPath(p string) string {
+	for _, v := range handler.APIVersions {
+		prefix := fmt.Sprintf("/api/v%d", v)
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return "/api" + strings.TrimPrefix(p, prefix)
+		}
+	}
+	return p
+}
+
+func supportedVersions() string {
+	s := make([]string, len(handler.APIVersions))
+	for i, v := range handler.APIVersions {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ", ")
+}
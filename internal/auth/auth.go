@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Role controls what an authenticated user may do
+type Role string
+
+const (
+	// RoleAdmin may do everything
+	RoleAdmin Role = "admin"
+	// RoleOperator may run compose and container operations
+	RoleOperator Role = "operator"
+	// RoleViewer has read-only access
+	RoleViewer Role = "viewer"
+)
+
+// rank orders roles so the most privileged mapped role wins
+func (r Role) rank() int {
+	switch r {
+	case RoleAdmin:
+		return 3
+	case RoleOperator:
+		return 2
+	case RoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// ParseRole validates a role name
+func ParseRole(s string) (Role, bool) {
+	r := Role(strings.ToLower(strings.TrimSpace(s)))
+	return r, r.rank() > 0
+}
+
+// CanMutate reports whether the role may perform state-changing requests
+func (r Role) CanMutate() bool {
+	return r.rank() >= RoleOperator.rank()
+}
+
+// User is an authenticated identity
+type User struct {
+	Subject  string   `json:"subject"`
+	Name     string   `json:"name"`
+	Email    string   `json:"email,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+	Role     Role     `json:"role"`
+	Provider string   `json:"provider"`
+}
+
+// SessionCookie is the cookie holding the session ID
+const SessionCookie = "gosei_session"
+
+// LoginStateCookie binds a login's OIDC state to the browser that started it
+const LoginStateCookie = "gosei_login_state"
+
+// Session is a logged-in browser session
+type Session struct {
+	ID      string
+	User    User
+	Expires time.Time
+}
+
+// SessionStore keeps sessions in memory; restarting gosei logs everyone out
+type SessionStore struct {
+	sessions map[string]*Session
+	ttl      time.Duration
+	mu       sync.RWMutex
+}
+
+// NewSessionStore creates a session store whose sessions expire after ttl
+func NewSessionStore(ttl time.Duration) *SessionStore {
+	s := &SessionStore{
+		sessions: make(map[string]*Session),
+		ttl:      ttl,
+	}
+	go s.cleanup()
+	return s
+}
+
+// Create starts a session for user
+func (s *SessionStore) Create(user User) *Session {
+	sess := &Session{
+		ID:      randomString(32),
+		User:    user,
+		Expires: time.Now().Add(s.ttl),
+	}
+
+	s.mu.Lock()
+	s.sessions[sess.ID] = sess
+	s.mu.Unlock()
+
+	return sess
+}
+
+// Get returns a live session by ID
+func (s *SessionStore) Get(id string) (*Session, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.sessions[id]
+	if !ok || time.Now().After(sess.Expires) {
+		return nil, false
+	}
+	return sess, true
+}
+
+// Delete ends a session
+func (s *SessionStore) Delete(id string) {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+}
+
+func (s *SessionStore) cleanup() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		s.mu.Lock()
+		for id, sess := range s.sessions {
+			if now.After(sess.Expires) {
+				delete(s.sessions, id)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+type contextKey struct{}
+
+// WithUser returns a context carrying the authenticated user
+func WithUser(ctx context.Context, u *User) context.Context {
+	return context.WithValue(ctx, contextKey{}, u)
+}
+
+// UserFromContext returns the authenticated user, if any
+func UserFromContext(ctx context.Context) (*User, bool) {
+	u, ok := ctx.Value(contextKey{}).(*User)
+	return u, ok && u != nil
+}
+
+// secureRequest reports whether the browser reached gosei over HTTPS
+func secureRequest(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("auth: failed to read random bytes: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
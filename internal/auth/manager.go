@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// LoginPath is where unauthenticated browsers are sent
+const LoginPath = "/auth/login"
+
+// publicPrefixes are reachable without logging in: the login flow itself,
+// assets for the login pages, and probes used by load balancers and systemd
+var publicPrefixes = []string{
+	"/static/",
+	"/auth/",
+	"/api/system/health",
+	"/api/system/ready",
+	"/api/v1/system/health",
+	"/api/v1/system/ready",
+}
+
+// Manager ties together the login provider and browser sessions
+type Manager struct {
+	oidc     *OIDCProvider
+	sessions *SessionStore
+}
+
+// NewManager creates an auth manager using OpenID Connect for login
+func NewManager(oidc *OIDCProvider, sessions *SessionStore) *Manager {
+	return &Manager{
+		oidc:     oidc,
+		sessions: sessions,
+	}
+}
+
+// OIDC returns the OpenID Connect provider
+func (m *Manager) OIDC() *OIDCProvider {
+	return m.oidc
+}
+
+// BeginLogin starts an OIDC login and returns the issuer URL to redirect the
+// browser to. The login's state is also set in a cookie, so the callback only
+// completes logins this browser started; otherwise anyone could log a victim
+// into the attacker's account by getting them to open a callback URL.
+func (m *Manager) BeginLogin(w http.ResponseWriter, r *http.Request, returnTo string) string {
+	authURL, state := m.oidc.AuthCodeURL(returnTo)
+	http.SetCookie(w, &http.Cookie{
+		Name:     LoginStateCookie,
+		Value:    state,
+		Path:     "/auth/",
+		MaxAge:   int(loginTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   secureRequest(r),
+		// Lax so the cookie is sent on the issuer's redirect back
+		SameSite: http.SameSiteLaxMode,
+	})
+	return authURL
+}
+
+// FinishLogin completes a login started by BeginLogin on this browser,
+// returning the user and the page they originally asked for
+func (m *Manager) FinishLogin(ctx context.Context, w http.ResponseWriter, r *http.Request, state, code string) (*User, string, error) {
+	c, err := r.Cookie(LoginStateCookie)
+	http.SetCookie(w, &http.Cookie{
+		Name:     LoginStateCookie,
+		Value:    "",
+		Path:     "/auth/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(c.Value), []byte(state)) != 1 {
+		return nil, "", errors.New("login was not started by this browser")
+	}
+	return m.oidc.Exchange(ctx, state, code)
+}
+
+// StartSession logs the user in on this browser
+func (m *Manager) StartSession(w http.ResponseWriter, r *http.Request, user User) {
+	sess := m.sessions.Create(user)
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    sess.ID,
+		Path:     "/",
+		Expires:  sess.Expires,
+		HttpOnly: true,
+		Secure:   secureRequest(r),
+		// Lax rather than Strict so following a link to gosei from elsewhere
+		// (including the issuer's redirect back) keeps the session
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// EndSession logs the browser out
+func (m *Manager) EndSession(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(SessionCookie); err == nil {
+		m.sessions.Delete(c.Value)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Middleware requires a logged-in user for everything but public paths and
+// enforces read-only access for viewers
+func (m *Manager) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if c, err := r.Cookie(SessionCookie); err == nil {
+				if sess, ok := m.sessions.Get(c.Value); ok {
+					user := sess.User
+					r = r.WithContext(WithUser(r.Context(), &user))
+				}
+			}
+
+			if isPublic(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, ok := UserFromContext(r.Context())
+			if !ok {
+				m.unauthenticated(w, r)
+				return
+			}
+
+			if isMutating(r.Method) && !user.Role.CanMutate() {
+				http.Error(w, "Forbidden: read-only access", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAdmin rejects users other than admins. Requests without a user,
+// when auth is disabled or in agent mode, are let through.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := UserFromContext(r.Context()); ok && user.Role != RoleAdmin {
+			http.Error(w, "Forbidden: admin access required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unauthenticated sends page requests to the login page and rejects API calls
+func (m *Manager) unauthenticated(w http.ResponseWriter, r *http.Request) {
+	login := LoginPath + "?" + url.Values{"return_to": {r.URL.RequestURI()}}.Encode()
+
+	// htmx follows this header with a full page navigation rather than
+	// swapping the login redirect into a fragment
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", LoginPath)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+		http.Redirect(w, r, login, http.StatusFound)
+		return
+	}
+
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// SafeReturnTo only allows redirects back to paths on this server
+func SafeReturnTo(returnTo string) string {
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		return "/"
+	}
+	return returnTo
+}
+
+func isPublic(path string) bool {
+	for _, prefix := range publicPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCConfig configures OpenID Connect login
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // e.g. https://gosei.lan/auth/callback
+	Scopes       []string
+	GroupsClaim  string
+	// RoleMap maps group names to roles; "*" matches every user. When empty,
+	// every authenticated user is an admin.
+	RoleMap map[string]Role
+}
+
+// loginTimeout is how long a started login can wait for the callback
+const loginTimeout = 10 * time.Minute
+
+// ParseRoleMap parses "group=role,group=role" mappings
+func ParseRoleMap(spec string) (map[string]Role, error) {
+	result := make(map[string]Role)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		group, roleName, ok := strings.Cut(item, "=")
+		role, valid := ParseRole(roleName)
+		if !ok || group == "" || !valid {
+			return nil, fmt.Errorf("invalid role mapping %q, expected group=admin|operator|viewer", item)
+		}
+		result[strings.TrimSpace(group)] = role
+	}
+	return result, nil
+}
+
+// maxPendingLogins caps the logins awaiting their callback, since anyone
+// can start one; past it the oldest is dropped
+const maxPendingLogins = 1000
+
+// OIDCProvider implements the authorization code flow with PKCE against an
+// OpenID Connect issuer
+type OIDCProvider struct {
+	cfg      OIDCConfig
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	client   *http.Client
+
+	pending   map[string]pendingLogin
+	pendingMu sync.Mutex
+}
+
+// pendingLogin is the state kept between redirecting to the issuer and the callback
+type pendingLogin struct {
+	nonce    string
+	verifier string
+	returnTo string
+	expires  time.Time
+}
+
+// NewOIDCProvider discovers the issuer's endpoints
+func NewOIDCProvider(ctx context.Context, cfg OIDCConfig) (*OIDCProvider, error) {
+	if cfg.IssuerURL == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("OIDC requires an issuer URL, client ID and redirect URL")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{oidc.ScopeOpenID, "profile", "email", "groups"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, client), cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+
+	return &OIDCProvider{
+		cfg: cfg,
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{
+			ClientID: cfg.ClientID,
+			// Allow a little clock skew between gosei and the issuer
+			Now: func() time.Time { return time.Now().Add(-time.Minute) },
+		}),
+		client:  client,
+		pending: make(map[string]pendingLogin),
+	}, nil
+}
+
+// AuthCodeURL starts a login and returns the issuer URL to redirect the
+// browser to, along with the login's state
+func (p *OIDCProvider) AuthCodeURL(returnTo string) (string, string) {
+	state := randomString(24)
+	login := pendingLogin{
+		nonce:    randomString(24),
+		verifier: oauth2.GenerateVerifier(),
+		returnTo: returnTo,
+		expires:  time.Now().Add(loginTimeout),
+	}
+
+	p.pendingMu.Lock()
+	p.prunePending(time.Now())
+	p.pending[state] = login
+	p.pendingMu.Unlock()
+
+	return p.oauth.AuthCodeURL(state, oidc.Nonce(login.nonce), oauth2.S256ChallengeOption(login.verifier)), state
+}
+
+// prunePending removes expired logins and, when the cap is reached, the
+// oldest one. The caller must hold pendingMu.
+func (p *OIDCProvider) prunePending(now time.Time) {
+	var oldest string
+	for s, pl := range p.pending {
+		if now.After(pl.expires) {
+			delete(p.pending, s)
+			continue
+		}
+		if oldest == "" || pl.expires.Before(p.pending[oldest].expires) {
+			oldest = s
+		}
+	}
+	if len(p.pending) >= maxPendingLogins {
+		delete(p.pending, oldest)
+	}
+}
+
+// Exchange completes a login from the callback's state and code, returning
+// the user and the page they originally asked for
+func (p *OIDCProvider) Exchange(ctx context.Context, state, code string) (*User, string, error) {
+	p.pendingMu.Lock()
+	login, ok := p.pending[state]
+	delete(p.pending, state)
+	p.pendingMu.Unlock()
+	if !ok || time.Now().After(login.expires) {
+		return nil, "", errors.New("unknown or expired login state")
+	}
+
+	ctx = oidc.ClientContext(ctx, p.client)
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(login.verifier))
+	if err != nil {
+		return nil, "", fmt.Errorf("token request failed: %w", err)
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, "", errors.New("token response has no ID token")
+	}
+
+	claims, err := p.verifyIDToken(ctx, rawIDToken, login.nonce)
+	if err != nil {
+		return nil, "", err
+	}
+
+	user, err := p.userFromClaims(claims)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, login.returnTo, nil
+}
+
+// verifyIDToken checks the ID token's signature, issuer, audience, expiry
+// and nonce, returning its claims
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, raw, nonce string) (map[string]interface{}, error) {
+	idToken, err := p.verifier.Verify(ctx, raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("ID token nonce mismatch")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("malformed ID token claims: %w", err)
+	}
+	return claims, nil
+}
+
+// userFromClaims builds a user and maps their groups to a role
+func (p *OIDCProvider) userFromClaims(claims map[string]interface{}) (*User, error) {
+	user := &User{Provider: "oidc"}
+	user.Subject, _ = claims["sub"].(string)
+	user.Email, _ = claims["email"].(string)
+	for _, claim := range []string{"name", "preferred_username", "email", "sub"} {
+		if v, _ := claims[claim].(string); v != "" {
+			user.Name = v
+			break
+		}
+	}
+
+	switch g := claims[p.cfg.GroupsClaim].(type) {
+	case []interface{}:
+		for _, item := range g {
+			if s, ok := item.(string); ok {
+				user.Groups = append(user.Groups, s)
+			}
+		}
+	case string:
+		user.Groups = []string{g}
+	}
+
+	if len(p.cfg.RoleMap) == 0 {
+		user.Role = RoleAdmin
+		return user, nil
+	}
+
+	user.Role = p.cfg.RoleMap["*"]
+	for _, g := range user.Groups {
+		if r, ok := p.cfg.RoleMap[g]; ok && r.rank() > user.Role.rank() {
+			user.Role = r
+		}
+	}
+	if user.Role == "" {
+		return nil, fmt.Errorf("user %s is not in any group with access to gosei", user.Name)
+	}
+	return user, nil
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// testIssuer is an OpenID Connect issuer serving discovery and one RSA
+// signing key with ID "k1"
+type testIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := &testIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                iss.URL,
+			"authorization_endpoint":                iss.URL + "/authorize",
+			"token_endpoint":                        iss.URL + "/token",
+			"jwks_uri":                              iss.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "k1", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	iss.Server = httptest.NewServer(mux)
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+// sign makes a compact JWT of claims with the given algorithm, key and key ID
+func sign(t *testing.T, alg jose.SignatureAlgorithm, key interface{}, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: jose.JSONWebKey{Key: key, KeyID: kid}}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	iss := newTestIssuer(t)
+	p, err := NewOIDCProvider(context.Background(), OIDCConfig{
+		IssuerURL:   iss.URL,
+		ClientID:    "gosei",
+		RedirectURL: "http://gosei.test/auth/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(exp time.Duration) map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   iss.URL,
+			"aud":   "gosei",
+			"sub":   "alice",
+			"nonce": "n1",
+			"exp":   time.Now().Add(exp).Unix(),
+			"iat":   time.Now().Unix(),
+		}
+	}
+	withClaim := func(c map[string]interface{}, k string, v interface{}) map[string]interface{} {
+		c[k] = v
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+		ok    bool
+	}{
+		{"valid", sign(t, jose.RS256, iss.key, "k1", claims(time.Hour)), "n1", true},
+		{"expired within clock skew", sign(t, jose.RS256, iss.key, "k1", claims(-30*time.Second)), "n1", true},
+		{"expired", sign(t, jose.RS256, iss.key, "k1", claims(-2*time.Minute)), "n1", false},
+		{"unknown kid", sign(t, jose.RS256, otherRSA, "k2", claims(time.Hour)), "n1", false},
+		{"kid of another key", sign(t, jose.RS256, otherRSA, "k1", claims(time.Hour)), "n1", false},
+		{"HMAC alg", sign(t, jose.HS256, []byte("0123456789abcdef0123456789abcdef"), "k1", claims(time.Hour)), "n1", false},
+		{"EC alg for RSA kid", sign(t, jose.ES256, ecKey, "k1", claims(time.Hour)), "n1", false},
+		{"RS384 not advertised", sign(t, jose.RS384, iss.key, "k1", claims(time.Hour)), "n1", false},
+		{"wrong nonce", sign(t, jose.RS256, iss.key, "k1", claims(time.Hour)), "n2", false},
+		{"wrong audience", sign(t, jose.RS256, iss.key, "k1", withClaim(claims(time.Hour), "aud", "other")), "n1", false},
+		{"wrong issuer", sign(t, jose.RS256, iss.key, "k1", withClaim(claims(time.Hour), "iss", "https://evil.test")), "n1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.verifyIDToken(context.Background(), tt.token, tt.nonce)
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("token was accepted")
+			}
+			if tt.ok && got["sub"] != "alice" {
+				t.Errorf("sub = %v, want alice", got["sub"])
+			}
+		})
+	}
+}
+
+func TestPendingLoginsCapped(t *testing.T) {
+	iss := newTestIssuer(t)
+	p, err := NewOIDCProvider(context.Background(), OIDCConfig{
+		IssuerURL:   iss.URL,
+		ClientID:    "gosei",
+		RedirectURL: "http://gosei.test/auth/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.pending["expired"] = pendingLogin{expires: time.Now().Add(-time.Second)}
+	for range maxPendingLogins + 10 {
+		p.AuthCodeURL("/")
+	}
+	if n := len(p.pending); n != maxPendingLogins {
+		t.Errorf("pending logins = %d, want %d", n, maxPendingLogins)
+	}
+	if _, ok := p.pending["expired"]; ok {
+		t.Error("expired login was kept")
+	}
+}
+
+func TestFinishLoginRequiresStateCookie(t *testing.T) {
+	iss := newTestIssuer(t)
+	p, err := NewOIDCProvider(context.Background(), OIDCConfig{
+		IssuerURL:   iss.URL,
+		ClientID:    "gosei",
+		RedirectURL: "http://gosei.test/auth/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(p, NewSessionStore(time.Hour))
+
+	rec := httptest.NewRecorder()
+	authURL := m.BeginLogin(rec, httptest.NewRequest(http.MethodGet, "/auth/login", nil), "/")
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := u.Query().Get("state")
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == LoginStateCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Value != state || !cookie.HttpOnly {
+		t.Fatalf("login state cookie = %+v, want HttpOnly with state %q", cookie, state)
+	}
+
+	tests := []struct {
+		name   string
+		cookie string
+	}{
+		{"no cookie", ""},
+		{"other login", "someone-elses-state"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/auth/callback", nil)
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: LoginStateCookie, Value: tt.cookie})
+			}
+			_, _, err := m.FinishLogin(r.Context(), httptest.NewRecorder(), r, state, "code")
+			if err == nil || !strings.Contains(err.Error(), "not started by this browser") {
+				t.Errorf("err = %v, want browser mismatch", err)
+			}
+			if _, ok := p.pending[state]; !ok {
+				t.Error("mismatched callback consumed the pending login")
+			}
+		})
+	}
+
+	// With the cookie the login proceeds to the token request, which the
+	// test issuer doesn't serve
+	r := httptest.NewRequest(http.MethodGet, "/auth/callback", nil)
+	r.AddCookie(cookie)
+	_, _, err = m.FinishLogin(r.Context(), httptest.NewRecorder(), r, state, "code")
+	if err == nil || !strings.Contains(err.Error(), "token request failed") {
+		t.Errorf("err = %v, want token request failure", err)
+	}
+}
@@ -0,0 +1,306 @@
+package autoupdate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/maintenance"
+	"github.com/lyall/gosei/internal/project"
+	"github.com/lyall/gosei/internal/sse"
+	"github.com/lyall/gosei/internal/store"
+)
+
+// Label opts a project into automatic updates, e.g. gosei.auto-update: daily
+const Label = project.LabelPrefix + "auto-update"
+
+// maxHistory is the number of results kept per project
+const maxHistory = 20
+
+// ParsePolicy converts a label value into an update interval. It accepts
+// hourly, daily, weekly or a Go duration such as 6h.
+func ParsePolicy(value string) (time.Duration, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "false", "off", "no", "never":
+		return 0, nil
+	case "hourly":
+		return time.Hour, nil
+	case "daily", "true", "on", "yes":
+		return 24 * time.Hour, nil
+	case "weekly":
+		return 7 * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q: expected hourly, daily, weekly or a duration", Label, value)
+	}
+	if d < 5*time.Minute {
+		return 0, fmt.Errorf("%s interval %s is too short (minimum 5m)", Label, d)
+	}
+	return d, nil
+}
+
+// Result records one automatic update attempt
+type Result struct {
+	ProjectID string    `json:"projectId"`
+	Time      time.Time `json:"time"`
+	Success   bool      `json:"success"`
+	Updated   []string  `json:"updated,omitempty"` // services whose image changed
+	Message   string    `json:"message"`
+}
+
+// Topic publishes results as autoupdate:complete
+func (Result) Topic() sse.Topic { return sse.TopicAutoUpdateComplete }
+
+// Status describes a project's update policy and recent results
+type Status struct {
+	ProjectID string     `json:"projectId"`
+	Policy    string     `json:"policy"`
+	Interval  string     `json:"interval,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	LastRun   *time.Time `json:"lastRun,omitempty"`
+	NextRun   *time.Time `json:"nextRun,omitempty"`
+	History   []Result   `json:"history"`
+
+	interval time.Duration
+}
+
+// Updater periodically pulls new images for opted-in projects and recreates
+// containers whose image changed, replacing a separate Watchtower container
+type Updater struct {
+	docker  docker.DockerClient
+	compose docker.ComposeExecutor
+	scanner *project.Scanner
+	broker  *sse.Broker
+	started time.Time
+	windows *maintenance.Manager
+	saved   store.Doc
+
+	lastRun map[string]time.Time
+	history map[string][]Result
+	running map[string]bool
+	mu      sync.Mutex
+}
+
+// New creates an updater
+func New(dc docker.DockerClient, ce docker.ComposeExecutor, s *project.Scanner, b *sse.Broker) *Updater {
+	return &Updater{
+		docker:  dc,
+		compose: ce,
+		scanner: s,
+		broker:  b,
+		started: time.Now(),
+		lastRun: make(map[string]time.Time),
+		history: make(map[string][]Result),
+		running: make(map[string]bool),
+	}
+}
+
+// SetMaintenance holds off scheduled updates of projects in a maintenance
+// window. Updates started through the API still run.
+func (u *Updater) SetMaintenance(m *maintenance.Manager) {
+	u.windows = m
+}
+
+// savedHistory is what the updater keeps across restarts
+type savedHistory struct {
+	LastRun map[string]time.Time `json:"lastRun"`
+	History map[string][]Result  `json:"history"`
+}
+
+// SetStore restores the last runs and results saved in doc and saves them
+// there after each update, so a restart neither forgets the history nor
+// pushes every project's next update back by a whole interval
+func (u *Updater) SetStore(doc store.Doc) error {
+	var saved savedHistory
+	if _, err := doc.Load(&saved); err != nil {
+		return err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.saved = doc
+	for id, t := range saved.LastRun {
+		u.lastRun[id] = t
+	}
+	for id, h := range saved.History {
+		u.history[id] = h
+	}
+	return nil
+}
+
+// Run checks for due projects every tick until ctx is cancelled
+func (u *Updater) Run(ctx context.Context, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, p := range u.scanner.ListProjects() {
+			st := u.Status(p)
+			if st.interval == 0 || st.NextRun == nil || time.Now().Before(*st.NextRun) {
+				continue
+			}
+			if w := u.windows.Active(p.ID); w != nil {
+				slog.Debug("Auto-update held off by maintenance window", "project", p.Name, "until", w.End)
+				continue
+			}
+			u.Update(ctx, p)
+		}
+	}
+}
+
+// Status returns a project's update policy and history
+func (u *Updater) Status(p *project.Project) Status {
+	st := Status{
+		ProjectID: p.ID,
+		Policy:    p.Labels[Label],
+	}
+
+	interval, err := ParsePolicy(st.Policy)
+	if err != nil {
+		st.Error = err.Error()
+	}
+	st.interval = interval
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	st.History = append([]Result{}, u.history[p.ID]...)
+	last, ran := u.lastRun[p.ID]
+	if ran {
+		st.LastRun = &last
+	}
+	if interval > 0 {
+		st.Interval = interval.String()
+		// The first update is one interval after startup, so restarting
+		// gosei does not update every project at once
+		base := u.started
+		if ran {
+			base = last
+		}
+		next := base.Add(interval)
+		st.NextRun = &next
+	}
+	return st
+}
+
+// Update pulls new images for a project and recreates changed containers.
+// Stopped projects are skipped so an update never starts them.
+func (u *Updater) Update(ctx context.Context, p *project.Project) Result {
+	u.mu.Lock()
+	if u.running[p.ID] {
+		u.mu.Unlock()
+		return Result{ProjectID: p.ID, Time: time.Now(), Message: "Update already in progress"}
+	}
+	u.running[p.ID] = true
+	u.mu.Unlock()
+
+	result := u.update(ctx, p)
+
+	u.mu.Lock()
+	delete(u.running, p.ID)
+	u.lastRun[p.ID] = result.Time
+	history := append(u.history[p.ID], result)
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	u.history[p.ID] = history
+	if u.saved != nil {
+		if err := u.saved.Save(savedHistory{LastRun: u.lastRun, History: u.history}); err != nil {
+			slog.Warn("Failed to save auto-update history", "error", err)
+		}
+	}
+	u.mu.Unlock()
+
+	level := slog.LevelInfo
+	if !result.Success {
+		level = slog.LevelError
+	}
+	slog.Log(ctx, level, "Automatic update finished",
+		"project", p.Name, "success", result.Success, "updated", result.Updated, "message", result.Message)
+	u.broker.Publish(result)
+
+	return result
+}
+
+func (u *Updater) update(ctx context.Context, p *project.Project) Result {
+	result := Result{ProjectID: p.ID, Time: time.Now()}
+
+	before, err := u.imagesByService(ctx, p.Name)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	if len(before) == 0 {
+		result.Success = true
+		result.Message = "Skipped: project is not running"
+		return result
+	}
+
+	pulled, err := u.compose.Pull(ctx, p.Path, nil)
+	if err != nil || !pulled.Success {
+		result.Message = "Pull failed: " + composeMessage(pulled, err)
+		return result
+	}
+
+	// up only recreates containers whose image or configuration changed
+	up, err := u.compose.Up(ctx, p.Path, docker.UpOptions{}, nil)
+	if err != nil || !up.Success {
+		result.Message = "Up failed: " + composeMessage(up, err)
+		return result
+	}
+
+	after, err := u.imagesByService(ctx, p.Name)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	for svc, img := range after {
+		if prev, ok := before[svc]; ok && prev != img {
+			result.Updated = append(result.Updated, svc)
+		}
+	}
+	sort.Strings(result.Updated)
+
+	result.Success = true
+	if len(result.Updated) == 0 {
+		result.Message = "Already up to date"
+	} else {
+		result.Message = "Updated " + strings.Join(result.Updated, ", ")
+	}
+	return result
+}
+
+// imagesByService maps running services to their image IDs
+func (u *Updater) imagesByService(ctx context.Context, projectName string) (map[string]string, error) {
+	containers, err := u.docker.ListContainers(ctx, projectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	images := make(map[string]string)
+	for _, c := range containers {
+		if c.State == "running" {
+			images[c.ServiceName] = c.ImageID
+		}
+	}
+	return images, nil
+}
+
+func composeMessage(r *docker.ComposeResult, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return r.Message
+}
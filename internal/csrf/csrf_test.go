@@ -0,0 +1,120 @@
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// testToken is a well-formed token for requests that already have a cookie
+var testToken = strings.Repeat("t", 43)
+
+func serve(r *http.Request) (*httptest.ResponseRecorder, string) {
+	var seen string
+	h := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = Token(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec, seen
+}
+
+func issued(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == CookieName {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestMiddlewareIssuesToken(t *testing.T) {
+	rec, seen := serve(httptest.NewRequest(http.MethodGet, "/", nil))
+	c := issued(rec)
+	if c == nil {
+		t.Fatal("no token cookie issued on GET")
+	}
+	if len(c.Value) != 43 || !c.HttpOnly || c.SameSite != http.SameSiteStrictMode {
+		t.Errorf("cookie = %+v, want a 43 character HttpOnly SameSite=Strict token", c)
+	}
+	if seen != c.Value {
+		t.Errorf("Token() = %q, want the issued %q", seen, c.Value)
+	}
+
+	// An existing token is kept rather than replaced
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: CookieName, Value: testToken})
+	rec, seen = serve(r)
+	if c := issued(rec); c != nil {
+		t.Errorf("token reissued: %q", c.Value)
+	}
+	if seen != testToken {
+		t.Errorf("Token() = %q, want %q", seen, testToken)
+	}
+
+	// A malformed one is replaced
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: CookieName, Value: "short"})
+	rec, _ = serve(r)
+	if c := issued(rec); c == nil || c.Value == "short" {
+		t.Errorf("malformed token not replaced: %+v", c)
+	}
+}
+
+func TestMiddlewareChecksToken(t *testing.T) {
+	form := func(token string) string {
+		return url.Values{FormField: {token}}.Encode()
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		cookie string // token cookie sent, if any
+		header map[string]string
+		body   string // form body, if any
+		want   int
+	}{
+		{"GET", http.MethodGet, testToken, nil, "", http.StatusNoContent},
+		{"HEAD", http.MethodHead, testToken, nil, "", http.StatusNoContent},
+		{"OPTIONS", http.MethodOptions, testToken, nil, "", http.StatusNoContent},
+		{"GET from browser without token", http.MethodGet, "", map[string]string{"Origin": "https://evil.example"}, "", http.StatusNoContent},
+
+		{"header token", http.MethodPost, testToken, map[string]string{HeaderName: testToken}, "", http.StatusNoContent},
+		{"form token", http.MethodPost, testToken, nil, form(testToken), http.StatusNoContent},
+		{"header preferred over form", http.MethodPost, testToken, map[string]string{HeaderName: testToken}, form("wrong"), http.StatusNoContent},
+		{"PUT with token", http.MethodPut, testToken, map[string]string{HeaderName: testToken}, "", http.StatusNoContent},
+		{"PATCH with token", http.MethodPatch, testToken, map[string]string{HeaderName: testToken}, "", http.StatusNoContent},
+
+		{"missing token", http.MethodPost, testToken, nil, "", http.StatusForbidden},
+		{"mismatched header", http.MethodPost, testToken, map[string]string{HeaderName: strings.Repeat("x", 43)}, "", http.StatusForbidden},
+		{"mismatched form", http.MethodPost, testToken, nil, form(strings.Repeat("x", 43)), http.StatusForbidden},
+		{"wrong header with right form", http.MethodPost, testToken, map[string]string{HeaderName: "wrong"}, form(testToken), http.StatusForbidden},
+		{"DELETE without token", http.MethodDelete, testToken, nil, "", http.StatusForbidden},
+		{"no cookie", http.MethodPost, "", map[string]string{"Origin": "https://evil.example", HeaderName: testToken}, "", http.StatusForbidden},
+		{"cross-site fetch", http.MethodPost, "", map[string]string{"Sec-Fetch-Site": "cross-site"}, "", http.StatusForbidden},
+
+		{"non-browser client", http.MethodPost, "", nil, "", http.StatusNoContent},
+		{"bearer token", http.MethodPost, testToken, map[string]string{"Authorization": "Bearer secret"}, "", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/projects/web/up", strings.NewReader(tt.body))
+			if tt.body != "" {
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: CookieName, Value: tt.cookie})
+			}
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			rec, _ := serve(r)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package docker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultContainerCacheTTL bounds how stale a cached container listing can
+// be when no Docker event invalidates it first
+const DefaultContainerCacheTTL = 2 * time.Second
+
+// CachingClient shares one listing of all containers between callers, so
+// rendering a dashboard of many projects lists containers once instead of
+// once per project. The listing is dropped after the TTL, when a container
+// event arrives on any WatchEvents stream, and when gosei itself changes a
+// container.
+type CachingClient struct {
+	DockerClient
+	ttl time.Duration
+
+	containers []ContainerInfo
+	fetched    time.Time
+	generation uint64 // bumped on invalidation, so a listing racing it isn't stored
+	mu         sync.RWMutex
+	fetchMu    sync.Mutex // lets concurrent callers wait for one listing
+}
+
+// NewCachingClient wraps dc with a container listing cache
+func NewCachingClient(dc DockerClient, ttl time.Duration) *CachingClient {
+	return &CachingClient{DockerClient: dc, ttl: ttl}
+}
+
+// ListContainers returns containers from the cached listing, filtered by
+// project like the wrapped client
+func (c *CachingClient) ListContainers(ctx context.Context, projectName string) ([]ContainerInfo, error) {
+	if all, ok := c.cached(); ok {
+		return filterProject(all, projectName), nil
+	}
+
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+
+	// Another caller may have listed while this one waited
+	if all, ok := c.cached(); ok {
+		return filterProject(all, projectName), nil
+	}
+
+	c.mu.RLock()
+	gen := c.generation
+	c.mu.RUnlock()
+
+	all, err := c.DockerClient.ListContainers(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.generation == gen {
+		c.containers, c.fetched = all, time.Now()
+	}
+	c.mu.Unlock()
+	return filterProject(all, projectName), nil
+}
+
+func (c *CachingClient) cached() ([]ContainerInfo, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.containers == nil || time.Since(c.fetched) > c.ttl {
+		return nil, false
+	}
+	return c.containers, true
+}
+
+// filterProject copies the containers of a project, or all for ""
+func filterProject(all []ContainerInfo, projectName string) []ContainerInfo {
+	result := make([]ContainerInfo, 0, len(all))
+	for _, ci := range all {
+		if projectName == "" || ci.ProjectName == projectName {
+			result = append(result, ci)
+		}
+	}
+	return result
+}
+
+// Invalidate drops the cached listing
+func (c *CachingClient) Invalidate() {
+	c.mu.Lock()
+	c.containers = nil
+	c.generation++
+	c.mu.Unlock()
+}
+
+// WatchEvents passes events through, invalidating the listing on container
+// events before the caller sees them, so its reaction lists fresh state
+func (c *CachingClient) WatchEvents(ctx context.Context) (<-chan Event, <-chan error) {
+	events, errs := c.DockerClient.WatchEvents(ctx)
+	out := make(chan Event)
+
+	go func() {
+		defer close(out)
+		for event := range events {
+			if event.Type == "container" {
+				c.Invalidate()
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, errs
+}
+
+// StartContainer starts a container and invalidates the listing
+func (c *CachingClient) StartContainer(ctx context.Context, id string) error {
+	defer c.Invalidate()
+	return c.DockerClient.StartContainer(ctx, id)
+}
+
+// StopContainer stops a container and invalidates the listing
+func (c *CachingClient) StopContainer(ctx context.Context, id string, timeout int) error {
+	defer c.Invalidate()
+	return c.DockerClient.StopContainer(ctx, id, timeout)
+}
+
+// RestartContainer restarts a container and invalidates the listing
+func (c *CachingClient) RestartContainer(ctx context.Context, id string, timeout int) error {
+	defer c.Invalidate()
+	return c.DockerClient.RestartContainer(ctx, id, timeout)
+}
+
+// CreateContainer creates a container and invalidates the listing
+func (c *CachingClient) CreateContainer(ctx context.Context, opts CreateOptions) (string, error) {
+	defer c.Invalidate()
+	return c.DockerClient.CreateContainer(ctx, opts)
+}
+
+// UpdateContainer updates a container and invalidates the listing
+func (c *CachingClient) UpdateContainer(ctx context.Context, id string, res ContainerResources) ([]string, error) {
+	defer c.Invalidate()
+	return c.DockerClient.UpdateContainer(ctx, id, res)
+}
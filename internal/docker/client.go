@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -13,30 +14,61 @@ import (
 	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/tlsconfig"
 )
 
 // Client wraps the Docker SDK client with convenience methods
 type Client struct {
-	cli *client.Client
-	mu  sync.RWMutex
+	cli     *client.Client
+	opts    ClientOptions
+	envMask *EnvMasker
+	// publicHost is where published ports are reachable, for port URLs
+	publicHost string
+	// pullVolumeBrowser allows pulling VolumeBrowserImage when missing
+	pullVolumeBrowser bool
+	mu                sync.RWMutex
+}
+
+// ClientOptions configures how the Docker daemon is reached. Zero values fall
+// back to the standard DOCKER_* environment variables.
+type ClientOptions struct {
+	Context   string // docker CLI context the options were resolved from, if any
+	Host      string // unix://, tcp:// or ssh://user@host
+	TLSCACert string
+	TLSCert   string
+	TLSKey    string
+	TLSVerify bool
+}
+
+// UsesTLS reports whether any TLS material was configured
+func (o ClientOptions) UsesTLS() bool {
+	return o.TLSCACert != "" || o.TLSCert != "" || o.TLSKey != ""
 }
 
 // ContainerInfo represents container information for the UI
 type ContainerInfo struct {
-	ID          string            `json:"id"`
-	Name        string            `json:"name"`
-	Image       string            `json:"image"`
-	ImageID     string            `json:"imageId"`
-	Status      string            `json:"status"`
-	State       string            `json:"state"`
-	Health      string            `json:"health"`
-	Created     time.Time         `json:"created"`
-	Ports       []PortMapping     `json:"ports"`
-	Labels      map[string]string `json:"labels"`
-	ProjectName string            `json:"projectName"`
-	ServiceName string            `json:"serviceName"`
-	ComposeFile string            `json:"composeFile"`
-	WorkingDir  string            `json:"workingDir"`
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Image       string              `json:"image"`
+	ImageID     string              `json:"imageId"`
+	Status      string              `json:"status"`
+	State       string              `json:"state"`
+	Health      string              `json:"health"`
+	Created     time.Time           `json:"created"`
+	Ports       []PortMapping       `json:"ports"`
+	Labels      map[string]string   `json:"labels"`
+	ProjectName string              `json:"projectName"`
+	ServiceName string              `json:"serviceName"`
+	ComposeFile string              `json:"composeFile"`
+	WorkingDir  string              `json:"workingDir"`
+	Env         []EnvVar            `json:"env,omitempty"`         // only set by GetContainer
+	Resources   *ContainerResources `json:"resources,omitempty"`   // only set by GetContainer
+	Healthcheck *Healthcheck        `json:"healthcheck,omitempty"` // only set by GetContainer
+	ExitCode    int                 `json:"exitCode,omitempty"`    // of an exited or restarting container
+	FinishedAt  *time.Time          `json:"finishedAt,omitempty"`  // only set by GetContainer
+	OOMKilled   bool                `json:"oomKilled,omitempty"`   // only set by GetContainer
+	Ingress     []IngressURL        `json:"ingress,omitempty"`
+	Networks    []NetworkAttachment `json:"networks,omitempty"` // only set by GetContainer
 }
 
 // PortMapping represents a port mapping
@@ -45,22 +77,33 @@ type PortMapping struct {
 	HostPort      string `json:"hostPort"`
 	ContainerPort string `json:"containerPort"`
 	Protocol      string `json:"protocol"`
+	URL           string `json:"url,omitempty"`
 }
 
 // ContainerStats represents container resource usage
 type ContainerStats struct {
 	ID            string  `json:"id"`
+	Name          string  `json:"name,omitempty"`
 	CPUPercent    float64 `json:"cpuPercent"`
 	MemoryUsage   uint64  `json:"memoryUsage"`
 	MemoryLimit   uint64  `json:"memoryLimit"`
 	MemoryPercent float64 `json:"memoryPercent"`
 	NetworkRx     uint64  `json:"networkRx"`
 	NetworkTx     uint64  `json:"networkTx"`
+	BlkioRead     uint64  `json:"blkioRead"`  // bytes read from block devices
+	BlkioWrite    uint64  `json:"blkioWrite"` // bytes written to block devices
+	PIDs          uint64  `json:"pids"`
+	PIDsLimit     uint64  `json:"pidsLimit,omitempty"` // zero when unlimited
 }
 
 // NewClient creates a new Docker client wrapper
-func NewClient() (*Client, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+func NewClient(opts ClientOptions) (*Client, error) {
+	clientOpts, err := opts.sdkOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := client.NewClientWithOpts(clientOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker client: %w", err)
 	}
@@ -74,7 +117,105 @@ func NewClient() (*Client, error) {
 		return nil, fmt.Errorf("failed to connect to docker daemon: %w", err)
 	}
 
-	return &Client{cli: cli}, nil
+	return &Client{
+		cli:        cli,
+		opts:       opts,
+		envMask:    NewEnvMasker(DefaultEnvMask),
+		publicHost: defaultPublicHost(opts.Host),
+	}, nil
+}
+
+// SetPublicHost sets the host (optionally with a scheme, e.g.
+// https://myserver.lan) used to build URLs for published ports. An empty
+// host restores the default derived from the daemon address.
+func (c *Client) SetPublicHost(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if host == "" {
+		host = defaultPublicHost(c.opts.Host)
+	}
+	c.publicHost = host
+}
+
+// SetEnvMasker replaces the rules used to hide secret environment values
+// in GetContainer results
+func (c *Client) SetEnvMasker(m *EnvMasker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.envMask = m
+}
+
+// sdkOptions translates ClientOptions into Docker SDK client options
+func (o ClientOptions) sdkOptions() ([]client.Opt, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+
+	if o.UsesTLS() {
+		tlsc, err := tlsconfig.Client(tlsconfig.Options{
+			CAFile:             o.TLSCACert,
+			CertFile:           o.TLSCert,
+			KeyFile:            o.TLSKey,
+			InsecureSkipVerify: !o.TLSVerify,
+			ExclusiveRootPools: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load docker TLS config: %w", err)
+		}
+		// The HTTP client must be replaced before WithHost so the host's
+		// transport settings are applied on top of the TLS transport.
+		opts = append(opts, client.WithHTTPClient(&http.Client{
+			Transport:     &http.Transport{TLSClientConfig: tlsc},
+			CheckRedirect: client.CheckRedirect,
+		}))
+	}
+
+	if o.Host == "" {
+		return opts, nil
+	}
+
+	if strings.HasPrefix(o.Host, "ssh://") {
+		dialer, err := sshDialer(o.Host)
+		if err != nil {
+			return nil, err
+		}
+		// The host is a placeholder; every connection goes through the ssh tunnel
+		return append(opts, client.WithHost("http://docker.example.com"), client.WithDialContext(dialer)), nil
+	}
+
+	return append(opts, client.WithHost(o.Host)), nil
+}
+
+// CLIArgs returns global docker CLI flags matching this client's connection,
+// so shelled-out commands talk to the same daemon as the SDK client
+func (c *Client) CLIArgs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// A named context already carries host and TLS settings for the CLI
+	if c.opts.Context != "" {
+		return []string{"--context", c.opts.Context}
+	}
+
+	var args []string
+	if c.opts.Host != "" {
+		args = append(args, "--host", c.opts.Host)
+	}
+	if c.opts.UsesTLS() {
+		if c.opts.TLSVerify {
+			args = append(args, "--tlsverify")
+		} else {
+			args = append(args, "--tls")
+		}
+		if c.opts.TLSCACert != "" {
+			args = append(args, "--tlscacert", c.opts.TLSCACert)
+		}
+		if c.opts.TLSCert != "" {
+			args = append(args, "--tlscert", c.opts.TLSCert)
+		}
+		if c.opts.TLSKey != "" {
+			args = append(args, "--tlskey", c.opts.TLSKey)
+		}
+	}
+	return args
 }
 
 // Close closes the Docker client
@@ -84,6 +225,17 @@ func (c *Client) Close() error {
 	return c.cli.Close()
 }
 
+// Ping checks that the Docker daemon is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if _, err := c.cli.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping docker daemon: %w", err)
+	}
+	return nil
+}
+
 // ListContainers returns all containers, optionally filtered by project
 func (c *Client) ListContainers(ctx context.Context, projectName string) ([]ContainerInfo, error) {
 	c.mu.RLock()
@@ -159,8 +311,9 @@ func (c *Client) RestartContainer(ctx context.Context, id string, timeout int) e
 	return nil
 }
 
-// GetContainerLogs returns a stream of container logs
-func (c *Client) GetContainerLogs(ctx context.Context, id string, tail string, follow bool) (io.ReadCloser, error) {
+// GetContainerLogs returns a stream of container logs, from since when it
+// isn't zero
+func (c *Client) GetContainerLogs(ctx context.Context, id string, tail string, since time.Time, follow bool) (io.ReadCloser, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -171,6 +324,9 @@ func (c *Client) GetContainerLogs(ctx context.Context, id string, tail string, f
 		Tail:       tail,
 		Timestamps: true,
 	}
+	if !since.IsZero() {
+		opts.Since = fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond())
+	}
 
 	logs, err := c.cli.ContainerLogs(ctx, id, opts)
 	if err != nil {
@@ -240,9 +396,9 @@ func (c *Client) StreamContainerStats(ctx context.Context, id string) (<-chan *C
 	return statsCh, errCh
 }
 
-// WatchEvents watches for Docker events and returns a channel
-func (c *Client) WatchEvents(ctx context.Context) (<-chan ContainerEvent, <-chan error) {
-	eventCh := make(chan ContainerEvent)
+// WatchEvents watches for container, image, volume and network events
+func (c *Client) WatchEvents(ctx context.Context) (<-chan Event, <-chan error) {
+	eventCh := make(chan Event)
 	errCh := make(chan error, 1)
 
 	go func() {
@@ -251,21 +407,31 @@ func (c *Client) WatchEvents(ctx context.Context) (<-chan ContainerEvent, <-chan
 
 		c.mu.RLock()
 		msgs, errs := c.cli.Events(ctx, events.ListOptions{
-			Filters: filters.NewArgs(filters.Arg("type", "container")),
+			Filters: filters.NewArgs(
+				filters.Arg("type", string(events.ContainerEventType)),
+				filters.Arg("type", string(events.ImageEventType)),
+				filters.Arg("type", string(events.VolumeEventType)),
+				filters.Arg("type", string(events.NetworkEventType)),
+			),
 		})
 		c.mu.RUnlock()
 
 		for {
 			select {
 			case msg := <-msgs:
-				event := ContainerEvent{
+				event := Event{
+					Type:      string(msg.Type),
 					ID:        msg.Actor.ID,
 					Action:    string(msg.Action),
 					Name:      msg.Actor.Attributes["name"],
 					Image:     msg.Actor.Attributes["image"],
 					Project:   msg.Actor.Attributes["com.docker.compose.project"],
 					Service:   msg.Actor.Attributes["com.docker.compose.service"],
-					Timestamp: time.Unix(msg.Time, msg.TimeNano),
+					Timestamp: time.Unix(0, msg.TimeNano),
+				}
+				// Volumes are identified by name
+				if event.Name == "" && msg.Type == events.VolumeEventType {
+					event.Name = msg.Actor.ID
 				}
 				select {
 				case eventCh <- event:
@@ -286,8 +452,10 @@ func (c *Client) WatchEvents(ctx context.Context) (<-chan ContainerEvent, <-chan
 	return eventCh, errCh
 }
 
-// ContainerEvent represents a Docker container event
-type ContainerEvent struct {
+// Event is a Docker event. Image, Project and Service are only set for
+// container events.
+type Event struct {
+	Type      string    `json:"type"` // container, image, volume or network
 	ID        string    `json:"id"`
 	Action    string    `json:"action"`
 	Name      string    `json:"name"`
@@ -337,12 +505,14 @@ func (c *Client) containerToInfo(ctr types.Container) ContainerInfo {
 		State:       ctr.State,
 		Health:      health,
 		Created:     time.Unix(ctr.Created, 0),
-		Ports:       ports,
+		Ports:       withURLs(c.publicHost, ports),
 		Labels:      ctr.Labels,
 		ProjectName: ctr.Labels["com.docker.compose.project"],
 		ServiceName: ctr.Labels["com.docker.compose.service"],
 		ComposeFile: ctr.Labels["com.docker.compose.project.config_files"],
 		WorkingDir:  ctr.Labels["com.docker.compose.project.working_dir"],
+		Ingress:     ingressURLs(ctr.Labels, nil),
+		ExitCode:    exitCodeFromStatus(ctr.Status),
 	}
 }
 
@@ -373,6 +543,18 @@ func (c *Client) inspectToInfo(inspect types.ContainerJSON) ContainerInfo {
 	}
 
 	created, _ := time.Parse(time.RFC3339Nano, inspect.Created)
+	env := c.envMask.Parse(inspect.Config.Env)
+
+	// A restarting container also counts as running. FinishedAt is the
+	// zero time until the container first stops.
+	var exitCode int
+	var finished *time.Time
+	if !inspect.State.Running || inspect.State.Restarting {
+		exitCode = inspect.State.ExitCode
+		if t, err := time.Parse(time.RFC3339Nano, inspect.State.FinishedAt); err == nil && !t.IsZero() {
+			finished = &t
+		}
+	}
 
 	return ContainerInfo{
 		ID:          inspect.ID[:12],
@@ -383,11 +565,19 @@ func (c *Client) inspectToInfo(inspect types.ContainerJSON) ContainerInfo {
 		State:       inspect.State.Status,
 		Health:      health,
 		Created:     created,
-		Ports:       ports,
+		Ports:       withURLs(c.publicHost, ports),
 		Labels:      inspect.Config.Labels,
 		ProjectName: inspect.Config.Labels["com.docker.compose.project"],
 		ServiceName: inspect.Config.Labels["com.docker.compose.service"],
 		ComposeFile: inspect.Config.Labels["com.docker.compose.project.config_files"],
 		WorkingDir:  inspect.Config.Labels["com.docker.compose.project.working_dir"],
+		Env:         env,
+		Ingress:     ingressURLs(inspect.Config.Labels, env),
+		Resources:   resourcesFromHostConfig(inspect.HostConfig),
+		Healthcheck: healthcheckFromInspect(inspect.Config, inspect.State),
+		ExitCode:    exitCode,
+		FinishedAt:  finished,
+		OOMKilled:   inspect.State.OOMKilled,
+		Networks:    networksFromInspect(inspect.NetworkSettings),
 	}
 }
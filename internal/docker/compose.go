@@ -2,23 +2,50 @@ package docker
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // ComposeClient handles Docker Compose operations
 type ComposeClient struct {
 	dockerClient *Client
+	info         ComposeInfo
+	maxLine      int
+}
+
+// DefaultComposeMaxLineSize is the longest line of compose output sent as
+// one line; longer lines, such as from build steps, are split
+const DefaultComposeMaxLineSize = 1 << 20
+
+// NewComposeClient creates a new Compose client running the implementation
+// found by DetectCompose
+func NewComposeClient(dockerClient *Client, info ComposeInfo) *ComposeClient {
+	return &ComposeClient{dockerClient: dockerClient, info: info, maxLine: DefaultComposeMaxLineSize}
 }
 
-// NewComposeClient creates a new Compose client
-func NewComposeClient(dockerClient *Client) *ComposeClient {
-	return &ComposeClient{dockerClient: dockerClient}
+// SetMaxLineSize sets the longest line of output sent as one line
+func (c *ComposeClient) SetMaxLineSize(n int) {
+	if n > 0 {
+		c.maxLine = n
+	}
+}
+
+// Info returns the compose implementation in use
+func (c *ComposeClient) Info() ComposeInfo {
+	return c.info
 }
 
 // ComposeOutput represents output from a compose command
@@ -33,9 +60,51 @@ type ComposeResult struct {
 	Message string `json:"message"`
 }
 
+// UpOptions configures docker compose up. The zero value is a plain up.
+type UpOptions struct {
+	Pull          string `json:"pull,omitempty"` // "always", "missing", "never" or "build"
+	Build         bool   `json:"build,omitempty"`
+	ForceRecreate bool   `json:"forceRecreate,omitempty"`
+	Timeout       *int   `json:"timeout,omitempty"` // seconds to wait for containers to stop
+}
+
+// Validate checks option values before they become command line flags
+func (o UpOptions) Validate() error {
+	switch o.Pull {
+	case "", "always", "missing", "never", "build":
+	default:
+		return fmt.Errorf("invalid pull policy %q: expected always, missing, never or build", o.Pull)
+	}
+	if o.Timeout != nil && *o.Timeout < 0 {
+		return fmt.Errorf("invalid timeout %d: must not be negative", *o.Timeout)
+	}
+	return nil
+}
+
+// Args returns the docker compose up arguments for the options
+func (o UpOptions) Args() []string {
+	args := []string{"up", "-d", "--remove-orphans"}
+	if o.Pull != "" {
+		args = append(args, "--pull", o.Pull)
+	}
+	if o.Build {
+		args = append(args, "--build")
+	}
+	if o.ForceRecreate {
+		args = append(args, "--force-recreate")
+	}
+	if o.Timeout != nil {
+		args = append(args, "--timeout", strconv.Itoa(*o.Timeout))
+	}
+	return args
+}
+
 // Up runs docker compose up for a project
-func (c *ComposeClient) Up(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
-	return c.runCompose(ctx, projectDir, []string{"up", "-d", "--remove-orphans"}, outputCh)
+func (c *ComposeClient) Up(ctx context.Context, projectDir string, opts UpOptions, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
+	if err := c.info.CheckUp(opts); err != nil {
+		return &ComposeResult{Success: false, Message: err.Error()}, err
+	}
+	return c.runCompose(ctx, projectDir, opts.Args(), outputCh)
 }
 
 // Down runs docker compose down for a project
@@ -43,6 +112,46 @@ func (c *ComposeClient) Down(ctx context.Context, projectDir string, outputCh ch
 	return c.runCompose(ctx, projectDir, []string{"down", "--remove-orphans"}, outputCh)
 }
 
+// Start runs docker compose start, starting existing stopped containers
+func (c *ComposeClient) Start(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
+	return c.runCompose(ctx, projectDir, []string{"start"}, outputCh)
+}
+
+// Stop runs docker compose stop, keeping containers, networks and
+// anonymous volumes for a fast start later
+func (c *ComposeClient) Stop(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
+	return c.runCompose(ctx, projectDir, []string{"stop"}, outputCh)
+}
+
+// RunOptions configures a one-off compose run
+type RunOptions struct {
+	Service string            `json:"service"`
+	Command []string          `json:"command,omitempty"` // overrides the service command
+	Env     map[string]string `json:"env,omitempty"`
+}
+
+// Args returns the docker compose run arguments for the options. The
+// container is removed afterwards and gets no TTY, since output is streamed.
+func (o RunOptions) Args() []string {
+	args := []string{"run", "--rm", "-T"}
+	keys := make([]string, 0, len(o.Env))
+	for k := range o.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-e", k+"="+o.Env[k])
+	}
+	// "--" stops compose parsing options, so the service can't be a flag
+	args = append(args, "--", o.Service)
+	return append(args, o.Command...)
+}
+
+// Run runs a one-off command in a new container for a service
+func (c *ComposeClient) Run(ctx context.Context, projectDir string, opts RunOptions, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
+	return c.runCompose(ctx, projectDir, opts.Args(), outputCh)
+}
+
 // Pull runs docker compose pull for a project
 func (c *ComposeClient) Pull(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
 	return c.runCompose(ctx, projectDir, []string{"pull"}, outputCh)
@@ -65,9 +174,13 @@ func (c *ComposeClient) Update(ctx context.Context, projectDir string, outputCh
 	}
 
 	// Then recreate with up
-	return c.runCompose(ctx, projectDir, []string{"up", "-d", "--remove-orphans", "--force-recreate"}, outputCh)
+	return c.runCompose(ctx, projectDir, UpOptions{ForceRecreate: true}.Args(), outputCh)
 }
 
+// composeStopGrace is how long a cancelled compose command may take to
+// shut down before it is killed
+const composeStopGrace = 10 * time.Second
+
 // runCompose executes a docker compose command
 func (c *ComposeClient) runCompose(ctx context.Context, projectDir string, args []string, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
 	// Find compose file
@@ -76,12 +189,15 @@ func (c *ComposeClient) runCompose(ctx context.Context, projectDir string, args
 		return &ComposeResult{Success: false, Message: err.Error()}, err
 	}
 
-	// Build command
-	cmdArgs := []string{"compose", "-f", composeFile}
-	cmdArgs = append(cmdArgs, args...)
-
-	cmd := exec.CommandContext(ctx, "docker", cmdArgs...)
+	cmd, err := c.command(ctx, composeFile, args...)
+	if err != nil {
+		return &ComposeResult{Success: false, Message: err.Error()}, err
+	}
 	cmd.Dir = projectDir
+	// On cancellation let compose stop what it started, as it does on
+	// Ctrl-C, before killing it
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = composeStopGrace
 
 	// Set up pipes for stdout and stderr
 	stdout, err := cmd.StdoutPipe()
@@ -101,8 +217,8 @@ func (c *ComposeClient) runCompose(ctx context.Context, projectDir string, args
 
 	// Stream output
 	done := make(chan struct{})
-	go streamOutput(stdout, "stdout", outputCh, done)
-	go streamOutput(stderr, "stderr", outputCh, done)
+	go streamOutput(stdout, "stdout", c.maxLine, outputCh, done)
+	go streamOutput(stderr, "stderr", c.maxLine, outputCh, done)
 
 	// Wait for streaming to complete
 	<-done
@@ -123,20 +239,68 @@ func (c *ComposeClient) runCompose(ctx context.Context, projectDir string, args
 	}, nil
 }
 
-// streamOutput reads from a reader and sends output to a channel
-func streamOutput(r io.Reader, stream string, outputCh chan<- ComposeOutput, done chan<- struct{}) {
+// command builds the command for a compose subcommand, including the
+// connection flags of the configured Docker client. The v2 plugin and the
+// standalone v1 binary accept the same connection flags.
+func (c *ComposeClient) command(ctx context.Context, composeFile string, args ...string) (*exec.Cmd, error) {
+	if c.info.Error != "" {
+		return nil, fmt.Errorf("%w: %s", ErrComposeUnavailable, c.info.Error)
+	}
+
+	var cmdArgs []string
+	if c.dockerClient != nil {
+		cmdArgs = append(cmdArgs, c.dockerClient.CLIArgs()...)
+	}
+	name := c.info.Command
+	if c.info.Plugin {
+		name = "docker"
+		cmdArgs = append(cmdArgs, "compose")
+	}
+	cmdArgs = append(cmdArgs, "-f", composeFile)
+	return exec.CommandContext(ctx, name, append(cmdArgs, args...)...), nil
+}
+
+// streamOutput reads lines from a reader and sends them to a channel. Lines
+// longer than maxLine are sent in pieces; the reader is always drained, as
+// a command blocks once its pipe is full.
+func streamOutput(r io.Reader, stream string, maxLine int, outputCh chan<- ComposeOutput, done chan<- struct{}) {
 	defer func() { done <- struct{}{} }()
 
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
+	send := func(line []byte) {
 		if outputCh != nil {
 			outputCh <- ComposeOutput{
-				Line:   line,
+				Line:   strings.TrimSuffix(string(line), "\r"),
 				Stream: stream,
 			}
 		}
 	}
+
+	reader := bufio.NewReader(r)
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		text, eol := bytes.CutSuffix(chunk, []byte("\n"))
+		for len(line)+len(text) > maxLine {
+			n := maxLine - len(line)
+			send(append(line, text[:n]...))
+			line, text = line[:0], text[n:]
+		}
+		line = append(line, text...)
+
+		if eol {
+			send(line)
+			line = line[:0]
+		}
+		if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+			if len(line) > 0 {
+				send(line)
+			}
+			if !errors.Is(err, io.EOF) {
+				io.Copy(io.Discard, r)
+			}
+			return
+		}
+	}
 }
 
 // findComposeFile finds the compose file in a directory
@@ -159,6 +323,54 @@ func findComposeFile(dir string) (string, error) {
 	return "", fmt.Errorf("no compose file found in %s", dir)
 }
 
+// Config returns the fully resolved project configuration from docker compose
+// config, after interpolation, merging and profile selection. format is
+// "json" or "yaml".
+func (c *ComposeClient) Config(ctx context.Context, projectDir string, profiles []string, format string) ([]byte, error) {
+	composeFile, err := findComposeFile(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	for _, p := range profiles {
+		args = append(args, "--profile", p)
+	}
+	args = append(args, "config")
+	// v1 only prints YAML, so JSON is converted below
+	if !c.info.V1() {
+		args = append(args, "--format", format)
+	}
+
+	cmd, err := c.command(ctx, composeFile, args...)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Dir = projectDir
+
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("compose config failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("compose config failed: %w", err)
+	}
+	if c.info.V1() && format == "json" {
+		return yamlToJSON(output)
+	}
+	return output, nil
+}
+
+// yamlToJSON converts a YAML document to JSON
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v map[string]interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
 // GetComposeServices returns the list of services defined in a compose file
 func (c *ComposeClient) GetComposeServices(ctx context.Context, projectDir string) ([]string, error) {
 	composeFile, err := findComposeFile(projectDir)
@@ -166,7 +378,10 @@ func (c *ComposeClient) GetComposeServices(ctx context.Context, projectDir strin
 		return nil, err
 	}
 
-	cmd := exec.CommandContext(ctx, "docker", "compose", "-f", composeFile, "config", "--services")
+	cmd, err := c.command(ctx, composeFile, "config", "--services")
+	if err != nil {
+		return nil, err
+	}
 	cmd.Dir = projectDir
 
 	output, err := cmd.Output()
@@ -188,12 +403,18 @@ func (c *ComposeClient) GetComposeServices(ctx context.Context, projectDir strin
 
 // GetComposePs returns the status of services in a compose project
 func (c *ComposeClient) GetComposePs(ctx context.Context, projectDir string) ([]map[string]string, error) {
+	if c.info.V1() {
+		return nil, fmt.Errorf("ps --format json %w", ErrComposeV2Required)
+	}
 	composeFile, err := findComposeFile(projectDir)
 	if err != nil {
 		return nil, err
 	}
 
-	cmd := exec.CommandContext(ctx, "docker", "compose", "-f", composeFile, "ps", "--format", "json")
+	cmd, err := c.command(ctx, composeFile, "ps", "--format", "json")
+	if err != nil {
+		return nil, err
+	}
 	cmd.Dir = projectDir
 
 	output, err := cmd.Output()
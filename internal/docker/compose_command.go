@@ -0,0 +1,78 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrComposeUnavailable is returned by compose operations when no compose
+// implementation was found at startup
+var ErrComposeUnavailable = errors.New("docker compose is not available")
+
+// ErrComposeV2Required is returned for options the standalone docker-compose
+// v1 fallback doesn't have
+var ErrComposeV2Required = errors.New("requires docker compose v2")
+
+// ComposeInfo describes the compose implementation operations run with
+type ComposeInfo struct {
+	Command string `json:"command"`         // "docker compose" or the path of a standalone binary
+	Version string `json:"version"`         // e.g. "2.29.1"
+	Plugin  bool   `json:"plugin"`          // the v2 docker CLI plugin
+	Error   string `json:"error,omitempty"` // why compose is unavailable
+}
+
+// V1 reports whether the implementation is docker-compose v1, which lacks
+// flags added in v2. A standalone v2 binary has them all.
+func (i ComposeInfo) V1() bool {
+	return strings.HasPrefix(i.Version, "1.")
+}
+
+// CheckUp returns ErrComposeV2Required for up options v1 can't apply
+func (i ComposeInfo) CheckUp(o UpOptions) error {
+	if i.V1() && o.Pull != "" {
+		return fmt.Errorf("pull policy %w (docker-compose %s has no up --pull)", ErrComposeV2Required, i.Version)
+	}
+	return nil
+}
+
+// DetectCompose finds the compose implementation to use: the v2 docker CLI
+// plugin, or the standalone binary fallback (e.g. docker-compose v1) when
+// the plugin is missing and a fallback is configured
+func DetectCompose(ctx context.Context, fallback string) ComposeInfo {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	version, pluginErr := composeVersion(ctx, "docker", "compose", "version", "--short")
+	if pluginErr == nil {
+		return ComposeInfo{Command: "docker compose", Version: version, Plugin: true}
+	}
+	if fallback == "" {
+		return ComposeInfo{Command: "docker compose", Error: pluginErr.Error()}
+	}
+
+	path, err := exec.LookPath(fallback)
+	if err != nil {
+		return ComposeInfo{Command: fallback, Error: fmt.Sprintf("%v; fallback: %v", pluginErr, err)}
+	}
+	version, err = composeVersion(ctx, path, "version", "--short")
+	if err != nil {
+		return ComposeInfo{Command: path, Error: fmt.Sprintf("%v; fallback: %v", pluginErr, err)}
+	}
+	return ComposeInfo{Command: path, Version: version}
+}
+
+func composeVersion(ctx context.Context, name string, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%s %s: %s", name, strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), "v"), nil
+}
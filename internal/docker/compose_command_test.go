@@ -0,0 +1,39 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCheckUp(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		opts    UpOptions
+		wantErr bool
+	}{
+		{"v2 with pull", "2.29.1", UpOptions{Pull: "always"}, false},
+		{"v1 with pull", "1.29.2", UpOptions{Pull: "always"}, true},
+		{"v1 plain", "1.29.2", UpOptions{}, false},
+		{"v1 with v1 flags", "1.29.2", UpOptions{Build: true, ForceRecreate: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ComposeInfo{Version: tt.version}.CheckUp(tt.opts)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrComposeV2Required)) {
+				t.Errorf("CheckUp = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Up must refuse v2-only options before running docker-compose v1, which
+// would fail with a usage error
+func TestUpRejectsV2OptionsOnV1(t *testing.T) {
+	c := NewComposeClient(nil, ComposeInfo{Command: "/nonexistent/docker-compose", Version: "1.29.2"})
+	result, err := c.Up(context.Background(), t.TempDir(), UpOptions{Pull: "missing"}, nil)
+	if !errors.Is(err, ErrComposeV2Required) || result.Success {
+		t.Errorf("Up = %+v, %v, want ErrComposeV2Required", result, err)
+	}
+}
@@ -0,0 +1,238 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// DefaultContextName is the implicit docker CLI context backed by DOCKER_HOST or the local socket
+const DefaultContextName = "default"
+
+// DockerContext describes a docker CLI context read from the CLI config directory
+type DockerContext struct {
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	Host          string `json:"host"`
+	SkipTLSVerify bool   `json:"skipTlsVerify"`
+	Current       bool   `json:"current"`
+	tlsDir        string
+}
+
+// ContextSwitcher is implemented by Docker clients that can move to another docker CLI context at runtime
+type ContextSwitcher interface {
+	CurrentContext() string
+	UseContext(ctx context.Context, name string) error
+}
+
+// contextMeta mirrors the meta.json files written by the docker CLI
+type contextMeta struct {
+	Name     string `json:"Name"`
+	Metadata struct {
+		Description string `json:"Description"`
+	} `json:"Metadata"`
+	Endpoints map[string]struct {
+		Host          string `json:"Host"`
+		SkipTLSVerify bool   `json:"SkipTLSVerify"`
+	} `json:"Endpoints"`
+}
+
+// dockerConfigDir returns the docker CLI config directory
+func dockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".docker"
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// contextDirName returns the on-disk directory name the CLI uses for a context
+func contextDirName(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+// currentContextName returns the context the docker CLI would use
+func currentContextName() string {
+	if name := os.Getenv("DOCKER_CONTEXT"); name != "" {
+		return name
+	}
+
+	data, err := os.ReadFile(filepath.Join(dockerConfigDir(), "config.json"))
+	if err != nil {
+		return DefaultContextName
+	}
+
+	var cfg struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil || cfg.CurrentContext == "" {
+		return DefaultContextName
+	}
+	return cfg.CurrentContext
+}
+
+// ListContexts returns the default context plus every context defined in the docker CLI config
+func ListContexts() ([]DockerContext, error) {
+	current := currentContextName()
+	contexts := []DockerContext{{
+		Name:        DefaultContextName,
+		Description: "Current DOCKER_HOST based configuration",
+		Host:        os.Getenv("DOCKER_HOST"),
+		Current:     current == DefaultContextName,
+	}}
+
+	metaRoot := filepath.Join(dockerConfigDir(), "contexts", "meta")
+	entries, err := os.ReadDir(metaRoot)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return contexts, nil
+		}
+		return nil, fmt.Errorf("failed to read docker contexts: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dc, err := readContextMeta(filepath.Join(metaRoot, entry.Name(), "meta.json"))
+		if err != nil {
+			continue
+		}
+		dc.Current = dc.Name == current
+		contexts = append(contexts, *dc)
+	}
+
+	sort.SliceStable(contexts[1:], func(i, j int) bool {
+		return contexts[i+1].Name < contexts[j+1].Name
+	})
+
+	return contexts, nil
+}
+
+// LoadContext returns a single docker CLI context by name
+func LoadContext(name string) (*DockerContext, error) {
+	if name == "" || name == DefaultContextName {
+		return &DockerContext{Name: DefaultContextName, Host: os.Getenv("DOCKER_HOST")}, nil
+	}
+
+	path := filepath.Join(dockerConfigDir(), "contexts", "meta", contextDirName(name), "meta.json")
+	dc, err := readContextMeta(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("docker context not found: %s", name)
+		}
+		return nil, err
+	}
+	return dc, nil
+}
+
+func readContextMeta(path string) (*DockerContext, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta contextMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse docker context %s: %w", path, err)
+	}
+
+	dc := &DockerContext{
+		Name:        meta.Name,
+		Description: meta.Metadata.Description,
+		tlsDir:      filepath.Join(dockerConfigDir(), "contexts", "tls", contextDirName(meta.Name), "docker"),
+	}
+	if ep, ok := meta.Endpoints["docker"]; ok {
+		dc.Host = ep.Host
+		dc.SkipTLSVerify = ep.SkipTLSVerify
+	}
+	return dc, nil
+}
+
+// ClientOptions returns client options that connect to this context's endpoint
+func (dc *DockerContext) ClientOptions() ClientOptions {
+	opts := ClientOptions{
+		Context:   dc.Name,
+		Host:      dc.Host,
+		TLSVerify: !dc.SkipTLSVerify,
+	}
+	if dc.Name == DefaultContextName {
+		// Defer to the environment like the CLI does for the default context
+		opts.Host = ""
+		return opts
+	}
+
+	for _, f := range []struct {
+		name string
+		dst  *string
+	}{
+		{"ca.pem", &opts.TLSCACert},
+		{"cert.pem", &opts.TLSCert},
+		{"key.pem", &opts.TLSKey},
+	} {
+		path := filepath.Join(dc.tlsDir, f.name)
+		if _, err := os.Stat(path); err == nil {
+			*f.dst = path
+		}
+	}
+	return opts
+}
+
+// CurrentContext returns the name of the docker context the client is connected through
+func (c *Client) CurrentContext() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.opts.Context == "" {
+		return DefaultContextName
+	}
+	return c.opts.Context
+}
+
+// UseContext reconnects the client to the endpoint of another docker context.
+// The existing connection is kept if the new endpoint cannot be reached.
+func (c *Client) UseContext(ctx context.Context, name string) error {
+	dc, err := LoadContext(name)
+	if err != nil {
+		return err
+	}
+	opts := dc.ClientOptions()
+
+	sdkOpts, err := opts.sdkOptions()
+	if err != nil {
+		return err
+	}
+	cli, err := client.NewClientWithOpts(sdkOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := cli.Ping(pingCtx); err != nil {
+		cli.Close()
+		return fmt.Errorf("failed to connect to docker context %s: %w", name, err)
+	}
+
+	c.mu.Lock()
+	old := c.cli
+	c.cli = cli
+	c.opts = opts
+	c.mu.Unlock()
+
+	return old.Close()
+}
+
+var _ ContextSwitcher = (*Client)(nil)
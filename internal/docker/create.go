@@ -0,0 +1,125 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-connections/nat"
+)
+
+// ErrImageNotPresent is returned when creating a container from an image
+// that has not been pulled
+var ErrImageNotPresent = errors.New("image is not present locally")
+
+// ErrNameInUse is returned when a container with the requested name exists
+var ErrNameInUse = errors.New("container name is already in use")
+
+// CreateOptions describes a one-off container, a small subset of docker run
+type CreateOptions struct {
+	Image         string            `json:"image"`
+	Name          string            `json:"name,omitempty"`
+	Ports         []string          `json:"ports,omitempty"` // [ip:][host:]container[/proto], as for docker run -p
+	Env           map[string]string `json:"env,omitempty"`
+	Volumes       []string          `json:"volumes,omitempty"` // source:target[:ro], a named volume or absolute host path
+	RestartPolicy *RestartPolicy    `json:"restartPolicy,omitempty"`
+}
+
+// Validate checks the options before anything is created
+func (o CreateOptions) Validate() error {
+	if strings.TrimSpace(o.Image) == "" {
+		return fmt.Errorf("image is required")
+	}
+	for k := range o.Env {
+		if k == "" || strings.ContainsAny(k, "= ") {
+			return fmt.Errorf("invalid environment variable name %q", k)
+		}
+	}
+	for _, v := range o.Volumes {
+		if err := validateVolumeSpec(v); err != nil {
+			return err
+		}
+	}
+	if _, _, err := nat.ParsePortSpecs(o.Ports); err != nil {
+		return fmt.Errorf("invalid port: %w", err)
+	}
+	return ContainerResources{RestartPolicy: o.RestartPolicy}.Validate()
+}
+
+// validateVolumeSpec checks a source:target[:ro|rw] volume, which the
+// engine accepts as a bind
+func validateVolumeSpec(spec string) error {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+		return fmt.Errorf("invalid volume %q: expected source:target[:ro]", spec)
+	}
+	if !path.IsAbs(parts[1]) {
+		return fmt.Errorf("invalid volume %q: target must be an absolute path", spec)
+	}
+	if len(parts) == 3 && parts[2] != "ro" && parts[2] != "rw" {
+		return fmt.Errorf("invalid volume %q: mode must be ro or rw", spec)
+	}
+	return nil
+}
+
+// env returns the environment as sorted KEY=VALUE pairs
+func (o CreateOptions) env() []string {
+	env := make([]string, 0, len(o.Env))
+	for k, v := range o.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// CreateContainer creates and starts a container like docker run -d. The
+// image must already be present; pulling is left to PullImage so progress
+// can be streamed. A container that fails to start is removed again.
+func (c *Client) CreateContainer(ctx context.Context, opts CreateOptions) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	exposed, bindings, err := nat.ParsePortSpecs(opts.Ports)
+	if err != nil {
+		return "", fmt.Errorf("invalid port: %w", err)
+	}
+
+	hostConfig := &container.HostConfig{
+		PortBindings: bindings,
+		Binds:        opts.Volumes,
+	}
+	if p := opts.RestartPolicy; p != nil {
+		hostConfig.RestartPolicy = container.RestartPolicy{
+			Name:              container.RestartPolicyMode(p.Name),
+			MaximumRetryCount: p.MaximumRetryCount,
+		}
+	}
+
+	resp, err := c.cli.ContainerCreate(ctx,
+		&container.Config{
+			Image:        opts.Image,
+			Env:          opts.env(),
+			ExposedPorts: exposed,
+		},
+		hostConfig, nil, nil, opts.Name)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return "", fmt.Errorf("%w: %s", ErrImageNotPresent, opts.Image)
+		}
+		if errdefs.IsConflict(err) {
+			return "", fmt.Errorf("%w: %s", ErrNameInUse, opts.Name)
+		}
+		return "", fmt.Errorf("failed to create container: %w", err)
+	}
+
+	if err := c.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		c.cli.ContainerRemove(context.Background(), resp.ID, container.RemoveOptions{Force: true})
+		return "", fmt.Errorf("failed to start container: %w", err)
+	}
+	return resp.ID, nil
+}
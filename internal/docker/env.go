@@ -0,0 +1,71 @@
+package docker
+
+import (
+	"path"
+	"strings"
+)
+
+// EnvVar is one environment variable of a container
+type EnvVar struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Masked bool   `json:"masked,omitempty"`
+}
+
+// DefaultEnvMask lists the glob patterns of variable names whose values are
+// hidden unless configured otherwise
+var DefaultEnvMask = []string{
+	"*PASSWORD*", "*PASSWD*", "*SECRET*", "*TOKEN*", "*CREDENTIAL*",
+	"*_KEY", "*_KEY_*", "*APIKEY*", "*DATABASE_URL*", "*_DSN",
+}
+
+// maskedValue replaces the value of a masked variable
+const maskedValue = "********"
+
+// EnvMasker hides the values of environment variables whose names match
+// any of its patterns (case-insensitive globs, e.g. *PASSWORD*). A nil
+// EnvMasker masks nothing.
+type EnvMasker struct {
+	patterns []string
+}
+
+// NewEnvMasker creates a masker from glob patterns, skipping blank ones
+func NewEnvMasker(patterns []string) *EnvMasker {
+	m := &EnvMasker{}
+	for _, p := range patterns {
+		if p = strings.TrimSpace(p); p != "" {
+			m.patterns = append(m.patterns, strings.ToUpper(p))
+		}
+	}
+	return m
+}
+
+// Matches reports whether a variable's value should be hidden
+func (m *EnvMasker) Matches(name string) bool {
+	if m == nil {
+		return false
+	}
+	name = strings.ToUpper(name)
+	for _, p := range m.patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse converts KEY=value pairs, as returned by inspect, into variables
+// with secret-looking values masked
+func (m *EnvMasker) Parse(env []string) []EnvVar {
+	vars := make([]EnvVar, 0, len(env))
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		v := EnvVar{Name: name, Value: value}
+		if value != "" && m.Matches(name) {
+			v.Value = maskedValue
+			v.Masked = true
+		}
+		vars = append(vars, v)
+	}
+	return vars
+}
@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"sync"
+	"time"
+)
+
+// EventLog keeps the most recent Docker events in a ring buffer, so
+// browsers can show activity from before they connected
+type EventLog struct {
+	events []Event
+	next   int // index the next event is written to
+	full   bool
+	mu     sync.RWMutex
+}
+
+// NewEventLog creates a log holding up to size events
+func NewEventLog(size int) *EventLog {
+	if size < 1 {
+		size = 1
+	}
+	return &EventLog{events: make([]Event, size)}
+}
+
+// Add records an event, replacing the oldest once the log is full
+func (l *EventLog) Add(e Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events[l.next] = e
+	l.next = (l.next + 1) % len(l.events)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Since returns the events after t, oldest first. A zero t returns all.
+func (l *EventLog) Since(t time.Time) []Event {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	ordered := l.events[:l.next]
+	if l.full {
+		ordered = append(append([]Event(nil), l.events[l.next:]...), l.events[:l.next]...)
+	}
+
+	result := make([]Event, 0, len(ordered))
+	for _, e := range ordered {
+		if e.Timestamp.After(t) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
@@ -0,0 +1,127 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// ErrContainerNotRunning is returned when running a command in a container
+// that is not running
+var ErrContainerNotRunning = errors.New("container is not running")
+
+// MaxExecOutput is how much of each of a command's stdout and stderr is
+// kept; the rest is read and dropped
+const MaxExecOutput = 1 << 20
+
+// ExecOptions describes a command to run in a container, like docker exec
+// without a TTY or stdin
+type ExecOptions struct {
+	Cmd        []string          `json:"cmd"`
+	Env        map[string]string `json:"env,omitempty"`
+	WorkingDir string            `json:"workingDir,omitempty"`
+	User       string            `json:"user,omitempty"`
+}
+
+// Validate checks the options before anything is run
+func (o ExecOptions) Validate() error {
+	if len(o.Cmd) == 0 || strings.TrimSpace(o.Cmd[0]) == "" {
+		return fmt.Errorf("cmd is required")
+	}
+	for k := range o.Env {
+		if k == "" || strings.ContainsAny(k, "= ") {
+			return fmt.Errorf("invalid environment variable name %q", k)
+		}
+	}
+	return nil
+}
+
+// env returns the environment as sorted KEY=VALUE pairs
+func (o ExecOptions) env() []string {
+	return CreateOptions{Env: o.Env}.env()
+}
+
+// ExecResult is the captured outcome of a command
+type ExecResult struct {
+	ExitCode  int           `json:"exitCode"`
+	Stdout    string        `json:"stdout"`
+	Stderr    string        `json:"stderr"`
+	Truncated bool          `json:"truncated,omitempty"` // output past MaxExecOutput was dropped
+	Duration  time.Duration `json:"duration"`
+}
+
+// ExecContainer runs a command in a running container and waits for it to
+// finish, capturing its output. Cancelling ctx stops waiting, but Docker
+// has no way to stop the command itself.
+func (c *Client) ExecContainer(ctx context.Context, id string, opts ExecOptions) (*ExecResult, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	started := time.Now()
+	exec, err := c.cli.ContainerExecCreate(ctx, id, container.ExecOptions{
+		Cmd:          opts.Cmd,
+		Env:          opts.env(),
+		WorkingDir:   opts.WorkingDir,
+		User:         opts.User,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		if errdefs.IsConflict(err) {
+			return nil, fmt.Errorf("%w: %s", ErrContainerNotRunning, id)
+		}
+		return nil, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	resp, err := c.cli.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start exec: %w", err)
+	}
+	defer resp.Close()
+
+	// The attached connection doesn't end with ctx by itself
+	stop := context.AfterFunc(ctx, resp.Close)
+	defer stop()
+
+	var stdout, stderr cappedBuffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, resp.Reader); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to read exec output: %w", err)
+	}
+
+	inspect, err := c.cli.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	return &ExecResult{
+		ExitCode:  inspect.ExitCode,
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: stdout.truncated || stderr.truncated,
+		Duration:  time.Since(started),
+	}, nil
+}
+
+// cappedBuffer keeps the first MaxExecOutput bytes written to it and drops
+// the rest, so a chatty command can't exhaust memory
+type cappedBuffer struct {
+	strings.Builder
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := MaxExecOutput - b.Len(); len(p) > room {
+		b.truncated = true
+		b.Builder.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.Builder.Write(p)
+}
@@ -0,0 +1,109 @@
+package docker
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ServiceExit describes a service whose latest container exited with a
+// non-zero code, which usually means it crashed rather than being stopped
+type ServiceExit struct {
+	Service    string     `json:"service"`
+	Container  string     `json:"container"`
+	ExitCode   int        `json:"exitCode"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	OOMKilled  bool       `json:"oomKilled,omitempty"`
+}
+
+// exitedStatus matches the exit code in a list status like "Exited (1) 2
+// hours ago" or "Restarting (1) 5 seconds ago"
+var exitedStatus = regexp.MustCompile(`^(?:Exited|Restarting) \((-?\d+)\)`)
+
+// exitCodeFromStatus reads the exit code from a container list status, so
+// crashed containers can be found without inspecting every container
+func exitCodeFromStatus(status string) int {
+	m := exitedStatus.FindStringSubmatch(status)
+	if m == nil {
+		return 0
+	}
+	code, _ := strconv.Atoi(m[1])
+	return code
+}
+
+// FailedServices returns the services of a project whose most recently
+// created container exited non-zero, including ones in a restart loop,
+// sorted by service. Only those containers are inspected, for the finish
+// time and OOM kill. Exits by SIGTERM or SIGKILL are what docker stop and
+// compose down leave behind, so they only count when the kernel killed the
+// container for memory or it is restarting, which a deliberate stop
+// prevents.
+func FailedServices(ctx context.Context, dc DockerClient, containers []ContainerInfo) []ServiceExit {
+	latest := make(map[string]ContainerInfo)
+	for _, c := range containers {
+		if c.ServiceName == "" {
+			continue
+		}
+		if cur, ok := latest[c.ServiceName]; !ok || c.Created.After(cur.Created) {
+			latest[c.ServiceName] = c
+		}
+	}
+
+	var failed []ServiceExit
+	for service, c := range latest {
+		if (c.State != "exited" && c.State != "restarting") || c.ExitCode == 0 {
+			continue
+		}
+		exit := ServiceExit{Service: service, Container: c.Name, ExitCode: c.ExitCode}
+		if info, err := dc.GetContainer(ctx, c.ID); err == nil {
+			exit.FinishedAt = info.FinishedAt
+			exit.OOMKilled = info.OOMKilled
+		}
+		if killedBySignal(c.ExitCode) && c.State == "exited" && !exit.OOMKilled {
+			continue
+		}
+		failed = append(failed, exit)
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Service < failed[j].Service })
+	return failed
+}
+
+// killedBySignal reports whether an exit code is 128 plus SIGKILL or
+// SIGTERM, the signals docker stop sends
+func killedBySignal(code int) bool {
+	return code == 137 || code == 143
+}
+
+// stopGrace is how long after a kill signal a container's death is taken
+// to be a deliberate stop, covering docker stop's timeout
+const stopGrace = time.Minute
+
+// CrashDetector tells crashes from deliberate stops in a stream of die and
+// kill events. Docker reports a die for every stop, after the kill signal
+// docker stop, compose and gosei send; only deaths without one are crashes.
+// It is not safe for concurrent use.
+type CrashDetector struct {
+	killed map[string]time.Time
+}
+
+// NewCrashDetector creates a detector with no kills seen
+func NewCrashDetector() *CrashDetector {
+	return &CrashDetector{killed: make(map[string]time.Time)}
+}
+
+// Crashed records a container's kill or die event and reports whether it
+// is a die without a kill in the grace period before it
+func (d *CrashDetector) Crashed(id, status string, at time.Time) bool {
+	kill, wasKilled := d.killed[id]
+	switch status {
+	case "kill":
+		d.killed[id] = at
+		return false
+	case "die":
+		delete(d.killed, id)
+		return !wasKilled || at.Sub(kill) >= stopGrace
+	}
+	return false
+}
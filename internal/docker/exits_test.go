@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// inspectStub answers GetContainer from a map; FailedServices needs nothing
+// else of the client
+type inspectStub struct {
+	DockerClient
+	info map[string]*ContainerInfo
+}
+
+func (s inspectStub) GetContainer(ctx context.Context, id string) (*ContainerInfo, error) {
+	return s.info[id], nil
+}
+
+func TestFailedServicesSignalExits(t *testing.T) {
+	created := time.Now()
+	tests := []struct {
+		name     string
+		state    string
+		exitCode int
+		oom      bool
+		failed   bool
+	}{
+		{"crash", "exited", 1, false, true},
+		{"docker stop after timeout", "exited", 137, false, false},
+		{"docker stop", "exited", 143, false, false},
+		{"oom killed", "exited", 137, true, true},
+		{"killed while restarting", "restarting", 137, false, true},
+		{"terminated while restarting", "restarting", 143, false, true},
+		{"clean exit", "exited", 0, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := ContainerInfo{
+				ID:          "c1",
+				Name:        "app-web-1",
+				State:       tt.state,
+				ExitCode:    tt.exitCode,
+				ServiceName: "web",
+				Created:     created,
+			}
+			dc := inspectStub{info: map[string]*ContainerInfo{"c1": {OOMKilled: tt.oom}}}
+
+			failed := FailedServices(context.Background(), dc, []ContainerInfo{c})
+			if got := len(failed) == 1; got != tt.failed {
+				t.Fatalf("failed = %v, want %v (%+v)", got, tt.failed, failed)
+			}
+			if tt.failed && (failed[0].ExitCode != tt.exitCode || failed[0].OOMKilled != tt.oom) {
+				t.Errorf("exit = %+v, want code %d oom %v", failed[0], tt.exitCode, tt.oom)
+			}
+		})
+	}
+}
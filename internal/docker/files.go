@@ -0,0 +1,178 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// FileInfo describes a file inside a container
+type FileInfo struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	Size       int64     `json:"size"`
+	Mode       string    `json:"mode"`
+	IsDir      bool      `json:"isDir"`
+	LinkTarget string    `json:"linkTarget,omitempty"`
+	ModTime    time.Time `json:"modTime"`
+}
+
+func newFileInfo(p string, size int64, mode os.FileMode, mtime time.Time, link string) *FileInfo {
+	return &FileInfo{
+		Name:       path.Base(p),
+		Path:       p,
+		Size:       size,
+		Mode:       mode.String(),
+		IsDir:      mode.IsDir(),
+		LinkTarget: link,
+		ModTime:    mtime,
+	}
+}
+
+// StatContainerPath returns information about a path inside a container
+func (c *Client) StatContainerPath(ctx context.Context, id, p string) (*FileInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stat, err := c.cli.ContainerStatPath(ctx, id, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat path: %w", err)
+	}
+	return newFileInfo(p, stat.Size, stat.Mode, stat.Mtime, stat.LinkTarget), nil
+}
+
+// CopyFromContainer returns a tar archive of a path inside a container
+func (c *Client) CopyFromContainer(ctx context.Context, id, p string) (io.ReadCloser, *FileInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	rc, stat, err := c.cli.CopyFromContainer(ctx, id, p)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to copy from container: %w", err)
+	}
+	return rc, newFileInfo(p, stat.Size, stat.Mode, stat.Mtime, stat.LinkTarget), nil
+}
+
+// CopyToContainer extracts a tar archive into a directory inside a container
+func (c *Client) CopyToContainer(ctx context.Context, id, dstDir string, content io.Reader) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.cli.CopyToContainer(ctx, id, dstDir, content, container.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("failed to copy to container: %w", err)
+	}
+	return nil
+}
+
+// SingleFileArchive wraps one file in a tar archive for CopyToContainer
+func SingleFileArchive(name string, mode int64, data io.Reader, size int64) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     mode,
+			Size:     size,
+			ModTime:  time.Now(),
+			Typeflag: tar.TypeReg,
+		})
+		if err == nil {
+			_, err = io.Copy(tw, data)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// Limits that keep browsing a large directory from streaming all of it. The
+// archive holds the whole tree with file contents, so reading stops after
+// maxArchiveBytes as well as after enough entries.
+const (
+	maxDirEntries    = 1000
+	maxArchiveHeader = 20000
+	maxArchiveBytes  = 64 << 20
+)
+
+// ListArchiveDir lists the immediate children of the directory archived in r,
+// as returned by CopyFromContainer. truncated is set when the listing was cut
+// short because the directory is too large; the caller should then close r
+// to stop the rest of the archive being sent.
+func ListArchiveDir(r io.Reader, dir string) (entries []FileInfo, truncated bool, err error) {
+	limited := &io.LimitedReader{R: r, N: maxArchiveBytes}
+	tr := tar.NewReader(limited)
+	root := ""
+	entries = []FileInfo{}
+
+	for seen := 0; ; seen++ {
+		hdr, err := tr.Next()
+		if err != nil && limited.N == 0 {
+			return entries, true, nil
+		}
+		if errors.Is(err, io.EOF) {
+			return entries, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if seen >= maxArchiveHeader || len(entries) >= maxDirEntries {
+			return entries, true, nil
+		}
+
+		name := strings.TrimSuffix(hdr.Name, "/")
+		if seen == 0 {
+			// The first entry is the directory itself, named after its base name
+			root = name
+			continue
+		}
+
+		rel, ok := strings.CutPrefix(name, root+"/")
+		if !ok || rel == "" || strings.Contains(rel, "/") {
+			continue
+		}
+		entries = append(entries, *newFileInfo(path.Join(dir, rel), hdr.Size, hdr.FileInfo().Mode(), hdr.ModTime, hdr.Linkname))
+	}
+}
+
+// ReadArchiveFile reads up to limit bytes of the single file archived in r
+func ReadArchiveFile(r io.Reader, limit int64) (data []byte, truncated bool, err error) {
+	tr := tar.NewReader(r)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if hdr.Typeflag != tar.TypeReg {
+		return nil, false, fmt.Errorf("%s is not a regular file", hdr.Name)
+	}
+
+	data, err = io.ReadAll(io.LimitReader(tr, limit))
+	if err != nil {
+		return nil, false, err
+	}
+	return data, hdr.Size > limit, nil
+}
+
+// IsText reports whether data looks like text worth previewing
+func IsText(data []byte) bool {
+	sample := data
+	if len(sample) > 8000 {
+		sample = sample[:8000]
+		// Don't misjudge a multi-byte character cut off by the sample
+		for i := 0; i < utf8.UTFMax-1 && !utf8.Valid(sample); i++ {
+			sample = sample[:len(sample)-1]
+		}
+	}
+	return !bytes.ContainsRune(sample, 0) && utf8.Valid(sample)
+}
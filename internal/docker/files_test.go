@@ -0,0 +1,131 @@
+package docker
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// archiveEntry is a file or directory (size -1) in a test archive
+type archiveEntry struct {
+	name string
+	size int64
+}
+
+// archive streams a tar of entries the way CopyFromContainer does, returning
+// the reader and a function reporting how many bytes were read from it
+func archive(t *testing.T, entries []archiveEntry) (io.Reader, func() int64) {
+	t.Helper()
+	pr, pw := io.Pipe()
+	t.Cleanup(func() { pr.Close() })
+	go func() {
+		tw := tar.NewWriter(pw)
+		for _, e := range entries {
+			hdr := &tar.Header{Name: e.name, Mode: 0o644, Size: e.size, Typeflag: tar.TypeReg}
+			if e.size < 0 {
+				hdr.Mode, hdr.Size, hdr.Typeflag = 0o755, 0, tar.TypeDir
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := io.CopyN(tw, zeros{}, hdr.Size); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(tw.Close())
+	}()
+	cr := &countingReader{r: pr}
+	return cr, func() int64 { return cr.n }
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func TestListArchiveDir(t *testing.T) {
+	r, _ := archive(t, []archiveEntry{
+		{"etc/", -1},
+		{"etc/hosts", 20},
+		{"etc/nginx/", -1},
+		{"etc/nginx/nginx.conf", 100},
+		{"etc/passwd", 30},
+	})
+	entries, truncated, err := ListArchiveDir(r, "/etc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if truncated {
+		t.Error("small directory reported truncated")
+	}
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, fmt.Sprintf("%s %d %v", e.Path, e.Size, e.IsDir))
+	}
+	want := []string{"/etc/hosts 20 false", "/etc/nginx 0 true", "/etc/passwd 30 false"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestListArchiveDirTruncates(t *testing.T) {
+	t.Run("entries", func(t *testing.T) {
+		entries := []archiveEntry{{"big/", -1}}
+		for i := range maxDirEntries + 100 {
+			entries = append(entries, archiveEntry{fmt.Sprintf("big/%05d", i), 0})
+		}
+		r, _ := archive(t, entries)
+		listed, truncated, err := ListArchiveDir(r, "/big")
+		if err != nil || !truncated || len(listed) != maxDirEntries {
+			t.Errorf("got %d entries, truncated %v, err %v; want %d truncated", len(listed), truncated, err, maxDirEntries)
+		}
+	})
+
+	t.Run("nested entries", func(t *testing.T) {
+		entries := []archiveEntry{{"deep/", -1}, {"deep/a/", -1}}
+		for i := range maxArchiveHeader + 100 {
+			entries = append(entries, archiveEntry{fmt.Sprintf("deep/a/%05d", i), 0})
+		}
+		entries = append(entries, archiveEntry{"deep/z", 1})
+		r, _ := archive(t, entries)
+		listed, truncated, err := ListArchiveDir(r, "/deep")
+		if err != nil || !truncated || len(listed) != 1 {
+			t.Errorf("got %d entries, truncated %v, err %v; want 1 truncated", len(listed), truncated, err)
+		}
+	})
+
+	// A large file deeper in the tree stops the listing rather than being
+	// read through to reach the entries after it
+	t.Run("bytes", func(t *testing.T) {
+		r, read := archive(t, []archiveEntry{
+			{"data/", -1},
+			{"data/db/", -1},
+			{"data/db/huge", 4 * maxArchiveBytes},
+			{"data/later", 1},
+		})
+		listed, truncated, err := ListArchiveDir(r, "/data")
+		if err != nil || !truncated || len(listed) != 1 {
+			t.Errorf("got %d entries, truncated %v, err %v; want 1 truncated", len(listed), truncated, err)
+		}
+		if n := read(); n > maxArchiveBytes {
+			t.Errorf("read %d bytes, want at most %d", n, maxArchiveBytes)
+		}
+	})
+}
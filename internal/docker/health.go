@@ -0,0 +1,105 @@
+package docker
+
+import (
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// maxProbeOutput bounds the output kept per probe; the engine keeps up to 4KB
+const maxProbeOutput = 1024
+
+// Engine defaults for healthcheck settings left unset
+const (
+	defaultHealthInterval = 30 * time.Second
+	defaultHealthTimeout  = 30 * time.Second
+	defaultHealthRetries  = 3
+)
+
+// Healthcheck is a container's healthcheck definition and its most recent
+// probes, for finding out why a container is unhealthy
+type Healthcheck struct {
+	Test          []string      `json:"test"` // e.g. ["CMD-SHELL", "curl -f http://localhost/"]
+	Interval      string        `json:"interval"`
+	Timeout       string        `json:"timeout"`
+	StartPeriod   string        `json:"startPeriod,omitempty"`
+	Retries       int           `json:"retries"`
+	Status        string        `json:"status,omitempty"` // starting, healthy or unhealthy
+	FailingStreak int           `json:"failingStreak"`
+	Probes        []HealthProbe `json:"probes"` // oldest first; the engine keeps the last five
+}
+
+// HealthProbe is the result of one healthcheck run
+type HealthProbe struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	ExitCode int       `json:"exitCode"` // 0 healthy, 1 unhealthy; other codes are reserved
+	Output   string    `json:"output"`
+}
+
+// Command returns the probe command as it would be written in a compose file
+func (h *Healthcheck) Command() string {
+	if len(h.Test) < 2 {
+		return ""
+	}
+	return strings.Join(h.Test[1:], " ")
+}
+
+// healthcheckFromInspect combines the healthcheck configuration, which
+// includes one inherited from the image, with the probe log. Containers
+// without a healthcheck, or with it disabled, return nil.
+func healthcheckFromInspect(cfg *container.Config, state *types.ContainerState) *Healthcheck {
+	if cfg == nil || cfg.Healthcheck == nil || len(cfg.Healthcheck.Test) == 0 || cfg.Healthcheck.Test[0] == "NONE" {
+		return nil
+	}
+	hc := cfg.Healthcheck
+
+	h := &Healthcheck{
+		Test:     hc.Test,
+		Interval: durationOr(hc.Interval, defaultHealthInterval),
+		Timeout:  durationOr(hc.Timeout, defaultHealthTimeout),
+		Retries:  hc.Retries,
+		Probes:   []HealthProbe{},
+	}
+	if hc.StartPeriod > 0 {
+		h.StartPeriod = hc.StartPeriod.String()
+	}
+	if h.Retries == 0 {
+		h.Retries = defaultHealthRetries
+	}
+
+	if state != nil && state.Health != nil {
+		h.Status = state.Health.Status
+		h.FailingStreak = state.Health.FailingStreak
+		for _, r := range state.Health.Log {
+			if r == nil {
+				continue
+			}
+			h.Probes = append(h.Probes, HealthProbe{
+				Start:    r.Start,
+				End:      r.End,
+				ExitCode: r.ExitCode,
+				Output:   probeExcerpt(r.Output),
+			})
+		}
+	}
+	return h
+}
+
+func durationOr(d, def time.Duration) string {
+	if d <= 0 {
+		d = def
+	}
+	return d.String()
+}
+
+// probeExcerpt trims a probe's output to its first maxProbeOutput bytes
+func probeExcerpt(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= maxProbeOutput {
+		return s
+	}
+	return strings.ToValidUTF8(s[:maxProbeOutput], "") + "…"
+}
@@ -0,0 +1,140 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// HostInfo describes the machine the Docker daemon runs on. Load and used
+// memory come from /proc, so they are only known when the daemon is local.
+type HostInfo struct {
+	Name        string    `json:"name"`
+	OS          string    `json:"os"`
+	CPUs        int       `json:"cpus"`
+	MemoryTotal uint64    `json:"memoryTotal"`
+	MemoryUsed  uint64    `json:"memoryUsed,omitempty"`
+	Load        []float64 `json:"load,omitempty"` // 1, 5 and 15 minute averages
+	Local       bool      `json:"local"`
+}
+
+// HostInfo returns the daemon host's capacity, plus its load and memory
+// use when gosei runs on the same machine
+func (c *Client) HostInfo(ctx context.Context) (*HostInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	info, err := c.cli.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get docker info: %w", err)
+	}
+
+	host := &HostInfo{
+		Name:        info.Name,
+		OS:          info.OperatingSystem,
+		CPUs:        info.NCPU,
+		MemoryTotal: uint64(info.MemTotal),
+		Local:       strings.HasPrefix(c.cli.DaemonHost(), "unix://"),
+	}
+	if host.Local {
+		// Errors leave the fields empty, e.g. on hosts without /proc
+		host.Load, _ = readLoadAvg("/proc/loadavg")
+		if total, available, err := readMemInfo("/proc/meminfo"); err == nil && total >= available {
+			host.MemoryUsed = total - available
+		}
+	}
+	return host, nil
+}
+
+// DaemonVersion identifies the Docker daemon and the API version gosei
+// talks to it with
+type DaemonVersion struct {
+	Version       string `json:"version"`
+	APIVersion    string `json:"apiVersion"`       // the newest the daemon supports
+	ClientAPI     string `json:"clientApiVersion"` // negotiated by gosei
+	GitCommit     string `json:"gitCommit,omitempty"`
+	GoVersion     string `json:"goVersion,omitempty"`
+	OS            string `json:"os"`
+	Arch          string `json:"arch"`
+	KernelVersion string `json:"kernelVersion,omitempty"`
+}
+
+// DaemonVersion asks the daemon for its version
+func (c *Client) DaemonVersion(ctx context.Context) (*DaemonVersion, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	v, err := c.cli.ServerVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get docker version: %w", err)
+	}
+	return &DaemonVersion{
+		Version:       v.Version,
+		APIVersion:    v.APIVersion,
+		ClientAPI:     c.cli.ClientVersion(),
+		GitCommit:     v.GitCommit,
+		GoVersion:     v.GoVersion,
+		OS:            v.Os,
+		Arch:          v.Arch,
+		KernelVersion: v.KernelVersion,
+	}, nil
+}
+
+// readLoadAvg reads the 1, 5 and 15 minute load averages
+func readLoadAvg(path string) ([]float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("unexpected %s format", path)
+	}
+
+	load := make([]float64, 3)
+	for i := range load {
+		if load[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return nil, err
+		}
+	}
+	return load, nil
+}
+
+// readMemInfo reads total and available memory in bytes. Available memory
+// counts reclaimable caches as free, like free(1).
+func readMemInfo(path string) (total, available uint64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Lines look like "MemTotal:       16318480 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			total = kb * 1024
+		case "MemAvailable:":
+			available = kb * 1024
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	if total == 0 {
+		return 0, 0, fmt.Errorf("MemTotal missing from %s", path)
+	}
+	return total, available, nil
+}
@@ -0,0 +1,106 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/errdefs"
+)
+
+// ErrImageNotFound is returned when an image reference matches no local image
+var ErrImageNotFound = errors.New("image not found")
+
+// RegistryAuth holds credentials for pulling from a private registry
+type RegistryAuth struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	ServerAddress string `json:"serverAddress,omitempty"`
+	IdentityToken string `json:"identityToken,omitempty"`
+}
+
+// PullImage starts pulling an image and returns the engine's JSON progress
+// stream, which can be decoded with ReadPullProgress
+func (c *Client) PullImage(ctx context.Context, ref string, auth *RegistryAuth) (io.ReadCloser, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var opts image.PullOptions
+	if auth != nil {
+		encoded, err := registry.EncodeAuthConfig(registry.AuthConfig{
+			Username:      auth.Username,
+			Password:      auth.Password,
+			ServerAddress: auth.ServerAddress,
+			IdentityToken: auth.IdentityToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode registry auth: %w", err)
+		}
+		opts.RegistryAuth = encoded
+	}
+
+	stream, err := c.cli.ImagePull(ctx, ref, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull image: %w", err)
+	}
+
+	return stream, nil
+}
+
+// ResolveImage returns the ID of the local image a name, short ID or
+// digest refers to
+func (c *Client) ResolveImage(ctx context.Context, ref string) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	inspect, _, err := c.cli.ImageInspectWithRaw(ctx, ref)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return "", fmt.Errorf("%w: %s", ErrImageNotFound, ref)
+		}
+		return "", fmt.Errorf("failed to inspect image: %w", err)
+	}
+	return inspect.ID, nil
+}
+
+// ImageLayer is one entry of an image's build history
+type ImageLayer struct {
+	ID        string    `json:"id,omitempty"` // empty for layers from a base image not present locally
+	Created   time.Time `json:"created"`
+	CreatedBy string    `json:"createdBy"`
+	Size      int64     `json:"size"`
+	Tags      []string  `json:"tags,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+}
+
+// GetImageHistory returns an image's layers, newest first
+func (c *Client) GetImageHistory(ctx context.Context, id string) ([]ImageLayer, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	history, err := c.cli.ImageHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image history: %w", err)
+	}
+
+	layers := make([]ImageLayer, 0, len(history))
+	for _, h := range history {
+		layerID := h.ID
+		if layerID == "<missing>" {
+			layerID = ""
+		}
+		layers = append(layers, ImageLayer{
+			ID:        layerID,
+			Created:   time.Unix(h.Created, 0),
+			CreatedBy: h.CreatedBy,
+			Size:      h.Size,
+			Tags:      h.Tags,
+			Comment:   h.Comment,
+		})
+	}
+	return layers, nil
+}
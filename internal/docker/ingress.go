@@ -0,0 +1,141 @@
+package docker
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// IngressURL is a URL a reverse proxy serves a container on, derived from
+// its labels or environment
+type IngressURL struct {
+	URL    string `json:"url"`
+	Source string `json:"source"` // "traefik", "caddy" or "nginx-proxy"
+}
+
+var (
+	// traefikRouterRule matches v2/v3 router rules, e.g.
+	// traefik.http.routers.app.rule=Host(`app.example.com`)
+	traefikRouterRule = regexp.MustCompile(`^traefik\.http\.routers\.([^.]+)\.rule$`)
+	// traefikHost matches the host arguments of a Host(...) matcher
+	traefikHost = regexp.MustCompile("Host\\(([^)]*)\\)")
+	// traefikPath matches the first PathPrefix or Path matcher
+	traefikPath = regexp.MustCompile("Path(?:Prefix)?\\(\\s*`([^`]*)`")
+	// quoted extracts backquoted or double-quoted arguments
+	quoted = regexp.MustCompile("[`\"]([^`\"]+)[`\"]")
+	// caddyLabel matches caddy-docker-proxy site labels: caddy, caddy_0, ...
+	caddyLabel = regexp.MustCompile(`^caddy(_\d+)?$`)
+)
+
+// ingressURLs derives proxy URLs from Traefik and caddy-docker-proxy labels
+// and nginx-proxy's VIRTUAL_HOST environment variable. env may be nil, as it
+// is only known after inspecting a container.
+func ingressURLs(labels map[string]string, env []EnvVar) []IngressURL {
+	var urls []IngressURL
+	seen := make(map[string]bool)
+	add := func(source, url string) {
+		if !seen[url] {
+			seen[url] = true
+			urls = append(urls, IngressURL{URL: url, Source: source})
+		}
+	}
+
+	// Labels are a map; sort keys so the order is stable between requests
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if labels["traefik.enable"] != "false" {
+		for _, k := range keys {
+			m := traefikRouterRule.FindStringSubmatch(k)
+			if m == nil {
+				continue
+			}
+			scheme := traefikScheme(labels, "traefik.http.routers."+m[1]+".")
+			for _, u := range ruleURLs(scheme, labels[k]) {
+				add("traefik", u)
+			}
+		}
+		// Traefik v1: traefik.frontend.rule=Host:a.example.com,b.example.com
+		if rule, ok := strings.CutPrefix(labels["traefik.frontend.rule"], "Host:"); ok {
+			for _, h := range strings.Split(rule, ",") {
+				if h = strings.TrimSpace(h); isPlainHost(h) {
+					add("traefik", "http://"+h)
+				}
+			}
+		}
+	}
+
+	for _, k := range keys {
+		if !caddyLabel.MatchString(k) {
+			continue
+		}
+		for _, addr := range strings.FieldsFunc(labels[k], func(r rune) bool { return r == ',' || r == ' ' }) {
+			scheme := "https"
+			if s, rest, ok := strings.Cut(addr, "://"); ok {
+				scheme, addr = s, rest
+			}
+			if isPlainHost(strings.SplitN(addr, "/", 2)[0]) {
+				add("caddy", scheme+"://"+addr)
+			}
+		}
+	}
+
+	vars := make(map[string]string)
+	for _, v := range env {
+		vars[v.Name] = v.Value
+	}
+	if hosts := vars["VIRTUAL_HOST"]; hosts != "" {
+		scheme := "http"
+		if vars["LETSENCRYPT_HOST"] != "" || vars["CERT_NAME"] != "" {
+			scheme = "https"
+		}
+		path := strings.TrimSuffix(vars["VIRTUAL_PATH"], "/")
+		for _, h := range strings.Split(hosts, ",") {
+			if h = strings.TrimSpace(h); isPlainHost(h) {
+				add("nginx-proxy", scheme+"://"+h+path)
+			}
+		}
+	}
+
+	return urls
+}
+
+// traefikScheme guesses a router's scheme from its TLS settings and entrypoints
+func traefikScheme(labels map[string]string, prefix string) string {
+	if labels[prefix+"tls"] == "true" || labels[prefix+"tls.certresolver"] != "" {
+		return "https"
+	}
+	for _, ep := range strings.Split(labels[prefix+"entrypoints"], ",") {
+		if ep = strings.TrimSpace(ep); ep == "websecure" || ep == "https" {
+			return "https"
+		}
+	}
+	return "http"
+}
+
+// ruleURLs lists the URLs matched by the Host matchers of a Traefik rule
+func ruleURLs(scheme, rule string) []string {
+	path := ""
+	if m := traefikPath.FindStringSubmatch(rule); m != nil {
+		path = strings.TrimSuffix(m[1], "/")
+	}
+
+	var urls []string
+	for _, m := range traefikHost.FindAllStringSubmatch(rule, -1) {
+		for _, h := range quoted.FindAllStringSubmatch(m[1], -1) {
+			if isPlainHost(h[1]) {
+				urls = append(urls, scheme+"://"+h[1]+path)
+			}
+		}
+	}
+	return urls
+}
+
+// isPlainHost rejects wildcards, regexes and bare ports, which have no
+// single URL to link to
+func isPlainHost(h string) bool {
+	return h != "" && !strings.HasPrefix(h, ":") && !strings.ContainsAny(h, "*~{}^$ ")
+}
@@ -3,25 +3,50 @@ package docker
 import (
 	"context"
 	"io"
+	"time"
 )
 
 // DockerClient defines the interface for Docker container operations
 type DockerClient interface {
 	Close() error
+	Ping(ctx context.Context) error
+	HostInfo(ctx context.Context) (*HostInfo, error)
+	DaemonVersion(ctx context.Context) (*DaemonVersion, error)
 	ListContainers(ctx context.Context, projectName string) ([]ContainerInfo, error)
 	GetContainer(ctx context.Context, id string) (*ContainerInfo, error)
 	StartContainer(ctx context.Context, id string) error
 	StopContainer(ctx context.Context, id string, timeout int) error
 	RestartContainer(ctx context.Context, id string, timeout int) error
-	GetContainerLogs(ctx context.Context, id string, tail string, follow bool) (io.ReadCloser, error)
+	UpdateContainer(ctx context.Context, id string, res ContainerResources) ([]string, error)
+	CreateContainer(ctx context.Context, opts CreateOptions) (string, error)
+	GetContainerLogs(ctx context.Context, id string, tail string, since time.Time, follow bool) (io.ReadCloser, error)
 	GetContainerStats(ctx context.Context, id string) (*ContainerStats, error)
-	WatchEvents(ctx context.Context) (<-chan ContainerEvent, <-chan error)
+	ExecContainer(ctx context.Context, id string, opts ExecOptions) (*ExecResult, error)
+	WatchEvents(ctx context.Context) (<-chan Event, <-chan error)
+	PullImage(ctx context.Context, ref string, auth *RegistryAuth) (io.ReadCloser, error)
+	GetImageHistory(ctx context.Context, id string) ([]ImageLayer, error)
+	ResolveImage(ctx context.Context, ref string) (string, error)
+	StatContainerPath(ctx context.Context, id, path string) (*FileInfo, error)
+	CopyFromContainer(ctx context.Context, id, path string) (io.ReadCloser, *FileInfo, error)
+	CopyToContainer(ctx context.Context, id, dstDir string, content io.Reader) error
+	ListNetworks(ctx context.Context) ([]NetworkInfo, error)
+	InspectNetwork(ctx context.Context, name string) (*NetworkDetail, error)
+	ListVolumes(ctx context.Context) ([]VolumeInfo, error)
+	ConnectNetwork(ctx context.Context, id string, opts ConnectOptions) error
+	DisconnectNetwork(ctx context.Context, id, network string, force bool) error
+	CreateVolumeBrowser(ctx context.Context, name string) (string, error)
+	RemoveVolumeBrowser(ctx context.Context, id string) error
 }
 
 // ComposeExecutor defines the interface for Docker Compose operations
 type ComposeExecutor interface {
-	Up(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error)
+	Up(ctx context.Context, projectDir string, opts UpOptions, outputCh chan<- ComposeOutput) (*ComposeResult, error)
 	Down(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error)
+	Start(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error)
+	Stop(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error)
+	Run(ctx context.Context, projectDir string, opts RunOptions, outputCh chan<- ComposeOutput) (*ComposeResult, error)
+	Config(ctx context.Context, projectDir string, profiles []string, format string) ([]byte, error)
+	Info() ComposeInfo
 	Pull(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error)
 	Restart(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error)
 	Update(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error)
@@ -30,5 +55,6 @@ type ComposeExecutor interface {
 // Verify that concrete types implement the interfaces
 var (
 	_ DockerClient    = (*Client)(nil)
+	_ DockerClient    = (*CachingClient)(nil)
 	_ ComposeExecutor = (*ComposeClient)(nil)
 )
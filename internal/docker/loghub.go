@@ -0,0 +1,170 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrTooManyLogStreams is returned when following another container's logs
+// would exceed the limit on followed log streams
+var ErrTooManyLogStreams = errors.New("too many followed log streams")
+
+// logSubscriberBuffer is how many lines a subscriber may fall behind by
+// before it is dropped
+const logSubscriberBuffer = 256
+
+// LogHub shares one followed Docker log stream per container between
+// everyone following it, such as several browser tabs, rather than each
+// opening its own
+type LogHub struct {
+	client DockerClient
+	limit  int // followed streams allowed at once (0 for no limit)
+
+	mu      sync.Mutex
+	streams map[string]*logStream // by container ID
+}
+
+// logStream is a followed container log stream and its subscribers
+type logStream struct {
+	subs   map[*LogSubscription]struct{}
+	cancel context.CancelFunc
+}
+
+// LogSubscription receives the lines of a followed container log stream
+// from when it subscribed. Lines is closed when the container's logs end,
+// or when the subscriber falls too far behind, in which case Dropped
+// reports true.
+type LogSubscription struct {
+	Lines <-chan LogLine
+
+	hub     *LogHub
+	id      string
+	stream  *logStream
+	lines   chan LogLine
+	closed  bool // guarded by hub.mu
+	dropped bool
+}
+
+// NewLogHub creates a hub following at most limit containers' logs at once
+// (0 for no limit)
+func NewLogHub(client DockerClient, limit int) *LogHub {
+	return &LogHub{client: client, limit: limit, streams: make(map[string]*logStream)}
+}
+
+// Subscribe follows the logs of a container, sharing the stream of an
+// existing subscriber. Only lines written from now on are received, so
+// earlier lines are asked for separately, after subscribing so none are
+// missed in between.
+func (h *LogHub) Subscribe(id string) (*LogSubscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stream, ok := h.streams[id]
+	if !ok {
+		if h.limit > 0 && len(h.streams) >= h.limit {
+			return nil, ErrTooManyLogStreams
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		stream = &logStream{subs: make(map[*LogSubscription]struct{}), cancel: cancel}
+		h.streams[id] = stream
+		go h.follow(ctx, id, stream)
+	}
+
+	lines := make(chan LogLine, logSubscriberBuffer)
+	sub := &LogSubscription{Lines: lines, hub: h, id: id, stream: stream, lines: lines}
+	stream.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+// Streams returns the number of container log streams being followed
+func (h *LogHub) Streams() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.streams)
+}
+
+// follow reads a container's logs and fans them out until they end or the
+// last subscriber leaves
+func (h *LogHub) follow(ctx context.Context, id string, stream *logStream) {
+	defer h.end(id, stream)
+
+	logs, err := h.client.GetContainerLogs(ctx, id, "0", time.Time{}, true)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("Failed to follow container logs", "container", id, "error", err)
+		}
+		return
+	}
+	defer logs.Close()
+
+	scanner := NewLogScanner(logs)
+	for scanner.Scan() {
+		line := scanner.Line()
+		h.mu.Lock()
+		for sub := range stream.subs {
+			select {
+			case sub.lines <- line:
+			default:
+				// Dropped rather than holding up the others; a browser
+				// reconnects and resumes after its last line
+				sub.dropped = true
+				sub.removeLocked()
+			}
+		}
+		h.mu.Unlock()
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		slog.Warn("Error following container logs", "container", id, "error", err)
+	}
+}
+
+// end closes the subscriptions of a stream that has ended
+func (h *LogHub) end(id string, stream *logStream) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stream.cancel()
+	for sub := range stream.subs {
+		sub.closeLocked()
+	}
+	stream.subs = nil
+	if h.streams[id] == stream {
+		delete(h.streams, id)
+	}
+}
+
+// Close unsubscribes, stopping the container's log stream if it was the
+// last subscriber
+func (s *LogSubscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.removeLocked()
+}
+
+// removeLocked closes the subscription and removes it from its stream,
+// stopping the stream without subscribers left
+func (s *LogSubscription) removeLocked() {
+	s.closeLocked()
+	delete(s.stream.subs, s)
+	if len(s.stream.subs) == 0 && s.hub.streams[s.id] == s.stream {
+		s.stream.cancel()
+		delete(s.hub.streams, s.id)
+	}
+}
+
+// Dropped reports whether the subscription was ended for falling behind
+func (s *LogSubscription) Dropped() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.dropped
+}
+
+func (s *LogSubscription) closeLocked() {
+	if !s.closed {
+		s.closed = true
+		close(s.lines)
+	}
+}
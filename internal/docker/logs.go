@@ -0,0 +1,180 @@
+package docker
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// MaxLogLineSize is the longest log line kept whole; longer lines are split
+// into lines of this size rather than dropped
+const MaxLogLineSize = 1 << 20
+
+// LogLine is a line of container output
+type LogLine struct {
+	Stream string // stdout or stderr
+	Text   string // without the trailing newline
+}
+
+// LogScanner reads the lines of a container log stream. Containers without
+// a TTY produce a multiplexed stream of frames, each with an 8-byte header
+// naming the stream and the payload size; lines may span frames, and frames
+// may hold many lines. Containers with a TTY produce raw output, which is
+// all stdout.
+type LogScanner struct {
+	r    *bufio.Reader
+	mode int
+
+	pending [3][]byte // partial line per stream (stdin, stdout, stderr)
+	chunk   []byte
+	lines   []LogLine
+	line    LogLine
+	err     error
+}
+
+const (
+	logModeUnknown = iota
+	logModeMultiplexed
+	logModeRaw
+)
+
+// frameHeaderSize is the size of a multiplexed stream frame header
+const frameHeaderSize = 8
+
+var streamNames = [3]string{"stdin", "stdout", "stderr"}
+
+// NewLogScanner creates a scanner reading the log stream r
+func NewLogScanner(r io.Reader) *LogScanner {
+	return &LogScanner{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Scan advances to the next line, returning false at the end of the stream
+// or on error. A final line without a newline is returned too.
+func (s *LogScanner) Scan() bool {
+	for len(s.lines) == 0 {
+		if s.err != nil {
+			return false
+		}
+		if s.err = s.fill(); s.err != nil {
+			for stream, p := range s.pending {
+				if len(p) > 0 {
+					s.emit(stream, p)
+					s.pending[stream] = nil
+				}
+			}
+		}
+	}
+	s.line, s.lines = s.lines[0], s.lines[1:]
+	return true
+}
+
+// Line returns the line read by the last Scan
+func (s *LogScanner) Line() LogLine {
+	return s.line
+}
+
+// Err returns the error that ended scanning, or nil at the end of the stream
+func (s *LogScanner) Err() error {
+	if errors.Is(s.err, io.EOF) {
+		return nil
+	}
+	return s.err
+}
+
+// fill reads the next frame, or the next line of a raw stream
+func (s *LogScanner) fill() error {
+	if s.mode == logModeUnknown {
+		s.mode = logModeRaw
+		if header, err := s.r.Peek(frameHeaderSize); err == nil && isFrameHeader(header) {
+			s.mode = logModeMultiplexed
+		}
+	}
+
+	if s.mode == logModeRaw {
+		data, err := s.r.ReadSlice('\n')
+		s.add(1, data)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return nil
+		}
+		return err
+	}
+
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(s.r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return io.EOF
+		}
+		return err
+	}
+	if !isFrameHeader(header[:]) {
+		return errors.New("invalid log stream frame header")
+	}
+	stream := int(header[0])
+	for size := binary.BigEndian.Uint32(header[4:]); size > 0; {
+		if s.chunk == nil {
+			s.chunk = make([]byte, 32*1024)
+		}
+		n, err := s.r.Read(s.chunk[:min(int(size), len(s.chunk))])
+		s.add(stream, s.chunk[:n])
+		size -= uint32(n)
+		if err != nil {
+			if errors.Is(err, io.EOF) && size > 0 {
+				return io.EOF
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// add appends output of a stream, emitting each line it completes
+func (s *LogScanner) add(stream int, data []byte) {
+	for len(data) > 0 {
+		room := MaxLogLineSize - len(s.pending[stream])
+		i := 0
+		for i < len(data) && i < room && data[i] != '\n' {
+			i++
+		}
+		s.pending[stream] = append(s.pending[stream], data[:i]...)
+
+		switch {
+		case i < len(data) && data[i] == '\n':
+			s.emit(stream, s.pending[stream])
+			s.pending[stream] = s.pending[stream][:0]
+			data = data[i+1:]
+		case i == room:
+			s.emit(stream, s.pending[stream])
+			s.pending[stream] = s.pending[stream][:0]
+			data = data[i:]
+		default:
+			data = data[i:]
+		}
+	}
+}
+
+func (s *LogScanner) emit(stream int, line []byte) {
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	s.lines = append(s.lines, LogLine{Stream: streamNames[stream], Text: string(line)})
+}
+
+// isFrameHeader reports whether b starts like a multiplexed stream frame:
+// a stream type of stdin, stdout or stderr followed by three zero bytes
+func isFrameHeader(b []byte) bool {
+	return len(b) >= frameHeaderSize && b[0] <= 2 && b[1] == 0 && b[2] == 0 && b[3] == 0
+}
+
+// SplitLogTimestamp splits the timestamp Docker prefixes log lines with from
+// the message. Lines without one are timestamped now.
+func SplitLogTimestamp(text string) (time.Time, string) {
+	if ts, message, ok := strings.Cut(text, " "); ok {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			return t, message
+		}
+	}
+	return time.Now(), text
+}
@@ -3,29 +3,50 @@ package docker
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/rand"
+	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/docker/go-connections/nat"
 )
 
 // MockClient provides a mock Docker client for development without Docker
 type MockClient struct {
 	mu         sync.RWMutex
 	containers map[string]*ContainerInfo
-	eventCh    chan ContainerEvent
-	eventSubs  []chan ContainerEvent
+	resources  map[string]ContainerResources  // by container ID, once updated
+	networks   map[string][]NetworkAttachment // by container ID, once connected or disconnected
+	eventCh    chan Event
+	eventSubs  []chan Event
+	envMask    *EnvMasker
+	publicHost string
+	faults     MockFaults
+	disconnect chan struct{} // closed while faults.Disconnected
+
+	// The containers and faults the client started with, for Reset
+	initial       []ContainerInfo
+	initialFaults MockFaults
 }
 
 // NewMockClient creates a new mock Docker client with demo containers
 func NewMockClient() *MockClient {
 	m := &MockClient{
 		containers: make(map[string]*ContainerInfo),
-		eventCh:    make(chan ContainerEvent, 100),
+		resources:  make(map[string]ContainerResources),
+		networks:   make(map[string][]NetworkAttachment),
+		eventCh:    make(chan Event, 100),
+		envMask:    NewEnvMasker(DefaultEnvMask),
+		publicHost: "localhost",
+		disconnect: make(chan struct{}),
 	}
 	m.initDemoContainers()
+	m.saveInitial()
 	return m
 }
 
@@ -34,16 +55,21 @@ func (m *MockClient) initDemoContainers() {
 
 	demoContainers := []ContainerInfo{
 		{
-			ID:          "abc123def456",
-			Name:        "webapp-web-1",
-			Image:       "nginx:alpine",
-			ImageID:     "sha256:a1b2c3d4e5f6",
-			Status:      "Up 2 hours",
-			State:       "running",
-			Health:      "healthy",
-			Created:     now.Add(-2 * time.Hour),
-			Ports:       []PortMapping{{HostIP: "0.0.0.0", HostPort: "8080", ContainerPort: "80", Protocol: "tcp"}},
-			Labels:      map[string]string{"com.docker.compose.project": "webapp", "com.docker.compose.service": "web"},
+			ID:      "abc123def456",
+			Name:    "webapp-web-1",
+			Image:   "nginx:alpine",
+			ImageID: "sha256:a1b2c3d4e5f6",
+			Status:  "Up 2 hours",
+			State:   "running",
+			Health:  "healthy",
+			Created: now.Add(-2 * time.Hour),
+			Ports:   []PortMapping{{HostIP: "0.0.0.0", HostPort: "8080", ContainerPort: "80", Protocol: "tcp"}},
+			Labels: map[string]string{
+				"com.docker.compose.project":                   "webapp",
+				"com.docker.compose.service":                   "web",
+				"traefik.http.routers.webapp.rule":             "Host(`webapp.example.com`)",
+				"traefik.http.routers.webapp.tls.certresolver": "letsencrypt",
+			},
 			ProjectName: "webapp",
 			ServiceName: "web",
 			WorkingDir:  "/projects/webapp",
@@ -108,6 +134,17 @@ func (m *MockClient) initDemoContainers() {
 			ServiceName: "grafana",
 			WorkingDir:  "/projects/monitoring",
 		},
+		{
+			ID:      "fgh678ijk901",
+			Name:    "adminer",
+			Image:   "adminer:latest",
+			ImageID: "sha256:f6a7b8c9d0e1",
+			Status:  "Up 3 days",
+			State:   "running",
+			Created: now.Add(-72 * time.Hour),
+			Ports:   []PortMapping{{HostIP: "0.0.0.0", HostPort: "8081", ContainerPort: "8080", Protocol: "tcp"}},
+			Labels:  map[string]string{},
+		},
 	}
 
 	for _, c := range demoContainers {
@@ -124,15 +161,27 @@ func (m *MockClient) Close() error {
 	return nil
 }
 
+// Ping succeeds unless Docker is made unreachable
+func (m *MockClient) Ping(ctx context.Context) error {
+	return m.unreachable()
+}
+
 // ListContainers returns containers, optionally filtered by project
 func (m *MockClient) ListContainers(ctx context.Context, projectName string) ([]ContainerInfo, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+	if m.faults.Disconnected {
+		return nil, errMockDisconnected
+	}
 
 	var result []ContainerInfo
 	for _, c := range m.containers {
 		if projectName == "" || c.ProjectName == projectName {
-			result = append(result, *c)
+			cpy := *c
+			cpy.Ports = withURLs(m.publicHost, append([]PortMapping(nil), c.Ports...))
+			cpy.Ingress = ingressURLs(c.Labels, nil)
+			cpy.ExitCode = exitCodeFromStatus(c.Status)
+			result = append(result, cpy)
 		}
 	}
 	return result, nil
@@ -142,21 +191,67 @@ func (m *MockClient) ListContainers(ctx context.Context, projectName string) ([]
 func (m *MockClient) GetContainer(ctx context.Context, id string) (*ContainerInfo, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+	if m.faults.Disconnected {
+		return nil, errMockDisconnected
+	}
 
-	// Handle both full ID and short ID lookups
+	// Handle full ID, short ID and name lookups, like Docker
 	for cid, c := range m.containers {
-		if cid == id || strings.HasPrefix(cid, id) {
+		if cid == id || strings.HasPrefix(cid, id) || c.Name == id {
 			cpy := *c
+			cpy.Ports = withURLs(m.publicHost, append([]PortMapping(nil), c.Ports...))
+			cpy.Env = m.envMask.Parse(mockEnv(c))
+			cpy.Ingress = ingressURLs(c.Labels, cpy.Env)
+			cpy.Resources = m.mockResources(cid)
+			cpy.Healthcheck = mockHealthcheck(c)
+			cpy.ExitCode = exitCodeFromStatus(c.Status)
+			cpy.Networks = m.mockNetworks(c)
 			return &cpy, nil
 		}
 	}
 	return nil, fmt.Errorf("container not found: %s", id)
 }
 
+// SetEnvMasker replaces the rules used to hide secret environment values
+func (m *MockClient) SetEnvMasker(em *EnvMasker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.envMask = em
+}
+
+// SetPublicHost sets the host used to build URLs for published ports
+func (m *MockClient) SetPublicHost(host string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if host == "" {
+		host = "localhost"
+	}
+	m.publicHost = host
+}
+
+// mockEnv returns a plausible environment for a demo container
+func mockEnv(c *ContainerInfo) []string {
+	env := []string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin", "HOSTNAME=" + c.ID}
+	switch {
+	case strings.HasPrefix(c.Image, "postgres"):
+		env = append(env, "POSTGRES_USER=app", "POSTGRES_PASSWORD=hunter2", "PGDATA=/var/lib/postgresql/data")
+	case strings.HasPrefix(c.Image, "node"):
+		env = append(env, "VIRTUAL_HOST=api.example.com", "VIRTUAL_PATH=/api/", "NODE_ENV=production", "DATABASE_URL=postgres://app:hunter2@db:5432/app", "JWT_SECRET=s3cr3t")
+	case strings.HasPrefix(c.Image, "redis"):
+		env = append(env, "REDIS_PASSWORD=hunter2")
+	default:
+		env = append(env, "NGINX_VERSION=1.25.3")
+	}
+	return env
+}
+
 // StartContainer starts a container
 func (m *MockClient) StartContainer(ctx context.Context, id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if m.faults.Disconnected {
+		return errMockDisconnected
+	}
 
 	c := m.findContainer(id)
 	if c == nil {
@@ -174,6 +269,9 @@ func (m *MockClient) StartContainer(ctx context.Context, id string) error {
 func (m *MockClient) StopContainer(ctx context.Context, id string, timeout int) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if m.faults.Disconnected {
+		return errMockDisconnected
+	}
 
 	c := m.findContainer(id)
 	if c == nil {
@@ -191,6 +289,9 @@ func (m *MockClient) StopContainer(ctx context.Context, id string, timeout int)
 func (m *MockClient) RestartContainer(ctx context.Context, id string, timeout int) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if m.faults.Disconnected {
+		return errMockDisconnected
+	}
 
 	c := m.findContainer(id)
 	if c == nil {
@@ -204,8 +305,169 @@ func (m *MockClient) RestartContainer(ctx context.Context, id string, timeout in
 	return nil
 }
 
+// UpdateContainer records new resource settings for a container
+func (m *MockClient) UpdateContainer(ctx context.Context, id string, res ContainerResources) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.findContainer(id)
+	if c == nil {
+		return nil, fmt.Errorf("container not found: %s", id)
+	}
+
+	cur := *m.mockResources(c.ID)
+	if res.RestartPolicy != nil {
+		cur.RestartPolicy = res.RestartPolicy
+	}
+	if res.Memory != nil {
+		cur.Memory = res.Memory
+	}
+	if res.MemorySwap != nil {
+		cur.MemorySwap = res.MemorySwap
+	}
+	if res.CPUQuota != nil {
+		cur.CPUQuota = res.CPUQuota
+	}
+	if res.CPUPeriod != nil {
+		cur.CPUPeriod = res.CPUPeriod
+	}
+	m.resources[c.ID] = cur
+	return nil, nil
+}
+
+// CreateContainer adds a running demo container
+func (m *MockClient) CreateContainer(ctx context.Context, opts CreateOptions) (string, error) {
+	_, bindings, err := nat.ParsePortSpecs(opts.Ports)
+	if err != nil {
+		return "", fmt.Errorf("invalid port: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := fmt.Sprintf("%012x", rand.Int63n(1<<48))
+	name := opts.Name
+	if name == "" {
+		name = "container-" + id[:6]
+	}
+	for _, c := range m.containers {
+		if c.Name == name {
+			return "", fmt.Errorf("%w: %s", ErrNameInUse, name)
+		}
+	}
+
+	ports := []PortMapping{}
+	for port, bs := range bindings {
+		for _, b := range bs {
+			ports = append(ports, PortMapping{HostIP: b.HostIP, HostPort: b.HostPort, ContainerPort: port.Port(), Protocol: port.Proto()})
+		}
+	}
+
+	c := &ContainerInfo{
+		ID:      id,
+		Name:    name,
+		Image:   opts.Image,
+		ImageID: "sha256:" + id,
+		Status:  "Up Less than a second",
+		State:   "running",
+		Created: time.Now(),
+		Ports:   ports,
+		Labels:  map[string]string{},
+	}
+	m.containers[id] = c
+	if opts.RestartPolicy != nil {
+		res := *m.mockResources(id)
+		res.RestartPolicy = opts.RestartPolicy
+		m.resources[id] = res
+	}
+
+	m.emitEvent(c, "start")
+	return id, nil
+}
+
+// HostInfo describes a plausible local host
+func (m *MockClient) HostInfo(ctx context.Context) (*HostInfo, error) {
+	if err := m.unreachable(); err != nil {
+		return nil, err
+	}
+	return &HostInfo{
+		Name:        "mock-host",
+		OS:          "Mock Linux",
+		CPUs:        4,
+		MemoryTotal: 8 << 30,
+		MemoryUsed:  uint64(3<<30 + rand.Intn(1<<30)),
+		Load:        []float64{0.4 + rand.Float64()*0.4, 0.45, 0.38},
+		Local:       true,
+	}, nil
+}
+
+// DaemonVersion describes a recent daemon
+func (m *MockClient) DaemonVersion(ctx context.Context) (*DaemonVersion, error) {
+	if err := m.unreachable(); err != nil {
+		return nil, err
+	}
+	return &DaemonVersion{
+		Version:    "27.0.3-mock",
+		APIVersion: "1.46",
+		ClientAPI:  "1.46",
+		OS:         "linux",
+		Arch:       "amd64",
+	}, nil
+}
+
+// mockResources returns a container's resource settings: compose's
+// defaults of no limits and no restart policy until updated
+func (m *MockClient) mockResources(id string) *ContainerResources {
+	if res, ok := m.resources[id]; ok {
+		return &res
+	}
+	var zero int64
+	return &ContainerResources{
+		RestartPolicy: &RestartPolicy{Name: "no"},
+		Memory:        &zero,
+		MemorySwap:    &zero,
+		CPUQuota:      &zero,
+		CPUPeriod:     &zero,
+	}
+}
+
+// mockHealthcheck returns a passing healthcheck for containers that report
+// a health status
+func mockHealthcheck(c *ContainerInfo) *Healthcheck {
+	if c.Health == "" {
+		return nil
+	}
+	test := []string{"CMD-SHELL", "wget -qO- http://localhost/ || exit 1"}
+	output := "<!DOCTYPE html>\n<html>\n<head><title>Welcome to nginx!</title></head>"
+	if strings.HasPrefix(c.Image, "postgres") {
+		test = []string{"CMD", "pg_isready", "-U", "postgres"}
+		output = "/var/run/postgresql:5432 - accepting connections"
+	}
+
+	h := &Healthcheck{
+		Test:     test,
+		Interval: defaultHealthInterval.String(),
+		Timeout:  "5s",
+		Retries:  defaultHealthRetries,
+		Status:   c.Health,
+	}
+	now := time.Now()
+	for i := 4; i >= 0; i-- {
+		start := now.Add(-time.Duration(i) * defaultHealthInterval)
+		h.Probes = append(h.Probes, HealthProbe{
+			Start:  start,
+			End:    start.Add(40 * time.Millisecond),
+			Output: output,
+		})
+	}
+	return h
+}
+
 // GetContainerLogs returns fake log output
-func (m *MockClient) GetContainerLogs(ctx context.Context, id string, tail string, follow bool) (io.ReadCloser, error) {
+func (m *MockClient) GetContainerLogs(ctx context.Context, id string, tail string, since time.Time, follow bool) (io.ReadCloser, error) {
+	if err := m.unreachable(); err != nil {
+		return nil, err
+	}
 	m.mu.RLock()
 	c := m.findContainerRLocked(id)
 	m.mu.RUnlock()
@@ -218,15 +480,29 @@ func (m *MockClient) GetContainerLogs(ctx context.Context, id string, tail strin
 		return newMockLogStream(ctx, c.Name), nil
 	}
 
-	return newMockLogBuffer(c.Name, 100), nil
+	lines := 100
+	if tail == "all" {
+		lines = 500
+	} else if n, err := strconv.Atoi(tail); err == nil {
+		lines = min(n, 500)
+	}
+	return newMockLogBuffer(c.Name, lines, since), nil
 }
 
 // GetContainerStats returns randomized but realistic stats
 func (m *MockClient) GetContainerStats(ctx context.Context, id string) (*ContainerStats, error) {
 	m.mu.RLock()
 	c := m.findContainerRLocked(id)
+	faults := m.faults
 	m.mu.RUnlock()
 
+	if faults.Disconnected {
+		return nil, errMockDisconnected
+	}
+	if faults.StatsError {
+		return nil, fmt.Errorf("failed to get stats for %s: Error response from daemon: mock stats fault", id)
+	}
+
 	if c == nil {
 		return nil, fmt.Errorf("container not found: %s", id)
 	}
@@ -250,20 +526,35 @@ func (m *MockClient) GetContainerStats(ctx context.Context, id string) (*Contain
 		MemoryPercent: float64(memoryUsage) / float64(memoryLimit) * 100,
 		NetworkRx:     uint64(rand.Intn(10000000)),
 		NetworkTx:     uint64(rand.Intn(5000000)),
+		BlkioRead:     uint64(rand.Intn(200000000)),
+		BlkioWrite:    uint64(rand.Intn(50000000)),
+		PIDs:          uint64(1 + rand.Intn(40)),
 	}, nil
 }
 
 // WatchEvents returns channels for container events
-func (m *MockClient) WatchEvents(ctx context.Context) (<-chan ContainerEvent, <-chan error) {
-	eventCh := make(chan ContainerEvent, 10)
+func (m *MockClient) WatchEvents(ctx context.Context) (<-chan Event, <-chan error) {
+	eventCh := make(chan Event, 10)
 	errCh := make(chan error, 1)
 
 	m.mu.Lock()
+	if m.faults.Disconnected {
+		m.mu.Unlock()
+		errCh <- errMockDisconnected
+		close(eventCh)
+		close(errCh)
+		return eventCh, errCh
+	}
 	m.eventSubs = append(m.eventSubs, eventCh)
+	disconnect := m.disconnect
 	m.mu.Unlock()
 
 	go func() {
-		<-ctx.Done()
+		select {
+		case <-ctx.Done():
+		case <-disconnect:
+			errCh <- errMockDisconnected
+		}
 		m.mu.Lock()
 		for i, ch := range m.eventSubs {
 			if ch == eventCh {
@@ -279,6 +570,129 @@ func (m *MockClient) WatchEvents(ctx context.Context) (<-chan ContainerEvent, <-
 	return eventCh, errCh
 }
 
+// GetImageHistory returns a plausible layer history for any image a mock container uses
+func (m *MockClient) GetImageHistory(ctx context.Context, id string) ([]ImageLayer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, c := range m.containers {
+		if c.ImageID != id && c.Image != id {
+			continue
+		}
+		created := c.Created.Add(-72 * time.Hour)
+		return []ImageLayer{
+			{ID: c.ImageID, Created: created, CreatedBy: `/bin/sh -c #(nop)  CMD ["/docker-entrypoint.sh"]`, Tags: []string{c.Image}},
+			{Created: created, CreatedBy: "/bin/sh -c apt-get update && apt-get install -y build-essential", Size: 412_000_000},
+			{Created: created, CreatedBy: "COPY . /app # buildkit", Size: 1_300_000_000},
+			{Created: created.Add(-24 * time.Hour), CreatedBy: "/bin/sh -c #(nop) ADD file:4b03b5f551e3 in / ", Size: 7_800_000},
+		}, nil
+	}
+	return nil, fmt.Errorf("image not found: %s", id)
+}
+
+// ResolveImage returns the ID of the image a mock container uses
+func (m *MockClient) ResolveImage(ctx context.Context, ref string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, c := range m.containers {
+		if c.ImageID == ref || c.Image == ref {
+			return c.ImageID, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrImageNotFound, ref)
+}
+
+// PullImage simulates an engine pull stream for a few layers
+func (m *MockClient) PullImage(ctx context.Context, ref string, auth *RegistryAuth) (io.ReadCloser, error) {
+	r, w := io.Pipe()
+
+	go func() {
+		enc := json.NewEncoder(w)
+		send := func(msg map[string]interface{}) bool {
+			select {
+			case <-ctx.Done():
+				w.CloseWithError(ctx.Err())
+				return false
+			case <-time.After(50 * time.Millisecond):
+			}
+			return enc.Encode(msg) == nil
+		}
+
+		layers := []string{"a2abf6c4d29d", "c7a4e4382001", "4044b9ba67c9"}
+		send(map[string]interface{}{"status": "Pulling from library/" + ref})
+		for _, id := range layers {
+			if !send(map[string]interface{}{"status": "Pulling fs layer", "id": id}) {
+				return
+			}
+		}
+		for _, id := range layers {
+			total := int64(rand.Intn(30_000_000) + 1_000_000)
+			for _, stage := range []string{"Downloading", "Extracting"} {
+				for pct := int64(0); pct <= 100; pct += 25 {
+					detail := map[string]int64{"current": total * pct / 100, "total": total}
+					if !send(map[string]interface{}{"status": stage, "id": id, "progressDetail": detail}) {
+						return
+					}
+				}
+			}
+			if !send(map[string]interface{}{"status": "Pull complete", "id": id}) {
+				return
+			}
+		}
+		send(map[string]interface{}{"status": "Status: Downloaded newer image for " + ref})
+		w.Close()
+		m.emit(Event{Type: "image", ID: ref, Name: ref, Action: "pull", Timestamp: time.Now()})
+	}()
+
+	return r, nil
+}
+
+// saveInitial records the current containers and faults as the state Reset
+// returns to
+func (m *MockClient) saveInitial() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.initial = m.initial[:0]
+	for _, c := range m.containers {
+		m.initial = append(m.initial, *c)
+	}
+	m.initialFaults = m.faults
+}
+
+// Reset returns the containers and faults to what the client started with,
+// emitting an event for each container that is removed or changes state
+func (m *MockClient) Reset() {
+	m.SetFaults(m.initialFaults)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	initial := make(map[string]*ContainerInfo, len(m.initial))
+	for _, c := range m.initial {
+		cpy := c
+		initial[c.ID] = &cpy
+	}
+	for id, c := range m.containers {
+		if _, ok := initial[id]; !ok {
+			m.emitEvent(c, "destroy")
+		}
+	}
+	for id, c := range initial {
+		if prev, ok := m.containers[id]; !ok || prev.State != c.State || prev.Health != c.Health {
+			action := "start"
+			if c.State != "running" {
+				action = "stop"
+			}
+			m.emitEvent(c, action)
+		}
+	}
+	m.containers = initial
+	m.resources = make(map[string]ContainerResources)
+	m.networks = make(map[string][]NetworkAttachment)
+}
+
 // SetContainerState allows external code (like MockComposeClient) to change container state
 func (m *MockClient) SetContainerState(id, state, status string) {
 	m.mu.Lock()
@@ -316,7 +730,7 @@ func (m *MockClient) SetAllContainersState(projectName, state, status string) {
 
 func (m *MockClient) findContainer(id string) *ContainerInfo {
 	for cid, c := range m.containers {
-		if cid == id || strings.HasPrefix(cid, id) {
+		if cid == id || strings.HasPrefix(cid, id) || c.Name == id {
 			return c
 		}
 	}
@@ -325,15 +739,23 @@ func (m *MockClient) findContainer(id string) *ContainerInfo {
 
 func (m *MockClient) findContainerRLocked(id string) *ContainerInfo {
 	for cid, c := range m.containers {
-		if cid == id || strings.HasPrefix(cid, id) {
+		if cid == id || strings.HasPrefix(cid, id) || c.Name == id {
 			return c
 		}
 	}
 	return nil
 }
 
+// emit sends an event to every subscriber
+func (m *MockClient) emit(event Event) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.sendEventLocked(event)
+}
+
 func (m *MockClient) emitEvent(c *ContainerInfo, action string) {
-	event := ContainerEvent{
+	event := Event{
+		Type:      "container",
 		ID:        c.ID,
 		Action:    action,
 		Name:      c.Name,
@@ -342,7 +764,11 @@ func (m *MockClient) emitEvent(c *ContainerInfo, action string) {
 		Service:   c.ServiceName,
 		Timestamp: time.Now(),
 	}
+	m.sendEventLocked(event)
+}
 
+// sendEventLocked sends an event to every subscriber; the caller holds mu
+func (m *MockClient) sendEventLocked(event Event) {
 	for _, ch := range m.eventSubs {
 		select {
 		case ch <- event:
@@ -356,7 +782,7 @@ type mockLogBuffer struct {
 	*bytes.Buffer
 }
 
-func newMockLogBuffer(containerName string, lines int) *mockLogBuffer {
+func newMockLogBuffer(containerName string, lines int, since time.Time) *mockLogBuffer {
 	var buf bytes.Buffer
 	now := time.Now()
 
@@ -371,12 +797,16 @@ func newMockLogBuffer(containerName string, lines int) *mockLogBuffer {
 		"Cache hit for key: user_123",
 		"Background job completed",
 		"Metrics exported successfully",
+		"\x1b[33mWARN\x1b[0m Slow query took \x1b[1m1.2s\x1b[0m",
 	}
 
 	for i := 0; i < lines; i++ {
-		ts := now.Add(-time.Duration(lines-i) * time.Second).Format(time.RFC3339Nano)
+		ts := now.Add(-time.Duration(lines-i) * time.Second)
+		if ts.Before(since) {
+			continue
+		}
 		msg := messages[i%len(messages)]
-		buf.WriteString(fmt.Sprintf("%s %s | %s\n", ts, containerName, msg))
+		buf.WriteString(fmt.Sprintf("%s %s | %s\n", ts.Format(time.RFC3339Nano), containerName, msg))
 	}
 
 	return &mockLogBuffer{Buffer: &buf}
@@ -447,3 +877,49 @@ func (s *mockLogStream) generate() {
 		}
 	}
 }
+
+// ExecContainer simulates a few commands: echo, true, false and sleep;
+// anything else prints that it ran
+func (m *MockClient) ExecContainer(ctx context.Context, id string, opts ExecOptions) (*ExecResult, error) {
+	m.mu.RLock()
+	c := m.findContainerRLocked(id)
+	faults := m.faults
+	m.mu.RUnlock()
+
+	if faults.Disconnected {
+		return nil, errMockDisconnected
+	}
+	if c == nil {
+		return nil, fmt.Errorf("container not found: %s", id)
+	}
+	if c.State != "running" {
+		return nil, fmt.Errorf("%w: %s", ErrContainerNotRunning, id)
+	}
+
+	started := time.Now()
+	result := &ExecResult{}
+	args := opts.Cmd[1:]
+	switch path.Base(opts.Cmd[0]) {
+	case "echo":
+		result.Stdout = strings.Join(args, " ") + "\n"
+	case "true":
+	case "false":
+		result.ExitCode = 1
+	case "sleep":
+		d := time.Second
+		if len(args) > 0 {
+			if secs, err := strconv.ParseFloat(args[0], 64); err == nil {
+				d = time.Duration(secs * float64(time.Second))
+			}
+		}
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	default:
+		result.Stdout = fmt.Sprintf("%s: ran %s\n", c.Name, strings.Join(opts.Cmd, " "))
+	}
+	result.Duration = time.Since(started)
+	return result, nil
+}
@@ -2,9 +2,15 @@ package docker
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // MockComposeClient provides mock Docker Compose operations
@@ -17,13 +23,22 @@ func NewMockComposeClient(dockerClient *MockClient) *MockComposeClient {
 	return &MockComposeClient{dockerClient: dockerClient}
 }
 
+// Info reports the mock as the compose implementation
+func (c *MockComposeClient) Info() ComposeInfo {
+	return ComposeInfo{Command: "mock", Version: "mock", Plugin: true}
+}
+
 // Up simulates docker compose up
-func (c *MockComposeClient) Up(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
+func (c *MockComposeClient) Up(ctx context.Context, projectDir string, opts UpOptions, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
+	if result := c.failure(projectDir, outputCh); result != nil {
+		return result, nil
+	}
+
 	projectName := projectNameFromDir(projectDir)
 	services := c.getProjectServices(projectName)
 
 	c.sendOutput(outputCh, fmt.Sprintf("[+] Running %d/%d", 0, len(services)))
-	time.Sleep(500 * time.Millisecond)
+	c.pause(ctx, 500*time.Millisecond)
 
 	for i, svc := range services {
 		select {
@@ -33,10 +48,10 @@ func (c *MockComposeClient) Up(ctx context.Context, projectDir string, outputCh
 		}
 
 		c.sendOutput(outputCh, fmt.Sprintf(" \u2714 Container %s-%s-1  Starting", projectName, svc))
-		time.Sleep(300 * time.Millisecond)
+		c.pause(ctx, 300*time.Millisecond)
 
 		c.sendOutput(outputCh, fmt.Sprintf(" \u2714 Container %s-%s-1  Started   %.1fs", projectName, svc, 0.3+float64(i)*0.2))
-		time.Sleep(200 * time.Millisecond)
+		c.pause(ctx, 200*time.Millisecond)
 
 		c.sendOutput(outputCh, fmt.Sprintf("[+] Running %d/%d", i+1, len(services)))
 	}
@@ -49,11 +64,15 @@ func (c *MockComposeClient) Up(ctx context.Context, projectDir string, outputCh
 
 // Down simulates docker compose down
 func (c *MockComposeClient) Down(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
+	if result := c.failure(projectDir, outputCh); result != nil {
+		return result, nil
+	}
+
 	projectName := projectNameFromDir(projectDir)
 	services := c.getProjectServices(projectName)
 
 	c.sendOutput(outputCh, fmt.Sprintf("[+] Running %d/%d", 0, len(services)))
-	time.Sleep(500 * time.Millisecond)
+	c.pause(ctx, 500*time.Millisecond)
 
 	for i, svc := range services {
 		select {
@@ -63,10 +82,10 @@ func (c *MockComposeClient) Down(ctx context.Context, projectDir string, outputC
 		}
 
 		c.sendOutput(outputCh, fmt.Sprintf(" \u2714 Container %s-%s-1  Stopping", projectName, svc))
-		time.Sleep(400 * time.Millisecond)
+		c.pause(ctx, 400*time.Millisecond)
 
 		c.sendOutput(outputCh, fmt.Sprintf(" \u2714 Container %s-%s-1  Stopped   %.1fs", projectName, svc, 0.4+float64(i)*0.2))
-		time.Sleep(200 * time.Millisecond)
+		c.pause(ctx, 200*time.Millisecond)
 
 		c.sendOutput(outputCh, fmt.Sprintf("[+] Running %d/%d", i+1, len(services)))
 	}
@@ -80,8 +99,99 @@ func (c *MockComposeClient) Down(ctx context.Context, projectDir string, outputC
 	return &ComposeResult{Success: true, Message: "Stopped successfully"}, nil
 }
 
+// Start simulates docker compose start
+func (c *MockComposeClient) Start(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
+	return c.simulateServices(ctx, projectDir, outputCh, "Starting", "Started", 200*time.Millisecond,
+		"running", "Up Less than a second", "Started successfully")
+}
+
+// Stop simulates docker compose stop; unlike Down the network is kept
+func (c *MockComposeClient) Stop(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
+	return c.simulateServices(ctx, projectDir, outputCh, "Stopping", "Stopped", 400*time.Millisecond,
+		"exited", "Exited (0) Less than a second ago", "Stopped successfully")
+}
+
+// Run simulates docker compose run --rm
+func (c *MockComposeClient) Run(ctx context.Context, projectDir string, opts RunOptions, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
+	if result := c.failure(projectDir, outputCh); result != nil {
+		return result, nil
+	}
+
+	projectName := projectNameFromDir(projectDir)
+	name := fmt.Sprintf("%s-%s-run-%s", projectName, opts.Service, mockLayerID(opts.Service)[:8])
+
+	c.sendOutput(outputCh, fmt.Sprintf(" \u2714 Container %s  Created", name))
+	c.pause(ctx, 300*time.Millisecond)
+	c.sendOutput(outputCh, "$ "+strings.Join(opts.Command, " "))
+	for i := 1; i <= 3; i++ {
+		select {
+		case <-ctx.Done():
+			return &ComposeResult{Success: false, Message: "Operation cancelled"}, ctx.Err()
+		default:
+		}
+		c.pause(ctx, 300*time.Millisecond)
+		c.sendOutput(outputCh, fmt.Sprintf("Applying migration %03d... ok", i))
+	}
+
+	return &ComposeResult{Success: true, Message: "Command completed successfully"}, nil
+}
+
+// Config returns the project's compose file as-is, since there is no
+// compose CLI to resolve it
+func (c *MockComposeClient) Config(ctx context.Context, projectDir string, profiles []string, format string) ([]byte, error) {
+	composeFile, err := findComposeFile(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(composeFile)
+	if err != nil {
+		return nil, err
+	}
+	if format == "yaml" {
+		return data, nil
+	}
+
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("compose config failed: %w", err)
+	}
+	config["name"] = projectNameFromDir(projectDir)
+	return json.Marshal(config)
+}
+
+// simulateServices prints compose-style progress for each service of a
+// project, then moves its containers to the given state
+func (c *MockComposeClient) simulateServices(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput,
+	doing, done string, delay time.Duration, state, status, message string) (*ComposeResult, error) {
+	if result := c.failure(projectDir, outputCh); result != nil {
+		return result, nil
+	}
+
+	projectName := projectNameFromDir(projectDir)
+	services := c.getProjectServices(projectName)
+
+	for i, svc := range services {
+		select {
+		case <-ctx.Done():
+			return &ComposeResult{Success: false, Message: "Operation cancelled"}, ctx.Err()
+		default:
+		}
+
+		c.sendOutput(outputCh, fmt.Sprintf(" \u2714 Container %s-%s-1  %s", projectName, svc, doing))
+		c.pause(ctx, delay)
+		c.sendOutput(outputCh, fmt.Sprintf(" \u2714 Container %s-%s-1  %s   %.1fs", projectName, svc, done, delay.Seconds()+float64(i)*0.1))
+	}
+
+	c.dockerClient.SetAllContainersState(projectName, state, status)
+	return &ComposeResult{Success: true, Message: message}, nil
+}
+
 // Pull simulates docker compose pull
 func (c *MockComposeClient) Pull(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
+	if result := c.failure(projectDir, outputCh); result != nil {
+		return result, nil
+	}
+
 	projectName := projectNameFromDir(projectDir)
 	services := c.getProjectServices(projectName)
 
@@ -92,16 +202,19 @@ func (c *MockComposeClient) Pull(ctx context.Context, projectDir string, outputC
 		default:
 		}
 
-		c.sendOutput(outputCh, fmt.Sprintf("[+] Pulling %s", svc))
-		time.Sleep(300 * time.Millisecond)
+		c.sendOutput(outputCh, fmt.Sprintf(" %s Pulling", svc))
+		c.pause(ctx, 300*time.Millisecond)
 
-		// Simulate progress
+		// Simulate layer progress in the format docker compose prints
+		layer := mockLayerID(svc)
 		for pct := 0; pct <= 100; pct += 25 {
-			c.sendOutput(outputCh, fmt.Sprintf("[+] %s Pulling  %d%%", svc, pct))
-			time.Sleep(200 * time.Millisecond)
+			bar := strings.Repeat("=", pct/10) + ">" + strings.Repeat(" ", 10-pct/10)
+			c.sendOutput(outputCh, fmt.Sprintf(" %s Downloading [%s]  %.1fMB/12.4MB", layer, bar, 12.4*float64(pct)/100))
+			c.pause(ctx, 200*time.Millisecond)
 		}
+		c.sendOutput(outputCh, fmt.Sprintf(" %s Pull complete", layer))
 
-		c.sendOutput(outputCh, fmt.Sprintf("[+] %s Pulled", svc))
+		c.sendOutput(outputCh, fmt.Sprintf(" %s Pulled", svc))
 	}
 
 	return &ComposeResult{Success: true, Message: "Pulled successfully"}, nil
@@ -109,11 +222,15 @@ func (c *MockComposeClient) Pull(ctx context.Context, projectDir string, outputC
 
 // Restart simulates docker compose restart
 func (c *MockComposeClient) Restart(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
+	if result := c.failure(projectDir, outputCh); result != nil {
+		return result, nil
+	}
+
 	projectName := projectNameFromDir(projectDir)
 	services := c.getProjectServices(projectName)
 
 	c.sendOutput(outputCh, fmt.Sprintf("[+] Restarting %d services", len(services)))
-	time.Sleep(500 * time.Millisecond)
+	c.pause(ctx, 500*time.Millisecond)
 
 	for i, svc := range services {
 		select {
@@ -123,10 +240,10 @@ func (c *MockComposeClient) Restart(ctx context.Context, projectDir string, outp
 		}
 
 		c.sendOutput(outputCh, fmt.Sprintf(" \u2714 Container %s-%s-1  Restarting", projectName, svc))
-		time.Sleep(600 * time.Millisecond)
+		c.pause(ctx, 600*time.Millisecond)
 
 		c.sendOutput(outputCh, fmt.Sprintf(" \u2714 Container %s-%s-1  Restarted   %.1fs", projectName, svc, 0.6+float64(i)*0.2))
-		time.Sleep(200 * time.Millisecond)
+		c.pause(ctx, 200*time.Millisecond)
 	}
 
 	// Emit restart events
@@ -137,7 +254,7 @@ func (c *MockComposeClient) Restart(ctx context.Context, projectDir string, outp
 
 // Update simulates docker compose pull && up --force-recreate
 func (c *MockComposeClient) Update(ctx context.Context, projectDir string, outputCh chan<- ComposeOutput) (*ComposeResult, error) {
-	// First pull
+	// First pull; it fails like Update would
 	result, err := c.Pull(ctx, projectDir, outputCh)
 	if err != nil || !result.Success {
 		return result, err
@@ -145,7 +262,7 @@ func (c *MockComposeClient) Update(ctx context.Context, projectDir string, outpu
 
 	c.sendOutput(outputCh, "")
 	c.sendOutput(outputCh, "[+] Recreating containers...")
-	time.Sleep(500 * time.Millisecond)
+	c.pause(ctx, 500*time.Millisecond)
 
 	// Then recreate
 	projectName := projectNameFromDir(projectDir)
@@ -159,10 +276,10 @@ func (c *MockComposeClient) Update(ctx context.Context, projectDir string, outpu
 		}
 
 		c.sendOutput(outputCh, fmt.Sprintf(" \u2714 Container %s-%s-1  Recreating", projectName, svc))
-		time.Sleep(400 * time.Millisecond)
+		c.pause(ctx, 400*time.Millisecond)
 
 		c.sendOutput(outputCh, fmt.Sprintf(" \u2714 Container %s-%s-1  Recreated   %.1fs", projectName, svc, 0.4+float64(i)*0.2))
-		time.Sleep(200 * time.Millisecond)
+		c.pause(ctx, 200*time.Millisecond)
 	}
 
 	c.dockerClient.SetAllContainersState(projectName, "running", "Up Less than a second")
@@ -208,3 +325,10 @@ func projectNameFromDir(dir string) string {
 
 // Verify MockComposeClient implements ComposeExecutor
 var _ ComposeExecutor = (*MockComposeClient)(nil)
+
+// mockLayerID derives a stable layer digest for a service
+func mockLayerID(service string) string {
+	h := fnv.New64a()
+	h.Write([]byte(service))
+	return fmt.Sprintf("%016x", h.Sum64())[:12]
+}
@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// MockFaults are failures injected into the mock clients, so error handling
+// in the UI and API can be exercised without a broken Docker daemon
+type MockFaults struct {
+	// ComposeFail lists projects whose compose operations fail, "*" for all
+	ComposeFail []string `json:"composeFail" yaml:"composeFail"`
+	// StatsError makes container stats fail
+	StatsError bool `json:"statsError" yaml:"statsError"`
+	// Disconnected makes Docker unreachable: calls fail and event streams
+	// end with an error until it is cleared
+	Disconnected bool `json:"disconnected" yaml:"disconnected"`
+	// Slowdown multiplies the duration of compose operations, e.g. 10 makes
+	// them ten times slower; 0 or 1 is normal speed
+	Slowdown float64 `json:"slowdown" yaml:"slowdown"`
+}
+
+// errMockDisconnected is what the mock returns while disconnected, worded
+// like the Docker client's error
+var errMockDisconnected = errors.New("Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?")
+
+// Faults returns the injected faults
+func (m *MockClient) Faults() MockFaults {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.faults
+}
+
+// SetFaults replaces the injected faults. Disconnecting ends the current
+// event streams with an error, as a daemon restart would.
+func (m *MockClient) SetFaults(f MockFaults) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if f.Disconnected && !m.faults.Disconnected {
+		close(m.disconnect)
+	}
+	if !f.Disconnected && m.faults.Disconnected {
+		m.disconnect = make(chan struct{})
+	}
+	m.faults = f
+}
+
+// unreachable returns the disconnected error while Docker is made
+// unreachable
+func (m *MockClient) unreachable() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.faults.Disconnected {
+		return errMockDisconnected
+	}
+	return nil
+}
+
+// failure returns the result of a compose operation on a project that is
+// made to fail, or nil if it should run
+func (c *MockComposeClient) failure(projectDir string, outputCh chan<- ComposeOutput) *ComposeResult {
+	faults := c.dockerClient.Faults()
+	if faults.Disconnected {
+		c.sendError(outputCh, errMockDisconnected.Error())
+		return &ComposeResult{Success: false, Message: "Command failed: exit status 1"}
+	}
+
+	projectName := projectNameFromDir(projectDir)
+	if slices.Contains(faults.ComposeFail, projectName) || slices.Contains(faults.ComposeFail, "*") {
+		c.sendOutput(outputCh, "[+] Running 0/1")
+		c.pause(context.Background(), 300*time.Millisecond)
+		c.sendError(outputCh, "Error response from daemon: driver failed programming external connectivity on endpoint "+
+			projectName+"-app-1: Bind for 0.0.0.0:8080 failed: port is already allocated")
+		return &ComposeResult{Success: false, Message: "Command failed: exit status 1"}
+	}
+	return nil
+}
+
+// pause waits as long as a step of a compose operation takes, scaled by
+// the injected slowdown, or until ctx is done
+func (c *MockComposeClient) pause(ctx context.Context, d time.Duration) {
+	if slowdown := c.dockerClient.Faults().Slowdown; slowdown > 0 {
+		d = time.Duration(float64(d) * slowdown)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+func (c *MockComposeClient) sendError(outputCh chan<- ComposeOutput, line string) {
+	if outputCh != nil {
+		outputCh <- ComposeOutput{Line: line, Stream: "stderr"}
+	}
+}
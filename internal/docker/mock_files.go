@@ -0,0 +1,201 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mockFiles is the file system every mock container appears to have;
+// directories end in a slash. mockFilesMu also guards mockVolumes.
+var (
+	mockFilesMu sync.RWMutex
+	mockFiles   = map[string]string{
+		"/":                     "",
+		"/app/":                 "",
+		"/app/config.yml":       "server:\n  port: 8080\n  log_level: info\n",
+		"/app/server":           "\x7fELF\x02\x01\x01\x00",
+		"/app/data/":            "",
+		"/app/data/cache.json":  "{\"entries\": []}\n",
+		"/etc/":                 "",
+		"/etc/hostname":         "", // filled in with the container name
+		"/etc/os-release":       "NAME=\"Alpine Linux\"\nID=alpine\nVERSION_ID=3.19.1\n",
+		"/var/":                 "",
+		"/var/log/":             "",
+		"/var/log/app.log":      "started\nlistening on :8080\n",
+		"/docker-entrypoint.sh": "#!/bin/sh\nexec \"$@\"\n",
+		"/docker-entrypoint.d/": "",
+	}
+)
+
+// mockVolumes holds the contents of the mock volumes, each mounted at
+// VolumeMountPath
+var mockVolumes = map[string]map[string]string{
+	"webapp_db-data": {
+		"/volume/":                "",
+		"/volume/PG_VERSION":      "16\n",
+		"/volume/postgresql.conf": "listen_addresses = '*'\nmax_connections = 100\n",
+		"/volume/base/":           "",
+		"/volume/base/1/":         "",
+		"/volume/pg_wal/":         "",
+		"/volume/postmaster.opts": "/usr/local/bin/postgres\n",
+	},
+	"webapp_uploads": {
+		"/volume/":           "",
+		"/volume/avatar.png": "\x89PNG\r\n\x1a\n\x00\x00",
+		"/volume/readme.txt": "User uploads\n",
+	},
+}
+
+// mockVolumeBrowserPrefix marks the IDs of mock volume browser containers
+const mockVolumeBrowserPrefix = "volume-browser-"
+
+// mockFS returns the file system of a mock container or volume browser,
+// along with its hostname and file modification time
+func (m *MockClient) mockFS(id string) (map[string]string, string, time.Time) {
+	if name, ok := strings.CutPrefix(id, mockVolumeBrowserPrefix); ok {
+		return mockVolumes[name], name, time.Now().Add(-24 * time.Hour)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c := m.findContainerRLocked(id)
+	if c == nil {
+		return nil, "", time.Time{}
+	}
+	return mockFiles, c.Name, c.Created
+}
+
+// CreateVolumeBrowser returns a mock browser container for a mock volume
+func (m *MockClient) CreateVolumeBrowser(ctx context.Context, name string) (string, error) {
+	if _, ok := mockVolumes[name]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrVolumeNotFound, name)
+	}
+	return mockVolumeBrowserPrefix + name, nil
+}
+
+// RemoveVolumeBrowser is a no-op: mock browser containers are never created
+func (m *MockClient) RemoveVolumeBrowser(ctx context.Context, id string) error {
+	return nil
+}
+
+// mockStat looks up a path in the mock file system
+func (m *MockClient) mockStat(id, p string) (*FileInfo, string, error) {
+	files, name, mtime := m.mockFS(id)
+	if files == nil {
+		return nil, "", fmt.Errorf("container not found: %s", id)
+	}
+
+	p = path.Clean("/" + p)
+
+	mockFilesMu.RLock()
+	defer mockFilesMu.RUnlock()
+	dir := strings.TrimSuffix(p, "/") + "/"
+	if _, ok := files[dir]; ok {
+		return newFileInfo(p, 4096, os.ModeDir|0o755, mtime, ""), "", nil
+	}
+	if content, ok := files[p]; ok {
+		if p == "/etc/hostname" {
+			content = name + "\n"
+		}
+		return newFileInfo(p, int64(len(content)), 0o644, mtime, ""), content, nil
+	}
+	return nil, "", fmt.Errorf("Could not find the file %s in container %s", p, id)
+}
+
+// StatContainerPath returns information about a path in the mock file system
+func (m *MockClient) StatContainerPath(ctx context.Context, id, p string) (*FileInfo, error) {
+	info, _, err := m.mockStat(id, p)
+	return info, err
+}
+
+// CopyFromContainer archives a path of the mock file system the way the
+// engine does: entries are named relative to the path's parent
+func (m *MockClient) CopyFromContainer(ctx context.Context, id, p string) (io.ReadCloser, *FileInfo, error) {
+	info, content, err := m.mockStat(id, p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	base := info.Name
+	if base == "/" {
+		base = "."
+	}
+
+	if !info.IsDir {
+		tw.WriteHeader(&tar.Header{Name: base, Mode: 0o644, Size: int64(len(content)), ModTime: info.ModTime, Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	} else {
+		tw.WriteHeader(&tar.Header{Name: base + "/", Mode: 0o755, ModTime: info.ModTime, Typeflag: tar.TypeDir})
+
+		prefix := strings.TrimSuffix(info.Path, "/") + "/"
+		files, _, _ := m.mockFS(id)
+		mockFilesMu.RLock()
+		defer mockFilesMu.RUnlock()
+		var names []string
+		for name := range files {
+			if strings.HasPrefix(name, prefix) && name != prefix {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			rel := base + "/" + strings.TrimPrefix(name, prefix)
+			if strings.HasSuffix(name, "/") {
+				tw.WriteHeader(&tar.Header{Name: rel, Mode: 0o755, ModTime: info.ModTime, Typeflag: tar.TypeDir})
+				continue
+			}
+			data := files[name]
+			tw.WriteHeader(&tar.Header{Name: rel, Mode: 0o644, Size: int64(len(data)), ModTime: time.Now(), Typeflag: tar.TypeReg})
+			tw.Write([]byte(data))
+		}
+	}
+	tw.Close()
+
+	return io.NopCloser(&buf), info, nil
+}
+
+// CopyToContainer extracts regular files from a tar archive into the mock
+// file system
+func (m *MockClient) CopyToContainer(ctx context.Context, id, dstDir string, content io.Reader) error {
+	info, _, err := m.mockStat(id, dstDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir {
+		return fmt.Errorf("%s is not a directory", dstDir)
+	}
+
+	tr := tar.NewReader(content)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		files, _, _ := m.mockFS(id)
+		mockFilesMu.Lock()
+		files[path.Join(info.Path, hdr.Name)] = string(data)
+		mockFilesMu.Unlock()
+	}
+}
@@ -0,0 +1,206 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// mockSharedNetworks exist on the mock host outside any project, like a
+// reverse proxy network stacks join as external
+var mockSharedNetworks = []string{"proxy"}
+
+// ListNetworks returns the default networks, a default network for each
+// mock project and the shared networks
+func (m *MockClient) ListNetworks(ctx context.Context) ([]NetworkInfo, error) {
+	if err := m.unreachable(); err != nil {
+		return nil, err
+	}
+
+	networks := []NetworkInfo{
+		mockNetwork("bridge", "bridge"),
+		mockNetwork("host", "host"),
+		mockNetwork("none", "null"),
+	}
+	for _, name := range mockSharedNetworks {
+		networks = append(networks, mockNetwork(name, "bridge"))
+	}
+
+	m.mu.RLock()
+	projects := make(map[string]bool)
+	for _, c := range m.containers {
+		if c.ProjectName != "" {
+			projects[c.ProjectName] = true
+		}
+	}
+	m.mu.RUnlock()
+	for p := range projects {
+		n := mockNetwork(p+"_default", "bridge")
+		n.Labels = map[string]string{"com.docker.compose.project": p, "com.docker.compose.network": "default"}
+		networks = append(networks, n)
+	}
+
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+	return networks, nil
+}
+
+// mockNetwork describes a local network with an ID derived from its name
+func mockNetwork(name, driver string) NetworkInfo {
+	return NetworkInfo{
+		ID:     fmt.Sprintf("%012x", mockID("network:"+name)),
+		Name:   name,
+		Driver: driver,
+		Scope:  "local",
+	}
+}
+
+// ListVolumes returns the mock volumes
+func (m *MockClient) ListVolumes(ctx context.Context) ([]VolumeInfo, error) {
+	if err := m.unreachable(); err != nil {
+		return nil, err
+	}
+
+	mockFilesMu.RLock()
+	defer mockFilesMu.RUnlock()
+
+	volumes := make([]VolumeInfo, 0, len(mockVolumes))
+	for name := range mockVolumes {
+		volumes = append(volumes, VolumeInfo{Name: name, Driver: "local", Mountpoint: "/var/lib/docker/volumes/" + name + "/_data"})
+	}
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+	return volumes, nil
+}
+
+// mockNetworks returns a container's endpoints: its project's default
+// network, or bridge for a standalone container, until changed. The caller
+// holds mu.
+func (m *MockClient) mockNetworks(c *ContainerInfo) []NetworkAttachment {
+	if attachments, ok := m.networks[c.ID]; ok {
+		return append([]NetworkAttachment(nil), attachments...)
+	}
+	if c.ProjectName == "" {
+		return []NetworkAttachment{mockAttachment("bridge", c.ID, "", nil)}
+	}
+	return []NetworkAttachment{mockAttachment(c.ProjectName+"_default", c.ID, "", []string{c.ServiceName, c.Name})}
+}
+
+// mockAttachment builds an endpoint with an address derived from the
+// network and container unless one is given
+func mockAttachment(network, id, ip string, aliases []string) NetworkAttachment {
+	subnet := 17 + mockID("network:"+network)%14
+	if network == "bridge" {
+		subnet = 17
+	}
+	if ip == "" {
+		ip = fmt.Sprintf("172.%d.0.%d", subnet, 2+mockID(id)%250)
+	}
+	return NetworkAttachment{
+		Network:    network,
+		IPAddress:  ip,
+		Gateway:    fmt.Sprintf("172.%d.0.1", subnet),
+		MacAddress: fmt.Sprintf("02:42:ac:%02x:00:%02x", subnet, mockID(network+id)%256),
+		Aliases:    aliases,
+	}
+}
+
+// ConnectNetwork attaches a mock container to a mock network
+func (m *MockClient) ConnectNetwork(ctx context.Context, id string, opts ConnectOptions) error {
+	networks, err := m.ListNetworks(ctx)
+	if err != nil {
+		return err
+	}
+	known := false
+	for _, n := range networks {
+		known = known || n.Name == opts.Network
+	}
+	if !known {
+		return fmt.Errorf("%w: network %s not found", ErrNetworkNotFound, opts.Network)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.findContainer(id)
+	if c == nil {
+		return fmt.Errorf("%w: container %s not found", ErrNetworkNotFound, id)
+	}
+	attachments := m.mockNetworks(c)
+	for _, a := range attachments {
+		if a.Network == opts.Network {
+			return fmt.Errorf("%w: endpoint with name %s already exists in network %s", ErrNetworkConflict, c.Name, opts.Network)
+		}
+	}
+	a := mockAttachment(opts.Network, c.ID, opts.IPv4Address, opts.Aliases)
+	a.IPv6Address = opts.IPv6Address
+	m.networks[c.ID] = append(attachments, a)
+	return nil
+}
+
+// DisconnectNetwork detaches a mock container from a network
+func (m *MockClient) DisconnectNetwork(ctx context.Context, id, network string, force bool) error {
+	if err := m.unreachable(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.findContainer(id)
+	if c == nil {
+		return fmt.Errorf("%w: container %s not found", ErrNetworkNotFound, id)
+	}
+	attachments := m.mockNetworks(c)
+	for i, a := range attachments {
+		if a.Network == network {
+			m.networks[c.ID] = append(attachments[:i], attachments[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: container %s is not connected to network %s", ErrNetworkConflict, c.Name, network)
+}
+
+// InspectNetwork returns a mock network with the running containers
+// attached to it
+func (m *MockClient) InspectNetwork(ctx context.Context, name string) (*NetworkDetail, error) {
+	networks, err := m.ListNetworks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var detail *NetworkDetail
+	for _, n := range networks {
+		if n.Name == name || n.ID == name {
+			detail = &NetworkDetail{NetworkInfo: n, Containers: []NetworkEndpoint{}}
+		}
+	}
+	if detail == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNetworkNotFound, name)
+	}
+	if detail.Driver == "bridge" {
+		gateway := mockAttachment(detail.Name, "", "", nil).Gateway
+		subnet := strings.TrimSuffix(gateway, ".0.1") + ".0.0/16"
+		detail.Subnets = []NetworkSubnet{{Subnet: subnet, Gateway: gateway}}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, c := range m.containers {
+		if c.State != "running" {
+			continue
+		}
+		for _, a := range m.mockNetworks(c) {
+			if a.Network == detail.Name {
+				detail.Containers = append(detail.Containers, NetworkEndpoint{
+					ID:          c.ID,
+					Name:        c.Name,
+					IPAddress:   a.IPAddress,
+					IPv6Address: a.IPv6Address,
+					MacAddress:  a.MacAddress,
+				})
+			}
+		}
+	}
+	sort.Slice(detail.Containers, func(i, j int) bool { return detail.Containers[i].Name < detail.Containers[j].Name })
+	return detail, nil
+}
@@ -0,0 +1,341 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"gopkg.in/yaml.v3"
+)
+
+// MockScenario describes the projects, containers and scripted events a
+// mock client starts with, for frontend work and demos beyond the built-in
+// demo stacks. For example:
+//
+//	projects:
+//	  - name: shop
+//	    services:
+//	      - name: web
+//	        image: nginx:alpine
+//	        ports: ["8080:80"]
+//	        health: healthy
+//	      - name: worker
+//	        image: python:3.12
+//	        state: exited
+//	        exitCode: 1
+//	containers:
+//	  - name: adminer
+//	    image: adminer
+//	events:
+//	  - after: 30s
+//	    container: shop-web-1
+//	    action: health_status
+//	    health: unhealthy
+//	  - after: 1m
+//	    every: 2m
+//	    container: shop-worker-1
+//	    action: die
+//	    exitCode: 139
+//	faults:
+//	  composeFail: [shop]
+type MockScenario struct {
+	Projects   []MockProject   `yaml:"projects"`
+	Containers []MockContainer `yaml:"containers"` // outside any project
+	Events     []MockEvent     `yaml:"events"`
+	Faults     MockFaults      `yaml:"faults"` // injected from the start
+}
+
+// MockProject is a compose project of a scenario. Its services become
+// containers named <project>-<service>-1.
+type MockProject struct {
+	Name     string          `yaml:"name"`
+	Services []MockContainer `yaml:"services"`
+}
+
+// MockContainer is a container of a scenario, or a service of a project
+type MockContainer struct {
+	Name     string            `yaml:"name"`
+	Image    string            `yaml:"image"`
+	State    string            `yaml:"state"`  // running (default), exited, restarting, paused or created
+	Health   string            `yaml:"health"` // healthy, unhealthy or starting; empty for none
+	ExitCode int               `yaml:"exitCode"`
+	Age      time.Duration     `yaml:"age"` // how long ago it was created, default 1h
+	Ports    []string          `yaml:"ports"`
+	Labels   map[string]string `yaml:"labels"`
+}
+
+// MockEvent changes a container after a delay and emits a Docker event
+// with its action
+type MockEvent struct {
+	After     time.Duration `yaml:"after"`
+	Every     time.Duration `yaml:"every"` // repeats the event when set
+	Container string        `yaml:"container"`
+	Action    string        `yaml:"action"` // start, stop, die, restart, pause, unpause, health_status, ...
+	State     string        `yaml:"state"`  // defaults from the action
+	Health    string        `yaml:"health"`
+	ExitCode  int           `yaml:"exitCode"`
+}
+
+// LoadMockScenario reads a scenario file
+func LoadMockScenario(path string) (*MockScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	var s MockScenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario: %w", err)
+	}
+
+	for _, p := range s.Projects {
+		if p.Name == "" || p.Name != filepath.Base(p.Name) {
+			return nil, fmt.Errorf("invalid scenario project name %q", p.Name)
+		}
+		for _, svc := range p.Services {
+			if svc.Name == "" || svc.Image == "" {
+				return nil, fmt.Errorf("scenario project %s: services need a name and an image", p.Name)
+			}
+		}
+	}
+	for _, c := range s.Containers {
+		if c.Name == "" || c.Image == "" {
+			return nil, fmt.Errorf("scenario containers need a name and an image")
+		}
+	}
+	for _, e := range s.Events {
+		if e.Container == "" || e.Action == "" {
+			return nil, fmt.Errorf("scenario events need a container and an action")
+		}
+	}
+	return &s, nil
+}
+
+// WriteProjects writes a compose file for each project into dir, so the
+// scanner finds them like real projects
+func (s *MockScenario) WriteProjects(dir string) error {
+	for _, p := range s.Projects {
+		services := make(map[string]map[string]interface{}, len(p.Services))
+		for _, svc := range p.Services {
+			def := map[string]interface{}{"image": svc.Image}
+			if len(svc.Ports) > 0 {
+				def["ports"] = svc.Ports
+			}
+			if len(svc.Labels) > 0 {
+				def["labels"] = svc.Labels
+			}
+			services[svc.Name] = def
+		}
+		data, err := yaml.Marshal(map[string]interface{}{"services": services})
+		if err != nil {
+			return err
+		}
+
+		projectDir := filepath.Join(dir, p.Name)
+		if err := os.MkdirAll(projectDir, 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(projectDir, "compose.yaml"), data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewMockClientFromScenario creates a mock client with a scenario's
+// containers instead of the demo containers. Run PlayScenario to play its
+// events.
+func NewMockClientFromScenario(s *MockScenario) (*MockClient, error) {
+	m := NewMockClient()
+	m.containers = make(map[string]*ContainerInfo)
+
+	now := time.Now()
+	add := func(mc MockContainer, project, service string) error {
+		c, err := mc.containerInfo(now, project, service)
+		if err != nil {
+			return err
+		}
+		m.containers[c.ID] = c
+		return nil
+	}
+	for _, p := range s.Projects {
+		for _, svc := range p.Services {
+			if err := add(svc, p.Name, svc.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, c := range s.Containers {
+		if err := add(c, "", ""); err != nil {
+			return nil, err
+		}
+	}
+	m.SetFaults(s.Faults)
+	m.saveInitial()
+	return m, nil
+}
+
+func (mc MockContainer) containerInfo(now time.Time, project, service string) (*ContainerInfo, error) {
+	name := mc.Name
+	labels := map[string]string{}
+	for k, v := range mc.Labels {
+		labels[k] = v
+	}
+	if project != "" {
+		name = project + "-" + service + "-1"
+		labels["com.docker.compose.project"] = project
+		labels["com.docker.compose.service"] = service
+	}
+
+	_, bindings, err := nat.ParsePortSpecs(mc.Ports)
+	if err != nil {
+		return nil, fmt.Errorf("container %s: invalid port: %w", name, err)
+	}
+	ports := []PortMapping{}
+	for port, bs := range bindings {
+		for _, b := range bs {
+			hostIP := b.HostIP
+			if hostIP == "" {
+				hostIP = "0.0.0.0"
+			}
+			ports = append(ports, PortMapping{HostIP: hostIP, HostPort: b.HostPort, ContainerPort: port.Port(), Protocol: port.Proto()})
+		}
+	}
+
+	state := mc.State
+	if state == "" {
+		state = "running"
+	}
+	age := mc.Age
+	if age <= 0 {
+		age = time.Hour
+	}
+
+	id := fmt.Sprintf("%012x", mockID(name))
+	c := &ContainerInfo{
+		ID:          id,
+		Name:        name,
+		Image:       mc.Image,
+		ImageID:     "sha256:" + id,
+		State:       state,
+		Health:      mc.Health,
+		Created:     now.Add(-age),
+		Ports:       ports,
+		Labels:      labels,
+		ProjectName: project,
+		ServiceName: service,
+	}
+	if project != "" {
+		c.WorkingDir = "/projects/" + project
+	}
+	c.Status = mockStatus(state, mc.ExitCode, mc.Health)
+	return c, nil
+}
+
+// mockID derives a stable 48-bit container ID from a name
+func mockID(name string) uint64 {
+	var h uint64 = 14695981039346656037
+	for i := 0; i < len(name); i++ {
+		h ^= uint64(name[i])
+		h *= 1099511628211
+	}
+	return h & (1<<48 - 1)
+}
+
+// mockStatus builds the list status Docker reports for a state
+func mockStatus(state string, exitCode int, health string) string {
+	switch state {
+	case "running":
+		if health != "" {
+			return fmt.Sprintf("Up Less than a second (%s)", health)
+		}
+		return "Up Less than a second"
+	case "exited":
+		return fmt.Sprintf("Exited (%d) Less than a second ago", exitCode)
+	case "restarting":
+		return fmt.Sprintf("Restarting (%d) Less than a second ago", exitCode)
+	case "paused":
+		return "Up Less than a second (Paused)"
+	default:
+		return "Created"
+	}
+}
+
+// actionStates maps Docker event actions to the state they leave a
+// container in
+var actionStates = map[string]string{
+	"start":   "running",
+	"restart": "running",
+	"unpause": "running",
+	"stop":    "exited",
+	"die":     "exited",
+	"kill":    "exited",
+	"pause":   "paused",
+}
+
+// PlayScenario applies the scenario's events at their times until ctx is
+// cancelled
+func (m *MockClient) PlayScenario(ctx context.Context, s *MockScenario) {
+	for _, e := range s.Events {
+		go func() {
+			timer := time.NewTimer(e.After)
+			defer timer.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-timer.C:
+				}
+				if err := m.applyEvent(e); err != nil {
+					slog.Warn("Mock scenario event failed", "container", e.Container, "action", e.Action, "error", err)
+					return
+				}
+				if e.Every <= 0 {
+					return
+				}
+				timer.Reset(e.Every)
+			}
+		}()
+	}
+}
+
+// applyEvent changes a container as a scenario event describes and emits
+// the event
+func (m *MockClient) applyEvent(e MockEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var c *ContainerInfo
+	for _, cand := range m.containers {
+		if cand.Name == e.Container || strings.HasPrefix(cand.ID, e.Container) {
+			c = cand
+			break
+		}
+	}
+	if c == nil {
+		return fmt.Errorf("container not found: %s", e.Container)
+	}
+
+	state := e.State
+	if state == "" {
+		state = actionStates[e.Action]
+	}
+	if state != "" {
+		c.State = state
+	}
+	if e.Health != "" {
+		c.Health = e.Health
+	}
+	c.Status = mockStatus(c.State, e.ExitCode, c.Health)
+
+	action := e.Action
+	if action == "health_status" && c.Health != "" {
+		action = "health_status: " + c.Health
+	}
+	m.emitEvent(c, action)
+	return nil
+}
@@ -0,0 +1,243 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+)
+
+// NetworkInfo describes a Docker network
+type NetworkInfo struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Driver   string            `json:"driver"`
+	Scope    string            `json:"scope"`
+	Internal bool              `json:"internal,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// ListNetworks returns the host's networks sorted by name
+func (c *Client) ListNetworks(ctx context.Context) ([]NetworkInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	list, err := c.cli.NetworkList(ctx, network.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	networks := make([]NetworkInfo, 0, len(list))
+	for _, n := range list {
+		networks = append(networks, NetworkInfo{
+			ID:       n.ID[:12],
+			Name:     n.Name,
+			Driver:   n.Driver,
+			Scope:    n.Scope,
+			Internal: n.Internal,
+			Labels:   n.Labels,
+		})
+	}
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+	return networks, nil
+}
+
+// NetworkDetail is a network with its subnets and the containers attached
+type NetworkDetail struct {
+	NetworkInfo
+	Subnets    []NetworkSubnet   `json:"subnets,omitempty"`
+	Containers []NetworkEndpoint `json:"containers"` // sorted by name
+}
+
+// NetworkSubnet is an address range of a network
+type NetworkSubnet struct {
+	Subnet  string `json:"subnet"` // in CIDR notation
+	Gateway string `json:"gateway,omitempty"`
+}
+
+// NetworkEndpoint is a container attached to a network. Its aliases are
+// only in the container's inspect output.
+type NetworkEndpoint struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	IPAddress   string `json:"ipAddress,omitempty"`
+	IPv6Address string `json:"ipv6Address,omitempty"`
+	MacAddress  string `json:"macAddress,omitempty"`
+}
+
+// InspectNetwork returns a network by name or ID with its attached
+// containers. Stopped containers aren't attached.
+func (c *Client) InspectNetwork(ctx context.Context, name string) (*NetworkDetail, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	n, err := c.cli.NetworkInspect(ctx, name, network.InspectOptions{})
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNetworkNotFound, name)
+		}
+		return nil, fmt.Errorf("failed to inspect network: %w", err)
+	}
+
+	detail := &NetworkDetail{
+		NetworkInfo: NetworkInfo{
+			ID:       n.ID[:12],
+			Name:     n.Name,
+			Driver:   n.Driver,
+			Scope:    n.Scope,
+			Internal: n.Internal,
+			Labels:   n.Labels,
+		},
+		Containers: make([]NetworkEndpoint, 0, len(n.Containers)),
+	}
+	for _, cfg := range n.IPAM.Config {
+		detail.Subnets = append(detail.Subnets, NetworkSubnet{Subnet: cfg.Subnet, Gateway: cfg.Gateway})
+	}
+	for id, ep := range n.Containers {
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		detail.Containers = append(detail.Containers, NetworkEndpoint{
+			ID:          id,
+			Name:        ep.Name,
+			IPAddress:   withoutPrefix(ep.IPv4Address),
+			IPv6Address: withoutPrefix(ep.IPv6Address),
+			MacAddress:  ep.MacAddress,
+		})
+	}
+	sort.Slice(detail.Containers, func(i, j int) bool { return detail.Containers[i].Name < detail.Containers[j].Name })
+	return detail, nil
+}
+
+// withoutPrefix strips the prefix length from an address in CIDR notation
+func withoutPrefix(cidr string) string {
+	addr, _, _ := strings.Cut(cidr, "/")
+	return addr
+}
+
+// ErrNetworkNotFound is returned when a network or the container to connect
+// to it does not exist
+var ErrNetworkNotFound = errors.New("network or container not found")
+
+// ErrNetworkConflict is returned when the engine refuses a connection, as
+// when the container is already connected or the network can't be joined
+var ErrNetworkConflict = errors.New("network connection refused")
+
+// NetworkAttachment is a container's endpoint on a network
+type NetworkAttachment struct {
+	Network     string   `json:"network"`
+	IPAddress   string   `json:"ipAddress,omitempty"`
+	IPv6Address string   `json:"ipv6Address,omitempty"`
+	Gateway     string   `json:"gateway,omitempty"`
+	MacAddress  string   `json:"macAddress,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"` // extra DNS names on the network
+}
+
+// networksFromInspect lists a container's endpoints sorted by network
+func networksFromInspect(settings *types.NetworkSettings) []NetworkAttachment {
+	if settings == nil {
+		return nil
+	}
+	attachments := make([]NetworkAttachment, 0, len(settings.Networks))
+	for name, ep := range settings.Networks {
+		if ep == nil {
+			continue
+		}
+		attachments = append(attachments, NetworkAttachment{
+			Network:     name,
+			IPAddress:   ep.IPAddress,
+			IPv6Address: ep.GlobalIPv6Address,
+			Gateway:     ep.Gateway,
+			MacAddress:  ep.MacAddress,
+			Aliases:     ep.Aliases,
+		})
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].Network < attachments[j].Network })
+	return attachments
+}
+
+// ConnectOptions connect a container to a network, optionally with extra
+// DNS aliases and a static address in the network's subnet
+type ConnectOptions struct {
+	Network     string   `json:"network"`
+	Aliases     []string `json:"aliases,omitempty"`
+	IPv4Address string   `json:"ipv4Address,omitempty"`
+	IPv6Address string   `json:"ipv6Address,omitempty"`
+}
+
+// networkAlias matches the DNS names Docker accepts as aliases
+var networkAlias = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Validate checks the options before they reach the engine
+func (o ConnectOptions) Validate() error {
+	if o.Network == "" {
+		return errors.New("network is required")
+	}
+	for _, a := range o.Aliases {
+		if !networkAlias.MatchString(a) {
+			return fmt.Errorf("invalid alias: %q", a)
+		}
+	}
+	if o.IPv4Address != "" {
+		if ip, err := netip.ParseAddr(o.IPv4Address); err != nil || !ip.Is4() {
+			return fmt.Errorf("invalid IPv4 address: %q", o.IPv4Address)
+		}
+	}
+	if o.IPv6Address != "" {
+		if ip, err := netip.ParseAddr(o.IPv6Address); err != nil || !ip.Is6() || ip.Is4In6() {
+			return fmt.Errorf("invalid IPv6 address: %q", o.IPv6Address)
+		}
+	}
+	return nil
+}
+
+// ConnectNetwork connects a container to a network. A running container
+// joins it at once; a stopped one when it next starts.
+func (c *Client) ConnectNetwork(ctx context.Context, id string, opts ConnectOptions) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	settings := &network.EndpointSettings{Aliases: opts.Aliases}
+	if opts.IPv4Address != "" || opts.IPv6Address != "" {
+		settings.IPAMConfig = &network.EndpointIPAMConfig{
+			IPv4Address: opts.IPv4Address,
+			IPv6Address: opts.IPv6Address,
+		}
+	}
+	if err := c.cli.NetworkConnect(ctx, opts.Network, id, settings); err != nil {
+		return networkError("connect", err)
+	}
+	return nil
+}
+
+// DisconnectNetwork disconnects a container from a network. Force removes
+// the endpoint even if the container is unreachable.
+func (c *Client) DisconnectNetwork(ctx context.Context, id, networkName string, force bool) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.cli.NetworkDisconnect(ctx, networkName, id, force); err != nil {
+		return networkError("disconnect", err)
+	}
+	return nil
+}
+
+// networkError maps the engine's error for a connect or disconnect to the
+// package's errors
+func networkError(op string, err error) error {
+	switch {
+	case errdefs.IsNotFound(err):
+		return fmt.Errorf("%w: %v", ErrNetworkNotFound, err)
+	case errdefs.IsForbidden(err), errdefs.IsConflict(err), errdefs.IsInvalidParameter(err):
+		return fmt.Errorf("%w: %v", ErrNetworkConflict, err)
+	default:
+		return fmt.Errorf("failed to %s network: %w", op, err)
+	}
+}
@@ -0,0 +1,264 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LayerProgress is the pull progress of a single image layer. Percent is
+// the progress of the current status (downloading, extracting, ...).
+type LayerProgress struct {
+	Layer   string  `json:"layer"`
+	Status  string  `json:"status"`
+	Current int64   `json:"current,omitempty"`
+	Total   int64   `json:"total,omitempty"`
+	Percent float64 `json:"percent"`
+}
+
+// Done reports whether the layer is fully pulled
+func (p LayerProgress) Done() bool {
+	return p.Status == "Pull complete" || p.Status == "Already exists"
+}
+
+// newLayerProgress builds a progress update, deriving the percentage
+func newLayerProgress(layer, status string, current, total int64) LayerProgress {
+	p := LayerProgress{
+		Layer:   layer,
+		Status:  status,
+		Current: current,
+		Total:   total,
+	}
+	switch {
+	case p.Done(), status == "Download complete":
+		p.Percent = 100
+	case total > 0:
+		p.Percent = float64(current) * 100 / float64(total)
+		if p.Percent > 100 {
+			p.Percent = 100
+		}
+	}
+	return p
+}
+
+// pullMessage is one message of the engine's JSON pull stream
+type pullMessage struct {
+	Status         string `json:"status"`
+	ID             string `json:"id"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error       string `json:"error"`
+	ErrorDetail struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+}
+
+// ReadPullProgress decodes an engine image pull stream, calling fn for every
+// layer update. Messages that are not about a layer (e.g. "Digest: ...")
+// are skipped. A failure reported inside the stream is returned as an error.
+func ReadPullProgress(r io.Reader, fn func(LayerProgress)) error {
+	dec := json.NewDecoder(r)
+	for {
+		var msg pullMessage
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != "" {
+			return errors.New(msg.Error)
+		}
+		if msg.ErrorDetail.Message != "" {
+			return errors.New(msg.ErrorDetail.Message)
+		}
+		if !isLayerID(msg.ID) {
+			continue
+		}
+		fn(newLayerProgress(msg.ID, msg.Status, msg.ProgressDetail.Current, msg.ProgressDetail.Total))
+	}
+}
+
+// progressLine matches layer lines of docker compose pull output, e.g.
+// "a2abf6c4d29d Downloading [==>      ]  3.2MB/31.37MB"
+var progressLine = regexp.MustCompile(`^([0-9a-f]{12}) ([A-Za-z][A-Za-z ]*?)\s*(?:\[[=> ]*\])?\s*(?:([0-9.]+\s*[kKMGT]?i?B)/([0-9.]+\s*[kKMGT]?i?B))?$`)
+
+// ParseProgressLine parses a layer line of docker compose pull output. It
+// applies the same progress rules as ReadPullProgress so both sources
+// produce identical events.
+func ParseProgressLine(line string) (LayerProgress, bool) {
+	line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "[+]"))
+	m := progressLine.FindStringSubmatch(line)
+	if m == nil {
+		return LayerProgress{}, false
+	}
+
+	var current, total int64
+	if m[3] != "" {
+		current, _ = parseSize(m[3])
+		total, _ = parseSize(m[4])
+	}
+	return newLayerProgress(m[1], m[2], current, total), true
+}
+
+// parseSize parses the human readable sizes docker prints, e.g. "31.37MB"
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	num := strings.TrimRight(s, "kKMGTiB")
+	unit := strings.TrimSpace(s[len(num):])
+
+	v, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil {
+		return 0, err
+	}
+
+	multipliers := map[string]float64{
+		"B": 1, "kB": 1e3, "KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12,
+		"KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30, "TiB": 1 << 40,
+	}
+	if mult, ok := multipliers[unit]; ok {
+		v *= mult
+	}
+	return int64(v), nil
+}
+
+// isLayerID reports whether id looks like a short layer digest
+func isLayerID(id string) bool {
+	if len(id) != 12 {
+		return false
+	}
+	for _, c := range id {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// ServiceProgress is the pull progress of one compose service, aggregated
+// over its layers
+type ServiceProgress struct {
+	Service string  `json:"service"`
+	Stage   string  `json:"stage"` // "pulling", "downloading", "extracting", "pulled", "skipped", "error"
+	Percent float64 `json:"percent"`
+}
+
+// serviceLine matches service lines of docker compose pull output, e.g.
+// "web Pulling", "db Pulled" or "app Skipped - No image to be pulled"
+var serviceLine = regexp.MustCompile(`^(\S+) (Pulling|Pulled|Skipped|Error|Interrupted|Warning)\b`)
+
+// downloadWeight is the share of a layer's progress spent downloading;
+// extraction is the rest
+const downloadWeight = 0.7
+
+// ComposePullTracker turns docker compose pull output into per-service
+// progress. Compose does not say which service a layer belongs to, so each
+// layer is attributed to the most recent service that started pulling;
+// layers shared between services count for the first one only.
+type ComposePullTracker struct {
+	services map[string]*servicePull
+	layers   map[string]string // layer -> service
+	current  string
+}
+
+type servicePull struct {
+	stage  string
+	layers map[string]float64 // layer -> overall completion
+}
+
+// NewComposePullTracker creates a tracker for one compose pull
+func NewComposePullTracker() *ComposePullTracker {
+	return &ComposePullTracker{
+		services: make(map[string]*servicePull),
+		layers:   make(map[string]string),
+	}
+}
+
+// Update consumes a line of output and returns the affected service's progress
+func (t *ComposePullTracker) Update(line string) (ServiceProgress, bool) {
+	if p, ok := ParseProgressLine(line); ok {
+		return t.updateLayer(p)
+	}
+
+	m := serviceLine.FindStringSubmatch(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "[+]")))
+	if m == nil || isLayerID(m[1]) {
+		return ServiceProgress{}, false
+	}
+
+	svc := t.service(m[1])
+	switch m[2] {
+	case "Pulling":
+		t.current = m[1]
+		svc.stage = "pulling"
+	case "Pulled":
+		svc.stage = "pulled"
+	case "Skipped":
+		svc.stage = "skipped"
+	default:
+		svc.stage = "error"
+	}
+	return t.progress(m[1]), true
+}
+
+func (t *ComposePullTracker) updateLayer(p LayerProgress) (ServiceProgress, bool) {
+	name, ok := t.layers[p.Layer]
+	if !ok {
+		if t.current == "" {
+			return ServiceProgress{}, false
+		}
+		name = t.current
+		t.layers[p.Layer] = name
+	}
+
+	svc := t.service(name)
+	switch {
+	case p.Done():
+		svc.layers[p.Layer] = 100
+	case p.Status == "Downloading":
+		svc.layers[p.Layer] = p.Percent * downloadWeight
+		svc.stage = "downloading"
+	case p.Status == "Extracting":
+		svc.layers[p.Layer] = downloadWeight*100 + p.Percent*(1-downloadWeight)
+		svc.stage = "extracting"
+	case p.Status == "Download complete" || p.Status == "Verifying Checksum":
+		svc.layers[p.Layer] = downloadWeight * 100
+	default:
+		if _, seen := svc.layers[p.Layer]; !seen {
+			svc.layers[p.Layer] = 0
+		}
+	}
+	return t.progress(name), true
+}
+
+func (t *ComposePullTracker) service(name string) *servicePull {
+	svc, ok := t.services[name]
+	if !ok {
+		svc = &servicePull{stage: "pulling", layers: make(map[string]float64)}
+		t.services[name] = svc
+	}
+	return svc
+}
+
+func (t *ComposePullTracker) progress(name string) ServiceProgress {
+	svc := t.services[name]
+	p := ServiceProgress{Service: name, Stage: svc.stage}
+
+	switch svc.stage {
+	case "pulled", "skipped":
+		p.Percent = 100
+	default:
+		if len(svc.layers) > 0 {
+			var sum float64
+			for _, v := range svc.layers {
+				sum += v
+			}
+			p.Percent = sum / float64(len(svc.layers))
+		}
+	}
+	return p
+}
@@ -0,0 +1,116 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// ContainerResources holds the settings of a container that can change
+// while it runs, without recreating it. In updates, nil fields are left
+// unchanged.
+type ContainerResources struct {
+	RestartPolicy *RestartPolicy `json:"restartPolicy,omitempty"`
+	Memory        *int64         `json:"memory,omitempty"`     // bytes; 0 is unlimited
+	MemorySwap    *int64         `json:"memorySwap,omitempty"` // memory plus swap in bytes; -1 is unlimited
+	CPUQuota      *int64         `json:"cpuQuota,omitempty"`   // microseconds per period; 0 is unlimited
+	CPUPeriod     *int64         `json:"cpuPeriod,omitempty"`  // microseconds; 0 is the 100ms default
+}
+
+// RestartPolicy is a container restart policy
+type RestartPolicy struct {
+	Name              string `json:"name"` // "no", "always", "unless-stopped" or "on-failure"
+	MaximumRetryCount int    `json:"maximumRetryCount,omitempty"`
+}
+
+// minMemory is the smallest memory limit the engine accepts
+const minMemory = 6 << 20
+
+// Validate rejects values the engine would refuse, with a clearer message
+func (r ContainerResources) Validate() error {
+	if p := r.RestartPolicy; p != nil {
+		switch p.Name {
+		case "no", "always", "unless-stopped", "on-failure":
+		default:
+			return fmt.Errorf("invalid restart policy %q: expected no, always, unless-stopped or on-failure", p.Name)
+		}
+		if p.MaximumRetryCount < 0 || (p.MaximumRetryCount > 0 && p.Name != "on-failure") {
+			return fmt.Errorf("maximumRetryCount is only valid for the on-failure restart policy")
+		}
+	}
+	// The engine treats a zero memory limit as "unchanged", so a limit on a
+	// running container can be changed but not removed
+	if r.Memory != nil && *r.Memory < minMemory {
+		return fmt.Errorf("memory must be at least %d bytes", minMemory)
+	}
+	if r.MemorySwap != nil && *r.MemorySwap != -1 && (*r.MemorySwap <= 0 || r.Memory != nil && *r.MemorySwap < *r.Memory) {
+		return fmt.Errorf("memorySwap must be -1 or at least the memory limit")
+	}
+	if r.CPUQuota != nil && *r.CPUQuota != 0 && *r.CPUQuota < 1000 {
+		return fmt.Errorf("cpuQuota must be 0 or at least 1000 microseconds")
+	}
+	if r.CPUPeriod != nil && *r.CPUPeriod != 0 && (*r.CPUPeriod < 1000 || *r.CPUPeriod > 1000000) {
+		return fmt.Errorf("cpuPeriod must be 0 or between 1000 and 1000000 microseconds")
+	}
+	return nil
+}
+
+// UpdateContainer changes a container's restart policy and resource limits
+// in place, like docker update. The engine's warnings are returned, e.g.
+// when the kernel does not support a limit.
+func (c *Client) UpdateContainer(ctx context.Context, id string, res ContainerResources) ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var cfg container.UpdateConfig
+	if p := res.RestartPolicy; p != nil {
+		cfg.RestartPolicy = container.RestartPolicy{
+			Name:              container.RestartPolicyMode(p.Name),
+			MaximumRetryCount: p.MaximumRetryCount,
+		}
+	}
+	if res.Memory != nil {
+		cfg.Memory = *res.Memory
+	}
+	if res.MemorySwap != nil {
+		cfg.MemorySwap = *res.MemorySwap
+	}
+	if res.CPUQuota != nil {
+		// Zero means "unchanged" to the engine; -1 removes the quota
+		cfg.CPUQuota = *res.CPUQuota
+		if cfg.CPUQuota == 0 {
+			cfg.CPUQuota = -1
+		}
+	}
+	if res.CPUPeriod != nil {
+		cfg.CPUPeriod = *res.CPUPeriod
+	}
+
+	resp, err := c.cli.ContainerUpdate(ctx, id, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update container: %w", err)
+	}
+	return resp.Warnings, nil
+}
+
+// resourcesFromHostConfig reads the updatable settings of a container
+func resourcesFromHostConfig(hc *container.HostConfig) *ContainerResources {
+	if hc == nil {
+		return nil
+	}
+	policy := &RestartPolicy{
+		Name:              string(hc.RestartPolicy.Name),
+		MaximumRetryCount: hc.RestartPolicy.MaximumRetryCount,
+	}
+	if policy.Name == "" {
+		policy.Name = "no"
+	}
+	return &ContainerResources{
+		RestartPolicy: policy,
+		Memory:        &hc.Memory,
+		MemorySwap:    &hc.MemorySwap,
+		CPUQuota:      &hc.CPUQuota,
+		CPUPeriod:     &hc.CPUPeriod,
+	}
+}
@@ -0,0 +1,123 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sshDialer returns a dial function that tunnels the Docker API over ssh by
+// running `docker system dial-stdio` on the remote host, the same approach the
+// docker CLI uses for ssh:// hosts. Authentication is left to the local ssh
+// client configuration (keys, agent, ~/.ssh/config).
+func sshDialer(host string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh docker host: %w", err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid ssh docker host: missing hostname in %q", host)
+	}
+
+	var args []string
+	if u.User != nil && u.User.Username() != "" {
+		args = append(args, "-l", u.User.Username())
+	}
+	if u.Port() != "" {
+		args = append(args, "-p", u.Port())
+	}
+	args = append(args, "--", u.Hostname(), "docker", "system", "dial-stdio")
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// Not CommandContext: the connection must outlive the dial context
+		cmd := exec.Command("ssh", args...)
+
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		conn := &cmdConn{cmd: cmd, stdin: stdin, stdout: stdout}
+		cmd.Stderr = &conn.stderr
+
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start ssh: %w", err)
+		}
+		return conn, nil
+	}, nil
+}
+
+// cmdConn adapts a subprocess's stdin/stdout to net.Conn
+type cmdConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr lockedBuffer
+	once   sync.Once
+}
+
+// lockedBuffer guards stderr, which exec writes from its own goroutine
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.TrimSpace(b.buf.String())
+}
+
+func (c *cmdConn) Read(p []byte) (int, error) {
+	n, err := c.stdout.Read(p)
+	if err == io.EOF {
+		if msg := c.stderr.String(); msg != "" {
+			return n, fmt.Errorf("ssh connection closed: %s", msg)
+		}
+	}
+	return n, err
+}
+
+func (c *cmdConn) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+func (c *cmdConn) Close() error {
+	c.once.Do(func() {
+		c.stdin.Close()
+		if c.cmd.Process != nil {
+			c.cmd.Process.Kill()
+		}
+		c.cmd.Wait()
+	})
+	return nil
+}
+
+func (c *cmdConn) LocalAddr() net.Addr  { return dummyAddr{} }
+func (c *cmdConn) RemoteAddr() net.Addr { return dummyAddr{} }
+
+// Deadlines are not supported on pipes; the HTTP client relies on context cancellation instead
+func (c *cmdConn) SetDeadline(t time.Time) error      { return nil }
+func (c *cmdConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *cmdConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type dummyAddr struct{}
+
+func (dummyAddr) Network() string { return "ssh" }
+func (dummyAddr) String() string  { return "ssh" }
@@ -0,0 +1,73 @@
+package docker
+
+import "sort"
+
+// StandaloneGroup is the ID of the synthetic group holding containers that
+// no compose project manages, e.g. ones started with docker run
+const StandaloneGroup = "standalone"
+
+// ContainerGroup is a set of containers summarised like a project
+type ContainerGroup struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	Status     string          `json:"status"` // "running", "partial" or "stopped"
+	Running    int             `json:"running"`
+	Total      int             `json:"total"`
+	Containers []ContainerInfo `json:"containers"`
+}
+
+// GroupByProject buckets containers by compose project name, so one
+// listing of all containers can update every project
+func GroupByProject(containers []ContainerInfo) map[string][]ContainerInfo {
+	groups := make(map[string][]ContainerInfo)
+	for _, c := range containers {
+		if c.ProjectName != "" {
+			groups[c.ProjectName] = append(groups[c.ProjectName], c)
+		}
+	}
+	return groups
+}
+
+// RunningByProject counts the running containers of each compose project
+func RunningByProject(containers []ContainerInfo) map[string]int {
+	running := make(map[string]int)
+	for _, c := range containers {
+		if c.ProjectName != "" && c.State == "running" {
+			running[c.ProjectName]++
+		}
+	}
+	return running
+}
+
+// IsStandalone reports whether a container is outside any compose project.
+// gosei's own volume browser containers are not counted.
+func (c ContainerInfo) IsStandalone() bool {
+	return c.ProjectName == "" && c.Labels[volumeBrowserLabel] == ""
+}
+
+// Standalone groups the standalone containers of a container list, sorted
+// by name
+func Standalone(containers []ContainerInfo) *ContainerGroup {
+	g := &ContainerGroup{ID: StandaloneGroup, Name: "Standalone", Containers: []ContainerInfo{}}
+	for _, c := range containers {
+		if !c.IsStandalone() {
+			continue
+		}
+		g.Containers = append(g.Containers, c)
+		if c.State == "running" {
+			g.Running++
+		}
+	}
+	sort.Slice(g.Containers, func(i, j int) bool { return g.Containers[i].Name < g.Containers[j].Name })
+
+	g.Total = len(g.Containers)
+	switch {
+	case g.Running == 0:
+		g.Status = "stopped"
+	case g.Running == g.Total:
+		g.Status = "running"
+	default:
+		g.Status = "partial"
+	}
+	return g
+}
@@ -1,12 +1,80 @@
 package docker
 
 import (
+	"context"
 	"encoding/json"
 	"io"
+	"math"
+	"strings"
+	"sync"
 
 	"github.com/docker/docker/api/types/container"
 )
 
+// maxParallelStats bounds concurrent stats requests to the daemon
+const maxParallelStats = 8
+
+// StatsSummary totals the resource usage of a set of containers
+type StatsSummary struct {
+	Containers  int     `json:"containers"` // running containers included
+	CPUPercent  float64 `json:"cpuPercent"` // 100 per fully used core
+	MemoryUsage uint64  `json:"memoryUsage"`
+	NetworkRx   uint64  `json:"networkRx"`
+	NetworkTx   uint64  `json:"networkTx"`
+	BlkioRead   uint64  `json:"blkioRead"`
+	BlkioWrite  uint64  `json:"blkioWrite"`
+	PIDs        uint64  `json:"pids"`
+}
+
+// Add includes one container's stats in the summary
+func (s *StatsSummary) Add(st *ContainerStats) {
+	s.Containers++
+	s.CPUPercent += st.CPUPercent
+	s.MemoryUsage += st.MemoryUsage
+	s.NetworkRx += st.NetworkRx
+	s.NetworkTx += st.NetworkTx
+	s.BlkioRead += st.BlkioRead
+	s.BlkioWrite += st.BlkioWrite
+	s.PIDs += st.PIDs
+}
+
+// CollectStats fetches stats for the running containers in parallel, since
+// each request takes the daemon a moment to sample. Containers whose stats
+// can't be read, e.g. because they just stopped, are left out. The result
+// follows the order of containers.
+func CollectStats(ctx context.Context, dc DockerClient, containers []ContainerInfo) []ContainerStats {
+	results := make([]*ContainerStats, len(containers))
+	sem := make(chan struct{}, maxParallelStats)
+	var wg sync.WaitGroup
+	for i, c := range containers {
+		if c.State != "running" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, c ContainerInfo) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			st, err := dc.GetContainerStats(ctx, c.ID)
+			if err != nil {
+				return
+			}
+			st.Name = c.Name
+			results[i] = st
+		}(i, c)
+	}
+	wg.Wait()
+
+	stats := make([]ContainerStats, 0, len(containers))
+	for _, st := range results {
+		if st != nil {
+			stats = append(stats, *st)
+		}
+	}
+	return stats
+}
+
 func decodeStats(r io.Reader, stats *container.StatsResponse) error {
 	return json.NewDecoder(r).Decode(stats)
 }
@@ -50,5 +118,21 @@ func calculateStats(id string, stats *container.StatsResponse) *ContainerStats {
 		result.NetworkTx += network.TxBytes
 	}
 
+	// cgroup v1 reports "Read"/"Write", v2 "read"/"write"
+	for _, entry := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			result.BlkioRead += entry.Value
+		case "write":
+			result.BlkioWrite += entry.Value
+		}
+	}
+
+	result.PIDs = stats.PidsStats.Current
+	// The limit is reported as the max uint64 when unlimited on some hosts
+	if stats.PidsStats.Limit != math.MaxUint64 {
+		result.PIDsLimit = stats.PidsStats.Limit
+	}
+
 	return result
 }
@@ -0,0 +1,79 @@
+package docker
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// defaultPublicHost derives the host published ports are reachable on from
+// the daemon address: the remote host for tcp:// and ssh://, else localhost
+func defaultPublicHost(daemonHost string) string {
+	u, err := url.Parse(daemonHost)
+	if err == nil && (u.Scheme == "tcp" || u.Scheme == "ssh") && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return "localhost"
+}
+
+// portURL returns a browsable URL for a published TCP port, or "" when the
+// port is not published or only bound to loopback on a remote host. A
+// publicHost with a scheme (https://myserver.lan) forces that scheme;
+// otherwise https is assumed for container ports 443 and 8443.
+func portURL(publicHost string, p PortMapping) string {
+	if p.Protocol != "tcp" || p.HostPort == "" || p.HostPort == "0" {
+		return ""
+	}
+
+	scheme := "http"
+	if p.ContainerPort == "443" || p.ContainerPort == "8443" {
+		scheme = "https"
+	}
+	host := publicHost
+	if s, h, ok := strings.Cut(publicHost, "://"); ok {
+		scheme, host = s, h
+	}
+	host = strings.TrimSuffix(host, "/")
+
+	if ip := net.ParseIP(p.HostIP); ip != nil && ip.IsLoopback() && !isLoopbackHost(host) {
+		return ""
+	}
+	return scheme + "://" + net.JoinHostPort(host, p.HostPort)
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// withURLs fills in the URL of each published port
+func withURLs(publicHost string, ports []PortMapping) []PortMapping {
+	for i := range ports {
+		ports[i].URL = portURL(publicHost, ports[i])
+	}
+	return ports
+}
+
+// URLs returns the distinct URLs a container is reachable on: reverse proxy
+// URLs first, then published ports. A port published on both IPv4 and IPv6
+// appears once.
+func (c ContainerInfo) URLs() []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, in := range c.Ingress {
+		if !seen[in.URL] {
+			seen[in.URL] = true
+			urls = append(urls, in.URL)
+		}
+	}
+	for _, p := range c.Ports {
+		if p.URL != "" && !seen[p.URL] {
+			seen[p.URL] = true
+			urls = append(urls, p.URL)
+		}
+	}
+	return urls
+}
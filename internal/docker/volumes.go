@@ -0,0 +1,165 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/errdefs"
+)
+
+// VolumeMountPath is where a volume is mounted inside its browser container
+const VolumeMountPath = "/volume"
+
+// VolumeBrowserImage only has to exist: the browser container is never
+// started, the archive API reads the mounted volume directly. It is pinned
+// so a retagged image can't be slipped in.
+const VolumeBrowserImage = "busybox@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209"
+
+// volumeBrowserLabel marks browser containers with the volume they mount
+const volumeBrowserLabel = "gosei.volume-browser"
+
+// ErrVolumeNotFound is returned when a volume does not exist
+var ErrVolumeNotFound = errors.New("volume not found")
+
+// ErrVolumeBrowserImage is returned when VolumeBrowserImage has not been
+// pulled and pulling it wasn't allowed with SetVolumeBrowserPull
+var ErrVolumeBrowserImage = errors.New("volume browser image is not present; pull " + VolumeBrowserImage)
+
+// VolumeInfo describes a Docker volume
+type VolumeInfo struct {
+	Name       string            `json:"name"`
+	Driver     string            `json:"driver"`
+	Mountpoint string            `json:"mountpoint,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// ListVolumes returns the host's volumes sorted by name
+func (c *Client) ListVolumes(ctx context.Context) ([]VolumeInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	resp, err := c.cli.VolumeList(ctx, volume.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	volumes := make([]VolumeInfo, 0, len(resp.Volumes))
+	for _, v := range resp.Volumes {
+		volumes = append(volumes, VolumeInfo{
+			Name:       v.Name,
+			Driver:     v.Driver,
+			Mountpoint: v.Mountpoint,
+			Labels:     v.Labels,
+		})
+	}
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+	return volumes, nil
+}
+
+// SetVolumeBrowserPull allows CreateVolumeBrowser to pull
+// VolumeBrowserImage when it is missing
+func (c *Client) SetVolumeBrowserPull(pull bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pullVolumeBrowser = pull
+}
+
+// CreateVolumeBrowser creates a stopped container with the named volume
+// mounted read-only at VolumeMountPath, so its contents can be read with
+// StatContainerPath and CopyFromContainer. A missing image is only pulled
+// when allowed, otherwise ErrVolumeBrowserImage is returned. The caller must
+// remove the container with RemoveVolumeBrowser.
+func (c *Client) CreateVolumeBrowser(ctx context.Context, name string) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if _, err := c.cli.VolumeInspect(ctx, name); err != nil {
+		if errdefs.IsNotFound(err) {
+			return "", fmt.Errorf("%w: %s", ErrVolumeNotFound, name)
+		}
+		return "", fmt.Errorf("failed to inspect volume: %w", err)
+	}
+
+	if _, _, err := c.cli.ImageInspectWithRaw(ctx, VolumeBrowserImage); errdefs.IsNotFound(err) {
+		if !c.pullVolumeBrowser {
+			return "", ErrVolumeBrowserImage
+		}
+		stream, err := c.cli.ImagePull(ctx, VolumeBrowserImage, image.PullOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to pull %s: %w", VolumeBrowserImage, err)
+		}
+		err = ReadPullProgress(stream, func(LayerProgress) {})
+		stream.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to pull %s: %w", VolumeBrowserImage, err)
+		}
+	}
+
+	resp, err := c.cli.ContainerCreate(ctx,
+		&container.Config{
+			Image:  VolumeBrowserImage,
+			Labels: map[string]string{volumeBrowserLabel: name},
+		},
+		&container.HostConfig{
+			NetworkMode: "none",
+			Mounts: []mount.Mount{{
+				Type:     mount.TypeVolume,
+				Source:   name,
+				Target:   VolumeMountPath,
+				ReadOnly: true,
+			}},
+		},
+		nil, nil, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to create volume browser: %w", err)
+	}
+	return resp.ID, nil
+}
+
+// RemoveVolumeBrowser removes a container created by CreateVolumeBrowser
+func (c *Client) RemoveVolumeBrowser(ctx context.Context, id string) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.cli.ContainerRemove(ctx, id, container.RemoveOptions{Force: true}); err != nil {
+		return fmt.Errorf("failed to remove volume browser: %w", err)
+	}
+	return nil
+}
+
+// RemoveStaleVolumeBrowsers removes browser containers created more than
+// olderThan ago, which a crash or lost response left behind, and returns
+// how many it removed. Newer ones may be in use by another gosei.
+func (c *Client) RemoveStaleVolumeBrowsers(ctx context.Context, olderThan time.Duration) (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	containers, err := c.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", volumeBrowserLabel)),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list volume browsers: %w", err)
+	}
+
+	removed := 0
+	cutoff := time.Now().Add(-olderThan)
+	for _, ctr := range containers {
+		if time.Unix(ctr.Created, 0).After(cutoff) {
+			continue
+		}
+		if err := c.cli.ContainerRemove(ctx, ctr.ID, container.RemoveOptions{Force: true}); err != nil && !errdefs.IsNotFound(err) {
+			return removed, fmt.Errorf("failed to remove volume browser: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,494 @@
+// Package logarchive captures container logs to disk, so they outlive the
+// containers that wrote them: an update or down removes a container and
+// with it the only copy Docker keeps.
+package logarchive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lyall/gosei/internal/docker"
+)
+
+// Archives are kept per container name rather than ID, so the logs of a
+// recreated container continue those of the one it replaced. Each
+// container's directory holds log files named by when they were started,
+// the newest being written to:
+//
+//	<dir>/<container name>/20261016T150226.000000000Z.log
+//
+// Each line is the Docker timestamp, the stream and the text.
+const fileTimeFormat = "20060102T150405.000000000Z"
+
+// Options configures an archiver
+type Options struct {
+	Dir      string
+	FileSize int64         // size a log file is rotated at
+	MaxSize  int64         // total size kept per container (0 for no limit)
+	MaxAge   time.Duration // age of the newest line in a file it is kept for (0 for no limit)
+}
+
+// Default sizes of log archives
+const (
+	DefaultFileSize = 10 << 20
+	DefaultMaxSize  = 100 << 20
+)
+
+// validName matches Docker container names, which name archive directories
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Archiver follows the logs of running containers and appends them to
+// rotated files
+type Archiver struct {
+	docker docker.DockerClient
+	opts   Options
+
+	mu        sync.Mutex
+	followers map[string]*follower // by container name
+	writing   map[string]string    // file being written per container name
+}
+
+// follower follows the logs of one container
+type follower struct {
+	id     string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates an archiver writing to opts.Dir, creating it if needed
+func New(dc docker.DockerClient, opts Options) (*Archiver, error) {
+	if opts.FileSize <= 0 {
+		opts.FileSize = DefaultFileSize
+	}
+	if err := os.MkdirAll(opts.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create log archive directory: %w", err)
+	}
+	return &Archiver{
+		docker:    dc,
+		opts:      opts,
+		followers: make(map[string]*follower),
+		writing:   make(map[string]string),
+	}, nil
+}
+
+// Run follows running containers, checking for started and stopped ones
+// every interval, until ctx is cancelled
+func (a *Archiver) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		a.sync(ctx)
+		a.prune(time.Now())
+
+		select {
+		case <-ctx.Done():
+			a.mu.Lock()
+			for _, f := range a.followers {
+				f.cancel()
+			}
+			a.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync starts following containers that are running and stops following
+// those that aren't. A container's logs end when it stops, but its
+// follower is also replaced when a container of the same name is
+// recreated.
+func (a *Archiver) sync(ctx context.Context) {
+	listCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	containers, err := a.docker.ListContainers(listCtx, "")
+	cancel()
+	if err != nil {
+		slog.Warn("Log archiver failed to list containers", "error", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	running := make(map[string]string, len(containers))
+	for _, c := range containers {
+		if c.State == "running" && validName.MatchString(c.Name) {
+			running[c.Name] = c.ID
+		}
+	}
+
+	for name, f := range a.followers {
+		if id, ok := running[name]; !ok || id != f.id {
+			f.cancel()
+		}
+		select {
+		case <-f.done:
+			delete(a.followers, name)
+		default:
+		}
+	}
+	for name, id := range running {
+		// A replaced follower is left to finish before its successor
+		// starts, so they don't write the same file
+		if _, ok := a.followers[name]; ok {
+			continue
+		}
+		fctx, cancel := context.WithCancel(ctx)
+		f := &follower{id: id, cancel: cancel, done: make(chan struct{})}
+		a.followers[name] = f
+		go func() {
+			defer close(f.done)
+			if err := a.follow(fctx, name, id); err != nil && fctx.Err() == nil {
+				slog.Warn("Log archiver stopped following container", "container", name, "error", err)
+			}
+		}()
+	}
+}
+
+// follow appends a container's logs to its archive until they end. Only
+// lines from the archive's last timestamp on are asked for, and those at
+// that timestamp already archived are skipped by content, so a restarted
+// container or gosei neither archives lines twice nor drops ones logged in
+// the same instant.
+func (a *Archiver) follow(ctx context.Context, name, id string) error {
+	w, err := a.openWriter(name)
+	if err != nil {
+		return err
+	}
+	defer w.close()
+
+	logs, err := a.docker.GetContainerLogs(ctx, id, "all", w.last, true)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	since, archived := w.last, maps.Clone(w.atLast)
+	scanner := docker.NewLogScanner(logs)
+	for scanner.Scan() {
+		line := scanner.Line()
+		ts, text := docker.SplitLogTimestamp(line.Text)
+		if ts.Before(since) {
+			continue
+		}
+		if key := line.Stream + " " + text; ts.Equal(since) && archived[key] > 0 {
+			archived[key]--
+			continue
+		}
+		if err := w.write(ts, line.Stream, text); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// writer appends lines to a container's newest log file, rotating it when
+// it grows past the file size
+type writer struct {
+	a    *Archiver
+	name string
+	file *os.File
+	size int64
+	last time.Time // timestamp of the last line archived
+	// atLast counts the lines archived with timestamp last, by "stream text"
+	atLast map[string]int
+}
+
+func (a *Archiver) openWriter(name string) (*writer, error) {
+	dir := filepath.Join(a.opts.Dir, name)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	w := &writer{a: a, name: name}
+
+	files, err := logFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return w, w.rotate(time.Now())
+	}
+	newest := filepath.Join(dir, files[len(files)-1].Name())
+	if w.last, w.atLast, err = lastLines(newest); err != nil {
+		return nil, err
+	}
+	return w, w.open(newest)
+}
+
+func (w *writer) open(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if w.file != nil {
+		w.file.Close()
+	}
+	w.file, w.size = f, info.Size()
+
+	w.a.mu.Lock()
+	w.a.writing[w.name] = path
+	w.a.mu.Unlock()
+	return nil
+}
+
+// rotate starts a new log file and applies the size limit to the old ones
+func (w *writer) rotate(now time.Time) error {
+	path := filepath.Join(w.a.opts.Dir, w.name, now.UTC().Format(fileTimeFormat)+".log")
+	if err := w.open(path); err != nil {
+		return err
+	}
+	w.a.pruneContainer(w.name, now)
+	return nil
+}
+
+func (w *writer) write(ts time.Time, stream, text string) error {
+	if w.size >= w.a.opts.FileSize {
+		if err := w.rotate(time.Now()); err != nil {
+			return err
+		}
+	}
+	n, err := fmt.Fprintf(w.file, "%s %s %s\n", ts.UTC().Format(time.RFC3339Nano), stream, text)
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if !ts.Equal(w.last) || w.atLast == nil {
+		w.atLast = make(map[string]int)
+	}
+	w.atLast[stream+" "+text]++
+	w.last = ts
+	return nil
+}
+
+func (w *writer) close() {
+	w.a.mu.Lock()
+	delete(w.a.writing, w.name)
+	w.a.mu.Unlock()
+	w.file.Close()
+}
+
+// lastLines returns the timestamp of the last line of a log file and the
+// lines with that timestamp, counted by "stream text". Only lines within a
+// line's length of the end are looked at.
+func lastLines(path string) (time.Time, map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	// Lines are at most docker.MaxLogLineSize plus their prefix
+	offset := max(info.Size()-docker.MaxLogLineSize-128, 0)
+	data, err := io.ReadAll(io.NewSectionReader(f, offset, info.Size()-offset))
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	lines := strings.Split(string(bytes.TrimRight(data, "\n")), "\n")
+	if offset > 0 && len(lines) > 1 {
+		// The first line may have been cut
+		lines = lines[1:]
+	}
+
+	var last time.Time
+	counts := make(map[string]int)
+	for i := len(lines) - 1; i >= 0; i-- {
+		ts, rest, _ := strings.Cut(lines[i], " ")
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil && last.IsZero() {
+			// An empty or damaged file: archive everything again
+			return time.Time{}, nil, nil
+		}
+		if err != nil || (!last.IsZero() && !t.Equal(last)) {
+			break
+		}
+		last = t
+		counts[rest]++
+	}
+	return last, counts, nil
+}
+
+// logFiles returns the log files in a container's archive, oldest first
+func logFiles(dir string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := entries[:0]
+	for _, e := range entries {
+		if e.Type().IsRegular() && strings.HasSuffix(e.Name(), ".log") {
+			files = append(files, e)
+		}
+	}
+	// Names are times, so they sort in the order files were started
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	return files, nil
+}
+
+// prune applies the retention limits to every archive, removing archives
+// left empty
+func (a *Archiver) prune(now time.Time) {
+	entries, err := os.ReadDir(a.opts.Dir)
+	if err != nil {
+		slog.Warn("Log archiver failed to read archive directory", "error", err)
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() && validName.MatchString(e.Name()) {
+			a.pruneContainer(e.Name(), now)
+		}
+	}
+}
+
+// pruneContainer removes a container's log files whose last line is older
+// than the maximum age, then its oldest files until it fits the maximum
+// size. The file being written is kept.
+func (a *Archiver) pruneContainer(name string, now time.Time) {
+	dir := filepath.Join(a.opts.Dir, name)
+	files, err := logFiles(dir)
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	current := a.writing[name]
+	a.mu.Unlock()
+
+	var total int64
+	infos := make([]os.FileInfo, 0, len(files))
+	for _, f := range files {
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+		total += info.Size()
+	}
+
+	remaining := 0
+	for _, info := range infos {
+		path := filepath.Join(dir, info.Name())
+		tooOld := a.opts.MaxAge > 0 && now.Sub(info.ModTime()) > a.opts.MaxAge
+		tooBig := a.opts.MaxSize > 0 && total > a.opts.MaxSize
+		if path == current || (!tooOld && !tooBig) {
+			remaining++
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			slog.Warn("Log archiver failed to remove log file", "path", path, "error", err)
+			remaining++
+			continue
+		}
+		total -= info.Size()
+	}
+	if remaining == 0 {
+		os.Remove(dir)
+	}
+}
+
+// Archive describes the archived logs of a container
+type Archive struct {
+	Name    string    `json:"name"`
+	Files   int       `json:"files"`
+	Size    int64     `json:"size"`
+	Updated time.Time `json:"updated"`
+	Active  bool      `json:"active"` // the container's logs are being followed
+}
+
+// List returns the archived containers by name
+func (a *Archiver) List() ([]Archive, error) {
+	entries, err := os.ReadDir(a.opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var archives []Archive
+	for _, e := range entries {
+		if !e.IsDir() || !validName.MatchString(e.Name()) {
+			continue
+		}
+		files, err := logFiles(filepath.Join(a.opts.Dir, e.Name()))
+		if err != nil || len(files) == 0 {
+			continue
+		}
+		archive := Archive{Name: e.Name(), Files: len(files)}
+		_, archive.Active = a.writing[e.Name()]
+		for _, f := range files {
+			if info, err := f.Info(); err == nil {
+				archive.Size += info.Size()
+				if info.ModTime().After(archive.Updated) {
+					archive.Updated = info.ModTime()
+				}
+			}
+		}
+		archives = append(archives, archive)
+	}
+	return archives, nil
+}
+
+// Has reports whether a container has archived logs
+func (a *Archiver) Has(name string) bool {
+	if !validName.MatchString(name) {
+		return false
+	}
+	files, err := logFiles(filepath.Join(a.opts.Dir, name))
+	return err == nil && len(files) > 0
+}
+
+// ErrNotFound is returned for containers without archived logs
+var ErrNotFound = errors.New("no archived logs")
+
+// WriteTo writes a container's archived logs to w, oldest first
+func (a *Archiver) WriteTo(w io.Writer, name string) error {
+	if !validName.MatchString(name) {
+		return ErrNotFound
+	}
+	dir := filepath.Join(a.opts.Dir, name)
+	files, err := logFiles(dir)
+	if os.IsNotExist(err) || (err == nil && len(files) == 0) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		// A file may be pruned while the archive is read
+		file, err := os.Open(filepath.Join(dir, f.Name()))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
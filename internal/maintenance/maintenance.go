@@ -0,0 +1,185 @@
+// Package maintenance keeps maintenance windows: periods, for every project
+// or one, during which gosei's automation holds off so planned work doesn't
+// set off updates, restarts, alerts and notifications.
+package maintenance
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lyall/gosei/internal/store"
+)
+
+// ErrNotFound is returned for a window that doesn't exist
+var ErrNotFound = errors.New("maintenance window not found")
+
+// maxWindow keeps a typo from silencing everything for years
+const maxWindow = 30 * 24 * time.Hour
+
+// Window is a period of maintenance
+type Window struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"projectId,omitempty"` // empty for every project
+	Project   string    `json:"project,omitempty"`   // compose project name, which events carry
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+	Created   time.Time `json:"created"`
+}
+
+// Global reports whether the window covers every project
+func (w Window) Global() bool { return w.ProjectID == "" }
+
+// Active reports whether t falls within the window
+func (w Window) Active(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// Validate checks the window's times
+func (w Window) Validate() error {
+	if w.Start.IsZero() || w.End.IsZero() {
+		return errors.New("start and end are required")
+	}
+	if !w.End.After(w.Start) {
+		return errors.New("end must be after start")
+	}
+	if w.End.Sub(w.Start) > maxWindow {
+		return fmt.Errorf("window is longer than %s", maxWindow)
+	}
+	if !w.End.After(time.Now()) {
+		return errors.New("window has already ended")
+	}
+	return nil
+}
+
+// Manager keeps maintenance windows, forgetting them once they end
+type Manager struct {
+	saved   store.Doc // where windows are saved, if anywhere
+	windows map[string]Window
+	mu      sync.RWMutex
+}
+
+// New creates a manager. Windows are saved to saved and loaded from it;
+// with no doc they last until gosei exits.
+func New(saved store.Doc) (*Manager, error) {
+	m := &Manager{saved: saved, windows: make(map[string]Window)}
+	if saved == nil {
+		return m, nil
+	}
+	var windows []Window
+	if _, err := saved.Load(&windows); err != nil {
+		return nil, err
+	}
+	for _, w := range windows {
+		m.windows[w.ID] = w
+	}
+	return m, nil
+}
+
+// Add validates and stores a window, giving it an ID
+func (m *Manager) Add(w Window) (Window, error) {
+	if err := w.Validate(); err != nil {
+		return Window{}, err
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	w.ID = hex.EncodeToString(b)
+	w.Created = time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.windows[w.ID] = w
+	return w, m.save()
+}
+
+// Remove ends a window early, or cancels one yet to start
+func (m *Manager) Remove(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.windows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.windows, id)
+	return m.save()
+}
+
+// List returns the current and upcoming windows, soonest first
+func (m *Manager) List() []Window {
+	m.prune()
+	m.mu.RLock()
+	windows := make([]Window, 0, len(m.windows))
+	for _, w := range m.windows {
+		windows = append(windows, w)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(windows, func(i, j int) bool {
+		if !windows[i].Start.Equal(windows[j].Start) {
+			return windows[i].Start.Before(windows[j].Start)
+		}
+		return windows[i].ID < windows[j].ID
+	})
+	return windows
+}
+
+// Active returns the window a project is in now, by project ID or compose
+// project name, or nil. Global windows cover every project, and an empty
+// project matches only them. A nil manager has no windows.
+func (m *Manager) Active(project string) *Window {
+	if m == nil {
+		return nil
+	}
+	now := time.Now()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var active *Window
+	for _, w := range m.windows {
+		if !w.Active(now) || (!w.Global() && (project == "" || (project != w.ProjectID && project != w.Project))) {
+			continue
+		}
+		// The one ending last says how long automation holds off
+		if active == nil || w.End.After(active.End) {
+			active = &w
+		}
+	}
+	return active
+}
+
+// prune forgets windows that have ended
+func (m *Manager) prune() {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pruned := false
+	for id, w := range m.windows {
+		if !now.Before(w.End) {
+			delete(m.windows, id)
+			pruned = true
+		}
+	}
+	if pruned {
+		m.save()
+	}
+}
+
+// save saves the windows. Callers hold mu.
+func (m *Manager) save() error {
+	if m.saved == nil {
+		return nil
+	}
+	windows := make([]Window, 0, len(m.windows))
+	for _, w := range m.windows {
+		windows = append(windows, w)
+	}
+	if err := m.saved.Save(windows); err != nil {
+		return fmt.Errorf("failed to save maintenance windows: %w", err)
+	}
+	return nil
+}
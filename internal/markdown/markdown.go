@@ -0,0 +1,474 @@
+// Package markdown renders the Markdown of project READMEs as HTML. It
+// covers what operational notes use (headings, paragraphs, emphasis, code,
+// lists, quotes, tables and links) rather than all of CommonMark, and is
+// safe to embed in the dashboard by construction: raw HTML in the source is
+// shown as text, and links and images only keep http(s), mailto and
+// in-page URLs.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	heading    = regexp.MustCompile(`^(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	rule       = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fence      = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*([^` \t]*)")
+	listItem   = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])(?:[ \t]+(.*))?$`)
+	quote      = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	tableDelim = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	setext     = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+)
+
+// Render returns the HTML for Markdown source
+func Render(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"))
+	return b.String()
+}
+
+// renderBlocks renders lines as a sequence of blocks
+func renderBlocks(b *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case fence.MatchString(line):
+			m := fence.FindStringSubmatch(line)
+			marker := m[1]
+			i++
+			var code []string
+			for ; i < len(lines); i++ {
+				if t := strings.TrimSpace(lines[i]); strings.HasPrefix(t, marker) && strings.Trim(t, marker[:1]) == "" {
+					i++
+					break
+				}
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code")
+			if m[2] != "" {
+				b.WriteString(` class="language-` + html.EscapeString(m[2]) + `"`)
+			}
+			b.WriteString(">")
+			for _, l := range code {
+				b.WriteString(html.EscapeString(l) + "\n")
+			}
+			b.WriteString("</code></pre>\n")
+
+		case heading.MatchString(trimmed):
+			m := heading.FindStringSubmatch(trimmed)
+			level := strconv.Itoa(len(m[1]))
+			b.WriteString("<h" + level + ">" + inline(m[2]) + "</h" + level + ">\n")
+			i++
+
+		case rule.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+
+		case quote.MatchString(line):
+			var inner []string
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				if m := quote.FindStringSubmatch(lines[i]); m != nil {
+					inner = append(inner, m[1])
+				} else {
+					// A lazy continuation of the quoted paragraph
+					inner = append(inner, lines[i])
+				}
+			}
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, inner)
+			b.WriteString("</blockquote>\n")
+
+		case listItem.MatchString(line):
+			i = renderList(b, lines, i)
+
+		case i+1 < len(lines) && strings.Contains(line, "|") && tableDelim.MatchString(lines[i+1]) &&
+			len(cells(line)) == len(cells(lines[i+1])):
+			i = renderTable(b, lines, i)
+
+		default:
+			i = renderParagraph(b, lines, i)
+		}
+	}
+}
+
+// startsBlock reports whether a line ends a paragraph by starting another
+// block
+func startsBlock(line string) bool {
+	t := strings.TrimSpace(line)
+	return t == "" || fence.MatchString(line) || heading.MatchString(t) || rule.MatchString(line) ||
+		quote.MatchString(line) || listItem.MatchString(line)
+}
+
+// renderParagraph renders the paragraph starting at lines[i], which a
+// following === or --- line makes a heading, and returns the index after it
+func renderParagraph(b *strings.Builder, lines []string, i int) int {
+	para := []string{lines[i]}
+	for i++; i < len(lines); i++ {
+		if m := setext.FindStringSubmatch(lines[i]); m != nil {
+			tag := "h2"
+			if m[1][0] == '=' {
+				tag = "h1"
+			}
+			b.WriteString("<" + tag + ">" + inline(strings.TrimSpace(strings.Join(para, " "))) + "</" + tag + ">\n")
+			return i + 1
+		}
+		if startsBlock(lines[i]) {
+			break
+		}
+		para = append(para, lines[i])
+	}
+
+	b.WriteString("<p>")
+	for j, l := range para {
+		if j > 0 {
+			b.WriteString("\n")
+		}
+		hard := strings.HasSuffix(l, "  ") || strings.HasSuffix(l, "\\")
+		l = strings.TrimSpace(l)
+		if hard && j < len(para)-1 {
+			b.WriteString(inline(strings.TrimSuffix(l, "\\")) + "<br>")
+		} else {
+			b.WriteString(inline(l))
+		}
+	}
+	b.WriteString("</p>\n")
+	return i
+}
+
+// renderList renders the list starting at lines[i] and returns the index
+// after it. Lines indented past an item's marker belong to the item, so
+// lists nest.
+func renderList(b *strings.Builder, lines []string, i int) int {
+	first := listItem.FindStringSubmatch(lines[i])
+	ordered := first[2][0] >= '0' && first[2][0] <= '9'
+	if ordered {
+		start, _ := strconv.Atoi(first[2][:len(first[2])-1])
+		if start != 1 {
+			b.WriteString(`<ol start="` + strconv.Itoa(start) + `">` + "\n")
+		} else {
+			b.WriteString("<ol>\n")
+		}
+	} else {
+		b.WriteString("<ul>\n")
+	}
+
+	for i < len(lines) {
+		m := listItem.FindStringSubmatch(lines[i])
+		if m == nil || (m[2][0] >= '0' && m[2][0] <= '9') != ordered {
+			break
+		}
+		indent := len(m[1]) + len(m[2]) + 1
+		item := []string{m[3]}
+		blank := false
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			if strings.TrimSpace(l) == "" {
+				blank = true
+				item = append(item, "")
+				continue
+			}
+			lead := len(l) - len(strings.TrimLeft(l, " "))
+			if lead >= indent {
+				item = append(item, l[indent:])
+			} else if !blank && !startsBlock(l) {
+				// A lazy continuation of the item's paragraph
+				item = append(item, strings.TrimSpace(l))
+			} else {
+				break
+			}
+			blank = false
+		}
+
+		item = trimBlank(item)
+		b.WriteString("<li>")
+		if containsBlank(item) || (len(item) > 0 && startsBlock(item[0])) {
+			// Items with several paragraphs hold them as blocks
+			b.WriteString("\n")
+			renderBlocks(b, item)
+		} else if len(item) > 0 {
+			// Otherwise the item's text goes without <p>, before any nested blocks
+			n := 1
+			for n < len(item) && !startsBlock(item[n]) {
+				n++
+			}
+			para := make([]string, n)
+			for j, l := range item[:n] {
+				para[j] = strings.TrimSpace(l)
+			}
+			b.WriteString(inline(strings.Join(para, "\n")))
+			if n < len(item) {
+				b.WriteString("\n")
+				renderBlocks(b, item[n:])
+			}
+		}
+		b.WriteString("</li>\n")
+
+		if blank && i < len(lines) && !listItem.MatchString(lines[i]) {
+			break
+		}
+	}
+
+	if ordered {
+		b.WriteString("</ol>\n")
+	} else {
+		b.WriteString("</ul>\n")
+	}
+	return i
+}
+
+// trimBlank drops trailing blank lines
+func trimBlank(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// containsBlank reports whether lines include a blank one, which separates
+// an item's paragraphs
+func containsBlank(lines []string) bool {
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// renderTable renders the table whose header is lines[i] and returns the
+// index after it
+func renderTable(b *strings.Builder, lines []string, i int) int {
+	header := cells(lines[i])
+	var aligns []string
+	for _, c := range cells(lines[i+1]) {
+		switch {
+		case strings.HasPrefix(c, ":") && strings.HasSuffix(c, ":"):
+			aligns = append(aligns, "center")
+		case strings.HasSuffix(c, ":"):
+			aligns = append(aligns, "right")
+		case strings.HasPrefix(c, ":"):
+			aligns = append(aligns, "left")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+
+	row := func(tag string, cs []string) {
+		b.WriteString("<tr>")
+		for j := range header {
+			b.WriteString("<" + tag)
+			if aligns[j] != "" {
+				b.WriteString(` style="text-align: ` + aligns[j] + `"`)
+			}
+			b.WriteString(">")
+			if j < len(cs) {
+				b.WriteString(inline(cs[j]))
+			}
+			b.WriteString("</" + tag + ">")
+		}
+		b.WriteString("</tr>\n")
+	}
+
+	b.WriteString("<table>\n<thead>\n")
+	row("th", header)
+	b.WriteString("</thead>\n<tbody>\n")
+	for i += 2; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|"); i++ {
+		row("td", cells(lines[i]))
+	}
+	b.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+// cells splits a table row into its trimmed cells; \| is a literal pipe
+func cells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var result []string
+	var cell strings.Builder
+	for j := 0; j < len(line); j++ {
+		switch {
+		case line[j] == '\\' && j+1 < len(line) && line[j+1] == '|':
+			cell.WriteByte('|')
+			j++
+		case line[j] == '|':
+			result = append(result, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[j])
+		}
+	}
+	return append(result, strings.TrimSpace(cell.String()))
+}
+
+var (
+	// link matches [text](url "title") and ![alt](url) at the start of a
+	// string; text may hold one level of brackets, e.g. a badge image
+	link = regexp.MustCompile(`^(!?)\[((?:[^\[\]]|\[[^\[\]]*\])*)\]\(\s*<?([^\s()<>]*(?:\([^\s()<>]*\)[^\s()<>]*)*)>?(?:\s+"[^"]*")?\s*\)`)
+	// autolink matches <https://...> and bare URLs at the start of a string
+	autolink = regexp.MustCompile(`^(?:<((?:https?://|mailto:)[^\s<>]+)>|(https?://[^\s<>]*[^\s<>.,:;"')\]!?*_~]))`)
+)
+
+// inline renders text with its emphasis, code spans and links, escaping
+// everything else
+func inline(s string) string {
+	return inlineSpans(s, make([]int, len(s)))
+}
+
+// inlineSpans is inline with the emphasis spans already found in s, which
+// emphasis nested in s shares rather than scanning again
+func inlineSpans(s string, spans []int) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		rest := s[i:]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.ContainsRune("\\`*_{}[]()#+-.!|~<>", rune(s[i+1])):
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == '`':
+			n := len(rest) - len(strings.TrimLeft(rest, "`"))
+			marker := rest[:n]
+			if end := strings.Index(rest[n:], marker); end >= 0 {
+				code := rest[n : n+end]
+				if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+					code = code[1 : len(code)-1]
+				}
+				b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i += 2*n + end
+				continue
+			}
+			b.WriteString(marker)
+			i += n
+			continue
+
+		case c == '[' || (c == '!' && strings.HasPrefix(rest, "![")):
+			if m := link.FindStringSubmatch(rest); m != nil {
+				b.WriteString(renderLink(m[1] == "!", m[2], m[3]))
+				i += len(m[0])
+				continue
+			}
+
+		case c == '<' || c == 'h':
+			// A bare URL only starts a word
+			if c == '<' || i == 0 || !isWordChar(s[i-1]) {
+				if m := autolink.FindStringSubmatch(rest); m != nil {
+					url := m[1] + m[2]
+					b.WriteString(`<a href="` + html.EscapeString(url) + `" rel="noopener noreferrer">` + html.EscapeString(strings.TrimPrefix(url, "mailto:")) + `</a>`)
+					i += len(m[0])
+					continue
+				}
+			}
+
+		case c == '*' || c == '_' || c == '~':
+			if n, end := emphasis(s, i, spans); end > 0 {
+				tag := map[bool]string{true: "strong", false: "em"}[n == 2]
+				if c == '~' {
+					tag = "del"
+				}
+				b.WriteString("<" + tag + ">" + inlineSpans(s[i+n:i+end-n], spans[i+n:i+end-n]) + "</" + tag + ">")
+				i += end
+				continue
+			}
+		}
+
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// emphasis returns the length of the emphasis marker opened at s[i] and of
+// the source the emphasis covers up to its closer, or 0 if s[i] opens none.
+// Lengths found are kept in spans by position, plus one so 0 means not yet
+// scanned, so each opener is only scanned once however deeply it's nested.
+func emphasis(s string, i int, spans []int) (int, int) {
+	c := s[i]
+	n := 1
+	if i+1 < len(s) && s[i+1] == c {
+		n = 2
+	}
+	if c == '~' && n != 2 {
+		return 0, 0
+	}
+	// An opener is followed by text, and _ only opens at the start of a word,
+	// so snake_case stays as it is
+	if i+n >= len(s) || s[i+n] == ' ' || (c == '_' && i > 0 && isWordChar(s[i-1])) {
+		return 0, 0
+	}
+	if spans[i] > 0 {
+		return n, spans[i] - 1
+	}
+
+	end := 0
+	for j := i + n; j < len(s); j++ {
+		if s[j] == '\\' {
+			j++
+			continue
+		}
+		if s[j] != c {
+			continue
+		}
+		run := len(s[j:]) - len(strings.TrimLeft(s[j:], s[i:i+1]))
+		// A run after text closes with its first characters, e.g. the * of
+		// the *** ending **a *b***; otherwise it may open emphasis nested in
+		// this one, which is skipped whole so its markers don't close this
+		if run >= n && j > i+n && s[j-1] != ' ' && !(c == '_' && run == n && j+n < len(s) && isWordChar(s[j+n])) {
+			end = j + n - i
+			break
+		}
+		m, nested := emphasis(s, j, spans)
+		if nested > 0 {
+			j += nested - 1
+		} else if m == n {
+			// An opener like this one without a closer means this one has
+			// none either
+			break
+		} else {
+			j += run - 1
+		}
+	}
+	spans[i] = end + 1
+	return n, end
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// renderLink renders a link or image. URLs with other schemes, such as
+// javascript:, and relative paths, which don't lead anywhere from the
+// dashboard, leave only the text.
+func renderLink(image bool, text, url string) string {
+	if !safeURL(url) {
+		if image {
+			return html.EscapeString(text)
+		}
+		return inline(text)
+	}
+	if image {
+		return `<img src="` + html.EscapeString(url) + `" alt="` + html.EscapeString(text) + `" loading="lazy">`
+	}
+	return `<a href="` + html.EscapeString(url) + `" rel="noopener noreferrer">` + inline(text) + `</a>`
+}
+
+// safeURL reports whether a URL may be linked to
+func safeURL(url string) bool {
+	lower := strings.ToLower(url)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://") ||
+		strings.HasPrefix(lower, "mailto:") || strings.HasPrefix(url, "#")
+}
@@ -0,0 +1,80 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		// Links and images only keep http(s), mailto and in-page URLs
+		{"https link", "[docs](https://example.com/a?b=1&c=2)", `<p><a href="https://example.com/a?b=1&amp;c=2" rel="noopener noreferrer">docs</a></p>` + "\n"},
+		{"in-page link", "[up](#top)", `<p><a href="#top" rel="noopener noreferrer">up</a></p>` + "\n"},
+		{"javascript link", "[x](javascript:alert(1))", "<p>x</p>\n"},
+		{"javascript link mixed case", "[x](JaVaScRiPt:alert(1))", "<p>x</p>\n"},
+		{"javascript link padded", "[x]( javascript:alert(1))", "<p>x</p>\n"},
+		{"vbscript link", "[x](vbscript:msgbox(1))", "<p>x</p>\n"},
+		{"data link", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>\n"},
+		{"data image", "![x](data:image/png;base64,AAAA)", "<p>x</p>\n"},
+		{"entity-encoded scheme", "[x](&#106;avascript:alert(1))", "<p>x</p>\n"},
+		{"relative link", "[x](docs/setup.md)", "<p>x</p>\n"},
+		{"unsafe link keeps emphasis", "[*x*](javascript:y)", "<p><em>x</em></p>\n"},
+		{"javascript autolink", "<javascript:alert(1)>", "<p>&lt;javascript:alert(1)&gt;</p>\n"},
+
+		// Quotes can't break out of attributes
+		{"quote in href", `[x](https://x/"onmouseover="alert(1))`, `<p><a href="https://x/&#34;onmouseover=&#34;alert(1)" rel="noopener noreferrer">x</a></p>` + "\n"},
+		{"quote in alt", `![a" onerror="alert(1)](https://x/y.png)`, `<p><img src="https://x/y.png" alt="a&#34; onerror=&#34;alert(1)" loading="lazy"></p>` + "\n"},
+		{"quote in autolink", `<https://x/"onmouseover=alert(1)>`, `<p><a href="https://x/&#34;onmouseover=alert(1)" rel="noopener noreferrer">https://x/&#34;onmouseover=alert(1)</a></p>` + "\n"},
+		{"quote in fence info", "```\"><script>\nx\n```", `<pre><code class="language-&#34;&gt;&lt;script&gt;">x` + "\n</code></pre>\n"},
+
+		// Raw HTML and entities are shown as text
+		{"script tag", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"event handler", "<img src=x onerror=alert(1)>", "<p>&lt;img src=x onerror=alert(1)&gt;</p>\n"},
+		{"html in heading", "# <h1>", "<h1>&lt;h1&gt;</h1>\n"},
+		{"html in code span", "`<b>`", "<p><code>&lt;b&gt;</code></p>\n"},
+		{"html in fenced code", "```html\n<script>x</script>\n```", `<pre><code class="language-html">&lt;script&gt;x&lt;/script&gt;` + "\n</code></pre>\n"},
+		{"html in list", "- <i>", "<ul>\n<li>&lt;i&gt;</li>\n</ul>\n"},
+		{"html in table", "| a | <b> |\n|---|---|\n| [x](javascript:y) | c |", "<table>\n<thead>\n<tr><th>a</th><th>&lt;b&gt;</th></tr>\n</thead>\n<tbody>\n<tr><td>x</td><td>c</td></tr>\n</tbody>\n</table>\n"},
+		{"entities", "&lt;script&gt; &#60;b&#62; &amp;", "<p>&amp;lt;script&amp;gt; &amp;#60;b&amp;#62; &amp;amp;</p>\n"},
+		{"escaped angle brackets", `\<b\>`, "<p>&lt;b&gt;</p>\n"},
+
+		// Emphasis
+		{"em in strong", "**a *b* c**", "<p><strong>a <em>b</em> c</strong></p>\n"},
+		{"strong in em", "*a **b** c*", "<p><em>a <strong>b</strong> c</em></p>\n"},
+		{"strong em", "***x***", "<p><strong><em>x</em></strong></p>\n"},
+		{"em closing with strong", "**a *b***", "<p><strong>a <em>b</em></strong></p>\n"},
+		{"underscore in star", "*a _b_ c*", "<p><em>a <em>b</em> c</em></p>\n"},
+		{"em in strikethrough", "~~a *b*~~", "<p><del>a <em>b</em></del></p>\n"},
+		{"snake case", "snake_case_name", "<p>snake_case_name</p>\n"},
+		{"unclosed", "*a **b", "<p>*a **b</p>\n"},
+		{"escaped marker", `*a \* b*`, "<p><em>a * b</em></p>\n"},
+		{"em in link", "[*x*](https://e.com)", `<p><a href="https://e.com" rel="noopener noreferrer"><em>x</em></a></p>` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.src); got != tt.want {
+				t.Errorf("Render(%q)\n got: %q\nwant: %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+// Unclosed and deeply nested emphasis must not make rendering quadratic in
+// the number of openers
+func TestRenderEmphasisScales(t *testing.T) {
+	for _, src := range []string{
+		strings.Repeat("*a _b **c ~~d ", 5000),
+		strings.Repeat("*a ", 5000) + strings.Repeat("a* ", 5000),
+	} {
+		start := time.Now()
+		Render(src)
+		if d := time.Since(start); d > 2*time.Second {
+			t.Errorf("rendering %d bytes took %v", len(src), d)
+		}
+	}
+}
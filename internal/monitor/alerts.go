@@ -0,0 +1,344 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lyall/gosei/internal/maintenance"
+	"github.com/lyall/gosei/internal/notify"
+	"github.com/lyall/gosei/internal/sse"
+)
+
+// defaultRestartWindow is the window restarts are counted in when a rule
+// gives none
+const defaultRestartWindow = time.Hour
+
+// Rule is an alert threshold, written metric>threshold[:duration]:
+//
+//	cpu>150%:10m     CPU above 150 (percent of one core) for 10 minutes
+//	memory>90%:5m    memory above 90% of its limit for 5 minutes
+//	restarts>3:1h    more than 3 exits within an hour
+type Rule struct {
+	Metric    string        `json:"metric"` // "cpu", "memory" or "restarts"
+	Threshold float64       `json:"threshold"`
+	Duration  time.Duration `json:"duration"` // how long cpu or memory must stay above; the window restarts are counted in
+	Spec      string        `json:"spec"`
+}
+
+// ParseRules parses a comma-separated list of rules
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+	for _, spec := range strings.Split(s, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		r, err := parseRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func parseRule(spec string) (Rule, error) {
+	r := Rule{Spec: spec}
+	cond, dur, hasDur := strings.Cut(spec, ":")
+	metric, value, ok := strings.Cut(cond, ">")
+	if !ok {
+		return r, fmt.Errorf("invalid alert rule %q: expected metric>threshold[:duration]", spec)
+	}
+
+	r.Metric = strings.ToLower(strings.TrimSpace(metric))
+	switch r.Metric {
+	case "cpu", "memory", "restarts":
+	default:
+		return r, fmt.Errorf("invalid alert rule %q: metric must be cpu, memory or restarts", spec)
+	}
+
+	threshold, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil || threshold < 0 {
+		return r, fmt.Errorf("invalid alert rule %q: bad threshold %q", spec, value)
+	}
+	r.Threshold = threshold
+
+	if hasDur {
+		if r.Duration, err = time.ParseDuration(strings.TrimSpace(dur)); err != nil || r.Duration < 0 {
+			return r, fmt.Errorf("invalid alert rule %q: bad duration %q", spec, dur)
+		}
+	}
+	if r.Metric == "restarts" && r.Duration == 0 {
+		r.Duration = defaultRestartWindow
+	}
+	return r, nil
+}
+
+// Alert is sent when a rule starts or stops matching a container
+type Alert struct {
+	Rule        string    `json:"rule"`
+	ContainerID string    `json:"containerId"`
+	Container   string    `json:"container"`
+	Project     string    `json:"project,omitempty"`
+	Value       float64   `json:"value"`
+	Resolved    bool      `json:"resolved"`
+	Message     string    `json:"message"`
+	Time        time.Time `json:"time"`
+}
+
+// Topic publishes alerts as alert
+func (Alert) Topic() sse.Topic { return sse.TopicAlert }
+
+// alertState tracks one rule for one container
+type alertState struct {
+	since  time.Time // when the threshold was first exceeded
+	firing bool
+	last   Alert
+}
+
+// exitLog records recent exits of a container
+type exitLog struct {
+	name    string
+	project string
+	times   []time.Time
+}
+
+// Alerter checks stats samples and container exits against rules and
+// reports alerts as SSE alert events and notifications
+type Alerter struct {
+	rules    []Rule
+	broker   *sse.Broker
+	notifier *notify.Dispatcher
+	windows  *maintenance.Manager
+
+	states map[string]*alertState // rule spec + "/" + container ID
+	exits  map[string]*exitLog    // by container ID
+	mu     sync.Mutex
+}
+
+// NewAlerter creates an alerter. notifier may be nil to only send SSE events.
+func NewAlerter(rules []Rule, b *sse.Broker, notifier *notify.Dispatcher) *Alerter {
+	return &Alerter{
+		rules:    rules,
+		broker:   b,
+		notifier: notifier,
+		states:   make(map[string]*alertState),
+		exits:    make(map[string]*exitLog),
+	}
+}
+
+// SetMaintenance holds off alerts for projects in a maintenance window.
+// Exits during one aren't counted, since restarts are expected then.
+func (a *Alerter) SetMaintenance(m *maintenance.Manager) {
+	a.windows = m
+}
+
+// Run records container exits from the broker's container:status events
+// until ctx is cancelled
+func (a *Alerter) Run(ctx context.Context) {
+	sub := a.broker.Subscribe(sse.TopicContainerStatus, func(m sse.Message) bool {
+		return m.(sse.ContainerStatusEvent).Status == "die"
+	})
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-sub.C:
+			a.recordExit(m.(sse.ContainerStatusEvent))
+		}
+	}
+}
+
+func (a *Alerter) recordExit(event sse.ContainerStatusEvent) {
+	if a.windows.Active(event.Project) != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	log, ok := a.exits[event.ID]
+	if !ok {
+		log = &exitLog{}
+		a.exits[event.ID] = log
+	}
+	log.name, log.project = event.Name, event.Project
+	log.times = append(log.times, event.Time)
+}
+
+// Observe checks a stats sample of the running containers against the rules
+func (a *Alerter) Observe(now time.Time, sample []sse.ContainerStatsEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, r := range a.rules {
+		if r.Metric == "restarts" {
+			a.checkRestarts(now, r, seen)
+			continue
+		}
+		for _, st := range sample {
+			value := st.CPUPercent
+			if r.Metric == "memory" {
+				value = st.MemoryPercent
+			}
+			key := r.Spec + "/" + st.ID
+			seen[key] = true
+			a.check(now, r, key, value > r.Threshold, Alert{
+				ContainerID: st.ID,
+				Container:   st.Name,
+				Project:     st.Project,
+				Value:       value,
+			})
+		}
+	}
+
+	// Containers that stopped or were removed no longer match
+	for key, state := range a.states {
+		if seen[key] {
+			continue
+		}
+		if state.firing {
+			alert := state.last
+			alert.Resolved = true
+			alert.Message = fmt.Sprintf("%s is no longer running", alert.Container)
+			a.emit(now, alert)
+		}
+		delete(a.states, key)
+	}
+	a.pruneExits(now)
+}
+
+// checkRestarts counts each container's exits within the rule's window
+func (a *Alerter) checkRestarts(now time.Time, r Rule, seen map[string]bool) {
+	for id, log := range a.exits {
+		n := 0
+		for _, t := range log.times {
+			if now.Sub(t) <= r.Duration {
+				n++
+			}
+		}
+		key := r.Spec + "/" + id
+		seen[key] = true
+		a.check(now, r, key, float64(n) > r.Threshold, Alert{
+			ContainerID: id,
+			Container:   log.name,
+			Project:     log.project,
+			Value:       float64(n),
+		})
+	}
+}
+
+// pruneExits forgets exits older than every restart window
+func (a *Alerter) pruneExits(now time.Time) {
+	var window time.Duration
+	for _, r := range a.rules {
+		if r.Metric == "restarts" && r.Duration > window {
+			window = r.Duration
+		}
+	}
+	for id, log := range a.exits {
+		kept := log.times[:0]
+		for _, t := range log.times {
+			if now.Sub(t) <= window {
+				kept = append(kept, t)
+			}
+		}
+		log.times = kept
+		if len(kept) == 0 {
+			delete(a.exits, id)
+		}
+	}
+}
+
+// check advances one rule's state for one container, alerting once the
+// threshold has been exceeded for the rule's duration and again when it
+// clears
+func (a *Alerter) check(now time.Time, r Rule, key string, exceeded bool, alert Alert) {
+	alert.Rule = r.Spec
+	state := a.states[key]
+	if !exceeded {
+		if state != nil {
+			if state.firing {
+				alert.Resolved = true
+				alert.Message = resolvedMessage(r, alert)
+				a.emit(now, alert)
+			}
+			delete(a.states, key)
+		}
+		return
+	}
+
+	if state == nil {
+		state = &alertState{since: now}
+		a.states[key] = state
+	}
+	state.last = alert
+	// Restart windows are already a duration; cpu and memory must persist
+	if state.firing || (r.Metric != "restarts" && now.Sub(state.since) < r.Duration) {
+		return
+	}
+	// Held off rather than dropped, so it fires once the window ends if
+	// it still holds
+	if a.windows.Active(alert.Project) != nil {
+		return
+	}
+	state.firing = true
+	alert.Message = firingMessage(r, alert)
+	a.emit(now, alert)
+}
+
+func firingMessage(r Rule, al Alert) string {
+	switch r.Metric {
+	case "restarts":
+		return fmt.Sprintf("%s exited %.0f times in %s (more than %g)", al.Container, al.Value, r.Duration, r.Threshold)
+	case "memory":
+		return fmt.Sprintf("%s memory at %.0f%% of its limit (above %g%% for %s)", al.Container, al.Value, r.Threshold, r.Duration)
+	default:
+		return fmt.Sprintf("%s CPU at %.0f%% (above %g%% for %s)", al.Container, al.Value, r.Threshold, r.Duration)
+	}
+}
+
+func resolvedMessage(r Rule, al Alert) string {
+	switch r.Metric {
+	case "restarts":
+		return fmt.Sprintf("%s exited %.0f times in %s", al.Container, al.Value, r.Duration)
+	case "memory":
+		return fmt.Sprintf("%s memory back to %.0f%% of its limit", al.Container, al.Value)
+	default:
+		return fmt.Sprintf("%s CPU back to %.0f%%", al.Container, al.Value)
+	}
+}
+
+// emit broadcasts an alert and sends it as a notification
+func (a *Alerter) emit(now time.Time, alert Alert) {
+	alert.Time = now
+	n := notify.Notification{
+		Title:   "Alert: " + alert.Container,
+		Message: alert.Message,
+		Level:   "warning",
+		Event:   notify.EventAlert,
+		Project: alert.Project,
+		Time:    now,
+		Key:     alert.Rule + "/" + alert.Container,
+	}
+	if alert.Resolved {
+		n.Title = "Resolved: " + alert.Container
+		n.Level = "info"
+		n.Event = notify.EventAlertResolved
+		slog.Info("Alert resolved", "rule", alert.Rule, "container", alert.Container, "message", alert.Message)
+	} else {
+		slog.Warn("Alert", "rule", alert.Rule, "container", alert.Container, "message", alert.Message)
+	}
+
+	a.broker.Publish(alert)
+	if a.notifier != nil {
+		a.notifier.Send(n)
+	}
+}
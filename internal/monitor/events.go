@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lyall/gosei/internal/autoupdate"
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/notify"
+	"github.com/lyall/gosei/internal/sse"
+)
+
+// EventNotifier sends notifications for container crashes, failed compose
+// operations and applied updates, for notification rules to pick from
+type EventNotifier struct {
+	broker   *sse.Broker
+	docker   docker.DockerClient
+	notifier *notify.Dispatcher
+}
+
+// NewEventNotifier creates an event notifier. The Docker client adds exit
+// codes to crash notifications.
+func NewEventNotifier(b *sse.Broker, dc docker.DockerClient, notifier *notify.Dispatcher) *EventNotifier {
+	return &EventNotifier{broker: b, docker: dc, notifier: notifier}
+}
+
+// Run sends notifications for events until ctx is cancelled
+func (e *EventNotifier) Run(ctx context.Context) {
+	sub := e.broker.Subscribe("", func(m sse.Message) bool {
+		switch m := m.(type) {
+		case sse.ContainerStatusEvent:
+			return m.Status == "die" || m.Status == "kill"
+		case sse.ComposeCompleteEvent:
+			return !m.Success
+		case autoupdate.Result:
+			return m.Success && len(m.Updated) > 0
+		}
+		return false
+	})
+	defer sub.Close()
+
+	crashes := docker.NewCrashDetector()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			switch m := m.(type) {
+			case sse.ContainerStatusEvent:
+				if crashes.Crashed(m.ID, m.Status, m.Time) {
+					e.notifier.Send(e.died(ctx, m))
+				}
+			case sse.ComposeCompleteEvent:
+				e.notifier.Send(operationFailed(m))
+			case autoupdate.Result:
+				e.notifier.Send(notify.Notification{
+					Title:   "Updated: " + m.ProjectID,
+					Message: fmt.Sprintf("Updated %s", strings.Join(m.Updated, ", ")),
+					Level:   "info",
+					Event:   notify.EventUpdateApplied,
+					Project: m.ProjectID,
+					Time:    m.Time,
+				})
+			}
+		}
+	}
+}
+
+// died describes a crashed container, with its exit code if it can still be
+// inspected
+func (e *EventNotifier) died(ctx context.Context, m sse.ContainerStatusEvent) notify.Notification {
+	n := notify.Notification{
+		Title:   "Died: " + m.Name,
+		Message: m.Name + " exited unexpectedly",
+		Level:   "error",
+		Event:   notify.EventContainerDied,
+		Project: m.Project,
+		Time:    m.Time,
+		// By name, which survives the container being recreated
+		Key: m.Name,
+	}
+	if c, err := e.docker.GetContainer(ctx, m.ID); err == nil {
+		n.Message = fmt.Sprintf("%s exited with code %d", m.Name, c.ExitCode)
+		if c.OOMKilled {
+			n.Message += " (out of memory)"
+		}
+	}
+	return n
+}
+
+func operationFailed(m sse.ComposeCompleteEvent) notify.Notification {
+	n := notify.Notification{
+		Title:   fmt.Sprintf("Failed: %s %s", m.Operation, m.ProjectID),
+		Message: m.Message,
+		Level:   "error",
+		Event:   notify.EventOperationFailed,
+		Project: m.ProjectID,
+	}
+	if m.Status == "cancelled" {
+		n.Title = fmt.Sprintf("Cancelled: %s %s", m.Operation, m.ProjectID)
+		n.Level = "warning"
+	}
+	return n
+}
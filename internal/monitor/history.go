@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lyall/gosei/internal/sse"
+)
+
+// Point is one container's usage in a stats sample
+type Point struct {
+	Time          time.Time
+	CPUPercent    float64
+	MemoryUsage   uint64
+	MemoryPercent float64
+}
+
+// History keeps the stats samples of the last retention period in memory,
+// so usage can be graphed over time. Series are kept by container name,
+// which survives a container being recreated.
+type History struct {
+	retention time.Duration
+	series    map[string][]Point
+	mu        sync.RWMutex
+}
+
+// NewHistory creates a history keeping samples for retention
+func NewHistory(retention time.Duration) *History {
+	return &History{retention: retention, series: make(map[string][]Point)}
+}
+
+// Record adds a sample of every running container taken at t, and forgets
+// samples older than the retention period
+func (h *History) Record(t time.Time, events sse.ContainerStatsEvents) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, e := range events {
+		h.series[e.Name] = append(h.series[e.Name], Point{
+			Time:          t,
+			CPUPercent:    e.CPUPercent,
+			MemoryUsage:   e.MemoryUsage,
+			MemoryPercent: e.MemoryPercent,
+		})
+	}
+
+	cutoff := t.Add(-h.retention)
+	for name, points := range h.series {
+		i := sort.Search(len(points), func(i int) bool { return points[i].Time.After(cutoff) })
+		switch {
+		case i == len(points):
+			delete(h.series, name)
+		case i > 0:
+			// Copy rather than reslice, so the dropped points can be freed
+			h.series[name] = append([]Point(nil), points[i:]...)
+		}
+	}
+}
+
+// Containers returns the names of containers with samples, sorted
+func (h *History) Containers() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	names := make([]string, 0, len(h.series))
+	for name := range h.series {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Range returns a container's samples from from to to, oldest first
+func (h *History) Range(name string, from, to time.Time) []Point {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	points := h.series[name]
+	start := sort.Search(len(points), func(i int) bool { return !points[i].Time.Before(from) })
+	end := sort.Search(len(points), func(i int) bool { return points[i].Time.After(to) })
+	if start >= end {
+		return nil
+	}
+	return append([]Point(nil), points[start:end]...)
+}
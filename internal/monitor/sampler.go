@@ -0,0 +1,131 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/sse"
+)
+
+// Sampler collects stats for running containers on an interval and
+// broadcasts them as one container:stats event, so browsers don't each poll
+// the daemon for every container they show
+type Sampler struct {
+	docker   docker.DockerClient
+	broker   *sse.Broker
+	interval time.Duration
+	reset    chan time.Duration
+	alerter  *Alerter
+	history  *History
+}
+
+// NewSampler creates a sampler that collects stats every interval, or
+// never with 0 until SetInterval is called
+func NewSampler(dc docker.DockerClient, b *sse.Broker, interval time.Duration) *Sampler {
+	return &Sampler{docker: dc, broker: b, interval: interval, reset: make(chan time.Duration, 1)}
+}
+
+// SetInterval changes how often stats are collected while running; 0
+// stops collecting
+func (s *Sampler) SetInterval(interval time.Duration) {
+	// Only the latest interval matters
+	select {
+	case <-s.reset:
+	default:
+	}
+	s.reset <- interval
+}
+
+// SetAlerter checks every sample against alert rules. Sampling then
+// continues while no browser is connected.
+func (s *Sampler) SetAlerter(a *Alerter) {
+	s.alerter = a
+}
+
+// SetHistory records every sample in h. Sampling then continues while no
+// browser is connected.
+func (s *Sampler) SetHistory(h *History) {
+	s.history = h
+}
+
+// Run samples until ctx is cancelled
+func (s *Sampler) Run(ctx context.Context) {
+	interval := s.interval
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	start := func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+		ticker, tick = nil, nil
+		if interval > 0 {
+			ticker = time.NewTicker(interval)
+			tick = ticker.C
+		}
+	}
+	start()
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case interval = <-s.reset:
+			start()
+			continue
+		case <-tick:
+		}
+
+		// Nobody is listening, so skip the work of asking the daemon
+		if s.broker.ClientCount() == 0 && s.alerter == nil && s.history == nil {
+			continue
+		}
+		s.sample(ctx, interval)
+	}
+}
+
+// sample broadcasts the current stats of every running container
+func (s *Sampler) sample(ctx context.Context, interval time.Duration) {
+	// A slow daemon must not make samples pile up
+	ctx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+
+	containers, err := s.docker.ListContainers(ctx, "")
+	if err != nil {
+		slog.Warn("Stats sampler failed to list containers", "error", err)
+		return
+	}
+
+	projects := make(map[string]string, len(containers))
+	for _, c := range containers {
+		projects[c.ID] = c.ProjectName
+	}
+
+	stats := docker.CollectStats(ctx, s.docker, containers)
+	events := make(sse.ContainerStatsEvents, 0, len(stats))
+	for _, st := range stats {
+		events = append(events, sse.ContainerStatsEvent{
+			ID:            st.ID,
+			Name:          st.Name,
+			Project:       projects[st.ID],
+			CPUPercent:    st.CPUPercent,
+			MemoryUsage:   st.MemoryUsage,
+			MemoryLimit:   st.MemoryLimit,
+			MemoryPercent: st.MemoryPercent,
+		})
+	}
+	now := time.Now()
+	s.broker.Publish(events)
+	if s.alerter != nil {
+		s.alerter.Observe(now, events)
+	}
+	if s.history != nil {
+		s.history.Record(now, events)
+	}
+}
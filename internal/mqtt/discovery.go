@@ -0,0 +1,136 @@
+package mqtt
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/lyall/gosei/internal/project"
+)
+
+// unsafeID matches what Home Assistant doesn't allow in discovery node and
+// object IDs
+var unsafeID = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// discoveryDevice groups a project's entities into one Home Assistant device
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+}
+
+// discoveryConfig is a Home Assistant MQTT discovery config message
+type discoveryConfig struct {
+	Name                string          `json:"name"`
+	UniqueID            string          `json:"unique_id"`
+	StateTopic          string          `json:"state_topic"`
+	ValueTemplate       string          `json:"value_template"`
+	AvailabilityTopic   string          `json:"availability_topic"`
+	PayloadAvailable    string          `json:"payload_available"`
+	PayloadNotAvailable string          `json:"payload_not_available"`
+	DeviceClass         string          `json:"device_class,omitempty"`
+	StateClass          string          `json:"state_class,omitempty"`
+	Icon                string          `json:"icon,omitempty"`
+	Device              discoveryDevice `json:"device"`
+}
+
+// discoveryEntity is one entity published for every project
+type discoveryEntity struct {
+	component string // binary_sensor or sensor
+	object    string
+	config    discoveryConfig
+}
+
+// projectEntities are a project's Home Assistant entities, all reading the
+// project's state topic: whether it runs, its status, and its running and
+// total container counts
+func projectEntities() []discoveryEntity {
+	return []discoveryEntity{
+		{"binary_sensor", "running", discoveryConfig{
+			Name:          "Running",
+			ValueTemplate: "{{ 'ON' if value_json.status in ['running', 'partial'] else 'OFF' }}",
+			DeviceClass:   "running",
+		}},
+		{"sensor", "status", discoveryConfig{
+			Name:          "Status",
+			ValueTemplate: "{{ value_json.status }}",
+			Icon:          "mdi:docker",
+		}},
+		{"sensor", "running_containers", discoveryConfig{
+			Name:          "Running containers",
+			ValueTemplate: "{{ value_json.running }}",
+			StateClass:    "measurement",
+			Icon:          "mdi:package-variant",
+		}},
+		{"sensor", "total_containers", discoveryConfig{
+			Name:          "Containers",
+			ValueTemplate: "{{ value_json.total }}",
+			StateClass:    "measurement",
+			Icon:          "mdi:package-variant-closed",
+		}},
+	}
+}
+
+// nodeID identifies a project among every gosei instance's projects
+func (p *Publisher) nodeID(proj *project.Project) string {
+	return strings.ToLower(unsafeID.ReplaceAllString(p.opts.ClientID+"_"+proj.ID, "_"))
+}
+
+// publishDiscovery announces a project's entities to Home Assistant
+func (p *Publisher) publishDiscovery(proj *project.Project) {
+	node := p.nodeID(proj)
+	for _, e := range projectEntities() {
+		c := e.config
+		c.UniqueID = node + "_" + e.object
+		c.StateTopic = p.topic("projects", proj.Name)
+		c.AvailabilityTopic = p.topic("status")
+		c.PayloadAvailable, c.PayloadNotAvailable = "online", "offline"
+		c.Device = discoveryDevice{
+			Identifiers:  []string{node},
+			Name:         proj.Name,
+			Manufacturer: "gosei",
+			Model:        "Compose project",
+		}
+		p.publishJSON(p.discoveryTopic(e.component, node, e.object), c)
+	}
+}
+
+// removeDiscovery removes a project's entities from Home Assistant, and
+// its retained state
+func (p *Publisher) removeDiscovery(node, name string) {
+	for _, e := range projectEntities() {
+		p.publish(p.discoveryTopic(e.component, node, e.object), nil)
+	}
+	p.publish(p.topic("projects", name), nil)
+}
+
+func (p *Publisher) discoveryTopic(component, node, object string) string {
+	return strings.TrimSuffix(p.opts.DiscoveryPrefix, "/") + "/" + component + "/" + node + "/" + object + "/config"
+}
+
+// syncDiscovery announces projects that are new since the last sync and
+// removes those that are gone. With all set, every project is announced
+// again, as after reconnecting.
+func (p *Publisher) syncDiscovery(all bool) {
+	if p.opts.DiscoveryPrefix == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seen := make(map[string]string)
+	for _, proj := range p.scanner.ListProjects() {
+		node := p.nodeID(proj)
+		seen[node] = proj.Name
+		if _, ok := p.announced[node]; all || !ok {
+			p.publishDiscovery(proj)
+		}
+	}
+	for node, name := range p.announced {
+		if _, ok := seen[node]; !ok {
+			p.removeDiscovery(node, name)
+		}
+	}
+	p.announced = seen
+}
@@ -0,0 +1,258 @@
+// Package mqtt publishes project and container status to an MQTT broker,
+// for home automation such as Home Assistant to act on stack health.
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/project"
+	"github.com/lyall/gosei/internal/sse"
+)
+
+const (
+	// publishTimeout bounds waiting for the broker to take a message
+	publishTimeout = 10 * time.Second
+
+	// discoveryInterval is how often added and removed projects are
+	// announced to Home Assistant
+	discoveryInterval = time.Minute
+)
+
+// Options configures the connection and the topics published to
+type Options struct {
+	Broker      string // tcp://, ssl://, ws:// or wss:// URL
+	ClientID    string
+	Username    string
+	Password    string
+	TopicPrefix string // e.g. gosei, for gosei/projects/<name>
+	QoS         byte   // 0, 1 or 2
+
+	// DiscoveryPrefix is where Home Assistant looks for MQTT discovery
+	// config, usually homeassistant; empty disables discovery
+	DiscoveryPrefix string
+}
+
+// Validate checks the options before connecting
+func (o Options) Validate() error {
+	u, err := url.Parse(o.Broker)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid MQTT broker URL %q", o.Broker)
+	}
+	switch u.Scheme {
+	case "tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss":
+	default:
+		return fmt.Errorf("invalid MQTT broker URL %q: expected tcp, ssl, ws or wss", o.Broker)
+	}
+	if o.QoS > 2 {
+		return fmt.Errorf("invalid MQTT QoS %d: expected 0, 1 or 2", o.QoS)
+	}
+	if o.TopicPrefix == "" || strings.ContainsAny(o.TopicPrefix, "+#") {
+		return fmt.Errorf("invalid MQTT topic prefix %q", o.TopicPrefix)
+	}
+	if strings.ContainsAny(o.DiscoveryPrefix, "+#") {
+		return fmt.Errorf("invalid MQTT discovery prefix %q", o.DiscoveryPrefix)
+	}
+	return nil
+}
+
+// ProjectState is the retained message of a project's topic
+type ProjectState struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Status  string `json:"status"` // "running", "partial", "stopped" or "unknown"
+	Running int    `json:"running"`
+	Total   int    `json:"total"`
+}
+
+// ContainerState is the retained message of a container's topic
+type ContainerState struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	State    string `json:"state"` // e.g. "running" or "exited"
+	Health   string `json:"health,omitempty"`
+	Status   string `json:"status"`
+	Project  string `json:"project,omitempty"`
+	Service  string `json:"service,omitempty"`
+	ExitCode int    `json:"exitCode"`
+}
+
+// Publisher mirrors status changes from the broker to retained MQTT
+// messages:
+//
+//	<prefix>/status                  online or offline (last will)
+//	<prefix>/projects/<project>      ProjectState
+//	<prefix>/containers/<container>  ContainerState, cleared on removal
+//
+// Retained messages give subscribers the current state as they subscribe,
+// and every state is published again on each (re)connect, so the broker
+// catches up with changes made while it was unreachable. With a discovery
+// prefix, each project is also announced to Home Assistant as a device.
+type Publisher struct {
+	opts    Options
+	client  paho.Client
+	broker  *sse.Broker
+	docker  docker.DockerClient
+	scanner *project.Scanner
+
+	mu        sync.Mutex
+	announced map[string]string // names of announced projects by node ID
+}
+
+// New creates a publisher; Run connects it
+func New(opts Options, b *sse.Broker, dc docker.DockerClient, s *project.Scanner) *Publisher {
+	p := &Publisher{opts: opts, broker: b, docker: dc, scanner: s, announced: make(map[string]string)}
+
+	co := paho.NewClientOptions().
+		AddBroker(opts.Broker).
+		SetClientID(opts.ClientID).
+		SetUsername(opts.Username).
+		SetPassword(opts.Password).
+		SetWill(p.topic("status"), "offline", opts.QoS, true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOnConnectHandler(func(paho.Client) {
+			slog.Info("Connected to MQTT broker", "broker", opts.Broker)
+			p.publishAll(context.Background())
+		}).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			slog.Warn("Lost connection to MQTT broker, reconnecting", "broker", opts.Broker, "error", err)
+		})
+	p.client = paho.NewClient(co)
+	return p
+}
+
+// Run publishes status changes until ctx is cancelled, then marks gosei
+// offline and disconnects
+func (p *Publisher) Run(ctx context.Context) {
+	sub := p.broker.Subscribe("", func(m sse.Message) bool {
+		switch m.(type) {
+		case sse.ContainerStatusEvent, sse.ProjectStatusEvent:
+			return true
+		}
+		return false
+	})
+	defer sub.Close()
+
+	// Retries in the background until the broker is reachable
+	p.client.Connect()
+
+	// Projects come and go with rescans, which publish no event
+	ticker := time.NewTicker(discoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if p.client.IsConnectionOpen() {
+				p.syncDiscovery(false)
+			}
+		case <-ctx.Done():
+			if p.client.IsConnectionOpen() {
+				p.publish(p.topic("status"), []byte("offline"))
+			}
+			p.client.Disconnect(250)
+			return
+		case m, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if !p.client.IsConnectionOpen() {
+				// Everything is published again on reconnecting
+				continue
+			}
+			switch m := m.(type) {
+			case sse.ProjectStatusEvent:
+				p.syncDiscovery(false)
+				p.publishJSON(p.topic("projects", m.Name), ProjectState{
+					ID: m.ID, Name: m.Name, Status: m.Status, Running: m.Running, Total: m.Total,
+				})
+			case sse.ContainerStatusEvent:
+				p.publishContainer(ctx, m.ID, m.Name)
+			}
+		}
+	}
+}
+
+// publishContainer publishes a container's current state, which the event
+// only hints at, or clears its topic once it is removed
+func (p *Publisher) publishContainer(ctx context.Context, id, name string) {
+	c, err := p.docker.GetContainer(ctx, id)
+	if err != nil {
+		// An empty retained message deletes the retained one
+		p.publish(p.topic("containers", name), nil)
+		return
+	}
+	p.publishJSON(p.topic("containers", c.Name), containerState(c))
+}
+
+// publishAll publishes gosei's availability and every project and
+// container state
+func (p *Publisher) publishAll(ctx context.Context) {
+	p.publish(p.topic("status"), []byte("online"))
+	p.syncDiscovery(true)
+	for _, proj := range p.scanner.ListProjects() {
+		p.publishJSON(p.topic("projects", proj.Name), ProjectState{
+			ID: proj.ID, Name: proj.Name, Status: proj.Status, Running: proj.Running, Total: proj.Total,
+		})
+	}
+
+	containers, err := p.docker.ListContainers(ctx, "")
+	if err != nil {
+		slog.Warn("Failed to list containers for MQTT", "error", err)
+		return
+	}
+	for i := range containers {
+		p.publishJSON(p.topic("containers", containers[i].Name), containerState(&containers[i]))
+	}
+}
+
+func containerState(c *docker.ContainerInfo) ContainerState {
+	return ContainerState{
+		ID:       c.ID,
+		Name:     c.Name,
+		State:    c.State,
+		Health:   c.Health,
+		Status:   c.Status,
+		Project:  c.ProjectName,
+		Service:  c.ServiceName,
+		ExitCode: c.ExitCode,
+	}
+}
+
+func (p *Publisher) publishJSON(topic string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("Failed to encode MQTT message", "topic", topic, "error", err)
+		return
+	}
+	p.publish(topic, data)
+}
+
+// publish sends a retained message, logging failures rather than holding
+// up later messages
+func (p *Publisher) publish(topic string, payload []byte) {
+	token := p.client.Publish(topic, p.opts.QoS, true, payload)
+	if !token.WaitTimeout(publishTimeout) {
+		slog.Warn("Timed out publishing to MQTT", "topic", topic)
+	} else if err := token.Error(); err != nil {
+		slog.Warn("Failed to publish to MQTT", "topic", topic, "error", err)
+	}
+}
+
+// topic joins the prefix and levels, replacing characters with a meaning
+// in topics
+func (p *Publisher) topic(levels ...string) string {
+	for i, l := range levels {
+		levels[i] = strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(l)
+	}
+	return strings.TrimSuffix(p.opts.TopicPrefix, "/") + "/" + strings.Join(levels, "/")
+}
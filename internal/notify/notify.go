@@ -0,0 +1,161 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lyall/gosei/internal/maintenance"
+)
+
+// sendTimeout bounds one delivery attempt
+const sendTimeout = 10 * time.Second
+
+// Notification is a message for the user, such as a triggered alert
+type Notification struct {
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+	Level   string    `json:"level"` // "info", "warning" or "error"
+	Event   string    `json:"event,omitempty"`
+	Project string    `json:"project,omitempty"`
+	Time    time.Time `json:"time"`
+
+	// Key identifies repeats of the same notification for deduplication,
+	// e.g. the same container dying again; event and title by default
+	Key string `json:"-"`
+}
+
+// Notifier delivers notifications to one channel
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Dispatcher sends notifications to the configured notifiers, as routed
+// by its rules if it has any
+type Dispatcher struct {
+	notifiers []Notifier
+	router    *router
+	windows   *maintenance.Manager
+	mu        sync.RWMutex // guards notifiers
+}
+
+// NewDispatcher creates a dispatcher for the given notifiers
+func NewDispatcher(notifiers ...Notifier) *Dispatcher {
+	return &Dispatcher{notifiers: notifiers}
+}
+
+// SetNotifiers replaces the notifiers, for when settings change
+func (d *Dispatcher) SetNotifiers(notifiers []Notifier) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notifiers = notifiers
+}
+
+// SetRules routes notifications by rules, checked in order with the first
+// match deciding. Notifications no rule matches are not sent.
+func (d *Dispatcher) SetRules(rules []Rule) {
+	if len(rules) == 0 {
+		d.router = nil
+		return
+	}
+	d.router = &router{rules: rules, sent: make(map[string]*dedupState)}
+}
+
+// SetMaintenance drops notifications about projects in a maintenance
+// window, and every notification during a global one
+func (d *Dispatcher) SetMaintenance(m *maintenance.Manager) {
+	d.windows = m
+}
+
+// Send delivers n in the background, so a slow endpoint can't hold up the
+// caller. Failures are logged.
+func (d *Dispatcher) Send(n Notification) {
+	if n.Time.IsZero() {
+		n.Time = time.Now()
+	}
+	if w := d.windows.Active(n.Project); w != nil {
+		slog.Debug("Notification dropped during maintenance window", "title", n.Title, "window", w.ID)
+		return
+	}
+	d.mu.RLock()
+	notifiers := d.notifiers
+	d.mu.RUnlock()
+	if d.router != nil {
+		out, rule, ok := d.router.route(n)
+		if !ok {
+			if rule != nil {
+				slog.Debug("Notification held back", "rule", rule.Name, "title", n.Title)
+			}
+			return
+		}
+		n = out
+		if len(rule.Notifiers) > 0 {
+			notifiers = slices.DeleteFunc(slices.Clone(notifiers), func(nt Notifier) bool {
+				return !slices.Contains(rule.Notifiers, strings.Fields(nt.Name())[0])
+			})
+		}
+	}
+
+	for _, nt := range notifiers {
+		go func(nt Notifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := nt.Notify(ctx, n); err != nil {
+				slog.Warn("Failed to send notification", "notifier", nt.Name(), "title", n.Title, "error", err)
+			}
+		}(nt)
+	}
+}
+
+// Webhook posts notifications as JSON to a URL
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a webhook notifier for an http or https URL
+func NewWebhook(rawURL string) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL %q", rawURL)
+	}
+	return &Webhook{url: rawURL, client: &http.Client{Timeout: sendTimeout}}, nil
+}
+
+// Name identifies the webhook in logs without its path, which may hold a token
+func (w *Webhook) Name() string {
+	u, _ := url.Parse(w.url)
+	return "webhook " + u.Host
+}
+
+// Notify posts n as JSON and expects a 2xx response
+func (w *Webhook) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Gotify pushes notifications to a Gotify server
+type Gotify struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewGotify creates a Gotify notifier for a server URL and application token
+func NewGotify(serverURL, token string) (*Gotify, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid Gotify URL %q", serverURL)
+	}
+	if token == "" {
+		return nil, errors.New("Gotify needs an application token")
+	}
+	return &Gotify{url: strings.TrimSuffix(serverURL, "/"), token: token, client: &http.Client{Timeout: sendTimeout}}, nil
+}
+
+// Name identifies the notifier in logs
+func (g *Gotify) Name() string { return "gotify" }
+
+// Notify posts n as a Gotify message, more urgent levels with a higher
+// priority
+func (g *Gotify) Notify(ctx context.Context, n Notification) error {
+	priority := map[string]int{"info": 4, "warning": 6, "error": 8}[n.Level]
+	body, err := json.Marshal(map[string]any{"title": n.Title, "message": n.Message, "priority": priority})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url+"/message", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// A header rather than ?token= keeps it out of proxy logs
+	req.Header.Set("X-Gotify-Key", g.token)
+	return send(g.client, req)
+}
+
+// pushoverURL is Pushover's message API
+const pushoverURL = "https://api.pushover.net/1/messages.json"
+
+// Pushover pushes notifications through Pushover
+type Pushover struct {
+	token  string
+	user   string
+	client *http.Client
+}
+
+// NewPushover creates a Pushover notifier for an application token and a
+// user or group key
+func NewPushover(token, user string) (*Pushover, error) {
+	if token == "" || user == "" {
+		return nil, errors.New("Pushover needs an application token and a user key")
+	}
+	return &Pushover{token: token, user: user, client: &http.Client{Timeout: sendTimeout}}, nil
+}
+
+// Name identifies the notifier in logs
+func (p *Pushover) Name() string { return "pushover" }
+
+// Notify sends n as a Pushover message: info quietly, errors with high
+// priority
+func (p *Pushover) Notify(ctx context.Context, n Notification) error {
+	priority := map[string]string{"info": "-1", "warning": "0", "error": "1"}[n.Level]
+	if priority == "" {
+		priority = "0"
+	}
+	form := url.Values{
+		"token":     {p.token},
+		"user":      {p.user},
+		"title":     {n.Title},
+		"message":   {n.Message},
+		"priority":  {priority},
+		"timestamp": {fmt.Sprint(n.Time.Unix())},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pushoverURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return send(p.client, req)
+}
+
+// telegramURL is the Telegram Bot API, followed by the bot token
+const telegramURL = "https://api.telegram.org/bot"
+
+// Telegram sends notifications from a Telegram bot to a chat
+type Telegram struct {
+	token  string
+	chat   string
+	client *http.Client
+}
+
+// NewTelegram creates a Telegram notifier for a bot token and the chat ID
+// (or @channel) the bot posts to
+func NewTelegram(token, chat string) (*Telegram, error) {
+	if token == "" || chat == "" {
+		return nil, errors.New("Telegram needs a bot token and a chat ID")
+	}
+	return &Telegram{token: token, chat: chat, client: &http.Client{Timeout: sendTimeout}}, nil
+}
+
+// Name identifies the notifier in logs
+func (t *Telegram) Name() string { return "telegram" }
+
+// Notify sends n as a message with a bold title
+func (t *Telegram) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(map[string]any{
+		"chat_id":    t.chat,
+		"text":       "<b>" + html.EscapeString(n.Title) + "</b>\n" + html.EscapeString(n.Message),
+		"parse_mode": "HTML",
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, telegramURL+t.token+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return send(t.client, req)
+}
+
+// send sends a notification request and expects a 2xx response. Errors
+// leave out the URL, which holds the token for some providers, and include
+// the start of the response, where providers explain what was wrong.
+func send(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("%s %s: %w", req.Method, req.URL.Host, urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Event types notifications are sent for
+const (
+	EventAlert           = "alert"
+	EventAlertResolved   = "alert.resolved"
+	EventContainerDied   = "container.died"
+	EventOperationFailed = "operation.failed"
+	EventUpdateApplied   = "update.applied"
+	EventProbeDown       = "probe.down"
+	EventProbeUp         = "probe.up"
+)
+
+// EventTypes lists every event type a rule can match
+var EventTypes = []string{EventAlert, EventAlertResolved, EventContainerDied, EventOperationFailed, EventUpdateApplied, EventProbeDown, EventProbeUp}
+
+// levels orders notification levels by severity
+var levels = map[string]int{"info": 0, "warning": 1, "error": 2}
+
+// notifierKinds are the first word of each Notifier's Name, which rules
+// select notifiers by
+var notifierKinds = []string{"webhook", "gotify", "pushover", "telegram"}
+
+// Rule decides whether and where matching notifications are sent. Empty
+// match fields match anything.
+type Rule struct {
+	Name     string   `yaml:"name"`
+	Events   []string `yaml:"events"`
+	Projects []string `yaml:"projects"` // glob patterns, e.g. media-*
+	Level    string   `yaml:"level"`    // the least severe level matched
+
+	Notifiers  []string      `yaml:"notifiers"`  // kinds, e.g. telegram; all by default
+	Drop       bool          `yaml:"drop"`       // discard matches instead of sending them
+	Dedup      time.Duration `yaml:"dedup"`      // hold back repeats of a notification for this long
+	QuietHours string        `yaml:"quietHours"` // local time range, e.g. 22:00-07:00
+
+	quietFrom, quietTo int // minutes after midnight
+}
+
+// LoadRules reads notification rules from a YAML file holding a list of
+// rules, checked in order
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid notification rules file: %w", err)
+	}
+	for i := range rules {
+		if err := rules[i].validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return rules, nil
+}
+
+func (r *Rule) validate() error {
+	if r.Name == "" {
+		r.Name = strings.Join(append(slices.Clone(r.Events), r.Projects...), ",")
+	}
+	for _, ev := range r.Events {
+		if !slices.Contains(EventTypes, ev) {
+			return fmt.Errorf("unknown event %q: expected one of %v", ev, EventTypes)
+		}
+	}
+	for _, p := range r.Projects {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid project pattern %q", p)
+		}
+	}
+	if _, ok := levels[r.Level]; r.Level != "" && !ok {
+		return fmt.Errorf("unknown level %q: expected info, warning or error", r.Level)
+	}
+	for _, k := range r.Notifiers {
+		if !slices.Contains(notifierKinds, k) {
+			return fmt.Errorf("unknown notifier %q: expected one of %v", k, notifierKinds)
+		}
+	}
+	if r.Dedup < 0 {
+		return fmt.Errorf("dedup must not be negative")
+	}
+	if r.QuietHours != "" {
+		from, to, ok := strings.Cut(r.QuietHours, "-")
+		var errFrom, errTo error
+		r.quietFrom, errFrom = parseClock(from)
+		r.quietTo, errTo = parseClock(to)
+		if !ok || errFrom != nil || errTo != nil {
+			return fmt.Errorf("invalid quietHours %q: expected HH:MM-HH:MM", r.QuietHours)
+		}
+	}
+	return nil
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	hours, err1 := strconv.Atoi(h)
+	minutes, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hours < 0 || hours > 24 || minutes < 0 || minutes > 59 || hours*60+minutes > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return hours*60 + minutes, nil
+}
+
+// matches reports whether n falls under the rule
+func (r *Rule) matches(n Notification) bool {
+	if len(r.Events) > 0 && !slices.Contains(r.Events, n.Event) {
+		return false
+	}
+	if len(r.Projects) > 0 && !slices.ContainsFunc(r.Projects, func(p string) bool {
+		ok, _ := path.Match(p, n.Project)
+		return ok
+	}) {
+		return false
+	}
+	return r.Level == "" || levels[n.Level] >= levels[r.Level]
+}
+
+// quiet reports whether t falls in the rule's quiet hours, which wrap past
+// midnight when they end earlier than they start
+func (r *Rule) quiet(t time.Time) bool {
+	if r.QuietHours == "" {
+		return false
+	}
+	t = t.Local()
+	now := t.Hour()*60 + t.Minute()
+	if r.quietFrom <= r.quietTo {
+		return now >= r.quietFrom && now < r.quietTo
+	}
+	return now >= r.quietFrom || now < r.quietTo
+}
+
+// router applies rules to notifications, remembering what each rule sent
+// recently to hold back duplicates
+type router struct {
+	rules []Rule
+	sent  map[string]*dedupState // rule index + "/" + notification key
+	mu    sync.Mutex
+}
+
+// dedupState is a notification sent recently and the repeats held back
+// since, which are counted in the next one sent after the window
+type dedupState struct {
+	at         time.Time
+	until      time.Time
+	suppressed int
+}
+
+// route returns the notification to send, with a count of held back
+// repeats added to its message, and the rule that matched. ok is false if
+// it shouldn't be sent.
+func (r *router) route(n Notification) (out Notification, rule *Rule, ok bool) {
+	idx := slices.IndexFunc(r.rules, func(rule Rule) bool { return rule.matches(n) })
+	if idx < 0 {
+		return n, nil, false
+	}
+	rule = &r.rules[idx]
+	if rule.Drop || rule.quiet(n.Time) {
+		return n, rule, false
+	}
+	if rule.Dedup <= 0 {
+		return n, rule, true
+	}
+
+	key := n.Key
+	if key == "" {
+		key = n.Event + "/" + n.Title
+	}
+	key = strconv.Itoa(idx) + "/" + key
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, seen := r.sent[key]; seen {
+		if n.Time.Before(s.until) {
+			s.suppressed++
+			return n, rule, false
+		}
+		if s.suppressed > 0 {
+			n.Message += fmt.Sprintf(" (%d similar held back since %s)", s.suppressed, s.at.Local().Format("15:04"))
+		}
+	}
+	// Expired entries go, unless they hold a count still to be reported
+	for k, s := range r.sent {
+		if !n.Time.Before(s.until) && s.suppressed == 0 {
+			delete(r.sent, k)
+		}
+	}
+	r.sent[key] = &dedupState{at: n.Time, until: n.Time.Add(rule.Dedup)}
+	return n, rule, true
+}
@@ -0,0 +1,63 @@
+package notify
+
+// Targets says where notifications go. A provider is enabled by its
+// destination (Gotify URL, Pushover user, Telegram chat); its token is then
+// required.
+type Targets struct {
+	Webhooks []string       `json:"webhooks"`
+	Gotify   GotifyTarget   `json:"gotify"`
+	Pushover PushoverTarget `json:"pushover"`
+	Telegram TelegramTarget `json:"telegram"`
+}
+
+// GotifyTarget is a Gotify server and application token
+type GotifyTarget struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// PushoverTarget is a Pushover user or group key and application token
+type PushoverTarget struct {
+	User  string `json:"user"`
+	Token string `json:"token"`
+}
+
+// TelegramTarget is a Telegram chat and bot token
+type TelegramTarget struct {
+	Chat  string `json:"chat"`
+	Token string `json:"token"`
+}
+
+// Notifiers creates the notifiers for the targets
+func (t Targets) Notifiers() ([]Notifier, error) {
+	var notifiers []Notifier
+	for _, u := range t.Webhooks {
+		wh, err := NewWebhook(u)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, wh)
+	}
+	if t.Gotify.URL != "" {
+		g, err := NewGotify(t.Gotify.URL, t.Gotify.Token)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, g)
+	}
+	if t.Pushover.User != "" {
+		p, err := NewPushover(t.Pushover.Token, t.Pushover.User)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, p)
+	}
+	if t.Telegram.Chat != "" {
+		tg, err := NewTelegram(t.Telegram.Token, t.Telegram.Chat)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, tg)
+	}
+	return notifiers, nil
+}
@@ -0,0 +1,462 @@
+// Package probe checks the HTTP health URLs services declare, alongside
+// Docker's own healthchecks, which only see a service from inside its
+// container.
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/notify"
+	"github.com/lyall/gosei/internal/project"
+	"github.com/lyall/gosei/internal/sse"
+	"github.com/lyall/gosei/internal/store"
+)
+
+// Labels declaring a service's health URL and how often it is checked
+const (
+	LabelURL      = project.LabelPrefix + "healthcheck.url"
+	LabelInterval = project.LabelPrefix + "healthcheck.interval"
+)
+
+const (
+	// DefaultInterval is how often a URL is checked unless set otherwise
+	DefaultInterval = 30 * time.Second
+
+	// minInterval keeps a typo from hammering a service
+	minInterval = 5 * time.Second
+
+	// checkTimeout bounds one check, and is shorter for shorter intervals
+	checkTimeout = 10 * time.Second
+
+	// failThreshold is how many checks in a row must fail before a URL is
+	// down, so one dropped request doesn't notify anyone
+	failThreshold = 2
+)
+
+// Statuses of a probe
+const (
+	StatusPending = "pending" // not checked yet
+	StatusUp      = "up"
+	StatusDown    = "down"
+	StatusStopped = "stopped" // the service isn't running, so isn't checked
+)
+
+// ErrNotFound is returned for a project or service without an API target
+var ErrNotFound = errors.New("probe not found")
+
+// Target is a URL to check for a service
+type Target struct {
+	URL      string `json:"url"`
+	Interval string `json:"interval,omitempty"` // a duration, DefaultInterval when empty
+}
+
+// Validate checks the URL and interval
+func (t Target) Validate() error {
+	_, err := t.interval()
+	return err
+}
+
+// interval validates the target and returns how often it is checked
+func (t Target) interval() (time.Duration, error) {
+	u, err := url.Parse(t.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return 0, fmt.Errorf("invalid health URL %q", t.URL)
+	}
+	if t.Interval == "" {
+		return DefaultInterval, nil
+	}
+	d, err := time.ParseDuration(t.Interval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval %q", t.Interval)
+	}
+	if d < minInterval {
+		return 0, fmt.Errorf("interval %s is too short (minimum %s)", d, minInterval)
+	}
+	return d, nil
+}
+
+// Result is the latest outcome of checking a service's URL
+type Result struct {
+	ProjectID  string     `json:"projectId"`
+	Project    string     `json:"project"` // compose project name
+	Service    string     `json:"service"`
+	URL        string     `json:"url"`
+	Source     string     `json:"source"` // "label" or "api"
+	Interval   string     `json:"interval"`
+	Status     string     `json:"status"`
+	StatusCode int        `json:"statusCode,omitempty"`
+	LatencyMs  int64      `json:"latencyMs,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Checked    *time.Time `json:"checked,omitempty"`
+	Since      *time.Time `json:"since,omitempty"` // when the status last changed
+
+	failures int
+	checking bool
+	next     time.Time // when the next check is due
+	interval time.Duration
+}
+
+// Topic publishes results as probe:status
+func (Result) Topic() sse.Topic { return sse.TopicProbeStatus }
+
+// Prober checks the health URLs of running services on their intervals,
+// publishing each result and notifying when a URL goes down or comes back
+type Prober struct {
+	docker   docker.DockerClient
+	scanner  *project.Scanner
+	broker   *sse.Broker
+	notifier *notify.Dispatcher
+	client   *http.Client
+
+	saved   store.Doc                    // where API targets are saved, if anywhere
+	targets map[string]map[string]Target // set through the API, by project ID and service
+	results map[string]*Result           // by project ID + "/" + service
+	mu      sync.Mutex
+}
+
+// New creates a prober. notifier may be nil to only publish results. API
+// targets are saved to saved and loaded from it; with no doc they last
+// until gosei exits.
+func New(dc docker.DockerClient, s *project.Scanner, b *sse.Broker, notifier *notify.Dispatcher, saved store.Doc) (*Prober, error) {
+	p := &Prober{
+		docker:   dc,
+		scanner:  s,
+		broker:   b,
+		notifier: notifier,
+		client:   &http.Client{Timeout: checkTimeout},
+		saved:    saved,
+		targets:  make(map[string]map[string]Target),
+		results:  make(map[string]*Result),
+	}
+	if saved == nil {
+		return p, nil
+	}
+	if _, err := saved.Load(&p.targets); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Run checks URLs as they fall due until ctx is cancelled
+func (p *Prober) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		p.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick brings the results in line with the declared targets and starts the
+// checks that are due
+func (p *Prober) tick(ctx context.Context) {
+	seen := make(map[string]bool)
+	for _, proj := range p.scanner.ListProjects() {
+		targets := p.projectTargets(proj)
+		if len(targets) == 0 {
+			continue
+		}
+
+		// Listed once a check of the project is due, since only running
+		// services are checked
+		var running map[string]bool
+		for service, t := range targets {
+			key := proj.ID + "/" + service
+			seen[key] = true
+			if !p.isDue(key, t) {
+				continue
+			}
+			if running == nil {
+				containers, err := p.docker.ListContainers(ctx, proj.Name)
+				if err != nil {
+					slog.Debug("Failed to list containers for probes", "project", proj.Name, "error", err)
+					break
+				}
+				running = make(map[string]bool)
+				for _, c := range containers {
+					if c.State == "running" {
+						running[c.ServiceName] = true
+					}
+				}
+			}
+			check, stopped := p.due(key, proj, service, t, running[service])
+			if stopped != nil {
+				p.broker.Publish(*stopped)
+			}
+			if check != nil {
+				go p.check(ctx, key, *check)
+			}
+		}
+	}
+
+	p.mu.Lock()
+	for key := range p.results {
+		if !seen[key] {
+			delete(p.results, key)
+		}
+	}
+	p.mu.Unlock()
+}
+
+// sourcedTarget is a target and where it was declared
+type sourcedTarget struct {
+	Target
+	source   string
+	interval time.Duration
+}
+
+// projectTargets returns a project's valid targets by service. API targets
+// override labels.
+func (p *Prober) projectTargets(proj *project.Project) map[string]sourcedTarget {
+	targets := make(map[string]sourcedTarget)
+	for _, svc := range proj.Services {
+		t := Target{URL: svc.Labels[LabelURL], Interval: svc.Labels[LabelInterval]}
+		if t.URL == "" {
+			continue
+		}
+		interval, err := t.interval()
+		if err != nil {
+			slog.Debug("Ignoring invalid health URL label", "project", proj.Name, "service", svc.Name, "error", err)
+			continue
+		}
+		targets[svc.Name] = sourcedTarget{Target: t, source: "label", interval: interval}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for service, t := range p.targets[proj.ID] {
+		interval, _ := t.interval() // validated when set
+		targets[service] = sourcedTarget{Target: t, source: "api", interval: interval}
+	}
+	return targets
+}
+
+// isDue reports whether a service's URL is due to be checked, or is new
+func (p *Prober) isDue(key string, t sourcedTarget) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r, ok := p.results[key]
+	if !ok || r.URL != t.URL || r.interval != t.interval {
+		return true
+	}
+	return !r.checking && !time.Now().Before(r.next)
+}
+
+// due updates a due service's result for its current target and whether it
+// is running. It returns a copy to check, or to publish if the service just
+// stopped.
+func (p *Prober) due(key string, proj *project.Project, service string, t sourcedTarget, running bool) (check, stopped *Result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r, ok := p.results[key]
+	if !ok || r.URL != t.URL || r.interval != t.interval {
+		r = &Result{ProjectID: proj.ID, Project: proj.Name, Service: service, Status: StatusPending}
+		p.results[key] = r
+	}
+	r.URL, r.Source, r.interval, r.Interval = t.URL, t.source, t.interval, t.interval.String()
+	r.next = time.Now().Add(r.interval)
+
+	if !running {
+		if r.Status != StatusStopped {
+			now := time.Now()
+			r.Status, r.Since, r.failures, r.StatusCode, r.LatencyMs, r.Error = StatusStopped, &now, 0, 0, 0, ""
+			result := *r
+			return nil, &result
+		}
+		return nil, nil
+	}
+	if r.Status == StatusStopped {
+		r.Status = StatusPending
+	}
+	r.checking = true
+	result := *r
+	return &result, nil
+}
+
+// check requests a URL and records the outcome
+func (p *Prober) check(ctx context.Context, key string, r Result) {
+	ctx, cancel := context.WithTimeout(ctx, min(checkTimeout, r.interval))
+	defer cancel()
+
+	code, latency, err := p.get(ctx, r.URL)
+	now := time.Now()
+
+	p.mu.Lock()
+	cur, ok := p.results[key]
+	if !ok || cur.URL != r.URL {
+		// Changed or removed while checking
+		p.mu.Unlock()
+		return
+	}
+	cur.checking = false
+	cur.Checked, cur.StatusCode, cur.LatencyMs, cur.Error = &now, code, latency.Milliseconds(), ""
+
+	prev := cur.Status
+	if err != nil {
+		cur.Error = err.Error()
+		cur.failures++
+		if cur.failures >= failThreshold || prev == StatusDown {
+			cur.Status = StatusDown
+		}
+	} else {
+		cur.failures = 0
+		cur.Status = StatusUp
+	}
+	if cur.Status != prev {
+		cur.Since = &now
+	}
+	result := *cur
+	p.mu.Unlock()
+
+	p.broker.Publish(result)
+	if result.Status != prev {
+		p.notify(prev, result)
+	}
+}
+
+// get requests rawURL and returns the status code and how long the response
+// took. Statuses of 400 and above are errors.
+func (p *Prober) get(ctx context.Context, rawURL string) (int, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("User-Agent", "gosei-probe")
+
+	start := time.Now()
+	resp, err := p.client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return 0, latency, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return resp.StatusCode, latency, fmt.Errorf("returned %s", resp.Status)
+	}
+	return resp.StatusCode, latency, nil
+}
+
+// notify reports a URL going down, or coming back up after being down
+func (p *Prober) notify(prev string, r Result) {
+	if p.notifier == nil {
+		return
+	}
+	n := notify.Notification{
+		Project: r.Project,
+		Time:    *r.Checked,
+		Key:     r.ProjectID + "/" + r.Service,
+	}
+	switch {
+	case r.Status == StatusDown:
+		slog.Warn("Health URL down", "project", r.Project, "service", r.Service, "url", r.URL, "error", r.Error)
+		n.Title = fmt.Sprintf("Down: %s/%s", r.Project, r.Service)
+		n.Message = fmt.Sprintf("%s %s", r.URL, r.Error)
+		n.Level = "error"
+		n.Event = notify.EventProbeDown
+	case r.Status == StatusUp && prev == StatusDown:
+		slog.Info("Health URL back up", "project", r.Project, "service", r.Service, "url", r.URL)
+		n.Title = fmt.Sprintf("Up: %s/%s", r.Project, r.Service)
+		n.Message = fmt.Sprintf("%s responded %d in %dms", r.URL, r.StatusCode, r.LatencyMs)
+		n.Level = "info"
+		n.Event = notify.EventProbeUp
+	default:
+		return
+	}
+	p.notifier.Send(n)
+}
+
+// Results returns the latest results, sorted by project and service. An
+// empty project ID returns every project's.
+func (p *Prober) Results(projectID string) []Result {
+	p.mu.Lock()
+	results := make([]Result, 0, len(p.results))
+	for _, r := range p.results {
+		if projectID == "" || r.ProjectID == projectID {
+			results = append(results, *r)
+		}
+	}
+	p.mu.Unlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Project != results[j].Project {
+			return results[i].Project < results[j].Project
+		}
+		return results[i].Service < results[j].Service
+	})
+	return results
+}
+
+// Lookup returns the latest result for a service by compose project name,
+// or nil if it has no health URL. A nil prober has none.
+func (p *Prober) Lookup(projectName, service string) *Result {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, r := range p.results {
+		if r.Project == projectName && r.Service == service {
+			result := *r
+			return &result
+		}
+	}
+	return nil
+}
+
+// SetTarget sets a service's health URL, overriding its labels
+func (p *Prober) SetTarget(projectID, service string, t Target) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.targets[projectID] == nil {
+		p.targets[projectID] = make(map[string]Target)
+	}
+	p.targets[projectID][service] = t
+	return p.save()
+}
+
+// RemoveTarget removes a health URL set through the API; labels apply again
+func (p *Prober) RemoveTarget(projectID, service string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.targets[projectID][service]; !ok {
+		return ErrNotFound
+	}
+	delete(p.targets[projectID], service)
+	if len(p.targets[projectID]) == 0 {
+		delete(p.targets, projectID)
+	}
+	return p.save()
+}
+
+// save saves the API targets. Callers hold mu.
+func (p *Prober) save() error {
+	if p.saved == nil {
+		return nil
+	}
+	if err := p.saved.Save(p.targets); err != nil {
+		return fmt.Errorf("failed to save probes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package project
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// ManifestName is the name of the metadata file inside a bundle
+const ManifestName = "gosei.json"
+
+// bundleVersion is bumped when the bundle layout changes incompatibly
+const bundleVersion = 1
+
+// maxBundleFile caps the size of any single file read from a bundle
+const maxBundleFile = 10 << 20
+
+// ErrProjectExists is returned when importing over an existing project
+var ErrProjectExists = errors.New("project already exists")
+
+// projectName matches directory names that are safe to create for an import
+var projectName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Manifest describes the contents of a project bundle
+type Manifest struct {
+	Version     int               `json:"version"`
+	Name        string            `json:"name"`
+	ComposeFile string            `json:"composeFile"`
+	EnvFiles    []string          `json:"envFiles"`
+	Labels      map[string]string `json:"labels"`
+	ExportedAt  time.Time         `json:"exportedAt"`
+}
+
+// Export writes a gzipped tar bundle of a project's compose file, env files
+// and a manifest to w. Other files in the project directory (build contexts,
+// bind-mounted data) are not included.
+func Export(p *Project, w io.Writer) error {
+	manifest := Manifest{
+		Version:     bundleVersion,
+		Name:        filepath.Base(p.Path),
+		ComposeFile: filepath.Base(p.ComposeFile),
+		EnvFiles:    p.EnvFiles,
+		Labels:      p.Labels,
+		ExportedAt:  time.Now().UTC(),
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := writeBundleFile(tw, ManifestName, data, 0o644, manifest.ExportedAt); err != nil {
+		return err
+	}
+	for _, name := range append([]string{manifest.ComposeFile}, p.EnvFiles...) {
+		path := filepath.Join(p.Path, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if err := writeBundleFile(tw, name, data, int64(info.Mode().Perm()), info.ModTime()); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeBundleFile(tw *tar.Writer, name string, data []byte, mode int64, mtime time.Time) error {
+	hdr := &tar.Header{
+		Name:     name,
+		Mode:     mode,
+		Size:     int64(len(data)),
+		ModTime:  mtime,
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Import unpacks a bundle created by Export into a new project directory
+// and adds the project. name overrides the bundled project name when set.
+func (s *Scanner) Import(r io.Reader, name string) (*Project, error) {
+	manifest, files, err := readBundle(r)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = manifest.Name
+	}
+	if !projectName.MatchString(name) {
+		return nil, fmt.Errorf("invalid project name %q", name)
+	}
+
+	// A concurrent Scan would replace the project map without this project
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Join(s.baseDir, name)
+	// Mkdir rather than MkdirAll so an existing directory is never overwritten
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("%w: %s", ErrProjectExists, name)
+		}
+		return nil, fmt.Errorf("failed to create project directory: %w", err)
+	}
+
+	for fname, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, fname), f.data, f.mode); err != nil {
+			os.RemoveAll(dir)
+			return nil, fmt.Errorf("failed to write %s: %w", fname, err)
+		}
+	}
+
+	project, err := s.parseProject(filepath.Join(dir, manifest.ComposeFile))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	s.projects[project.ID] = project
+	return project, nil
+}
+
+type bundleFile struct {
+	data []byte
+	mode os.FileMode
+}
+
+// readBundle reads and validates a bundle. Only the files named in the
+// manifest are accepted, and only as plain names without directories.
+func readBundle(r io.Reader) (*Manifest, map[string]bundleFile, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest *Manifest
+	files := make(map[string]bundleFile)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || hdr.Name != filepath.Base(hdr.Name) || hdr.Name == "." || hdr.Name == ".." {
+			return nil, nil, fmt.Errorf("invalid bundle: unexpected entry %q", hdr.Name)
+		}
+		if hdr.Size > maxBundleFile {
+			return nil, nil, fmt.Errorf("invalid bundle: %s is too large", hdr.Name)
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+		if hdr.Name == ManifestName {
+			manifest = &Manifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			continue
+		}
+		files[hdr.Name] = bundleFile{data: data, mode: os.FileMode(hdr.Mode).Perm() | 0o600}
+	}
+
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("invalid bundle: missing %s", ManifestName)
+	}
+	if manifest.Version != bundleVersion {
+		return nil, nil, fmt.Errorf("unsupported bundle version %d", manifest.Version)
+	}
+
+	allowed := map[string]bool{manifest.ComposeFile: true}
+	for _, name := range manifest.EnvFiles {
+		allowed[name] = true
+	}
+	for name := range files {
+		if !allowed[name] {
+			return nil, nil, fmt.Errorf("invalid bundle: %s is not listed in the manifest", name)
+		}
+	}
+	if _, ok := files[manifest.ComposeFile]; !ok || !isComposeFileName(manifest.ComposeFile) {
+		return nil, nil, fmt.Errorf("invalid bundle: missing compose file")
+	}
+	return manifest, files, nil
+}
+
+func isComposeFileName(name string) bool {
+	for _, n := range composeFileNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package project
+
+import (
+	"regexp"
+	"strings"
+)
+
+// IconLabel sets a service's icon, as the name of a well-known icon or an
+// http(s) URL of an image
+const IconLabel = LabelPrefix + "icon"
+
+// iconName matches the names icons are looked up by
+var iconName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// imageIcons maps the names of common images, without registry, namespace
+// or tag, to icon names. Names follow the dashboard-icons and Simple Icons
+// slugs, so a UI can look them up in either set.
+var imageIcons = map[string]string{
+	// Databases and caches
+	"postgres":      "postgres",
+	"postgis":       "postgres",
+	"timescaledb":   "postgres",
+	"mysql":         "mysql",
+	"mariadb":       "mariadb",
+	"mongo":         "mongodb",
+	"mongodb":       "mongodb",
+	"redis":         "redis",
+	"redis-stack":   "redis",
+	"valkey":        "valkey",
+	"keydb":         "redis",
+	"memcached":     "memcached",
+	"influxdb":      "influxdb",
+	"clickhouse":    "clickhouse",
+	"couchdb":       "couchdb",
+	"cassandra":     "cassandra",
+	"neo4j":         "neo4j",
+	"elasticsearch": "elasticsearch",
+	"opensearch":    "opensearch",
+	"meilisearch":   "meilisearch",
+	"adminer":       "adminer",
+	"pgadmin4":      "pgadmin",
+	"phpmyadmin":    "phpmyadmin",
+
+	// Web servers and proxies
+	"nginx":               "nginx",
+	"nginx-unprivileged":  "nginx",
+	"openresty":           "nginx",
+	"nginx-proxy-manager": "nginx-proxy-manager",
+	"httpd":               "apache",
+	"caddy":               "caddy",
+	"traefik":             "traefik",
+	"haproxy":             "haproxy",
+	"envoy":               "envoy",
+	"cloudflared":         "cloudflare",
+	"wireguard":           "wireguard",
+	"wg-easy":             "wireguard",
+	"certbot":             "letsencrypt",
+	"oauth2-proxy":        "oauth2-proxy",
+	"authelia":            "authelia",
+	"authentik":           "authentik",
+	"keycloak":            "keycloak",
+	"vaultwarden":         "vaultwarden",
+	"adguardhome":         "adguard-home",
+	"pihole":              "pi-hole",
+	"unbound":             "unbound",
+
+	// Messaging
+	"rabbitmq":  "rabbitmq",
+	"kafka":     "apache-kafka",
+	"nats":      "nats",
+	"mosquitto": "mosquitto",
+	"emqx":      "emqx",
+
+	// Monitoring
+	"grafana":       "grafana",
+	"prometheus":    "prometheus",
+	"alertmanager":  "prometheus",
+	"node-exporter": "prometheus",
+	"pushgateway":   "prometheus",
+	"loki":          "loki",
+	"promtail":      "loki",
+	"tempo":         "tempo",
+	"jaeger":        "jaeger",
+	"cadvisor":      "cadvisor",
+	"uptime-kuma":   "uptime-kuma",
+	"netdata":       "netdata",
+	"telegraf":      "influxdb",
+	"kibana":        "kibana",
+	"logstash":      "logstash",
+	"dozzle":        "dozzle",
+	"portainer-ce":  "portainer",
+	"portainer":     "portainer",
+	"watchtower":    "watchtower",
+
+	// Storage and apps
+	"minio":          "minio",
+	"nextcloud":      "nextcloud",
+	"wordpress":      "wordpress",
+	"ghost":          "ghost",
+	"gitea":          "gitea",
+	"forgejo":        "forgejo",
+	"gitlab-ce":      "gitlab",
+	"gitlab-ee":      "gitlab",
+	"jenkins":        "jenkins",
+	"drone":          "drone",
+	"registry":       "docker",
+	"jellyfin":       "jellyfin",
+	"plex":           "plex",
+	"pms-docker":     "plex",
+	"emby":           "emby",
+	"sonarr":         "sonarr",
+	"radarr":         "radarr",
+	"prowlarr":       "prowlarr",
+	"qbittorrent":    "qbittorrent",
+	"transmission":   "transmission",
+	"home-assistant": "home-assistant",
+	"homeassistant":  "home-assistant",
+	"node-red":       "node-red",
+	"zigbee2mqtt":    "zigbee2mqtt",
+	"esphome":        "esphome",
+	"immich-server":  "immich",
+	"paperless-ngx":  "paperless-ngx",
+	"syncthing":      "syncthing",
+	"n8n":            "n8n",
+	"mailpit":        "mailpit",
+
+	// Language runtimes, for services built on them
+	"node":            "nodejs",
+	"python":          "python",
+	"golang":          "go",
+	"php":             "php",
+	"ruby":            "ruby",
+	"openjdk":         "java",
+	"eclipse-temurin": "java",
+	"aspnet":          "dotnet",
+	"dotnet":          "dotnet",
+	"rust":            "rust",
+	"alpine":          "alpine-linux",
+	"ubuntu":          "ubuntu",
+	"debian":          "debian",
+	"busybox":         "linux",
+}
+
+// serviceIcon returns the icon for a service: its gosei.icon label if set,
+// otherwise the icon of its image if well known
+func serviceIcon(image string, labels map[string]string) string {
+	if icon := strings.TrimSpace(labels[IconLabel]); icon != "" {
+		// Anything but a name or a URL the UI can load is ignored
+		if iconName.MatchString(icon) || httpURL(icon) {
+			return icon
+		}
+		return ""
+	}
+	return imageIcons[imageName(image)]
+}
+
+// imageName returns an image reference's name without registry, namespace,
+// tag or digest, e.g. postgres for docker.io/library/postgres:16-alpine
+func imageName(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	name, _, _ = strings.Cut(name, ":")
+	return strings.ToLower(name)
+}
@@ -0,0 +1,76 @@
+package project
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// LinkLabelPrefix names a quick link in a service label, as
+// gosei.link.<name>: <url>
+const LinkLabelPrefix = LabelPrefix + "link."
+
+// Link is a quick link from a project to something about it, such as its
+// app, docs or monitoring
+type Link struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// extension is the top-level x-gosei block of a compose file, for gosei
+// settings that belong to the project rather than a service
+type extension struct {
+	// Links maps names to URLs, or lists {name, url} to keep their order
+	Links interface{} `yaml:"links"`
+}
+
+// projectLinks collects a project's links: those of x-gosei first, then
+// gosei.link.* labels by name. A name is used once, the first time it is
+// seen. Variables are interpolated as compose does; links that aren't
+// http(s) URLs are left out, since they're rendered as links.
+func projectLinks(ext extension, labels map[string]string, env map[string]string) []Link {
+	var candidates []Link
+	switch l := ext.Links.(type) {
+	case map[string]interface{}:
+		for name, u := range l {
+			candidates = append(candidates, Link{Name: name, URL: fmt.Sprint(u)})
+		}
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].Name < candidates[j].Name })
+	case []interface{}:
+		for _, item := range l {
+			if m, ok := item.(map[string]interface{}); ok {
+				name, _ := m["name"].(string)
+				u, _ := m["url"].(string)
+				candidates = append(candidates, Link{Name: name, URL: u})
+			}
+		}
+	}
+
+	var fromLabels []Link
+	for k, v := range labels {
+		if name, ok := strings.CutPrefix(k, LinkLabelPrefix); ok {
+			fromLabels = append(fromLabels, Link{Name: name, URL: v})
+		}
+	}
+	sort.Slice(fromLabels, func(i, j int) bool { return fromLabels[i].Name < fromLabels[j].Name })
+	candidates = append(candidates, fromLabels...)
+
+	var links []Link
+	seen := make(map[string]bool)
+	for _, link := range candidates {
+		link.URL = strings.TrimSpace(interpolate(link.URL, env))
+		if link.Name == "" || seen[link.Name] || !httpURL(link.URL) {
+			continue
+		}
+		seen[link.Name] = true
+		links = append(links, link)
+	}
+	return links
+}
+
+// httpURL reports whether s is an absolute http(s) URL
+func httpURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
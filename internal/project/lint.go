@@ -0,0 +1,359 @@
+package project
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Issue is a problem found in a compose file
+type Issue struct {
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"` // "error" or "warning"
+	Message  string `json:"message"`
+}
+
+// LintResult is the outcome of validating a compose file
+type LintResult struct {
+	Valid  bool    `json:"valid"` // no errors; warnings are allowed
+	Issues []Issue `json:"issues"`
+}
+
+// topLevelKeys are the keys the compose specification allows at the root
+var topLevelKeys = keySet("version", "name", "include", "services", "networks", "volumes", "configs", "secrets", "models")
+
+// serviceKeys are the keys the compose specification allows in a service
+var serviceKeys = keySet(
+	"annotations", "attach", "blkio_config", "build", "cap_add", "cap_drop", "cgroup", "cgroup_parent",
+	"command", "configs", "container_name", "cpu_count", "cpu_percent", "cpu_period", "cpu_quota",
+	"cpu_rt_period", "cpu_rt_runtime", "cpu_shares", "cpus", "cpuset", "credential_spec", "depends_on",
+	"deploy", "develop", "device_cgroup_rules", "devices", "dns", "dns_opt", "dns_search", "domainname",
+	"driver_opts", "entrypoint", "env_file", "environment", "expose", "extends", "external_links",
+	"extra_hosts", "gpus", "group_add", "healthcheck", "hostname", "image", "init", "ipc", "isolation",
+	"label_file", "labels", "links", "logging", "mac_address", "mem_limit", "mem_reservation",
+	"mem_swappiness", "memswap_limit", "models", "network_mode", "networks", "oom_kill_disable",
+	"oom_score_adj", "pid", "pids_limit", "platform", "ports", "post_start", "pre_stop", "privileged",
+	"profiles", "provider", "pull_policy", "read_only", "restart", "runtime", "scale", "secrets",
+	"security_opt", "shm_size", "stdin_open", "stop_grace_period", "stop_signal", "storage_opt",
+	"sysctls", "tmpfs", "tty", "ulimits", "use_api_socket", "user", "userns_mode", "uts", "volumes",
+	"volumes_from", "working_dir",
+)
+
+func keySet(keys ...string) map[string]bool {
+	m := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		m[k] = true
+	}
+	return m
+}
+
+// yamlErrorLine extracts the line number from yaml.v3 errors ("yaml: line 3: ...")
+var yamlErrorLine = regexp.MustCompile(`line (\d+): (.*)`)
+
+// Lint validates a compose file: YAML syntax, unknown keys, services without
+// an image or build, host ports published twice and, when env is not nil,
+// variables that interpolation can't resolve.
+func Lint(data []byte, env map[string]string) LintResult {
+	l := &linter{}
+	l.lint(data, env)
+
+	sort.SliceStable(l.issues, func(i, j int) bool { return l.issues[i].Line < l.issues[j].Line })
+	result := LintResult{Valid: true, Issues: l.issues}
+	if result.Issues == nil {
+		result.Issues = []Issue{}
+	}
+	for _, is := range result.Issues {
+		if is.Severity == "error" {
+			result.Valid = false
+		}
+	}
+	return result
+}
+
+type linter struct {
+	issues []Issue
+}
+
+func (l *linter) add(n *yaml.Node, severity, format string, args ...interface{}) {
+	is := Issue{Severity: severity, Message: fmt.Sprintf(format, args...)}
+	if n != nil {
+		is.Line, is.Column = n.Line, n.Column
+	}
+	l.issues = append(l.issues, is)
+}
+
+func (l *linter) lint(data []byte, env map[string]string) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		is := Issue{Severity: "error", Message: err.Error()}
+		if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+			is.Line, _ = strconv.Atoi(m[1])
+			is.Message = m[2]
+		}
+		l.issues = append(l.issues, is)
+		return
+	}
+	if len(doc.Content) == 0 {
+		l.add(nil, "error", "compose file is empty")
+		return
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		l.add(root, "error", "compose file must be a mapping")
+		return
+	}
+
+	var services *yaml.Node
+	for i := 0; i < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch {
+		case key.Value == "services":
+			services = value
+		case key.Value == "version":
+			l.add(key, "warning", "version is obsolete and ignored by docker compose")
+		case !topLevelKeys[key.Value] && !strings.HasPrefix(key.Value, "x-"):
+			l.add(key, "error", "unknown top-level key %q", key.Value)
+		}
+	}
+
+	if services == nil {
+		l.add(root, "error", "no services defined")
+	} else {
+		l.lintServices(services)
+	}
+
+	if env != nil {
+		l.issues = append(l.issues, variableIssues(&doc, env)...)
+	}
+}
+
+// variableIssues reports variable references that interpolation with env
+// can't resolve: an error when the variable is required, else a warning
+func variableIssues(doc *yaml.Node, env map[string]string) []Issue {
+	var issues []Issue
+	for _, ref := range Variables(doc) {
+		if _, ok := env[ref.Name]; ok || ref.HasDefault {
+			continue
+		}
+		is := Issue{Line: ref.Line, Column: ref.node.Column, Severity: "warning",
+			Message: fmt.Sprintf("variable %s is not set and defaults to an empty string", ref.Name)}
+		if ref.Required {
+			is.Severity = "error"
+			is.Message = fmt.Sprintf("required variable %s is not set", ref.Name)
+		}
+		issues = append(issues, is)
+	}
+	return issues
+}
+
+// UnresolvedVariables lists the variables of a compose file that are
+// neither set in env nor have a default, for warning before an up fails
+func UnresolvedVariables(data []byte, env map[string]string) []Issue {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+	return variableIssues(&doc, env)
+}
+
+func (l *linter) lintServices(services *yaml.Node) {
+	if services.Kind != yaml.MappingNode {
+		l.add(services, "error", "services must be a mapping")
+		return
+	}
+
+	published := make(map[string][]portBinding) // "port/proto" -> bindings
+	for i := 0; i < len(services.Content); i += 2 {
+		name, svc := services.Content[i].Value, services.Content[i+1]
+		if svc.Kind != yaml.MappingNode {
+			l.add(svc, "error", "service %s must be a mapping", name)
+			continue
+		}
+
+		var hasImage bool
+		for j := 0; j < len(svc.Content); j += 2 {
+			key, value := svc.Content[j], svc.Content[j+1]
+			switch {
+			case key.Value == "image" || key.Value == "build" || key.Value == "extends" || key.Value == "provider":
+				hasImage = true
+			case key.Value == "ports":
+				l.lintPorts(name, value, published)
+			case !serviceKeys[key.Value] && !strings.HasPrefix(key.Value, "x-"):
+				l.add(key, "error", "service %s: unknown key %q", name, key.Value)
+			}
+		}
+		if !hasImage {
+			l.add(services.Content[i], "error", "service %s has neither an image nor a build section", name)
+		}
+	}
+}
+
+// lintPorts reports host ports published by more than one service, which
+// fails when the second container starts
+func (l *linter) lintPorts(service string, ports *yaml.Node, published map[string][]portBinding) {
+	if ports.Kind != yaml.SequenceNode {
+		l.add(ports, "error", "service %s: ports must be a list", service)
+		return
+	}
+
+	for _, p := range ports.Content {
+		var ip, host, proto string
+		switch p.Kind {
+		case yaml.ScalarNode:
+			ip, host, proto = parsePortSpec(p.Value)
+		case yaml.MappingNode:
+			proto = "tcp"
+			for k := 0; k < len(p.Content); k += 2 {
+				switch p.Content[k].Value {
+				case "published":
+					host = p.Content[k+1].Value
+				case "host_ip":
+					ip = p.Content[k+1].Value
+				case "protocol":
+					proto = p.Content[k+1].Value
+				}
+			}
+		}
+		// Ranges and ports chosen by the engine can't collide predictably
+		if host == "" || strings.ContainsAny(host, "-$") {
+			continue
+		}
+
+		key := host + "/" + proto
+		for _, b := range published[key] {
+			if b.ip == ip || anyAddr(b.ip) || anyAddr(ip) {
+				l.add(p, "error", "service %s: host port %s is already published by service %s", service, key, b.service)
+				break
+			}
+		}
+		published[key] = append(published[key], portBinding{ip: ip, service: service})
+	}
+}
+
+// portBinding is a host address a service publishes a port on
+type portBinding struct {
+	ip      string
+	service string
+}
+
+// anyAddr reports whether ip binds every interface
+func anyAddr(ip string) bool {
+	return ip == "" || ip == "0.0.0.0" || ip == "::"
+}
+
+// parsePortSpec splits the short port syntax [ip:][host:]container[/proto]
+func parsePortSpec(spec string) (ip, host, proto string) {
+	proto = "tcp"
+	if s, p, ok := strings.Cut(spec, "/"); ok {
+		spec, proto = s, p
+	}
+	// IPv6 addresses are bracketed: [::1]:8080:80
+	if strings.HasPrefix(spec, "[") {
+		if end := strings.Index(spec, "]"); end > 0 {
+			ip, spec = spec[1:end], strings.TrimPrefix(spec[end+1:], ":")
+		}
+	}
+
+	parts := strings.Split(spec, ":")
+	switch len(parts) {
+	case 2:
+		host = parts[0]
+	case 3:
+		ip, host = parts[0], parts[1]
+	}
+	return ip, host, proto
+}
+
+// VarRef is a ${VAR} reference in a compose file
+type VarRef struct {
+	Name       string `json:"name"`
+	Line       int    `json:"line"`
+	HasDefault bool   `json:"-"` // ${VAR:-x} or ${VAR-x}
+	Required   bool   `json:"-"` // ${VAR:?msg} or ${VAR?msg}
+	node       *yaml.Node
+}
+
+// varPattern matches $$ escapes, $VAR and ${VAR...} with an optional
+// modifier (:-, -, :?, ?, :+, +)
+var varPattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:?[-?+])?[^}]*\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// Variables lists the variable references in every key and scalar value
+func Variables(doc *yaml.Node) []VarRef {
+	var refs []VarRef
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode && strings.Contains(n.Value, "$") {
+			for _, m := range varPattern.FindAllStringSubmatch(n.Value, -1) {
+				if m[0] == "$$" {
+					continue
+				}
+				ref := VarRef{Name: m[1], Line: n.Line, node: n}
+				if ref.Name == "" {
+					ref.Name = m[3]
+				}
+				switch m[2] {
+				case ":-", "-", ":+", "+":
+					ref.HasDefault = true
+				case ":?", "?":
+					ref.Required = true
+				}
+				refs = append(refs, ref)
+			}
+		}
+		for _, c := range n.Content {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return refs
+}
+
+// InterpolationEnv returns the variables compose interpolates a project
+// with: the project's .env file, overridden by the process environment
+func InterpolationEnv(projectDir string) map[string]string {
+	env, _ := ReadEnvFile(filepath.Join(projectDir, ".env"))
+	if env == nil {
+		env = make(map[string]string)
+	}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// ReadEnvFile parses KEY=VALUE lines, ignoring comments and blank lines
+func ReadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		k, v, _ := strings.Cut(line, "=")
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		env[strings.TrimSpace(k)] = v
+	}
+	return env, scanner.Err()
+}
@@ -0,0 +1,223 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParsePortSpec(t *testing.T) {
+	tests := []struct {
+		spec            string
+		ip, host, proto string
+	}{
+		{"80", "", "", "tcp"},
+		{"8080:80", "", "8080", "tcp"},
+		{"8080:80/udp", "", "8080", "udp"},
+		{"127.0.0.1:8080:80", "127.0.0.1", "8080", "tcp"},
+		{"127.0.0.1:8080:80/udp", "127.0.0.1", "8080", "udp"},
+		{"127.0.0.1::80", "127.0.0.1", "", "tcp"},
+		{"[::1]:8080:80", "::1", "8080", "tcp"},
+		{"[::]:53:53/udp", "::", "53", "udp"},
+		{"9000-9010:9000-9010", "", "9000-9010", "tcp"},
+		{"${PORT}:80", "", "${PORT}", "tcp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			ip, host, proto := parsePortSpec(tt.spec)
+			if ip != tt.ip || host != tt.host || proto != tt.proto {
+				t.Errorf("parsePortSpec(%q) = %q, %q, %q, want %q, %q, %q", tt.spec, ip, host, proto, tt.ip, tt.host, tt.proto)
+			}
+		})
+	}
+}
+
+func TestLintDuplicatePorts(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   string // port entries of two services
+		wantOK bool
+	}{
+		{"same host port", `"8080:80"`, `"8080:81"`, false},
+		{"different host ports", `"8080:80"`, `"8081:80"`, true},
+		{"any address and specific", `"0.0.0.0:8080:80"`, `"127.0.0.1:8080:80"`, false},
+		{"implicit any address", `"8080:80"`, `"127.0.0.1:8080:80"`, false},
+		{"IPv6 any address", `"[::]:8080:80"`, `"127.0.0.1:8080:80"`, false},
+		{"different addresses", `"127.0.0.1:8080:80"`, `"127.0.0.2:8080:80"`, true},
+		{"same address", `"127.0.0.1:8080:80"`, `"127.0.0.1:8080:81"`, false},
+		{"tcp and udp", `"53:53/udp"`, `"53:53"`, true},
+		{"explicit tcp", `"53:53/tcp"`, `"53:53"`, false},
+		{"long syntax", `{target: 80, published: "8080"}`, `"8080:80"`, false},
+		{"long syntax udp", `{target: 53, published: 53, protocol: udp}`, `"53:53"`, true},
+		{"long syntax host IP", `{target: 80, published: 8080, host_ip: 127.0.0.1}`, `"127.0.0.2:8080:80"`, true},
+		{"engine-chosen ports", `"80"`, `"80"`, true},
+		{"ranges", `"9000-9010:9000-9010"`, `"9000-9010:9000-9010"`, true},
+		{"variables", `"${PORT}:80"`, `"${PORT}:80"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := "services:\n  a:\n    image: nginx\n    ports:\n      - " + tt.a +
+				"\n  b:\n    image: nginx\n    ports:\n      - " + tt.b + "\n"
+			result := Lint([]byte(data), nil)
+			if result.Valid != tt.wantOK {
+				t.Errorf("valid = %v, want %v; issues: %+v", result.Valid, tt.wantOK, result.Issues)
+			}
+			if !tt.wantOK && (len(result.Issues) != 1 || result.Issues[0].Line != 9) {
+				t.Errorf("issues = %+v, want one on line 9", result.Issues)
+			}
+		})
+	}
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		env   map[string]string
+		valid bool
+		want  []Issue
+	}{
+		{
+			name:  "valid",
+			data:  "services:\n  web:\n    image: nginx\n    x-note: fine\nx-common: {}\n",
+			valid: true,
+			want:  []Issue{},
+		},
+		{
+			name:  "syntax error",
+			data:  "services:\n  web:\n    image: nginx\n  db: [\n",
+			valid: false,
+			want:  []Issue{{Line: 4, Severity: "error", Message: "did not find expected node content"}},
+		},
+		{
+			name:  "empty",
+			data:  "",
+			valid: false,
+			want:  []Issue{{Severity: "error", Message: "compose file is empty"}},
+		},
+		{
+			name:  "unknown keys and obsolete version",
+			data:  "version: \"3\"\nservices:\n  web:\n    image: nginx\n    imagee: typo\nservice: {}\n",
+			valid: false,
+			want: []Issue{
+				{Line: 1, Column: 1, Severity: "warning", Message: "version is obsolete and ignored by docker compose"},
+				{Line: 5, Column: 5, Severity: "error", Message: `service web: unknown key "imagee"`},
+				{Line: 6, Column: 1, Severity: "error", Message: `unknown top-level key "service"`},
+			},
+		},
+		{
+			name:  "no image",
+			data:  "services:\n  web:\n    ports: [\"80:80\"]\n",
+			valid: false,
+			want:  []Issue{{Line: 2, Column: 3, Severity: "error", Message: "service web has neither an image nor a build section"}},
+		},
+		{
+			name:  "no services",
+			data:  "networks: {}\n",
+			valid: false,
+			want:  []Issue{{Line: 1, Column: 1, Severity: "error", Message: "no services defined"}},
+		},
+		{
+			name:  "variables",
+			data:  "services:\n  web:\n    image: nginx:${TAG}\n    environment:\n      A: ${SET}\n      B: ${DEFAULTED:-x}\n      C: ${NEEDED:?set NEEDED}\n      D: $$ESCAPED\n",
+			env:   map[string]string{"SET": "1"},
+			valid: false,
+			want: []Issue{
+				{Line: 3, Column: 12, Severity: "warning", Message: "variable TAG is not set and defaults to an empty string"},
+				{Line: 7, Column: 10, Severity: "error", Message: "required variable NEEDED is not set"},
+			},
+		},
+		{
+			name:  "variables unchecked without env",
+			data:  "services:\n  web:\n    image: nginx:${TAG:?}\n",
+			valid: true,
+			want:  []Issue{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Lint([]byte(tt.data), tt.env)
+			if result.Valid != tt.valid {
+				t.Errorf("valid = %v, want %v", result.Valid, tt.valid)
+			}
+			if !reflect.DeepEqual(result.Issues, tt.want) {
+				t.Errorf("issues:\n got: %+v\nwant: %+v", result.Issues, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariables(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []VarRef
+	}{
+		{"plain", nil},
+		{"$VAR", []VarRef{{Name: "VAR"}}},
+		{"${VAR}", []VarRef{{Name: "VAR"}}},
+		{"${VAR:-default}", []VarRef{{Name: "VAR", HasDefault: true}}},
+		{"${VAR-default}", []VarRef{{Name: "VAR", HasDefault: true}}},
+		{"${VAR:+alt}", []VarRef{{Name: "VAR", HasDefault: true}}},
+		{"${VAR:?message}", []VarRef{{Name: "VAR", Required: true}}},
+		{"${VAR?}", []VarRef{{Name: "VAR", Required: true}}},
+		{"$$VAR", nil},
+		{"$${VAR}", nil},
+		{"$$$VAR", []VarRef{{Name: "VAR"}}},
+		{"echo $$HOME and ${USER}", []VarRef{{Name: "USER"}}},
+		{"$A-$B_2", []VarRef{{Name: "A"}, {Name: "B_2"}}},
+		{"costs $5", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var doc yaml.Node
+			if err := yaml.Unmarshal([]byte("key: '"+tt.value+"'\n"), &doc); err != nil {
+				t.Fatal(err)
+			}
+			refs := Variables(&doc)
+			for i := range refs {
+				if refs[i].Line != 1 {
+					t.Errorf("%s on line %d, want 1", refs[i].Name, refs[i].Line)
+				}
+				refs[i].Line, refs[i].node = 0, nil
+			}
+			if !reflect.DeepEqual(refs, tt.want) {
+				t.Errorf("Variables(%q) = %+v, want %+v", tt.value, refs, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterpolationEnv(t *testing.T) {
+	dir := t.TempDir()
+	envFile := "# comment\n\nFROM_FILE=file\nexport EXPORTED=yes\nQUOTED=\"a b\"\nSINGLE='c d'\nOVERRIDDEN=file\n"
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(envFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OVERRIDDEN", "process")
+
+	env := InterpolationEnv(dir)
+	want := map[string]string{
+		"FROM_FILE":  "file",
+		"EXPORTED":   "yes",
+		"QUOTED":     "a b",
+		"SINGLE":     "c d",
+		"OVERRIDDEN": "process",
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Errorf("%s = %q, want %q", k, env[k], v)
+		}
+	}
+	if _, ok := env["# comment"]; ok {
+		t.Error("comment read as a variable")
+	}
+
+	// Without a .env file only the process environment applies
+	env = InterpolationEnv(t.TempDir())
+	if env["OVERRIDDEN"] != "process" || env["FROM_FILE"] != "" {
+		t.Errorf("env without .env file = %v", env)
+	}
+}
@@ -0,0 +1,332 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lyall/gosei/internal/docker"
+)
+
+// maxPortRange bounds how many host ports of a range are checked for
+// conflicts
+const maxPortRange = 1024
+
+// DeclaredPort is a port mapping as a compose file declares it, with
+// variables interpolated. HostPort may be a range, or empty for a port the
+// engine picks.
+type DeclaredPort struct {
+	HostIP        string `json:"hostIp,omitempty"`
+	HostPort      string `json:"hostPort,omitempty"`
+	ContainerPort string `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+}
+
+// parsePorts parses the ports field, whose entries can be in the short
+// syntax [ip:][host:]container[/proto] or the long one, returning them as
+// written in the short syntax and as declared ports
+func parsePorts(ports interface{}, env map[string]string) ([]string, []DeclaredPort) {
+	list, _ := ports.([]interface{})
+	specs := make([]string, 0, len(list))
+	var declared []DeclaredPort
+	for _, item := range list {
+		switch p := item.(type) {
+		case map[string]interface{}:
+			d := DeclaredPort{
+				HostIP:        interpolate(stringField(p["host_ip"]), env),
+				HostPort:      interpolate(stringField(p["published"]), env),
+				ContainerPort: interpolate(stringField(p["target"]), env),
+				Protocol:      interpolate(stringField(p["protocol"]), env),
+			}
+			if d.Protocol == "" {
+				d.Protocol = "tcp"
+			}
+			specs = append(specs, d.String())
+			declared = append(declared, d)
+		case nil:
+		default:
+			spec := fmt.Sprint(p)
+			specs = append(specs, spec)
+			declared = append(declared, parseDeclaredPort(interpolate(spec, env)))
+		}
+	}
+	return specs, declared
+}
+
+// stringField formats a field of a compose mapping, which YAML may have
+// parsed as a number or bool
+func stringField(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// parseDeclaredPort parses the short port syntax [ip:][host:]container[/proto]
+func parseDeclaredPort(spec string) DeclaredPort {
+	ip, host, proto := parsePortSpec(spec)
+	spec, _, _ = strings.Cut(spec, "/")
+	container := spec[strings.LastIndex(spec, ":")+1:]
+	return DeclaredPort{HostIP: ip, HostPort: host, ContainerPort: container, Protocol: proto}
+}
+
+// String formats the port in the short syntax
+func (d DeclaredPort) String() string {
+	s := d.ContainerPort
+	if d.HostPort != "" {
+		s = d.HostPort + ":" + s
+	}
+	if d.HostIP != "" {
+		ip := d.HostIP
+		if strings.Contains(ip, ":") {
+			ip = "[" + ip + "]"
+		}
+		s = ip + ":" + s
+	}
+	if d.Protocol != "" && d.Protocol != "tcp" {
+		s += "/" + d.Protocol
+	}
+	return s
+}
+
+// hostPorts lists the host ports the mapping publishes, expanding a range;
+// none when the engine picks the port or it can't be parsed
+func (d DeclaredPort) hostPorts() []int {
+	from, to, isRange := strings.Cut(d.HostPort, "-")
+	first, err := strconv.Atoi(from)
+	if err != nil {
+		return nil
+	}
+	last := first
+	if isRange {
+		if last, err = strconv.Atoi(to); err != nil || last < first || last-first >= maxPortRange {
+			return nil
+		}
+	}
+	ports := make([]int, 0, last-first+1)
+	for p := first; p <= last; p++ {
+		ports = append(ports, p)
+	}
+	return ports
+}
+
+// PortUse is a service defined to publish a host port, or a running
+// container outside the scanned projects that publishes it
+type PortUse struct {
+	Project   string `json:"project,omitempty"` // project ID, for defined services
+	Service   string `json:"service,omitempty"`
+	Container string `json:"container,omitempty"` // for running containers
+	HostIP    string `json:"hostIp,omitempty"`
+	Spec      string `json:"spec"` // the mapping, in the short syntax
+}
+
+// PortConflict is a host port more than one service or container would
+// publish on overlapping addresses
+type PortConflict struct {
+	Port     int       `json:"port"`
+	Protocol string    `json:"protocol"`
+	Uses     []PortUse `json:"uses"`
+}
+
+// PortConflicts finds host ports that services of the projects, whether or
+// not they run, would publish on overlapping addresses, so the second to
+// start fails with "port is already allocated". Running containers that
+// publish ports but aren't services of the projects, such as standalone
+// containers, are included as they hold their ports too.
+func PortConflicts(projects []*Project, running []docker.ContainerInfo) []PortConflict {
+	type key struct {
+		port  int
+		proto string
+	}
+	uses := make(map[key][]PortUse)
+
+	defined := make(map[string]bool) // project name/service
+	for _, p := range projects {
+		for _, svc := range p.Services {
+			defined[p.Name+"/"+svc.Name] = true
+			for _, d := range svc.DeclaredPorts {
+				for _, port := range d.hostPorts() {
+					k := key{port, d.Protocol}
+					uses[k] = append(uses[k], PortUse{Project: p.ID, Service: svc.Name, HostIP: d.HostIP, Spec: d.String()})
+				}
+			}
+		}
+	}
+	for _, c := range running {
+		if c.State != "running" || defined[c.ProjectName+"/"+c.ServiceName] {
+			continue
+		}
+		for _, m := range c.Ports {
+			port, err := strconv.Atoi(m.HostPort)
+			if err != nil || port == 0 {
+				continue
+			}
+			// Docker lists a port bound on all interfaces once per address family
+			k := key{port, m.Protocol}
+			if containsContainer(uses[k], c.Name) {
+				continue
+			}
+			d := DeclaredPort{HostIP: m.HostIP, HostPort: m.HostPort, ContainerPort: m.ContainerPort, Protocol: m.Protocol}
+			uses[k] = append(uses[k], PortUse{Container: c.Name, HostIP: m.HostIP, Spec: d.String()})
+		}
+	}
+
+	conflicts := []PortConflict{}
+	for k, us := range uses {
+		if overlapping(us) {
+			conflicts = append(conflicts, PortConflict{Port: k.port, Protocol: k.proto, Uses: us})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Port != conflicts[j].Port {
+			return conflicts[i].Port < conflicts[j].Port
+		}
+		return conflicts[i].Protocol < conflicts[j].Protocol
+	})
+	return conflicts
+}
+
+// overlapping reports whether two different services or containers publish
+// on the same address, or one of them on every address
+func overlapping(uses []PortUse) bool {
+	for i, a := range uses {
+		for _, b := range uses[i+1:] {
+			same := a.Project == b.Project && a.Service == b.Service && a.Container == b.Container
+			if !same && (a.HostIP == b.HostIP || anyAddr(a.HostIP) || anyAddr(b.HostIP)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsContainer(uses []PortUse, name string) bool {
+	for _, u := range uses {
+		if u.Container == name {
+			return true
+		}
+	}
+	return false
+}
+
+// PortCheck compares a running container's published ports with those its
+// service declares. They differ when the container was created from an
+// older version of the compose file and hasn't been recreated since.
+type PortCheck struct {
+	Service    string               `json:"service"`
+	Container  string               `json:"container"`
+	Declared   []DeclaredPort       `json:"declared"`
+	Published  []docker.PortMapping `json:"published"`
+	Missing    []DeclaredPort       `json:"missing,omitempty"`    // declared but not published
+	Unexpected []docker.PortMapping `json:"unexpected,omitempty"` // published but not declared
+	Mismatch   bool                 `json:"mismatch"`
+}
+
+// CheckPorts compares the ports a container of a service publishes with
+// those the service declares. Only running containers publish ports.
+func CheckPorts(svc ServiceInfo, c docker.ContainerInfo) PortCheck {
+	check := PortCheck{
+		Service:   svc.Name,
+		Container: c.Name,
+		Declared:  svc.DeclaredPorts,
+		Published: c.Ports,
+	}
+	if check.Declared == nil {
+		check.Declared = []DeclaredPort{}
+	}
+
+	covered := make([]bool, len(c.Ports))
+	for _, d := range svc.DeclaredPorts {
+		// Each port of a range must be published
+		from, to, complete := portRange(d.ContainerPort)
+		for port := from; complete && port <= to; port++ {
+			found := false
+			for i, m := range c.Ports {
+				if m.ContainerPort == strconv.Itoa(port) && d.covers(m) {
+					covered[i], found = true, true
+				}
+			}
+			complete = found
+		}
+		if !complete {
+			check.Missing = append(check.Missing, d)
+		}
+	}
+	for i, m := range c.Ports {
+		// Exposed but unpublished ports have no host port
+		if !covered[i] && m.HostPort != "" {
+			check.Unexpected = append(check.Unexpected, m)
+		}
+	}
+	check.Mismatch = len(check.Missing) > 0 || len(check.Unexpected) > 0
+	return check
+}
+
+// PortMismatches checks the project's running containers against their
+// services, returning those that don't publish what is declared
+func PortMismatches(p *Project, containers []docker.ContainerInfo) []PortCheck {
+	var mismatches []PortCheck
+	for _, c := range containers {
+		svc, ok := p.Service(c.ServiceName)
+		if !ok || c.State != "running" {
+			continue
+		}
+		if check := CheckPorts(svc, c); check.Mismatch {
+			mismatches = append(mismatches, check)
+		}
+	}
+	return mismatches
+}
+
+// covers reports whether a published binding is one the declared port
+// makes. A declared port without a host port, or with a range for one
+// container port, is published on a host port the engine picks.
+func (d DeclaredPort) covers(m docker.PortMapping) bool {
+	if d.Protocol != m.Protocol {
+		return false
+	}
+	if d.HostIP != m.HostIP && !(anyAddr(d.HostIP) && anyAddr(m.HostIP)) {
+		return false
+	}
+	target, err := strconv.Atoi(m.ContainerPort)
+	if err != nil {
+		return false
+	}
+	containerFrom, containerTo, ok := portRange(d.ContainerPort)
+	if !ok || target < containerFrom || target > containerTo {
+		return false
+	}
+	if d.HostPort == "" {
+		return true
+	}
+	host, err := strconv.Atoi(m.HostPort)
+	if err != nil {
+		return false
+	}
+	hostFrom, hostTo, ok := portRange(d.HostPort)
+	if !ok {
+		return false
+	}
+	if containerFrom == containerTo {
+		return host >= hostFrom && host <= hostTo
+	}
+	// Ranges map port to port
+	return host == hostFrom+target-containerFrom
+}
+
+// portRange parses a port or a range of ports
+func portRange(s string) (from, to int, ok bool) {
+	a, b, isRange := strings.Cut(s, "-")
+	from, err := strconv.Atoi(a)
+	if err != nil {
+		return 0, 0, false
+	}
+	to = from
+	if isRange {
+		if to, err = strconv.Atoi(b); err != nil || to < from {
+			return 0, 0, false
+		}
+	}
+	return from, to, true
+}
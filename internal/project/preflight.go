@@ -0,0 +1,118 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// PreflightIssue is something a project needs that is missing, so that
+// docker compose up would fail
+type PreflightIssue struct {
+	Kind     string   `json:"kind"`               // "network", "volume" or "env_file"
+	Name     string   `json:"name"`               // on the host, or the env file's path
+	Services []string `json:"services,omitempty"` // that need it
+	Message  string   `json:"message"`
+}
+
+// PreflightResult is the outcome of checking what a project needs before
+// bringing it up
+type PreflightResult struct {
+	Ready  bool             `json:"ready"`
+	Issues []PreflightIssue `json:"issues"`
+}
+
+// Preflight finds the external networks and volumes the project's services
+// use that aren't among those on the host, and required env files that
+// don't exist. Compose creates the project's other networks and volumes,
+// so only external ones are checked.
+func Preflight(p *Project, networks, volumes map[string]bool) PreflightResult {
+	issues := []PreflightIssue{}
+
+	for _, n := range p.Networks {
+		if !n.External || networks[n.Name] {
+			continue
+		}
+		users := servicesUsing(p, func(svc ServiceInfo) bool { return slices.Contains(svc.Networks, n.Key) })
+		if len(users) > 0 {
+			issues = append(issues, PreflightIssue{
+				Kind:     "network",
+				Name:     n.Name,
+				Services: users,
+				Message:  "external network " + n.Name + " not found; create it with docker network create " + n.Name,
+			})
+		}
+	}
+
+	for _, v := range p.Volumes {
+		if !v.External || volumes[v.Name] {
+			continue
+		}
+		users := servicesUsing(p, func(svc ServiceInfo) bool { return slices.Contains(namedVolumes(svc.Volumes), v.Key) })
+		if len(users) > 0 {
+			issues = append(issues, PreflightIssue{
+				Kind:     "volume",
+				Name:     v.Name,
+				Services: users,
+				Message:  "external volume " + v.Name + " not found; create it with docker volume create " + v.Name,
+			})
+		}
+	}
+
+	missing := make(map[string][]string) // path to services
+	for _, svc := range p.Services {
+		for _, f := range svc.EnvFile {
+			if !f.Required {
+				continue
+			}
+			path := f.Path
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(p.Path, path)
+			}
+			if _, err := os.Stat(path); err != nil {
+				missing[f.Path] = append(missing[f.Path], svc.Name)
+			}
+		}
+	}
+	paths := make([]string, 0, len(missing))
+	for path := range missing {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		issues = append(issues, PreflightIssue{
+			Kind:     "env_file",
+			Name:     path,
+			Services: missing[path],
+			Message:  "env file " + path + " not found",
+		})
+	}
+
+	return PreflightResult{Ready: len(issues) == 0, Issues: issues}
+}
+
+// servicesUsing returns the names of the services that match
+func servicesUsing(p *Project, match func(ServiceInfo) bool) []string {
+	var names []string
+	for _, svc := range p.Services {
+		if match(svc) {
+			names = append(names, svc.Name)
+		}
+	}
+	return names
+}
+
+// namedVolumes returns the volume keys among short syntax mounts; other
+// sources are host paths
+func namedVolumes(mounts []string) []string {
+	var names []string
+	for _, m := range mounts {
+		source, _, ok := strings.Cut(m, ":")
+		if ok && source != "" && !strings.HasPrefix(source, ".") && !strings.ContainsAny(source, "/~$") {
+			names = append(names, source)
+		}
+	}
+	return names
+}
@@ -0,0 +1,60 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lyall/gosei/internal/markdown"
+)
+
+// maxReadme bounds the README read into memory and rendered
+const maxReadme = 1 << 20
+
+// ErrNoReadme is returned for projects without a README.md
+var ErrNoReadme = errors.New("project has no README.md")
+
+// Readme is the README.md in a project's directory, for notes on running
+// the stack
+type Readme struct {
+	File     string    `json:"file"` // as named in the directory
+	Markdown string    `json:"markdown"`
+	HTML     string    `json:"html"` // sanitized, safe to embed
+	Modified time.Time `json:"modified"`
+}
+
+// ReadReadme reads and renders the project's README.md, matched without
+// regard to case. It must be a regular file in the directory rather than a
+// symlink, which could point anywhere on the host.
+func ReadReadme(p *Project) (*Readme, error) {
+	entries, err := os.ReadDir(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !strings.EqualFold(e.Name(), "README.md") || !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		if info.Size() > maxReadme {
+			return nil, fmt.Errorf("%s is larger than %d KiB", e.Name(), maxReadme>>10)
+		}
+		data, err := os.ReadFile(filepath.Join(p.Path, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		return &Readme{
+			File:     e.Name(),
+			Markdown: string(data),
+			HTML:     markdown.Render(string(data)),
+			Modified: info.ModTime(),
+		}, nil
+	}
+	return nil, ErrNoReadme
+}
@@ -0,0 +1,90 @@
+package project
+
+import "sort"
+
+// Resource is a network or named volume a compose file defines at the top
+// level. Compose creates it as <project>_<key> unless it is named, and
+// expects an external one to exist already.
+type Resource struct {
+	Key      string `json:"key"`  // as the compose file names it
+	Name     string `json:"name"` // as it is named on the host
+	External bool   `json:"external,omitempty"`
+	Driver   string `json:"driver,omitempty"`
+	Exists   *bool  `json:"exists,omitempty"` // on the host, when checked
+}
+
+// Missing reports whether the resource was checked and isn't on the host
+func (r Resource) Missing() bool {
+	return r.Exists != nil && !*r.Exists
+}
+
+// parseResources parses the top-level networks or volumes of a compose file,
+// sorted by key
+func parseResources(defs map[string]interface{}, projectName string, env map[string]string) []Resource {
+	resources := make([]Resource, 0, len(defs))
+	for key, def := range defs {
+		r := Resource{Key: key}
+		var name string
+		if m, ok := def.(map[string]interface{}); ok {
+			name = stringField(m["name"])
+			r.Driver = interpolate(stringField(m["driver"]), env)
+			switch ext := m["external"].(type) {
+			case bool:
+				r.External = ext
+			case map[string]interface{}:
+				// The legacy external: {name: ...}
+				r.External = true
+				if name == "" {
+					name = stringField(ext["name"])
+				}
+			}
+		}
+		switch name = interpolate(name, env); {
+		case name != "":
+			r.Name = name
+		case r.External:
+			r.Name = key
+		default:
+			r.Name = projectName + "_" + key
+		}
+		resources = append(resources, r)
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Key < resources[j].Key })
+	return resources
+}
+
+// WithExistence returns a copy of the resources with Exists set from the
+// names of those on the host
+func WithExistence(resources []Resource, onHost map[string]bool) []Resource {
+	checked := make([]Resource, len(resources))
+	for i, r := range resources {
+		exists := onHost[r.Name]
+		r.Exists = &exists
+		checked[i] = r
+	}
+	return checked
+}
+
+// JoinedNetworks returns the networks the project's services join: those
+// the compose file declares and that a service uses, plus the default
+// network compose creates for services that list none
+func (p *Project) JoinedNetworks() []Resource {
+	used := make(map[string]bool)
+	for _, svc := range p.Services {
+		for _, key := range svc.Networks {
+			used[key] = true
+		}
+	}
+	var networks []Resource
+	for _, n := range p.Networks {
+		if used[n.Key] {
+			networks = append(networks, n)
+			delete(used, n.Key)
+		}
+	}
+	if used["default"] {
+		networks = append(networks, Resource{Key: "default", Name: p.Name + "_default"})
+		sort.Slice(networks, func(i, j int) bool { return networks[i].Key < networks[j].Key })
+	}
+	return networks
+}
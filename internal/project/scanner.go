@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -13,31 +14,58 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// LabelPrefix marks compose service labels that configure gosei itself
+const LabelPrefix = "gosei."
+
 // Project represents a Docker Compose project
 type Project struct {
-	ID          string            `json:"id"`
-	Name        string            `json:"name"`
-	Path        string            `json:"path"`
-	ComposeFile string            `json:"composeFile"`
-	Services    []ServiceInfo     `json:"services"`
-	Status      string            `json:"status"` // "running", "partial", "stopped", "unknown"
-	Running     int               `json:"running"`
-	Total       int               `json:"total"`
-	LastUpdated time.Time         `json:"lastUpdated"`
-	EnvFiles    []string          `json:"envFiles"`
-	Labels      map[string]string `json:"labels"`
+	ID            string            `json:"id"`
+	Name          string            `json:"name"` // compose project name, as in the com.docker.compose.project label
+	Path          string            `json:"path"`
+	ComposeFile   string            `json:"composeFile"`
+	Services      []ServiceInfo     `json:"services"`
+	Status        string            `json:"status"` // "running", "partial", "stopped", "unknown"
+	Running       int               `json:"running"`
+	Total         int               `json:"total"`
+	LastUpdated   time.Time         `json:"lastUpdated"`
+	EnvFiles      []string          `json:"envFiles"`
+	Labels        map[string]string `json:"labels"`
+	Links         []Link            `json:"links,omitempty"`    // from x-gosei and gosei.link.* labels
+	Networks      []Resource        `json:"networks,omitempty"` // top-level networks of the compose file
+	Volumes       []Resource        `json:"volumes,omitempty"`  // top-level named volumes
+	Warnings      []Issue           `json:"warnings,omitempty"` // unresolved ${VAR} references
+	Conflicts     []Conflict        `json:"conflicts,omitempty"`
+	LastOperation *Operation        `json:"lastOperation,omitempty"`
+
+	stamp string // fileStamp when parsed
+}
+
+// Conflict is a compose file in a project directory that gosei ignores
+type Conflict struct {
+	File    string `json:"file"` // relative to the project directory
+	Message string `json:"message"`
 }
 
 // ServiceInfo represents a service defined in compose file
 type ServiceInfo struct {
-	Name        string            `json:"name"`
-	Image       string            `json:"image"`
-	Build       *BuildInfo        `json:"build,omitempty"`
-	Ports       []string          `json:"ports"`
-	Volumes     []string          `json:"volumes"`
-	Environment map[string]string `json:"environment"`
-	DependsOn   []string          `json:"dependsOn"`
-	Labels      map[string]string `json:"labels"`
+	Name          string            `json:"name"`
+	Image         string            `json:"image"`
+	Build         *BuildInfo        `json:"build,omitempty"`
+	Ports         []string          `json:"ports"`
+	DeclaredPorts []DeclaredPort    `json:"declaredPorts,omitempty"` // Ports parsed, variables interpolated
+	Volumes       []string          `json:"volumes"`
+	Environment   map[string]string `json:"environment"`
+	DependsOn     []string          `json:"dependsOn"`
+	Labels        map[string]string `json:"labels"`
+	Icon          string            `json:"icon,omitempty"`     // an icon name such as postgres, or an image URL
+	Networks      []string          `json:"networks,omitempty"` // keys of the networks it joins
+	EnvFile       []EnvFileRef      `json:"envFile,omitempty"`
+}
+
+// EnvFileRef is an env_file of a service, relative to the project directory
+type EnvFileRef struct {
+	Path     string `json:"path"`
+	Required bool   `json:"required"` // compose fails if a required file is missing
 }
 
 // BuildInfo represents build configuration for a service
@@ -51,6 +79,10 @@ type Scanner struct {
 	baseDir  string
 	projects map[string]*Project
 	mu       sync.RWMutex
+	scanMu   sync.Mutex // serializes Scan and Import, which replace or add projects
+
+	changes uint64 // bumped when a status or operation changes, for PersistState
+	saved   uint64 // changes as of the last SaveState
 }
 
 // NewScanner creates a new project scanner
@@ -61,13 +93,21 @@ func NewScanner(baseDir string) *Scanner {
 	}
 }
 
-// Scan scans the base directory for compose projects
-func (s *Scanner) Scan(ctx context.Context) ([]*Project, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// BaseDir returns the directory scanned for projects
+func (s *Scanner) BaseDir() string {
+	return s.baseDir
+}
 
-	// Clear existing projects
-	s.projects = make(map[string]*Project)
+// scanWorkers bounds how many compose files Scan parses at once
+const scanWorkers = 8
+
+// Scan scans the base directory for compose projects. Projects whose
+// compose and env files are unchanged since the last scan are kept as they
+// are; the rest are parsed concurrently and keep the status of the project
+// they replace.
+func (s *Scanner) Scan(ctx context.Context) ([]*Project, error) {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
 
 	// Read immediate subdirectories only (no recursive walk)
 	entries, err := os.ReadDir(s.baseDir)
@@ -75,13 +115,13 @@ func (s *Scanner) Scan(ctx context.Context) ([]*Project, error) {
 		return nil, fmt.Errorf("failed to read directory: %w", err)
 	}
 
-	for _, entry := range entries {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-		}
+	s.mu.RLock()
+	previous := s.projects
+	s.mu.RUnlock()
 
+	projects := make(map[string]*Project, len(entries))
+	var changed []string
+	for _, entry := range entries {
 		// Skip non-directories and hidden directories
 		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
 			continue
@@ -95,26 +135,93 @@ func (s *Scanner) Scan(ctx context.Context) ([]*Project, error) {
 			continue
 		}
 
-		project, err := s.parseProject(composeFile)
-		if err != nil {
-			// Log error but continue scanning
+		id := generateProjectID(projectDir)
+		if p, ok := previous[id]; ok && p.ComposeFile == composeFile && p.stamp == fileStamp(composeFile) {
+			projects[id] = p
 			continue
 		}
+		changed = append(changed, composeFile)
+	}
 
-		s.projects[project.ID] = project
+	parsed, err := s.parseAll(ctx, changed)
+	if err != nil {
+		return nil, err
 	}
 
-	// Convert map to slice and sort by name
-	projects := make([]*Project, 0, len(s.projects))
-	for _, p := range s.projects {
-		projects = append(projects, p)
+	s.mu.Lock()
+	for _, p := range parsed {
+		// Status is read under the lock, so updates made during the scan
+		// aren't lost
+		if prev, ok := s.projects[p.ID]; ok {
+			p.keepState(prev)
+		}
+		projects[p.ID] = p
 	}
+	s.projects = projects
+	s.mu.Unlock()
 
-	sort.Slice(projects, func(i, j int) bool {
-		return projects[i].Name < projects[j].Name
-	})
+	return sortedProjects(projects), nil
+}
+
+// parseAll parses compose files with up to scanWorkers at a time. Files
+// that fail to parse are left out, as are files not yet parsed when ctx is
+// cancelled, in which case the context's error is returned.
+func (s *Scanner) parseAll(ctx context.Context, files []string) ([]*Project, error) {
+	results := make([]*Project, len(files))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(scanWorkers, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if p, err := s.parseProject(files[i]); err == nil {
+					results[i] = p
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range files {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	parsed := make([]*Project, 0, len(results))
+	for _, p := range results {
+		if p != nil {
+			parsed = append(parsed, p)
+		}
+	}
+	return parsed, nil
+}
 
-	return projects, nil
+// fileStamp fingerprints a compose file and the env files beside it by
+// size and modification time, plus the conflicting compose files, to tell
+// whether a project needs parsing again
+func fileStamp(composeFile string) string {
+	dir := filepath.Dir(composeFile)
+	var b strings.Builder
+	for _, name := range append([]string{filepath.Base(composeFile)}, findEnvFiles(dir)...) {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			fmt.Fprintf(&b, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	for _, c := range composeConflicts(composeFile) {
+		fmt.Fprintf(&b, "%s;", c.File)
+	}
+	return b.String()
 }
 
 // GetProject returns a project by ID
@@ -138,13 +245,31 @@ func (s *Scanner) GetProjectByPath(path string) (*Project, bool) {
 	return nil, false
 }
 
+// GetProjectByName returns a project by its compose project name, as its
+// containers are labelled with
+func (s *Scanner) GetProjectByName(name string) (*Project, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, p := range s.projects {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
 // ListProjects returns all projects
 func (s *Scanner) ListProjects() []*Project {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return sortedProjects(s.projects)
+}
 
-	projects := make([]*Project, 0, len(s.projects))
-	for _, p := range s.projects {
+// sortedProjects returns the projects of a map sorted by name
+func sortedProjects(m map[string]*Project) []*Project {
+	projects := make([]*Project, 0, len(m))
+	for _, p := range m {
 		projects = append(projects, p)
 	}
 
@@ -170,12 +295,16 @@ func (s *Scanner) RefreshProject(id string) (*Project, error) {
 		return nil, err
 	}
 
+	project.keepState(existing)
 	s.projects[id] = project
 	return project, nil
 }
 
 // parseProject parses a compose file and creates a Project
 func (s *Scanner) parseProject(composeFilePath string) (*Project, error) {
+	// Stamped before reading, so a write during parsing is seen next scan
+	stamp := fileStamp(composeFilePath)
+
 	data, err := os.ReadFile(composeFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read compose file: %w", err)
@@ -187,7 +316,8 @@ func (s *Scanner) parseProject(composeFilePath string) (*Project, error) {
 	}
 
 	projectDir := filepath.Dir(composeFilePath)
-	projectName := filepath.Base(projectDir)
+	env := InterpolationEnv(projectDir)
+	projectName := composeProjectName(projectDir, compose.Name, env)
 
 	// Generate a stable ID based on the path
 	id := generateProjectID(projectDir)
@@ -195,15 +325,20 @@ func (s *Scanner) parseProject(composeFilePath string) (*Project, error) {
 	// Parse services
 	services := make([]ServiceInfo, 0, len(compose.Services))
 	for name, svc := range compose.Services {
+		ports, declared := parsePorts(svc.Ports, env)
 		serviceInfo := ServiceInfo{
-			Name:        name,
-			Image:       svc.Image,
-			Ports:       svc.Ports,
-			Volumes:     svc.Volumes,
-			Environment: parseEnvironment(svc.Environment),
-			DependsOn:   parseDependsOn(svc.DependsOn),
-			Labels:      parseLabels(svc.Labels),
+			Name:          name,
+			Image:         svc.Image,
+			Ports:         ports,
+			DeclaredPorts: declared,
+			Volumes:       svc.Volumes,
+			Environment:   parseEnvironment(svc.Environment),
+			DependsOn:     parseDependsOn(svc.DependsOn),
+			Labels:        parseLabels(svc.Labels),
+			Networks:      parseNetworks(svc.Networks, svc.NetworkMode),
+			EnvFile:       parseEnvFile(svc.EnvFile, env),
 		}
+		serviceInfo.Icon = serviceIcon(svc.Image, serviceInfo.Labels)
 
 		if svc.Build != nil {
 			serviceInfo.Build = parseBuild(svc.Build)
@@ -220,6 +355,17 @@ func (s *Scanner) parseProject(composeFilePath string) (*Project, error) {
 	// Find .env files
 	envFiles := findEnvFiles(projectDir)
 
+	// Compose has no project-level labels, so gosei.* settings may be set on
+	// any service; the first service (by name) to set one wins
+	labels := make(map[string]string)
+	for _, svc := range services {
+		for k, v := range svc.Labels {
+			if _, set := labels[k]; !set && strings.HasPrefix(k, LabelPrefix) {
+				labels[k] = v
+			}
+		}
+	}
+
 	return &Project{
 		ID:          id,
 		Name:        projectName,
@@ -230,40 +376,105 @@ func (s *Scanner) parseProject(composeFilePath string) (*Project, error) {
 		Total:       len(services),
 		LastUpdated: time.Now(),
 		EnvFiles:    envFiles,
+		Labels:      labels,
+		Links:       projectLinks(compose.Gosei, labels, env),
+		Networks:    parseResources(compose.Networks, projectName, env),
+		Volumes:     parseResources(compose.Volumes, projectName, env),
+		Warnings:    UnresolvedVariables(data, env),
+		Conflicts:   composeConflicts(composeFilePath),
+		stamp:       stamp,
 	}, nil
 }
 
-// UpdateProjectStatus updates the running status of a project
-func (s *Scanner) UpdateProjectStatus(id string, running int, status string) {
+// keepState carries the status and last operation of the project a
+// reparsed one replaces. The service count may have changed.
+func (p *Project) keepState(prev *Project) {
+	p.LastOperation = prev.LastOperation
+	if prev.Status != "unknown" {
+		p.Running = prev.Running
+		p.Status = Status(p.Running, p.Total)
+	}
+}
+
+// Service returns the project's service of the given name
+func (p *Project) Service(name string) (ServiceInfo, bool) {
+	for _, svc := range p.Services {
+		if svc.Name == name {
+			return svc, true
+		}
+	}
+	return ServiceInfo{}, false
+}
+
+// Status derives a project's status from how many of its containers run.
+// Scaled services can run more containers than the project has services.
+func Status(running, total int) string {
+	switch {
+	case running == 0:
+		return "stopped"
+	case running >= total:
+		return "running"
+	default:
+		return "partial"
+	}
+}
+
+// UpdateProjectStatus sets a project's status from its running containers
+func (s *Scanner) UpdateProjectStatus(id string, running int) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if project, ok := s.projects[id]; ok {
-		project.Running = running
-		project.Status = status
-		project.LastUpdated = time.Now()
+		s.setStatus(project, running, time.Now())
+	}
+}
+
+// UpdateStatuses sets every project's status at once from the number of
+// running containers per project name, as counted from one listing of all
+// containers. Projects missing from running have none running.
+func (s *Scanner) UpdateStatuses(running map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, p := range s.projects {
+		s.setStatus(p, running[p.Name], now)
+	}
+}
+
+// setStatus updates a project's status; s.mu must be held
+func (s *Scanner) setStatus(p *Project, running int, now time.Time) {
+	status := Status(running, p.Total)
+	if p.Running != running || p.Status != status {
+		s.changes++
 	}
+	p.Running, p.Status, p.LastUpdated = running, status, now
 }
 
 // composeFile represents the structure of a docker-compose.yml
 type composeFile struct {
+	Name     string                    `yaml:"name"`
 	Version  string                    `yaml:"version"`
 	Services map[string]composeService `yaml:"services"`
 	Networks map[string]interface{}    `yaml:"networks"`
 	Volumes  map[string]interface{}    `yaml:"volumes"`
+	Gosei    extension                 `yaml:"x-gosei"`
 }
 
 // composeService represents a service in docker-compose.yml
 type composeService struct {
 	Image       string      `yaml:"image"`
 	Build       interface{} `yaml:"build"` // Can be string or object
-	Ports       []string    `yaml:"ports"`
+	Ports       interface{} `yaml:"ports"` // Can be short or long syntax
 	Volumes     []string    `yaml:"volumes"`
 	Environment interface{} `yaml:"environment"` // Can be list or map
 	DependsOn   interface{} `yaml:"depends_on"`  // Can be list or map
 	Labels      interface{} `yaml:"labels"`      // Can be list or map
 	Command     interface{} `yaml:"command"`
 	Restart     string      `yaml:"restart"`
+	Networks    interface{} `yaml:"networks"` // Can be list or map
+	NetworkMode string      `yaml:"network_mode"`
+	EnvFile     interface{} `yaml:"env_file"` // Can be string, list or list of objects
 }
 
 // composeFileNames lists valid compose file names in priority order
@@ -274,6 +485,100 @@ var composeFileNames = []string{
 	"docker-compose.yml",
 }
 
+// composeProjectName returns the project name docker compose uses for a
+// project directory, in its order of precedence: COMPOSE_PROJECT_NAME from
+// the environment or .env, the compose file's top-level name, then the
+// directory name. Like compose, the name is lowercased and stripped of
+// characters other than letters, digits, dashes and underscores.
+func composeProjectName(projectDir, fileName string, env map[string]string) string {
+	name := env["COMPOSE_PROJECT_NAME"]
+	// A name compose couldn't interpolate, e.g. ${VAR:?err} with VAR unset,
+	// makes compose fail; the directory name is the best guess then
+	if n := interpolate(fileName, env); name == "" && !strings.Contains(n, "${") {
+		name = n
+	}
+	if name == "" {
+		name = filepath.Base(projectDir)
+	}
+	if normalized := normalizeProjectName(name); normalized != "" {
+		return normalized
+	}
+	return filepath.Base(projectDir)
+}
+
+var projectNameChars = regexp.MustCompile(`[a-z0-9_-]`)
+
+// normalizeProjectName mirrors compose's normalization of project names
+func normalizeProjectName(s string) string {
+	s = strings.Join(projectNameChars.FindAllString(strings.ToLower(s), -1), "")
+	return strings.TrimLeft(s, "_-")
+}
+
+// interpolatePattern matches $$, $VAR, ${VAR} and ${VAR:-default} or
+// ${VAR-default}
+var interpolatePattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?-)([^}]*))?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// interpolate substitutes variables in s the way compose does for simple
+// values. Other modifiers, such as ${VAR:?err}, are left as they are.
+func interpolate(s string, env map[string]string) string {
+	return interpolatePattern.ReplaceAllStringFunc(s, func(m string) string {
+		if m == "$$" {
+			return "$"
+		}
+		sub := interpolatePattern.FindStringSubmatch(m)
+		name := sub[1] + sub[4]
+		v, set := env[name]
+		switch sub[2] {
+		case ":-":
+			if v == "" {
+				return sub[3]
+			}
+		case "-":
+			if !set {
+				return sub[3]
+			}
+		}
+		return v
+	})
+}
+
+// composeConflicts lists the compose files in a project directory other
+// than the chosen one. Lower-priority names beside it are ignored, as by
+// docker compose itself; compose files in subdirectories are not projects
+// of their own, since only the top level of the projects directory is
+// scanned. Either usually means a project was copied or nested by mistake.
+func composeConflicts(composeFile string) []Conflict {
+	dir, chosen := filepath.Dir(composeFile), filepath.Base(composeFile)
+
+	var conflicts []Conflict
+	for _, name := range composeFileNames {
+		if name == chosen {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			conflicts = append(conflicts, Conflict{
+				File:    name,
+				Message: fmt.Sprintf("%s is ignored; %s takes precedence", name, chosen),
+			})
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if nested := findComposeFile(filepath.Join(dir, entry.Name())); nested != "" {
+			rel := filepath.Join(entry.Name(), filepath.Base(nested))
+			conflicts = append(conflicts, Conflict{
+				File:    rel,
+				Message: fmt.Sprintf("%s is ignored; nested projects are not scanned", rel),
+			})
+		}
+	}
+	return conflicts
+}
+
 // findComposeFile looks for a compose file in the given directory
 func findComposeFile(dir string) string {
 	for _, name := range composeFileNames {
@@ -346,6 +651,45 @@ func parseDependsOn(deps interface{}) []string {
 	return result
 }
 
+// parseNetworks returns the networks a service joins, listed or keyed like
+// depends_on. Without any it joins the default network, unless it uses
+// another network mode such as host.
+func parseNetworks(networks interface{}, mode string) []string {
+	if mode != "" {
+		return nil
+	}
+	if names := parseDependsOn(networks); len(names) > 0 {
+		return names
+	}
+	return []string{"default"}
+}
+
+// parseEnvFile parses the env_file field: a path, a list of paths, or a
+// list of {path, required}
+func parseEnvFile(envFile interface{}, env map[string]string) []EnvFileRef {
+	var refs []EnvFileRef
+	items, ok := envFile.([]interface{})
+	if !ok && envFile != nil {
+		items = []interface{}{envFile}
+	}
+	for _, item := range items {
+		ref := EnvFileRef{Required: true}
+		switch f := item.(type) {
+		case string:
+			ref.Path = f
+		case map[string]interface{}:
+			ref.Path = stringField(f["path"])
+			if required, ok := f["required"].(bool); ok {
+				ref.Required = required
+			}
+		}
+		if ref.Path = interpolate(ref.Path, env); ref.Path != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
 // parseLabels parses the labels field which can be a list or map
 func parseLabels(labels interface{}) map[string]string {
 	result := make(map[string]string)
@@ -0,0 +1,132 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lyall/gosei/internal/store"
+)
+
+// Operation is the outcome of the last compose operation run on a project
+type Operation struct {
+	Name     string    `json:"name"`   // up, down, pull, ...
+	Status   string    `json:"status"` // success, failed, cancelled or timeout
+	Message  string    `json:"message,omitempty"`
+	Finished time.Time `json:"finished"`
+}
+
+// stateVersion is bumped when the state format changes incompatibly; saved
+// state of another version is ignored
+const stateVersion = 1
+
+// savedState is the last-known state of projects, so a restarted gosei
+// shows it until Docker has been queried
+type savedState struct {
+	Version  int                     `json:"version"`
+	Saved    time.Time               `json:"saved"`
+	Projects map[string]projectState `json:"projects"` // by project ID
+}
+
+type projectState struct {
+	Status        string     `json:"status"`
+	Running       int        `json:"running"`
+	LastUpdated   time.Time  `json:"lastUpdated"`
+	LastOperation *Operation `json:"lastOperation,omitempty"`
+}
+
+// RecordOperation stores the outcome of a project's latest compose operation
+func (s *Scanner) RecordOperation(id string, op Operation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if project, ok := s.projects[id]; ok {
+		project.LastOperation = &op
+		s.changes++
+	}
+}
+
+// SaveState saves the status and last operation of every project to doc
+func (s *Scanner) SaveState(doc store.Doc) error {
+	s.mu.RLock()
+	state := savedState{Version: stateVersion, Saved: time.Now(), Projects: make(map[string]projectState, len(s.projects))}
+	for id, p := range s.projects {
+		state.Projects[id] = projectState{
+			Status:        p.Status,
+			Running:       p.Running,
+			LastUpdated:   p.LastUpdated,
+			LastOperation: p.LastOperation,
+		}
+	}
+	changes := s.changes
+	s.mu.RUnlock()
+
+	if err := doc.Save(state); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+
+	s.mu.Lock()
+	s.saved = changes
+	s.mu.Unlock()
+	return nil
+}
+
+// LoadState restores the saved status and last operation of the projects
+// found by the last scan. Projects that have since disappeared are dropped
+// and new ones stay unknown. Nothing saved yet is not an error.
+func (s *Scanner) LoadState(doc store.Doc) error {
+	var state savedState
+	if ok, err := doc.Load(&state); err != nil {
+		return fmt.Errorf("failed to read state: %w", err)
+	} else if !ok {
+		return nil
+	}
+	if state.Version != stateVersion {
+		return fmt.Errorf("unsupported state version %d", state.Version)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, saved := range state.Projects {
+		p, ok := s.projects[id]
+		if !ok {
+			continue
+		}
+		p.Running = saved.Running
+		p.Status = saved.Status
+		if saved.Status != "unknown" {
+			// The service count may have changed while gosei was down
+			p.Status = Status(saved.Running, p.Total)
+		}
+		p.LastUpdated = saved.LastUpdated
+		p.LastOperation = saved.LastOperation
+	}
+	return nil
+}
+
+// PersistState saves the state to doc every interval when it has changed,
+// until ctx is done. The caller saves once more on shutdown.
+func (s *Scanner) PersistState(ctx context.Context, doc store.Doc, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.RLock()
+		dirty := s.changes != s.saved
+		s.mu.RUnlock()
+		if !dirty {
+			continue
+		}
+		if err := s.SaveState(doc); err != nil {
+			slog.Warn("Failed to save project state", "error", err)
+		}
+	}
+}
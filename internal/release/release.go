@@ -0,0 +1,243 @@
+// Package release checks GitHub for newer gosei releases, so a dashboard
+// can say when an update is out. It's opt-in: nothing is fetched until an
+// interval is set.
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRepo is the GitHub repository releases are checked in
+const DefaultRepo = "lyallcooper/gosei"
+
+// MinInterval keeps checks well inside GitHub's limit of 60 unauthenticated
+// requests an hour, shared by everything on the host
+const MinInterval = time.Hour
+
+// checkTimeout bounds one request to GitHub
+const checkTimeout = 15 * time.Second
+
+// Build identifies the running gosei
+type Build struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+	Date    string `json:"date,omitempty"` // when it was built, RFC 3339
+}
+
+// Release is a published gosei release
+type Release struct {
+	Version   string    `json:"version"`
+	URL       string    `json:"url"`
+	Published time.Time `json:"published"`
+}
+
+// Status is the outcome of the last check
+type Status struct {
+	Current         Build      `json:"current"`
+	Enabled         bool       `json:"enabled"`
+	Interval        string     `json:"interval,omitempty"`
+	Latest          *Release   `json:"latest,omitempty"`
+	UpdateAvailable bool       `json:"updateAvailable"`
+	Checked         *time.Time `json:"checked,omitempty"`
+	Error           string     `json:"error,omitempty"`
+}
+
+// Checker looks up the latest release on an interval
+type Checker struct {
+	build  Build
+	url    string
+	client *http.Client
+	reset  chan time.Duration
+
+	interval time.Duration
+	latest   *Release
+	checked  time.Time
+	err      error
+	notified string // the newer version last logged, so it's logged once
+	mu       sync.Mutex
+}
+
+// New creates a checker for the running build against a GitHub repository
+// given as owner/name. It checks nothing until SetInterval.
+func New(build Build, repo string) *Checker {
+	return &Checker{
+		build:  build,
+		url:    "https://api.github.com/repos/" + repo + "/releases/latest",
+		client: &http.Client{Timeout: checkTimeout},
+		reset:  make(chan time.Duration, 1),
+	}
+}
+
+// SetInterval changes how often releases are checked; 0 stops checking
+func (c *Checker) SetInterval(interval time.Duration) {
+	// Only the latest interval matters
+	select {
+	case <-c.reset:
+	default:
+	}
+	c.reset <- interval
+}
+
+// Status returns the outcome of the last check
+func (c *Checker) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := Status{Current: c.build, Enabled: c.interval > 0, Latest: c.latest}
+	if c.interval > 0 {
+		st.Interval = c.interval.String()
+	}
+	if !c.checked.IsZero() {
+		checked := c.checked
+		st.Checked = &checked
+	}
+	if c.err != nil {
+		st.Error = c.err.Error()
+	}
+	if c.latest != nil {
+		st.UpdateAvailable = Newer(c.latest.Version, c.build.Version)
+	}
+	return st
+}
+
+// Run checks on the interval until ctx is cancelled. Turning checks on, or
+// shortening the interval past when the next check was due, checks at once.
+func (c *Checker) Run(ctx context.Context) {
+	var timer *time.Timer
+	var due <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case interval := <-c.reset:
+			c.mu.Lock()
+			c.interval = interval
+			last := c.checked
+			c.mu.Unlock()
+			if timer != nil {
+				timer.Stop()
+			}
+			timer, due = nil, nil
+			if interval > 0 {
+				timer = time.NewTimer(max(0, time.Until(last.Add(interval))))
+				due = timer.C
+			}
+		case <-due:
+			c.check(ctx)
+			c.mu.Lock()
+			interval := c.interval
+			c.mu.Unlock()
+			if interval > 0 {
+				timer.Reset(interval)
+			} else {
+				timer, due = nil, nil
+			}
+		}
+	}
+}
+
+// check fetches the latest release and records the outcome
+func (c *Checker) check(ctx context.Context) {
+	latest, err := c.fetch(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checked, c.err = time.Now(), err
+	if err != nil {
+		slog.Warn("Failed to check for gosei releases", "error", err)
+		return
+	}
+	c.latest = latest
+	if Newer(latest.Version, c.build.Version) && c.notified != latest.Version {
+		c.notified = latest.Version
+		slog.Info("A newer gosei release is available", "version", latest.Version, "current", c.build.Version, "url", latest.URL)
+	}
+}
+
+// fetch asks GitHub for the latest release, which excludes drafts and
+// pre-releases
+func (c *Checker) fetch(ctx context.Context) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "gosei/"+c.build.Version)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub returned %s", resp.Status)
+	}
+
+	var body struct {
+		TagName     string    `json:"tag_name"`
+		HTMLURL     string    `json:"html_url"`
+		PublishedAt time.Time `json:"published_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid GitHub response: %w", err)
+	}
+	return &Release{Version: strings.TrimPrefix(body.TagName, "v"), URL: body.HTMLURL, Published: body.PublishedAt}, nil
+}
+
+// Newer reports whether version a is newer than b, comparing them as
+// semantic versions with an optional leading v. A version that doesn't
+// parse, like a development build's, is never newer or older.
+func Newer(a, b string) bool {
+	va, ok := parse(a)
+	if !ok {
+		return false
+	}
+	vb, ok := parse(b)
+	if !ok {
+		return false
+	}
+	for i := range 3 {
+		if va.parts[i] != vb.parts[i] {
+			return va.parts[i] > vb.parts[i]
+		}
+	}
+	// A release is newer than its pre-releases
+	if (va.pre == "") != (vb.pre == "") {
+		return va.pre == ""
+	}
+	return va.pre > vb.pre
+}
+
+type semver struct {
+	parts [3]int
+	pre   string
+}
+
+func parse(v string) (semver, bool) {
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "+")
+	v, pre, _ := strings.Cut(v, "-")
+	fields := strings.Split(v, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return semver{}, false
+	}
+	var s semver
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return semver{}, false
+		}
+		s.parts[i] = n
+	}
+	s.pre = pre
+	return s, true
+}
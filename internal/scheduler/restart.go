@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/project"
+)
+
+// RestartLabel schedules restarts of a container, or of a compose
+// service's containers, e.g. gosei.restart.schedule: "0 4 * * *"
+const RestartLabel = project.LabelPrefix + "restart.schedule"
+
+// restartTimeout is how long a scheduled restart waits for the container to
+// stop before killing it, as for restarts from the UI
+const restartTimeout = 30
+
+// Restarts keeps the scheduler's restart jobs in line with the labels of
+// containers and compose services
+type Restarts struct {
+	scheduler *Scheduler
+	docker    docker.DockerClient
+	scanner   *project.Scanner
+}
+
+// NewRestarts creates a restart job source for the scheduler
+func NewRestarts(s *Scheduler, dc docker.DockerClient, ps *project.Scanner) *Restarts {
+	return &Restarts{scheduler: s, docker: dc, scanner: ps}
+}
+
+// Run syncs the restart jobs every interval until ctx is cancelled
+func (r *Restarts) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Sync(ctx); err != nil {
+			slog.Warn("Failed to sync scheduled restarts", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync replaces the restart jobs with those the labels declare. A label on
+// the container wins over one on its service in the compose file, which
+// applies before the container is recreated with it.
+func (r *Restarts) Sync(ctx context.Context) error {
+	containers, err := r.docker.ListContainers(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	services := make(map[string]string) // compose project/service -> schedule
+	for _, p := range r.scanner.ListProjects() {
+		for _, svc := range p.Services {
+			if schedule := svc.Labels[RestartLabel]; schedule != "" {
+				services[p.Name+"/"+svc.Name] = schedule
+			}
+		}
+	}
+
+	var jobs []Job
+	for _, c := range containers {
+		schedule, source := c.Labels[RestartLabel], "container label"
+		if schedule == "" && c.ServiceName != "" {
+			schedule, source = services[c.ProjectName+"/"+c.ServiceName], "compose file"
+		}
+		if schedule == "" {
+			continue
+		}
+		name := c.Name
+		jobs = append(jobs, Job{
+			ID:       "restart:" + name,
+			Target:   name,
+			Schedule: schedule,
+			Source:   source,
+			Project:  c.ProjectName,
+			Run:      func(ctx context.Context) (string, error) { return r.restart(ctx, name) },
+		})
+	}
+	r.scheduler.Sync("restart", jobs)
+	return nil
+}
+
+// restart restarts a container by name, which survives it being recreated,
+// unless it isn't running: a restart would start a stopped container
+func (r *Restarts) restart(ctx context.Context, name string) (string, error) {
+	c, err := r.docker.GetContainer(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if c.State != "running" {
+		return fmt.Sprintf("skipped: container is %s", c.State), nil
+	}
+	if err := r.docker.RestartContainer(ctx, name, restartTimeout); err != nil {
+		return "", err
+	}
+	return "restarted", nil
+}
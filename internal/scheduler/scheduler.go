@@ -0,0 +1,212 @@
+// Package scheduler runs jobs on cron schedules, such as nightly restarts
+// of containers that leak memory.
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lyall/gosei/internal/maintenance"
+	"github.com/robfig/cron/v3"
+)
+
+// Job is work to run on a schedule
+type Job struct {
+	ID       string // unique across kinds, e.g. restart:webapp-web-1
+	Kind     string // what syncs it, e.g. restart
+	Target   string // what it acts on, e.g. a container name
+	Schedule string // a cron expression or @daily, @hourly, ...
+	Source   string // where it was declared, e.g. a label
+	Project  string // compose project it belongs to, for maintenance windows
+
+	// Run does the work and describes the outcome, e.g. "restarted"
+	Run func(ctx context.Context) (string, error)
+}
+
+// Status describes a job, when it runs next and how its last run went
+type Status struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Target     string     `json:"target"`
+	Schedule   string     `json:"schedule"`
+	Source     string     `json:"source"`
+	Error      string     `json:"error,omitempty"` // why the schedule is invalid
+	NextRun    *time.Time `json:"nextRun,omitempty"`
+	LastRun    *time.Time `json:"lastRun,omitempty"`
+	LastResult string     `json:"lastResult,omitempty"`
+	LastError  string     `json:"lastError,omitempty"`
+	Running    bool       `json:"running,omitempty"`
+}
+
+// entry is a job with its parsed schedule and state
+type entry struct {
+	Job
+	schedule cron.Schedule
+	err      error
+	next     time.Time
+	last     Status
+	running  bool
+}
+
+// Scheduler runs jobs when their schedules fall due. Jobs are declared in
+// sets by kind, so each source can replace its own as they change.
+type Scheduler struct {
+	jobs    map[string]*entry
+	kick    chan struct{}
+	windows *maintenance.Manager
+	mu      sync.Mutex
+}
+
+// New creates a scheduler with no jobs
+func New() *Scheduler {
+	return &Scheduler{
+		jobs: make(map[string]*entry),
+		kick: make(chan struct{}, 1),
+	}
+}
+
+// SetMaintenance skips runs of jobs whose project, or every project, is in
+// a maintenance window
+func (s *Scheduler) SetMaintenance(m *maintenance.Manager) {
+	s.windows = m
+}
+
+// Sync replaces the jobs of a kind. A job that keeps its ID and schedule
+// keeps its next and last run.
+func (s *Scheduler) Sync(kind string, jobs []Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keep := make(map[string]bool, len(jobs))
+	now := time.Now()
+	for _, j := range jobs {
+		j.Kind = kind
+		keep[j.ID] = true
+		if cur, ok := s.jobs[j.ID]; ok && cur.Schedule == j.Schedule {
+			cur.Job = j
+			continue
+		}
+		e := &entry{Job: j}
+		e.schedule, e.err = cron.ParseStandard(j.Schedule)
+		if e.err != nil {
+			slog.Warn("Invalid schedule", "job", j.ID, "schedule", j.Schedule, "error", e.err)
+		} else {
+			e.next = e.schedule.Next(now)
+		}
+		s.jobs[j.ID] = e
+	}
+	for id, e := range s.jobs {
+		if e.Kind == kind && !keep[id] {
+			delete(s.jobs, id)
+		}
+	}
+
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Jobs returns the status of every job, sorted by next run with invalid
+// schedules last
+func (s *Scheduler) Jobs() []Status {
+	s.mu.Lock()
+	jobs := make([]Status, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		jobs = append(jobs, e.status())
+	}
+	s.mu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		a, b := jobs[i].NextRun, jobs[j].NextRun
+		switch {
+		case (a == nil) != (b == nil):
+			return b == nil
+		case a != nil && !a.Equal(*b):
+			return a.Before(*b)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs
+}
+
+// status describes the entry. Callers hold mu.
+func (e *entry) status() Status {
+	st := e.last
+	st.ID, st.Kind, st.Target, st.Schedule, st.Source = e.ID, e.Kind, e.Target, e.Schedule, e.Source
+	st.Running = e.running
+	if e.err != nil {
+		st.Error = e.err.Error()
+	} else {
+		next := e.next
+		st.NextRun = &next
+	}
+	return st
+}
+
+// Run starts due jobs until ctx is cancelled. A job still running when it
+// falls due again skips that run.
+func (s *Scheduler) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.kick:
+		case <-timer.C:
+		}
+
+		timer.Stop()
+		timer.Reset(s.startDue(ctx))
+	}
+}
+
+// startDue starts the jobs that are due and returns how long until the
+// next one is
+func (s *Scheduler) startDue(ctx context.Context) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	wait := time.Hour
+	for _, e := range s.jobs {
+		if e.err != nil {
+			continue
+		}
+		if !now.Before(e.next) {
+			if w := s.windows.Active(e.Project); w != nil {
+				e.last.LastRun, e.last.LastResult, e.last.LastError = &now, "skipped: maintenance window", ""
+				slog.Info("Scheduled job held off by maintenance window", "job", e.ID, "until", w.End)
+			} else if e.running {
+				slog.Warn("Skipping scheduled job that is still running", "job", e.ID)
+			} else {
+				e.running = true
+				go s.run(ctx, e, e.Job)
+			}
+			e.next = e.schedule.Next(now)
+		}
+		wait = min(wait, e.next.Sub(now))
+	}
+	return wait
+}
+
+// run runs a job and records the outcome
+func (s *Scheduler) run(ctx context.Context, e *entry, j Job) {
+	started := time.Now()
+	slog.Info("Running scheduled job", "job", j.ID, "schedule", j.Schedule)
+	result, err := j.Run(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.running = false
+	e.last.LastRun, e.last.LastResult, e.last.LastError = &started, result, ""
+	if err != nil {
+		e.last.LastError = err.Error()
+		slog.Warn("Scheduled job failed", "job", j.ID, "error", err)
+	}
+}
@@ -0,0 +1,255 @@
+// Package settings holds the options that can be changed while gosei runs,
+// through the API rather than flags and a restart. Flags give the defaults;
+// values set through the API are saved and take precedence over them.
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lyall/gosei/internal/notify"
+	"github.com/lyall/gosei/internal/release"
+	"github.com/lyall/gosei/internal/store"
+)
+
+// masked stands in for tokens in responses. Sent back unchanged, it keeps
+// the current token.
+const masked = "********"
+
+// ErrNotSaved is returned when settings were applied but couldn't be saved
+var ErrNotSaved = errors.New("settings applied but not saved")
+
+// minStatsInterval keeps a typo from having the daemon asked for stats
+// continuously
+const minStatsInterval = time.Second
+
+// Settings are the options that can be changed at runtime
+type Settings struct {
+	StatsInterval       string         `json:"statsInterval"`       // a duration, 0 for no sampling
+	UpdateCheckInterval string         `json:"updateCheckInterval"` // a duration, 0 for no release checks
+	EnvMask             []string       `json:"envMask"`             // globs of env var names whose values are hidden
+	Notify              notify.Targets `json:"notify"`
+}
+
+// Stats returns the stats sampling interval
+func (s Settings) Stats() time.Duration {
+	d, _ := time.ParseDuration(s.StatsInterval)
+	return d
+}
+
+// UpdateCheck returns how often gosei releases are checked
+func (s Settings) UpdateCheck() time.Duration {
+	d, _ := time.ParseDuration(s.UpdateCheckInterval)
+	return d
+}
+
+// Validate checks the values that can be checked on their own; notifiers
+// are checked when they are created
+func (s Settings) Validate() error {
+	d, err := time.ParseDuration(s.StatsInterval)
+	if err != nil {
+		return fmt.Errorf("invalid statsInterval %q", s.StatsInterval)
+	}
+	if d < 0 || (d > 0 && d < minStatsInterval) {
+		return fmt.Errorf("statsInterval must be 0 or at least %s", minStatsInterval)
+	}
+	d, err = time.ParseDuration(s.UpdateCheckInterval)
+	if err != nil {
+		return fmt.Errorf("invalid updateCheckInterval %q", s.UpdateCheckInterval)
+	}
+	if d < 0 || (d > 0 && d < release.MinInterval) {
+		return fmt.Errorf("updateCheckInterval must be 0 or at least %s", release.MinInterval)
+	}
+	return nil
+}
+
+// Masked returns the settings with tokens hidden
+func (s Settings) Masked() Settings {
+	s.EnvMask = append([]string{}, s.EnvMask...)
+	s.Notify.Webhooks = append([]string{}, s.Notify.Webhooks...)
+	for _, token := range []*string{&s.Notify.Gotify.Token, &s.Notify.Pushover.Token, &s.Notify.Telegram.Token} {
+		if *token != "" {
+			*token = masked
+		}
+	}
+	return s
+}
+
+// View is the settings in effect, as the API shows them
+type View struct {
+	Settings
+	Saved []string `json:"saved"` // settings set through the API rather than flags
+}
+
+// saved are the settings set through the API; nil fields come from flags
+type saved struct {
+	StatsInterval       *string         `json:"statsInterval,omitempty"`
+	UpdateCheckInterval *string         `json:"updateCheckInterval,omitempty"`
+	EnvMask             *[]string       `json:"envMask,omitempty"`
+	Notify              *notify.Targets `json:"notify,omitempty"`
+}
+
+// over returns defaults with the saved settings applied
+func (sv saved) over(defaults Settings) Settings {
+	s := defaults
+	if sv.StatsInterval != nil {
+		s.StatsInterval = *sv.StatsInterval
+	}
+	if sv.UpdateCheckInterval != nil {
+		s.UpdateCheckInterval = *sv.UpdateCheckInterval
+	}
+	if sv.EnvMask != nil {
+		s.EnvMask = *sv.EnvMask
+	}
+	if sv.Notify != nil {
+		s.Notify = *sv.Notify
+	}
+	return s
+}
+
+// names lists the settings that are set
+func (sv saved) names() []string {
+	names := []string{}
+	if sv.StatsInterval != nil {
+		names = append(names, "statsInterval")
+	}
+	if sv.UpdateCheckInterval != nil {
+		names = append(names, "updateCheckInterval")
+	}
+	if sv.EnvMask != nil {
+		names = append(names, "envMask")
+	}
+	if sv.Notify != nil {
+		names = append(names, "notify")
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Manager holds the settings in effect and applies changes
+type Manager struct {
+	defaults Settings
+	saved    saved
+	doc      store.Doc // where settings set through the API are saved, if anywhere
+	apply    func(Settings) error
+	mu       sync.Mutex
+}
+
+// New creates a manager with the defaults from flags and the settings saved
+// in doc, and applies them. apply puts settings into effect; it returns an
+// error, having changed nothing, if they can't be. With no doc, settings
+// set through the API last until gosei exits.
+func New(defaults Settings, doc store.Doc, apply func(Settings) error) (*Manager, error) {
+	m := &Manager{defaults: defaults, doc: doc, apply: apply}
+	if doc != nil {
+		if _, err := doc.Load(&m.saved); err != nil {
+			return nil, err
+		}
+	}
+	s := m.saved.over(defaults)
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	if err := apply(s); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Get returns the settings in effect
+func (m *Manager) Get() View {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.view()
+}
+
+// view describes the settings in effect, tokens hidden. Callers hold mu.
+func (m *Manager) view() View {
+	return View{Settings: m.saved.over(m.defaults).Masked(), Saved: m.saved.names()}
+}
+
+// Update changes settings from a JSON object. Settings it has replace the
+// current ones, null returns one to its flag value and the rest stay as
+// they are. A masked token keeps the current one. Nothing changes if the
+// result is invalid or can't be applied.
+func (m *Manager) Update(patch []byte) (View, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &fields); err != nil {
+		return View{}, fmt.Errorf("invalid settings: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current := m.saved.over(m.defaults)
+	next := m.saved
+	for name, raw := range fields {
+		reset := bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+		var err error
+		switch name {
+		case "statsInterval":
+			next.StatsInterval = nil
+			if !reset {
+				err = json.Unmarshal(raw, &next.StatsInterval)
+			}
+		case "updateCheckInterval":
+			next.UpdateCheckInterval = nil
+			if !reset {
+				err = json.Unmarshal(raw, &next.UpdateCheckInterval)
+			}
+		case "envMask":
+			next.EnvMask = nil
+			if !reset {
+				err = json.Unmarshal(raw, &next.EnvMask)
+			}
+		case "notify":
+			next.Notify = nil
+			if !reset {
+				if err = json.Unmarshal(raw, &next.Notify); err == nil {
+					keepTokens(next.Notify, current.Notify)
+				}
+			}
+		default:
+			err = fmt.Errorf("unknown setting")
+		}
+		if err != nil {
+			return View{}, fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	s := next.over(m.defaults)
+	if err := s.Validate(); err != nil {
+		return View{}, err
+	}
+	if err := m.apply(s); err != nil {
+		return View{}, err
+	}
+	m.saved = next
+	if m.doc != nil {
+		if err := m.doc.Save(m.saved); err != nil {
+			return m.view(), fmt.Errorf("%w: %v", ErrNotSaved, err)
+		}
+	}
+	return m.view(), nil
+}
+
+// keepTokens replaces masked tokens in t with the current ones
+func keepTokens(t *notify.Targets, current notify.Targets) {
+	pairs := []struct {
+		token   *string
+		current string
+	}{
+		{&t.Gotify.Token, current.Gotify.Token},
+		{&t.Pushover.Token, current.Pushover.Token},
+		{&t.Telegram.Token, current.Telegram.Token},
+	}
+	for _, p := range pairs {
+		if *p.token == masked {
+			*p.token = p.current
+		}
+	}
+}
@@ -4,10 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/lyall/gosei/internal/ansi"
+	"github.com/lyall/gosei/internal/docker"
 )
 
 // Event represents a server-sent event
@@ -18,10 +24,16 @@ type Event struct {
 
 // Client represents a connected SSE client
 type Client struct {
-	ID       string
-	Events   chan Event
-	Done     chan struct{}
-	LastSeen time.Time
+	ID        string
+	Events    chan Event
+	Done      chan struct{}
+	LastSeen  time.Time
+	Connected time.Time
+
+	RemoteAddr string
+	Topics     []Topic // empty for every topic
+
+	dropped atomic.Uint64 // events dropped or replaced because Events was full
 }
 
 // Broker manages SSE connections and event distribution
@@ -30,6 +42,10 @@ type Broker struct {
 	register   chan *Client
 	unregister chan *Client
 	broadcast  chan Event
+	policy     Policy
+	dropped    atomic.Uint64 // events dropped because broadcast was full
+	subs       map[*Subscription]struct{}
+	subsMu     sync.RWMutex
 	mu         sync.RWMutex
 	ctx        context.Context
 	cancel     context.CancelFunc
@@ -43,6 +59,8 @@ func NewBroker() *Broker {
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan Event, 256),
+		policy:     PolicyDropNewest,
+		subs:       make(map[*Subscription]struct{}),
 		ctx:        ctx,
 		cancel:     cancel,
 	}
@@ -51,6 +69,13 @@ func NewBroker() *Broker {
 	return b
 }
 
+// SetPolicy sets what happens to events for clients that fall behind
+func (b *Broker) SetPolicy(p Policy) {
+	b.mu.Lock()
+	b.policy = p
+	b.mu.Unlock()
+}
+
 // run is the main broker loop
 func (b *Broker) run() {
 	for {
@@ -59,7 +84,7 @@ func (b *Broker) run() {
 			b.mu.Lock()
 			b.clients[client.ID] = client
 			b.mu.Unlock()
-			log.Printf("SSE client connected: %s (total: %d)", client.ID, len(b.clients))
+			slog.Debug("SSE client connected", "client", client.ID, "total", len(b.clients))
 
 		case client := <-b.unregister:
 			b.mu.Lock()
@@ -68,20 +93,33 @@ func (b *Broker) run() {
 				close(client.Events)
 			}
 			b.mu.Unlock()
-			log.Printf("SSE client disconnected: %s (total: %d)", client.ID, len(b.clients))
+			slog.Debug("SSE client disconnected", "client", client.ID, "total", len(b.clients))
 
 		case event := <-b.broadcast:
+			var slow []*Client
 			b.mu.RLock()
 			for _, client := range b.clients {
-				select {
-				case client.Events <- event:
-				default:
-					// Client buffer full, skip this event
-					log.Printf("SSE client %s buffer full, skipping event", client.ID)
+				if !client.wants(Topic(event.Type)) {
+					continue
+				}
+				if !b.deliver(client, event, b.policy) {
+					slow = append(slow, client)
 				}
 			}
 			b.mu.RUnlock()
 
+			if len(slow) > 0 {
+				b.mu.Lock()
+				for _, client := range slow {
+					if _, ok := b.clients[client.ID]; ok {
+						delete(b.clients, client.ID)
+						close(client.Events)
+						slog.Warn("Disconnected slow SSE client", "client", client.ID, "dropped", client.dropped.Load())
+					}
+				}
+				b.mu.Unlock()
+			}
+
 		case <-b.ctx.Done():
 			b.mu.Lock()
 			for _, client := range b.clients {
@@ -94,31 +132,49 @@ func (b *Broker) run() {
 	}
 }
 
+// wants reports whether the client subscribed to a topic
+func (c *Client) wants(topic Topic) bool {
+	if len(c.Topics) == 0 {
+		return true
+	}
+	for _, t := range c.Topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// Disconnect ends a client's stream, reporting false if it isn't connected.
+// A browser's EventSource reconnects as a new client.
+func (b *Broker) Disconnect(id string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	client, ok := b.clients[id]
+	if !ok {
+		return false
+	}
+	delete(b.clients, id)
+	close(client.Events)
+	return true
+}
+
 // Close shuts down the broker
 func (b *Broker) Close() {
 	b.cancel()
 }
 
-// Broadcast sends an event to all connected clients
-func (b *Broker) Broadcast(eventType string, data interface{}) {
+// send queues an event for all connected clients
+func (b *Broker) send(eventType string, data interface{}) {
 	select {
 	case b.broadcast <- Event{Type: eventType, Data: data}:
 	default:
-		log.Printf("Broadcast channel full, dropping event: %s", eventType)
+		b.dropped.Add(1)
+		slog.Warn("Broadcast channel full, dropping event", "event", eventType)
 	}
 }
 
-// BroadcastJSON sends a JSON-serializable event to all clients
-func (b *Broker) BroadcastJSON(eventType string, data interface{}) error {
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event data: %w", err)
-	}
-
-	b.Broadcast(eventType, string(jsonData))
-	return nil
-}
-
 // ClientCount returns the number of connected clients
 func (b *Broker) ClientCount() int {
 	b.mu.RLock()
@@ -126,19 +182,64 @@ func (b *Broker) ClientCount() int {
 	return len(b.clients)
 }
 
-// ServeHTTP handles SSE connections
+// ClientStats describes a connected client's delivery
+type ClientStats struct {
+	ID         string    `json:"id"`
+	RemoteAddr string    `json:"remoteAddr"`
+	Topics     []Topic   `json:"topics"` // empty for every topic
+	Connected  time.Time `json:"connected"`
+	Queued     int       `json:"queued"` // events waiting to be written
+	Capacity   int       `json:"capacity"`
+	Dropped    uint64    `json:"dropped"`
+}
+
+// Stats describes the broker's delivery to clients
+type Stats struct {
+	Policy  Policy        `json:"policy"`
+	Dropped uint64        `json:"dropped"` // dropped before reaching any client
+	Clients []ClientStats `json:"clients"`
+}
+
+// Stats returns the backpressure policy and drop counters, oldest client
+// first
+func (b *Broker) Stats() Stats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	stats := Stats{Policy: b.policy, Dropped: b.dropped.Load(), Clients: make([]ClientStats, 0, len(b.clients))}
+	for _, c := range b.clients {
+		stats.Clients = append(stats.Clients, ClientStats{
+			ID:         c.ID,
+			RemoteAddr: c.RemoteAddr,
+			Topics:     append([]Topic{}, c.Topics...),
+			Connected:  c.Connected,
+			Queued:     len(c.Events),
+			Capacity:   cap(c.Events),
+			Dropped:    c.dropped.Load(),
+		})
+	}
+	sort.Slice(stats.Clients, func(i, j int) bool { return stats.Clients[i].Connected.Before(stats.Clients[j].Connected) })
+	return stats
+}
+
+// Backlog returns the number of queued broadcast events and the queue capacity
+func (b *Broker) Backlog() (int, int) {
+	return len(b.broadcast), cap(b.broadcast)
+}
+
+// ServeHTTP handles SSE connections. ?topics= takes a comma-separated list
+// of event types to receive instead of all of them.
 func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Set headers for SSE
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("X-Accel-Buffering", "no")
 
 	// Disable write deadline for SSE connections (they are long-lived)
 	rc := http.NewResponseController(w)
 	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
-		log.Printf("Warning: could not disable write deadline: %v", err)
+		slog.WarnContext(r.Context(), "Could not disable write deadline", "error", err)
 	}
 
 	// Create flusher
@@ -151,10 +252,18 @@ func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Create client
 	clientID := fmt.Sprintf("%d", time.Now().UnixNano())
 	client := &Client{
-		ID:       clientID,
-		Events:   make(chan Event, 64),
-		Done:     make(chan struct{}),
-		LastSeen: time.Now(),
+		ID:        clientID,
+		Events:    make(chan Event, 64),
+		Done:      make(chan struct{}),
+		LastSeen:  time.Now(),
+		Connected: time.Now(),
+
+		RemoteAddr: r.RemoteAddr,
+	}
+	for _, t := range strings.Split(r.URL.Query().Get("topics"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			client.Topics = append(client.Topics, Topic(t))
+		}
 	}
 
 	// Register client
@@ -183,7 +292,7 @@ func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 			data, err := formatEventData(event.Data)
 			if err != nil {
-				log.Printf("Failed to format event data: %v", err)
+				slog.ErrorContext(r.Context(), "Failed to format event data", "event", event.Type, "error", err)
 				continue
 			}
 
@@ -219,40 +328,60 @@ func formatEventData(data interface{}) (string, error) {
 
 // ContainerStatusEvent represents a container status change
 type ContainerStatusEvent struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Status  string `json:"status"`
-	State   string `json:"state"`
-	Health  string `json:"health"`
-	Project string `json:"project"`
-	Service string `json:"service"`
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Status  string    `json:"status"`
+	State   string    `json:"state"`
+	Health  string    `json:"health"`
+	Project string    `json:"project"`
+	Service string    `json:"service"`
+	Time    time.Time `json:"time"`
 }
 
 // ContainerStatsEvent represents container resource usage
 type ContainerStatsEvent struct {
 	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	Project       string  `json:"project,omitempty"`
 	CPUPercent    float64 `json:"cpuPercent"`
 	MemoryUsage   uint64  `json:"memoryUsage"`
 	MemoryLimit   uint64  `json:"memoryLimit"`
 	MemoryPercent float64 `json:"memoryPercent"`
 }
 
+// ContainerStatsEvents is the stats of every running container, sampled
+// together
+type ContainerStatsEvents []ContainerStatsEvent
+
+// ResourceEvent is a Docker image, volume or network event, broadcast as
+// image:event, volume:event or network:event
+type ResourceEvent struct {
+	Kind      string    `json:"-"` // image, volume or network
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Action    string    `json:"action"` // e.g. pull, delete, create, destroy, connect
+	Timestamp time.Time `json:"timestamp"`
+}
+
 // LogLineEvent represents a log line
 type LogLineEvent struct {
-	ContainerID string    `json:"containerId"`
-	Container   string    `json:"container"`
-	Line        string    `json:"line"`
-	Stream      string    `json:"stream"`
-	Timestamp   time.Time `json:"timestamp"`
+	ContainerID string      `json:"containerId"`
+	Container   string      `json:"container"`
+	Service     string      `json:"service,omitempty"`
+	Line        string      `json:"line"`
+	Spans       []ansi.Span `json:"spans,omitempty"` // with ?ansi=spans, for colorized lines
+	Stream      string      `json:"stream"`
+	Timestamp   time.Time   `json:"timestamp"`
 }
 
 // ProjectStatusEvent represents a project status change
 type ProjectStatusEvent struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Status  string `json:"status"`
-	Running int    `json:"running"`
-	Total   int    `json:"total"`
+	ID      string               `json:"id"`
+	Name    string               `json:"name"`
+	Status  string               `json:"status"`
+	Running int                  `json:"running"`
+	Total   int                  `json:"total"`
+	Failed  []docker.ServiceExit `json:"failed,omitempty"` // services that exited non-zero
 }
 
 // ComposeOutputEvent represents compose command output
@@ -268,5 +397,34 @@ type ComposeCompleteEvent struct {
 	ProjectID string `json:"projectId"`
 	Operation string `json:"operation"`
 	Success   bool   `json:"success"`
+	Status    string `json:"status"` // "success", "failed", "cancelled" or "timeout"
 	Message   string `json:"message"`
 }
+
+// ComposeProgressEvent represents pull progress of one compose service
+type ComposeProgressEvent struct {
+	ProjectID string  `json:"projectId"`
+	Operation string  `json:"operation"`
+	Service   string  `json:"service"`
+	Stage     string  `json:"stage"`
+	Percent   float64 `json:"percent"`
+}
+
+// ImageProgressEvent represents pull progress of one image layer
+type ImageProgressEvent struct {
+	PullID  string  `json:"pullId"`
+	Image   string  `json:"image"`
+	Layer   string  `json:"layer"`
+	Status  string  `json:"status"`
+	Percent float64 `json:"percent"`
+	Current int64   `json:"current,omitempty"`
+	Total   int64   `json:"total,omitempty"`
+}
+
+// ImagePullCompleteEvent represents image pull completion
+type ImagePullCompleteEvent struct {
+	PullID  string `json:"pullId"`
+	Image   string `json:"image"`
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,119 @@
+package sse
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// Topic names a kind of event; it is the SSE event type browsers listen for
+type Topic string
+
+const (
+	TopicContainerStatus    Topic = "container:status"
+	TopicContainerStats     Topic = "container:stats"
+	TopicProjectStatus      Topic = "project:status"
+	TopicComposeOutput      Topic = "compose:output"
+	TopicComposeProgress    Topic = "compose:progress"
+	TopicComposeComplete    Topic = "compose:complete"
+	TopicImageProgress      Topic = "image:progress"
+	TopicImageComplete      Topic = "image:complete"
+	TopicImageScan          Topic = "image:scan"
+	TopicImageEvent         Topic = "image:event"
+	TopicVolumeEvent        Topic = "volume:event"
+	TopicNetworkEvent       Topic = "network:event"
+	TopicAlert              Topic = "alert"
+	TopicAutoUpdateComplete Topic = "autoupdate:complete"
+	TopicProbeStatus        Topic = "probe:status"
+)
+
+// Message is an event published on the broker. Its JSON encoding is what
+// SSE clients receive; subscribers inside gosei receive the value itself.
+type Message interface {
+	Topic() Topic
+}
+
+// subscriptionBuffer is how many messages a subscriber may fall behind by
+// before messages are dropped for it
+const subscriptionBuffer = 64
+
+// Subscription receives the messages published on a topic that pass its
+// filter, for consumers inside gosei such as alerts
+type Subscription struct {
+	C <-chan Message
+
+	c       chan Message
+	topic   Topic
+	filter  func(Message) bool
+	dropped atomic.Uint64
+	broker  *Broker
+	once    sync.Once
+}
+
+// Subscribe returns a subscription to topic, or to every topic when topic
+// is empty. filter may be nil to receive all of the topic's messages. A
+// subscriber that falls behind misses messages rather than blocking
+// publishers.
+func (b *Broker) Subscribe(topic Topic, filter func(Message) bool) *Subscription {
+	c := make(chan Message, subscriptionBuffer)
+	s := &Subscription{C: c, c: c, topic: topic, filter: filter, broker: b}
+
+	b.subsMu.Lock()
+	b.subs[s] = struct{}{}
+	b.subsMu.Unlock()
+	return s
+}
+
+// Close ends the subscription and closes C
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.broker.subsMu.Lock()
+		delete(s.broker.subs, s)
+		close(s.c)
+		s.broker.subsMu.Unlock()
+	})
+}
+
+// Dropped returns how many messages were dropped because C was full
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Publish sends a message to the subscribers of its topic and, as JSON, to
+// every SSE client
+func (b *Broker) Publish(m Message) {
+	topic := m.Topic()
+
+	b.subsMu.RLock()
+	for s := range b.subs {
+		if (s.topic != "" && s.topic != topic) || (s.filter != nil && !s.filter(m)) {
+			continue
+		}
+		select {
+		case s.c <- m:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+	b.subsMu.RUnlock()
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		slog.Error("Failed to marshal event", "event", topic, "error", err)
+		return
+	}
+	b.send(string(topic), string(data))
+}
+
+func (ContainerStatusEvent) Topic() Topic   { return TopicContainerStatus }
+func (ContainerStatsEvents) Topic() Topic   { return TopicContainerStats }
+func (ProjectStatusEvent) Topic() Topic     { return TopicProjectStatus }
+func (ComposeOutputEvent) Topic() Topic     { return TopicComposeOutput }
+func (ComposeProgressEvent) Topic() Topic   { return TopicComposeProgress }
+func (ComposeCompleteEvent) Topic() Topic   { return TopicComposeComplete }
+func (ImageProgressEvent) Topic() Topic     { return TopicImageProgress }
+func (ImagePullCompleteEvent) Topic() Topic { return TopicImageComplete }
+
+// Topic is image:event, volume:event or network:event by the event's kind
+func (e ResourceEvent) Topic() Topic { return Topic(e.Kind + ":event") }
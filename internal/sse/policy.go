@@ -0,0 +1,160 @@
+package sse
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Policy decides what happens to an event for a client whose buffer is
+// full, i.e. a browser that isn't reading fast enough
+type Policy string
+
+const (
+	// PolicyDropNewest drops the new event
+	PolicyDropNewest Policy = "drop-newest"
+	// PolicyDropOldest drops the oldest queued event to make room
+	PolicyDropOldest Policy = "drop-oldest"
+	// PolicyCoalesce replaces a queued event about the same thing, such as
+	// the status of the same container, with the new one; other events
+	// fall back to drop-oldest
+	PolicyCoalesce Policy = "coalesce"
+	// PolicyDisconnect disconnects the client, whose EventSource reconnects
+	// and reloads its state
+	PolicyDisconnect Policy = "disconnect"
+)
+
+// ParsePolicy parses a backpressure policy name
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case PolicyDropNewest, PolicyDropOldest, PolicyCoalesce, PolicyDisconnect:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid SSE policy %q: expected drop-newest, drop-oldest, coalesce or disconnect", s)
+}
+
+// coalesceFields lists, for event types that carry the latest state of
+// something, the fields identifying that something. Only the latest event
+// per key matters, so older ones can be replaced. Other types, such as
+// compose:output lines, can't be coalesced.
+var coalesceFields = map[string][]string{
+	"container:status": {"id"},
+	"container:stats":  {}, // each event has the stats of every container
+	"project:status":   {"id"},
+	"compose:progress": {"projectId", "service"},
+	"image:progress":   {"pullId", "layer"},
+}
+
+// coalesceKey returns the key events replacing each other share, or "" if
+// the event can't be coalesced
+func coalesceKey(e Event) string {
+	fields, ok := coalesceFields[e.Type]
+	if !ok {
+		return ""
+	}
+
+	data, err := formatEventData(e.Data)
+	if err != nil {
+		return ""
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &values); err != nil && len(fields) > 0 {
+		return ""
+	}
+
+	key := []string{e.Type}
+	for _, f := range fields {
+		key = append(key, string(values[f]))
+	}
+	return strings.Join(key, "\x00")
+}
+
+// deliver queues an event for a client, applying the policy when its buffer
+// is full. It reports false when the client should be disconnected. Only
+// the broker loop sends to client.Events, so the buffer can't fill up again
+// while it is being made room in.
+func (b *Broker) deliver(client *Client, event Event, policy Policy) bool {
+	select {
+	case client.Events <- event:
+		return true
+	default:
+	}
+
+	client.dropped.Add(1)
+	slog.Debug("SSE client buffer full", "client", client.ID, "event", event.Type, "policy", policy)
+
+	switch policy {
+	case PolicyDisconnect:
+		return false
+
+	case PolicyDropOldest:
+		select {
+		case <-client.Events:
+		default:
+		}
+
+	case PolicyCoalesce:
+		if b.coalesce(client, event) {
+			return true
+		}
+		select {
+		case <-client.Events:
+		default:
+		}
+
+	default:
+		return true
+	}
+
+	select {
+	case client.Events <- event:
+	default:
+	}
+	return true
+}
+
+// coalesce replaces the queued event with the same key, if any. The queue
+// is drained and refilled in order; the client reading meanwhile only takes
+// events older than those drained.
+func (b *Broker) coalesce(client *Client, event Event) bool {
+	key := coalesceKey(event)
+	if key == "" {
+		return false
+	}
+
+	var queued []Event
+drain:
+	for {
+		select {
+		case e := <-client.Events:
+			queued = append(queued, e)
+		default:
+			break drain
+		}
+	}
+
+	replaced := false
+	for i, e := range queued {
+		if coalesceKey(e) == key {
+			queued[i] = event
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		// Make room for the event as drop-oldest would
+		if len(queued) > 0 {
+			queued = queued[1:]
+		}
+		queued = append(queued, event)
+	}
+
+	for _, e := range queued {
+		select {
+		case client.Events <- e:
+		default:
+		}
+	}
+	return true
+}
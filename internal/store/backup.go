@@ -0,0 +1,162 @@
+package store
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// manifestName is the backup's description within the archive
+const manifestName = "manifest.json"
+
+// Manifest describes a backup
+type Manifest struct {
+	Version string    `json:"version"` // of the gosei that made it
+	Schema  int       `json:"schema"`
+	Created time.Time `json:"created"`
+}
+
+// Backup writes a gzipped tar archive of the database, a consistent
+// snapshot taken while gosei keeps running, with a manifest
+func (s *Store) Backup(w io.Writer, version string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		schema, err := schema(tx)
+		if err != nil {
+			return err
+		}
+		manifest, err := json.MarshalIndent(Manifest{Version: version, Schema: schema, Created: now}, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: manifestName, Mode: 0o600, Size: int64(len(manifest)), ModTime: now}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(manifest); err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: FileName, Mode: 0o600, Size: tx.Size(), ModTime: now}); err != nil {
+			return err
+		}
+		_, err = tx.WriteTo(tw)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Restore replaces the database in dir with the one in a backup archive
+// from Backup. The current database, if any, is kept beside it as
+// gosei.db.bak. gosei must not be running on dir.
+func Restore(dir string, r io.Reader) (Manifest, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return Manifest{}, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, FileName+".*.restore")
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	manifest, err := extract(r, tmp)
+	tmp.Close()
+	if err != nil {
+		return Manifest{}, err
+	}
+	version, err := check(tmp.Name())
+	if err != nil {
+		return Manifest{}, fmt.Errorf("backup database is unusable: %w", err)
+	}
+	if version > len(migrations) {
+		return Manifest{}, fmt.Errorf("backup schema %d is newer than this gosei supports (%d)", version, len(migrations))
+	}
+
+	// Holding the current database shows gosei isn't using it, and keeps it
+	// from starting on it until the new one is in place
+	path := filepath.Join(dir, FileName)
+	if _, err := os.Stat(path); err == nil {
+		current, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout})
+		if errors.Is(err, bolt.ErrTimeout) {
+			return Manifest{}, fmt.Errorf("%s is in use; stop gosei before restoring", path)
+		} else if err != nil {
+			return Manifest{}, fmt.Errorf("failed to open current database: %w", err)
+		}
+		defer current.Close()
+		if err := os.Rename(path, path+".bak"); err != nil {
+			return Manifest{}, err
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return Manifest{}, err
+	}
+	return manifest, nil
+}
+
+// extract reads a backup archive, writing its database to db
+func extract(r io.Reader, db io.Writer) (Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("not a gosei backup: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest *Manifest
+	found := false
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return Manifest{}, fmt.Errorf("invalid backup archive: %w", err)
+		}
+		switch h.Name {
+		case manifestName:
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return Manifest{}, fmt.Errorf("invalid backup manifest: %w", err)
+			}
+		case FileName:
+			if _, err := io.Copy(db, tr); err != nil {
+				return Manifest{}, err
+			}
+			found = true
+		}
+	}
+	if manifest == nil || !found {
+		return Manifest{}, errors.New("not a gosei backup: missing manifest or database")
+	}
+	return *manifest, nil
+}
+
+// check opens the database at path read-only and returns its schema
+// version. Older schemas are migrated when gosei next opens the database.
+func check(path string) (int, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout, ReadOnly: true})
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	var version int
+	err = db.View(func(tx *bolt.Tx) error {
+		version, err = schema(tx)
+		return err
+	})
+	return version, err
+}
@@ -0,0 +1,236 @@
+// Package store keeps gosei's own state in an embedded bbolt database in
+// the data directory, so it survives restarts without a database server.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// FileName is the database file within the data directory
+const FileName = "gosei.db"
+
+// openTimeout bounds waiting for the database lock, so a second gosei on
+// the same data directory fails instead of hanging
+const openTimeout = 2 * time.Second
+
+var (
+	metaBucket = []byte("meta")
+	docsBucket = []byte("docs")
+	schemaKey  = []byte("schema")
+)
+
+// migrations bring the database up to date, in order. The schema version
+// is the number applied; append new ones, never change or remove old ones.
+var migrations = []func(tx *bolt.Tx) error{
+	// 1: state kept as whole JSON documents
+	func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(docsBucket)
+		return err
+	},
+}
+
+// Store is gosei's database
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens the database in dir, creating both if needed, and applies
+// pending migrations
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	path := filepath.Join(dir, FileName)
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%s is in use by another process", path)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	s := &Store{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Path returns the database file
+func (s *Store) Path() string {
+	return s.db.Path()
+}
+
+// Schema returns the schema version of the database
+func (s *Store) Schema() (int, error) {
+	var version int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		version, err = schema(tx)
+		return err
+	})
+	return version, err
+}
+
+// migrate applies the migrations the database hasn't had in one
+// transaction, so a failing one leaves the database as it was
+func (s *Store) migrate() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		version, err := schema(tx)
+		if err != nil {
+			return err
+		}
+		if version > len(migrations) {
+			return fmt.Errorf("database schema %d is newer than this gosei supports (%d)", version, len(migrations))
+		}
+		for i := version; i < len(migrations); i++ {
+			if err := migrations[i](tx); err != nil {
+				return fmt.Errorf("migration %d failed: %w", i+1, err)
+			}
+		}
+		return meta.Put(schemaKey, []byte(strconv.Itoa(len(migrations))))
+	})
+}
+
+// schema reads the schema version, 0 for a new database
+func schema(tx *bolt.Tx) (int, error) {
+	meta := tx.Bucket(metaBucket)
+	if meta == nil {
+		return 0, nil
+	}
+	v := meta.Get(schemaKey)
+	if v == nil {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(string(v))
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema version %q", v)
+	}
+	return version, nil
+}
+
+// Doc is a piece of state saved and loaded whole as JSON, either in the
+// database or in a file of its own
+type Doc interface {
+	// Load decodes the saved state into v, reporting false if none is saved
+	Load(v any) (bool, error)
+	// Save replaces the saved state with v
+	Save(v any) error
+}
+
+// Doc returns the document called name in the database
+func (s *Store) Doc(name string) Doc {
+	return dbDoc{db: s.db, name: []byte(name)}
+}
+
+// Import copies the file at path into the document called name, unless
+// the document is already saved, so state kept in a file before the data
+// directory was set carries over. It reports whether anything was copied.
+func (s *Store) Import(name, path string) (bool, error) {
+	if path == "" {
+		return false, nil
+	}
+	doc := s.Doc(name)
+	var raw json.RawMessage
+	if ok, err := doc.Load(&raw); ok || err != nil {
+		return false, err
+	}
+	ok, err := File(path).Load(&raw)
+	if !ok || err != nil {
+		return false, err
+	}
+	return true, doc.Save(raw)
+}
+
+type dbDoc struct {
+	db   *bolt.DB
+	name []byte
+}
+
+func (d dbDoc) Load(v any) (bool, error) {
+	var data []byte
+	err := d.db.View(func(tx *bolt.Tx) error {
+		// Copied, since bbolt's slices are only valid within the transaction
+		data = append(data, tx.Bucket(docsBucket).Get(d.name)...)
+		return nil
+	})
+	if err != nil || data == nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("invalid %s: %w", d.name, err)
+	}
+	return true, nil
+}
+
+func (d dbDoc) Save(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(docsBucket).Put(d.name, data)
+	})
+}
+
+// File returns a document kept in the file at path, or nil for an empty
+// path
+func File(path string) Doc {
+	if path == "" {
+		return nil
+	}
+	return fileDoc(path)
+}
+
+type fileDoc string
+
+func (f fileDoc) Load(v any) (bool, error) {
+	data, err := os.ReadFile(string(f))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("invalid %s: %w", filepath.Base(string(f)), err)
+	}
+	return true, nil
+}
+
+// Save replaces the file atomically, so a crash mid-write keeps the old one
+func (f fileDoc) Save(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := string(f)
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,98 @@
+// Package terminal tracks the command sessions users have open in
+// containers, so admins can see who is running what where and end them.
+package terminal
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Kind is the kind of a session
+type Kind string
+
+// KindExec is a non-interactive command run through the exec endpoint
+const KindExec Kind = "exec"
+
+// ErrTerminated is the cause a terminated session's context is cancelled
+// with
+var ErrTerminated = errors.New("session terminated by an admin")
+
+// Session is a command running in a container on a user's behalf
+type Session struct {
+	ID          string    `json:"id"`
+	Kind        Kind      `json:"kind"`
+	ContainerID string    `json:"containerId"`
+	Container   string    `json:"container"`
+	Command     string    `json:"command"`        // the executable only, as arguments may hold secrets
+	User        string    `json:"user,omitempty"` // empty without authentication
+	RemoteAddr  string    `json:"remoteAddr"`
+	Started     time.Time `json:"started"`
+
+	cancel context.CancelCauseFunc
+}
+
+// Registry tracks open sessions
+type Registry struct {
+	sessions map[string]*Session
+	next     uint64
+	mu       sync.Mutex
+}
+
+// NewRegistry creates an empty session registry
+func NewRegistry() *Registry {
+	return &Registry{sessions: make(map[string]*Session)}
+}
+
+// Start registers a session, returning a context for its command that is
+// cancelled with ErrTerminated if it's terminated, and a func to call when
+// it ends
+func (r *Registry) Start(ctx context.Context, s Session) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	r.mu.Lock()
+	r.next++
+	s.ID = strconv.FormatUint(r.next, 10)
+	s.Started = time.Now()
+	s.cancel = cancel
+	r.sessions[s.ID] = &s
+	r.mu.Unlock()
+
+	end := func() {
+		r.mu.Lock()
+		delete(r.sessions, s.ID)
+		r.mu.Unlock()
+		cancel(nil)
+	}
+	return ctx, end
+}
+
+// List returns the open sessions, oldest first
+func (r *Registry) List() []Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sessions := make([]Session, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		sessions = append(sessions, *s)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Started.Before(sessions[j].Started) })
+	return sessions
+}
+
+// Terminate ends a session by cancelling its context, reporting false if
+// there is no such session
+func (r *Registry) Terminate(id string) bool {
+	r.mu.Lock()
+	s, ok := r.sessions[id]
+	delete(r.sessions, id)
+	r.mu.Unlock()
+
+	if ok {
+		s.cancel(ErrTerminated)
+	}
+	return ok
+}
@@ -0,0 +1,97 @@
+package vuln
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Trivy scans images with the trivy CLI
+type Trivy struct {
+	path       string
+	dockerHost string
+}
+
+// NewTrivy creates a Trivy scanner. dockerHost is passed to trivy so it
+// inspects images on the same daemon gosei manages.
+func NewTrivy(path, dockerHost string) *Trivy {
+	if path == "" {
+		path = "trivy"
+	}
+	return &Trivy{path: path, dockerHost: dockerHost}
+}
+
+// Name returns the scanner name
+func (t *Trivy) Name() string {
+	return "trivy"
+}
+
+// trivyOutput is the subset of trivy's JSON report gosei uses
+type trivyOutput struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID  string `json:"VulnerabilityID"`
+			PkgName          string `json:"PkgName"`
+			InstalledVersion string `json:"InstalledVersion"`
+			FixedVersion     string `json:"FixedVersion"`
+			Severity         string `json:"Severity"`
+			Title            string `json:"Title"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// Scan runs trivy against an image
+func (t *Trivy) Scan(ctx context.Context, image string) (*Report, error) {
+	args := []string{"image", "--format", "json", "--quiet", "--scanners", "vuln"}
+	if t.dockerHost != "" {
+		args = append(args, "--docker-host", t.dockerHost)
+	}
+	// The image ends the flags, so it can't be taken for one
+	args = append(args, "--", image)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("trivy failed: %s", lastLine(msg))
+		}
+		return nil, fmt.Errorf("trivy failed: %w", err)
+	}
+
+	var out trivyOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, fmt.Errorf("invalid trivy output: %w", err)
+	}
+
+	report := &Report{
+		Image:     image,
+		Scanner:   t.Name(),
+		Status:    "complete",
+		ScannedAt: time.Now(),
+	}
+	for _, result := range out.Results {
+		for _, v := range result.Vulnerabilities {
+			report.Counts.Add(v.Severity)
+			report.Vulnerabilities = append(report.Vulnerabilities, Vulnerability{
+				ID:               v.VulnerabilityID,
+				Package:          v.PkgName,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+				Severity:         v.Severity,
+				Title:            v.Title,
+			})
+		}
+	}
+	return report, nil
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(s, "\n")
+	return lines[len(lines)-1]
+}
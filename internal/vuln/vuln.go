@@ -0,0 +1,140 @@
+package vuln
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lyall/gosei/internal/sse"
+)
+
+// Severity levels in the order they are reported
+const (
+	SeverityCritical = "CRITICAL"
+	SeverityHigh     = "HIGH"
+	SeverityMedium   = "MEDIUM"
+	SeverityLow      = "LOW"
+	SeverityUnknown  = "UNKNOWN"
+)
+
+// Scanner scans images for known vulnerabilities
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, image string) (*Report, error)
+}
+
+// Counts holds the number of vulnerabilities per severity
+type Counts struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+	Unknown  int `json:"unknown"`
+}
+
+// Add counts one vulnerability of the given severity
+func (c *Counts) Add(severity string) {
+	switch strings.ToUpper(severity) {
+	case SeverityCritical:
+		c.Critical++
+	case SeverityHigh:
+		c.High++
+	case SeverityMedium:
+		c.Medium++
+	case SeverityLow:
+		c.Low++
+	default:
+		c.Unknown++
+	}
+}
+
+// Total returns the number of vulnerabilities of any severity
+func (c Counts) Total() int {
+	return c.Critical + c.High + c.Medium + c.Low + c.Unknown
+}
+
+// Worst returns the highest severity found, lowercased for use in CSS
+// classes, or "none"
+func (c Counts) Worst() string {
+	switch {
+	case c.Critical > 0:
+		return "critical"
+	case c.High > 0:
+		return "high"
+	case c.Medium > 0:
+		return "medium"
+	case c.Low > 0:
+		return "low"
+	case c.Unknown > 0:
+		return "unknown"
+	}
+	return "none"
+}
+
+// Vulnerability is a single finding
+type Vulnerability struct {
+	ID               string `json:"id"`
+	Package          string `json:"package"`
+	InstalledVersion string `json:"installedVersion"`
+	FixedVersion     string `json:"fixedVersion,omitempty"`
+	Severity         string `json:"severity"`
+	Title            string `json:"title,omitempty"`
+}
+
+// Report is the result of scanning one image
+type Report struct {
+	Image           string          `json:"image"`
+	Scanner         string          `json:"scanner"`
+	Status          string          `json:"status"` // "scanning", "complete", "failed"
+	Error           string          `json:"error,omitempty"`
+	StartedAt       time.Time       `json:"startedAt"`
+	ScannedAt       time.Time       `json:"scannedAt,omitempty"`
+	Counts          Counts          `json:"counts"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities,omitempty"`
+}
+
+// Topic publishes finished scans as image:scan
+func (*Report) Topic() sse.Topic { return sse.TopicImageScan }
+
+// Store keeps the latest report per image in memory
+type Store struct {
+	reports map[string]*Report
+	mu      sync.RWMutex
+}
+
+// NewStore creates an empty report store
+func NewStore() *Store {
+	return &Store{reports: make(map[string]*Report)}
+}
+
+// Get returns the latest report for an image
+func (s *Store) Get(image string) (*Report, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.reports[image]
+	return r, ok
+}
+
+// Delete forgets the report for an image, e.g. once it was removed. A scan
+// in progress is kept so its result isn't lost.
+func (s *Store) Delete(image string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.reports[image]; ok && r.Status != "scanning" {
+		delete(s.reports, image)
+	}
+}
+
+// Put records a report for an image, replacing any previous one
+func (s *Store) Put(image string, r *Report) {
+	s.mu.Lock()
+	s.reports[image] = r
+	s.mu.Unlock()
+}
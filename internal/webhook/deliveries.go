@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// deliveryLogSize is how many recent deliveries are kept
+const deliveryLogSize = 500
+
+// ErrNotFound is returned for a delivery that isn't in the log
+var ErrNotFound = errors.New("delivery not found")
+
+// Delivery states
+const (
+	StatePending   = "pending"
+	StateDelivered = "delivered"
+	StateFailed    = "failed"
+)
+
+// Delivery is one event sent to one webhook, with its attempts so far
+type Delivery struct {
+	ID       string    `json:"id"`
+	Webhook  string    `json:"webhook"`
+	State    string    `json:"state"`
+	Error    string    `json:"error,omitempty"` // why it failed
+	Created  time.Time `json:"created"`
+	Attempts []Attempt `json:"attempts"`
+	Payload  Payload   `json:"payload"`
+}
+
+// Attempt is one try at a delivery
+type Attempt struct {
+	Time     time.Time     `json:"time"`
+	Status   int           `json:"status,omitempty"` // HTTP status, if the webhook answered
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+
+	retry bool // whether a failure may succeed when tried again
+}
+
+func (d *Delivery) snapshot() *Delivery {
+	c := *d
+	c.Attempts = append([]Attempt{}, d.Attempts...)
+	return &c
+}
+
+// deliveryLog keeps the most recent deliveries, oldest first
+type deliveryLog struct {
+	mu         sync.Mutex
+	size       int
+	deliveries []*Delivery
+}
+
+func newDeliveryLog(size int) *deliveryLog {
+	return &deliveryLog{size: size}
+}
+
+// add logs a new pending delivery of a payload. Each delivery has its own
+// ID, while redeliveries keep the payload's.
+func (l *deliveryLog) add(webhook string, p Payload) *Delivery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	d := &Delivery{ID: newID(), Webhook: webhook, State: StatePending, Created: time.Now(), Payload: p}
+	l.deliveries = append(l.deliveries, d)
+	if len(l.deliveries) > l.size {
+		l.deliveries = l.deliveries[len(l.deliveries)-l.size:]
+	}
+	return d
+}
+
+func (l *deliveryLog) attempt(d *Delivery, a Attempt) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	d.Attempts = append(d.Attempts, a)
+}
+
+func (l *deliveryLog) finish(d *Delivery, state, err string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	d.State, d.Error = state, err
+}
+
+func (l *deliveryLog) get(id string) (*Delivery, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, d := range l.deliveries {
+		if d.ID == id {
+			return d.snapshot(), true
+		}
+	}
+	return nil, false
+}
+
+// Filter selects deliveries; empty fields match any
+type Filter struct {
+	Webhook string
+	Event   string
+	State   string
+}
+
+// Deliveries returns logged deliveries matching f, newest first
+func (s *Sink) Deliveries(f Filter) []*Delivery {
+	s.log.mu.Lock()
+	defer s.log.mu.Unlock()
+
+	deliveries := []*Delivery{}
+	for i := len(s.log.deliveries) - 1; i >= 0; i-- {
+		d := s.log.deliveries[i]
+		if (f.Webhook == "" || d.Webhook == f.Webhook) &&
+			(f.Event == "" || d.Payload.Type == f.Event) &&
+			(f.State == "" || d.State == f.State) {
+			deliveries = append(deliveries, d.snapshot())
+		}
+	}
+	return deliveries
+}
+
+// Delivery returns a logged delivery
+func (s *Sink) Delivery(id string) (*Delivery, error) {
+	d, ok := s.log.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return d, nil
+}
@@ -0,0 +1,365 @@
+// Package webhook delivers selected gosei events as signed JSON to outgoing
+// webhooks, retrying failed deliveries with backoff and keeping a log of
+// recent deliveries.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/lyall/gosei/internal/autoupdate"
+	"github.com/lyall/gosei/internal/docker"
+	"github.com/lyall/gosei/internal/monitor"
+	"github.com/lyall/gosei/internal/sse"
+	"gopkg.in/yaml.v3"
+)
+
+// Event types a webhook can select
+const (
+	EventContainerDied   = "container.died"
+	EventOperationFailed = "operation.failed"
+	EventUpdateApplied   = "update.applied"
+	EventAlert           = "alert"
+)
+
+// EventTypes lists every event type, the default selection
+var EventTypes = []string{EventContainerDied, EventOperationFailed, EventUpdateApplied, EventAlert}
+
+const (
+	// maxAttempts is how many times a delivery is tried before it fails
+	maxAttempts = 5
+
+	// firstRetry is the wait before the first retry, doubling after each
+	firstRetry = 5 * time.Second
+
+	// maxRetryAfter caps how long a Retry-After header can delay a retry
+	maxRetryAfter = 5 * time.Minute
+
+	// queueSize is how many deliveries may wait for a webhook before new
+	// ones fail rather than pile up behind an endpoint that is down
+	queueSize = 100
+
+	sendTimeout = 10 * time.Second
+)
+
+// Endpoint is an outgoing webhook as configured
+type Endpoint struct {
+	Name   string   `yaml:"name" json:"name"`
+	URL    string   `yaml:"url" json:"-"` // may hold a token, so never shown
+	Events []string `yaml:"events" json:"events"`
+	Secret string   `yaml:"secret" json:"-"` // signs payloads; $VARS are expanded
+}
+
+// LoadFile reads webhooks from a YAML file holding a list of endpoints,
+// each with a url and optionally a name (the URL's host by default), the
+// events it receives (all by default) and a secret to sign payloads with
+func LoadFile(path string) ([]Endpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []Endpoint
+	if err := yaml.Unmarshal(data, &endpoints); err != nil {
+		return nil, fmt.Errorf("invalid webhooks file: %w", err)
+	}
+	for i := range endpoints {
+		if err := endpoints[i].validate(); err != nil {
+			return nil, fmt.Errorf("webhook %d: %w", i+1, err)
+		}
+		endpoints[i].Secret = os.ExpandEnv(endpoints[i].Secret)
+	}
+	return endpoints, nil
+}
+
+func (e *Endpoint) validate() error {
+	u, err := url.Parse(e.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q", e.URL)
+	}
+	if e.Name == "" {
+		e.Name = u.Host
+	}
+	if len(e.Events) == 0 {
+		e.Events = EventTypes
+	}
+	for _, ev := range e.Events {
+		if !slices.Contains(EventTypes, ev) {
+			return fmt.Errorf("unknown event %q: expected one of %v", ev, EventTypes)
+		}
+	}
+	return nil
+}
+
+// Payload is the JSON body posted to a webhook
+type Payload struct {
+	ID   string    `json:"id"` // the delivery, the same across its retries
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// ContainerDied is the data of a container.died event
+type ContainerDied struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Project   string `json:"project,omitempty"`
+	Service   string `json:"service,omitempty"`
+	ExitCode  int    `json:"exitCode"`
+	OOMKilled bool   `json:"oomKilled,omitempty"`
+}
+
+// Sink turns events published on the broker into webhook deliveries
+type Sink struct {
+	broker *sse.Broker
+	docker docker.DockerClient
+	client *http.Client
+	hooks  []*hook
+	log    *deliveryLog
+}
+
+// hook is an endpoint with its queue of deliveries
+type hook struct {
+	Endpoint
+	queue chan *Delivery
+}
+
+// NewSink creates a sink for the endpoints. The Docker client adds exit
+// codes to container.died events.
+func NewSink(endpoints []Endpoint, b *sse.Broker, dc docker.DockerClient) *Sink {
+	s := &Sink{
+		broker: b,
+		docker: dc,
+		client: &http.Client{Timeout: sendTimeout},
+		log:    newDeliveryLog(deliveryLogSize),
+	}
+	for _, e := range endpoints {
+		s.hooks = append(s.hooks, &hook{Endpoint: e, queue: make(chan *Delivery, queueSize)})
+	}
+	return s
+}
+
+// Endpoints returns the configured webhooks
+func (s *Sink) Endpoints() []Endpoint {
+	endpoints := make([]Endpoint, len(s.hooks))
+	for i, h := range s.hooks {
+		endpoints[i] = h.Endpoint
+	}
+	return endpoints
+}
+
+// Run delivers events until ctx is cancelled. Each webhook gets its events
+// in order, one delivery at a time, so retries delay later deliveries to
+// the same webhook but not to others.
+func (s *Sink) Run(ctx context.Context) {
+	for _, h := range s.hooks {
+		go s.work(ctx, h)
+	}
+
+	sub := s.broker.Subscribe("", func(m sse.Message) bool {
+		switch m := m.(type) {
+		case sse.ContainerStatusEvent:
+			return m.Status == "die" || m.Status == "kill"
+		case sse.ComposeCompleteEvent:
+			return !m.Success
+		case autoupdate.Result:
+			return m.Success && len(m.Updated) > 0
+		case monitor.Alert:
+			return true
+		}
+		return false
+	})
+	defer sub.Close()
+
+	crashes := docker.NewCrashDetector()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if e, ok := m.(sse.ContainerStatusEvent); ok && !crashes.Crashed(e.ID, e.Status, e.Time) {
+				continue
+			}
+			typ, data := s.event(ctx, m)
+			s.publish(typ, data)
+		}
+	}
+}
+
+// event converts a broker message into a webhook event
+func (s *Sink) event(ctx context.Context, m sse.Message) (string, any) {
+	switch m := m.(type) {
+	case sse.ContainerStatusEvent:
+		died := ContainerDied{ID: m.ID, Name: m.Name, Project: m.Project, Service: m.Service}
+		// The die event doesn't say how; inspecting does while the
+		// container is still there
+		if c, err := s.docker.GetContainer(ctx, m.ID); err == nil {
+			died.ExitCode, died.OOMKilled = c.ExitCode, c.OOMKilled
+		}
+		return EventContainerDied, died
+	case sse.ComposeCompleteEvent:
+		return EventOperationFailed, m
+	case autoupdate.Result:
+		return EventUpdateApplied, m
+	default:
+		return EventAlert, m
+	}
+}
+
+// publish queues an event for every webhook that selected it
+func (s *Sink) publish(typ string, data any) {
+	for _, h := range s.hooks {
+		if !slices.Contains(h.Events, typ) {
+			continue
+		}
+		d := s.log.add(h.Name, Payload{ID: newID(), Type: typ, Time: time.Now(), Data: data})
+		select {
+		case h.queue <- d:
+		default:
+			s.log.finish(d, StateFailed, "queue full: webhook is too far behind")
+			slog.Warn("Webhook queue full, dropping event", "webhook", h.Name, "event", typ)
+		}
+	}
+}
+
+// Redeliver queues a logged delivery again as a new delivery with the same
+// payload, e.g. once an endpoint is fixed
+func (s *Sink) Redeliver(id string) (*Delivery, error) {
+	old, ok := s.log.get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	for _, h := range s.hooks {
+		if h.Name != old.Webhook {
+			continue
+		}
+		d := s.log.add(h.Name, old.Payload)
+		select {
+		case h.queue <- d:
+			return d.snapshot(), nil
+		default:
+			s.log.finish(d, StateFailed, "queue full: webhook is too far behind")
+			return nil, fmt.Errorf("webhook %s is too far behind", h.Name)
+		}
+	}
+	return nil, fmt.Errorf("webhook %s is no longer configured", old.Webhook)
+}
+
+// work delivers a webhook's queued deliveries until ctx is cancelled
+func (s *Sink) work(ctx context.Context, h *hook) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-h.queue:
+			s.deliver(ctx, h, d)
+		}
+	}
+}
+
+// deliver tries a delivery until it succeeds, fails permanently or runs
+// out of attempts, waiting longer after each failure
+func (s *Sink) deliver(ctx context.Context, h *hook, d *Delivery) {
+	body, err := json.Marshal(d.Payload)
+	if err != nil {
+		s.log.finish(d, StateFailed, err.Error())
+		return
+	}
+
+	wait := firstRetry
+	for attempt := 1; ; attempt++ {
+		a, retryAfter := s.send(ctx, h, d, body)
+		s.log.attempt(d, a)
+		switch {
+		case a.Error == "":
+			s.log.finish(d, StateDelivered, "")
+			return
+		case !a.retry || attempt == maxAttempts:
+			s.log.finish(d, StateFailed, a.Error)
+			slog.Warn("Webhook delivery failed", "webhook", h.Name, "event", d.Payload.Type, "attempts", attempt, "error", a.Error)
+			return
+		}
+
+		if retryAfter > 0 {
+			wait = min(retryAfter, maxRetryAfter)
+		}
+		select {
+		case <-ctx.Done():
+			s.log.finish(d, StateFailed, "shutting down")
+			return
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// send makes one delivery attempt, reporting how long the webhook asked to
+// wait before retrying, if it did
+func (s *Sink) send(ctx context.Context, h *hook, d *Delivery, body []byte) (Attempt, time.Duration) {
+	a := Attempt{Time: time.Now()}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		a.Error = err.Error()
+		return a, 0
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gosei-webhook")
+	req.Header.Set("X-Gosei-Event", d.Payload.Type)
+	req.Header.Set("X-Gosei-Delivery", d.Payload.ID)
+	if h.Secret != "" {
+		req.Header.Set("X-Gosei-Signature", Sign(h.Secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		a.Error, a.retry = err.Error(), true
+		a.Duration = time.Since(a.Time)
+		return a, 0
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	a.Status = resp.StatusCode
+	a.Duration = time.Since(a.Time)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return a, 0
+	}
+	a.Error = "webhook returned " + resp.Status
+	// Other client errors won't change by sending the same payload again
+	a.retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	secs, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+	return a, time.Duration(secs) * time.Second
+}
+
+// Sign returns the X-Gosei-Signature header for a payload: the hex
+// HMAC-SHA256 of the body keyed with the webhook's secret, prefixed
+// "sha256=". Receivers compute the same over the raw body and compare in
+// constant time.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
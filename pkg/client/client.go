@@ -0,0 +1,280 @@
+// Package client is a Go client for the gosei API, for tools that manage
+// compose projects on a gosei server or agent without hand-rolling HTTP
+// requests. It speaks version 1 of the API, under /api/v1.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// apiPrefix is where the version of the API this package speaks is served
+const apiPrefix = "/api/v1"
+
+// Client calls the API of one gosei server or agent
+type Client struct {
+	// HTTPClient sends the requests, http.DefaultClient when nil. Its
+	// Timeout also ends event and log streams, so leave it unset and use
+	// contexts to bound calls instead.
+	HTTPClient *http.Client
+
+	// Token is sent as a bearer token, as gosei agents require
+	Token string
+
+	base *url.URL
+}
+
+// New creates a client for the gosei instance at baseURL, such as
+// http://localhost:8080
+func New(baseURL string) (*Client, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: expected http or https", baseURL)
+	}
+	base.Path = strings.TrimSuffix(base.Path, "/")
+	return &Client{base: base}, nil
+}
+
+// Error is a request the API answered with an error status
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound reports whether err is the API answering 404 Not Found
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// ListProjects returns every compose project
+func (c *Client) ListProjects(ctx context.Context) ([]Project, error) {
+	var projects []Project
+	err := c.do(ctx, http.MethodGet, "/projects", nil, nil, &projects)
+	return projects, err
+}
+
+// GetProject returns a project with its containers
+func (c *Client) GetProject(ctx context.Context, id string) (*Project, error) {
+	var p Project
+	if err := c.do(ctx, http.MethodGet, "/projects/"+url.PathEscape(id), nil, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Preflight checks that a project's external networks and volumes and its
+// env files exist, so that up won't fail for lack of them
+func (c *Client) Preflight(ctx context.Context, id string) (*PreflightResult, error) {
+	var result PreflightResult
+	if err := c.do(ctx, http.MethodGet, "/projects/"+url.PathEscape(id)+"/preflight", nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ProjectNetworks returns the networks a project's services join, with the
+// containers attached to each and their addresses and aliases
+func (c *Client) ProjectNetworks(ctx context.Context, id string) ([]ProjectNetwork, error) {
+	var networks []ProjectNetwork
+	err := c.do(ctx, http.MethodGet, "/projects/"+url.PathEscape(id)+"/networks", nil, nil, &networks)
+	return networks, err
+}
+
+// Up runs docker compose up for a project. The operation runs in the
+// background: its output and outcome arrive as compose:output and
+// compose:complete events (see StreamEvents).
+func (c *Client) Up(ctx context.Context, id string, opts UpOptions) (*Operation, error) {
+	return c.operation(ctx, id, "up", opts)
+}
+
+// Down runs docker compose down for a project in the background
+func (c *Client) Down(ctx context.Context, id string) (*Operation, error) {
+	return c.operation(ctx, id, "down", nil)
+}
+
+// Start runs docker compose start for a project in the background
+func (c *Client) Start(ctx context.Context, id string) (*Operation, error) {
+	return c.operation(ctx, id, "start", nil)
+}
+
+// Stop runs docker compose stop for a project in the background
+func (c *Client) Stop(ctx context.Context, id string) (*Operation, error) {
+	return c.operation(ctx, id, "stop", nil)
+}
+
+// Restart runs docker compose restart for a project in the background
+func (c *Client) Restart(ctx context.Context, id string) (*Operation, error) {
+	return c.operation(ctx, id, "restart", nil)
+}
+
+// Pull runs docker compose pull for a project in the background
+func (c *Client) Pull(ctx context.Context, id string) (*Operation, error) {
+	return c.operation(ctx, id, "pull", nil)
+}
+
+// CancelOperation cancels the operation running on a project
+func (c *Client) CancelOperation(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/projects/"+url.PathEscape(id)+"/operations/cancel", nil, nil, nil)
+}
+
+func (c *Client) operation(ctx context.Context, id, name string, body any) (*Operation, error) {
+	var op Operation
+	if err := c.do(ctx, http.MethodPost, "/projects/"+url.PathEscape(id)+"/"+name, nil, body, &op); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// ListContainers returns every container
+func (c *Client) ListContainers(ctx context.Context) ([]Container, error) {
+	var containers []Container
+	err := c.do(ctx, http.MethodGet, "/containers", nil, nil, &containers)
+	return containers, err
+}
+
+// GetContainer returns a container by ID, ID prefix or name
+func (c *Client) GetContainer(ctx context.Context, id string) (*Container, error) {
+	var container Container
+	if err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id), nil, nil, &container); err != nil {
+		return nil, err
+	}
+	return &container, nil
+}
+
+// StartContainer starts a container
+func (c *Client) StartContainer(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/start", nil, nil, nil)
+}
+
+// StopContainer stops a container
+func (c *Client) StopContainer(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/stop", nil, nil, nil)
+}
+
+// RestartContainer restarts a container
+func (c *Client) RestartContainer(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/restart", nil, nil, nil)
+}
+
+// ConnectNetwork connects a container to a network, optionally with DNS
+// aliases and a static address
+func (c *Client) ConnectNetwork(ctx context.Context, id string, opts ConnectOptions) error {
+	return c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/networks", nil, opts, nil)
+}
+
+// DisconnectNetwork disconnects a container from a network. Force removes
+// the endpoint even if the container is unreachable.
+func (c *Client) DisconnectNetwork(ctx context.Context, id, network string, force bool) error {
+	var query url.Values
+	if force {
+		query = url.Values{"force": {"true"}}
+	}
+	return c.do(ctx, http.MethodDelete, "/containers/"+url.PathEscape(id)+"/networks/"+url.PathEscape(network), query, nil, nil)
+}
+
+// Logs returns a container's recent log lines
+func (c *Client) Logs(ctx context.Context, id string, opts LogOptions) ([]LogLine, error) {
+	var resp struct {
+		Lines []LogLine `json:"lines"`
+	}
+	err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/logs", opts.query(false), nil, &resp)
+	return resp.Lines, err
+}
+
+// Version returns the version of the gosei instance
+func (c *Client) Version(ctx context.Context) (*Version, error) {
+	var v Version
+	if err := c.do(ctx, http.MethodGet, "/system/version", nil, nil, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// do sends a request with an optional JSON body, decoding a successful
+// response into out when it isn't nil
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	resp, err := c.send(ctx, method, path, query, r, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", path, err)
+	}
+	return nil
+}
+
+// send sends a request, turning error statuses into an *Error
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body io.Reader, accept string) (*http.Response, error) {
+	u := *c.base
+	u.Path += apiPrefix + path
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, readError(resp)
+	}
+	return resp, nil
+}
+
+// readError reads the message of an error response, which is JSON from
+// handlers and plain text from middleware
+func readError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var body struct {
+		Error string `json:"error"`
+	}
+	msg := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		msg = body.Error
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: msg}
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Event is a server-sent event from the API
+type Event struct {
+	Type string
+	Data json.RawMessage
+}
+
+// Decode decodes the event's data, e.g. into a ProjectStatusEvent for a
+// project:status event
+func (e Event) Decode(v any) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// EventStream reads events until it is closed or the connection ends
+type EventStream struct {
+	sse   *sseReader
+	event Event
+}
+
+// StreamEvents opens the stream of server-sent events: container and
+// project status changes, compose output and the like. types limits it to
+// those event types, all of them when empty. To follow an operation, open
+// the stream before starting it, so its compose:complete event can't be
+// missed.
+func (c *Client) StreamEvents(ctx context.Context, types ...string) (*EventStream, error) {
+	q := url.Values{}
+	if len(types) > 0 {
+		q.Set("topics", strings.Join(types, ","))
+	}
+	resp, err := c.send(ctx, http.MethodGet, "/events", q, nil, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+	return &EventStream{sse: newSSEReader(resp.Body)}, nil
+}
+
+// Next waits for the next event, reporting false once the stream ends
+func (s *EventStream) Next() bool {
+	for s.sse.next() {
+		// The greeting only names the connection
+		if s.sse.event == "connected" {
+			continue
+		}
+		s.event = Event{Type: s.sse.event, Data: json.RawMessage(s.sse.data)}
+		return true
+	}
+	return false
+}
+
+// Event returns the event read by Next
+func (s *EventStream) Event() Event { return s.event }
+
+// Err returns the error that ended the stream, if it didn't end by being
+// closed or by the server closing it
+func (s *EventStream) Err() error { return s.sse.error() }
+
+// Close ends the stream
+func (s *EventStream) Close() error { return s.sse.close() }
+
+// LogStream reads the lines of a followed container log
+type LogStream struct {
+	sse  *sseReader
+	line LogLine
+	err  error
+}
+
+// FollowLogs streams a container's log lines, starting with those opts
+// selects and then the lines it writes, until the container stops or the
+// stream is closed
+func (c *Client) FollowLogs(ctx context.Context, id string, opts LogOptions) (*LogStream, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/logs", opts.query(true), nil, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+	return &LogStream{sse: newSSEReader(resp.Body)}, nil
+}
+
+// Next waits for the next line, reporting false once the stream ends
+func (s *LogStream) Next() bool {
+	for s.err == nil && s.sse.next() {
+		if s.sse.event != "log" {
+			continue
+		}
+		var event struct {
+			Line      string    `json:"line"`
+			Stream    string    `json:"stream"`
+			Timestamp time.Time `json:"timestamp"`
+		}
+		if err := json.Unmarshal([]byte(s.sse.data), &event); err != nil {
+			s.err = fmt.Errorf("failed to decode log line: %w", err)
+			return false
+		}
+		s.line = LogLine{Timestamp: event.Timestamp, Stream: event.Stream, Message: event.Line}
+		return true
+	}
+	return false
+}
+
+// Line returns the line read by Next
+func (s *LogStream) Line() LogLine { return s.line }
+
+// Err returns the error that ended the stream, if it didn't end by being
+// closed or by the server closing it
+func (s *LogStream) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.sse.error()
+}
+
+// Close ends the stream
+func (s *LogStream) Close() error { return s.sse.close() }
+
+// sseReader parses a text/event-stream response into events
+type sseReader struct {
+	body   io.ReadCloser
+	r      *bufio.Reader
+	event  string
+	data   string
+	err    error
+	closed atomic.Bool // Close may be called while next is reading
+}
+
+func newSSEReader(body io.ReadCloser) *sseReader {
+	return &sseReader{body: body, r: bufio.NewReader(body)}
+}
+
+// next reads the next event, skipping comments such as keepalives
+func (s *sseReader) next() bool {
+	s.event, s.data = "", ""
+	var data []string
+	for s.err == nil {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			s.err = err
+			return false
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line == "" {
+			if data == nil {
+				continue
+			}
+			if s.event == "" {
+				s.event = "message"
+			}
+			s.data = strings.Join(data, "\n")
+			return true
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			s.event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	return false
+}
+
+func (s *sseReader) error() error {
+	if s.closed.Load() || s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
+
+func (s *sseReader) close() error {
+	s.closed.Store(true)
+	return s.body.Close()
+}
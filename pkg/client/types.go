@@ -0,0 +1,305 @@
+package client
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Project is a compose project
+type Project struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Host       string      `json:"host,omitempty"` // the agent serving it, for projects of other hosts
+	Path       string      `json:"path"`
+	Status     string      `json:"status"` // "running", "partial", "stopped" or "unknown"
+	Running    int         `json:"running"`
+	Total      int         `json:"total"`
+	Services   []Service   `json:"services"`
+	Links      []Link      `json:"links,omitempty"`
+	Networks   []Resource  `json:"networks,omitempty"`
+	Volumes    []Resource  `json:"volumes,omitempty"`
+	Containers []Container `json:"containers,omitempty"` // only set by GetProject
+
+	// PortMismatches lists running containers that don't publish the ports
+	// their service declares, as when started from an older compose file.
+	// Only set by GetProject.
+	PortMismatches []PortCheck `json:"portMismatches,omitempty"`
+
+	// Preflight lists external networks and volumes and env files the
+	// project needs but lacks. Only set by GetProject.
+	Preflight []PreflightIssue `json:"preflight,omitempty"`
+}
+
+// PreflightIssue is something a project needs that is missing, so that up
+// would fail
+type PreflightIssue struct {
+	Kind     string   `json:"kind"` // "network", "volume" or "env_file"
+	Name     string   `json:"name"` // on the host, or the env file's path
+	Services []string `json:"services,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// PreflightResult is the outcome of checking a project before up
+type PreflightResult struct {
+	Ready  bool             `json:"ready"`
+	Issues []PreflightIssue `json:"issues"`
+}
+
+// Link is a quick link from a project to its app, docs or monitoring
+type Link struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Resource is a network or named volume a compose file defines
+type Resource struct {
+	Key      string `json:"key"`  // as the compose file names it
+	Name     string `json:"name"` // as it is named on the host
+	External bool   `json:"external,omitempty"`
+	Driver   string `json:"driver,omitempty"`
+	Exists   *bool  `json:"exists,omitempty"` // on the host; only checked by GetProject
+}
+
+// ProjectNetwork is a network a project's services join, with every
+// container attached to it
+type ProjectNetwork struct {
+	Resource
+	Subnets    []Subnet        `json:"subnets,omitempty"`
+	Containers []NetworkMember `json:"containers"`
+}
+
+// Subnet is an address range of a network
+type Subnet struct {
+	Subnet  string `json:"subnet"` // in CIDR notation
+	Gateway string `json:"gateway,omitempty"`
+}
+
+// NetworkMember is a container on a network
+type NetworkMember struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Project     string   `json:"project,omitempty"`
+	Service     string   `json:"service,omitempty"`
+	IPAddress   string   `json:"ipAddress,omitempty"`
+	IPv6Address string   `json:"ipv6Address,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"` // DNS names on the network besides the container's
+}
+
+// Service is a service of a compose project as its compose file declares it
+type Service struct {
+	Name      string            `json:"name"`
+	Image     string            `json:"image"`
+	Ports     []string          `json:"ports"`
+	DependsOn []string          `json:"dependsOn"`
+	Labels    map[string]string `json:"labels"`
+	Icon      string            `json:"icon,omitempty"` // an icon name such as postgres, or an image URL
+}
+
+// Container is a Docker container
+type Container struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Image       string            `json:"image"`
+	ImageID     string            `json:"imageId"`
+	Status      string            `json:"status"` // as docker ps shows it, e.g. "Up 2 hours"
+	State       string            `json:"state"`  // e.g. "running" or "exited"
+	Health      string            `json:"health"`
+	Created     time.Time         `json:"created"`
+	Ports       []Port            `json:"ports"`
+	Labels      map[string]string `json:"labels"`
+	ProjectName string            `json:"projectName"`
+	ServiceName string            `json:"serviceName"`
+	ExitCode    int               `json:"exitCode,omitempty"`
+	PortCheck   *PortCheck        `json:"portCheck,omitempty"` // only set by GetContainer
+	Networks    []Attachment      `json:"networks,omitempty"`  // only set by GetContainer
+}
+
+// Attachment is a container's endpoint on a network
+type Attachment struct {
+	Network     string   `json:"network"`
+	IPAddress   string   `json:"ipAddress,omitempty"`
+	IPv6Address string   `json:"ipv6Address,omitempty"`
+	Gateway     string   `json:"gateway,omitempty"`
+	MacAddress  string   `json:"macAddress,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+}
+
+// ConnectOptions connect a container to a network
+type ConnectOptions struct {
+	Network     string   `json:"network"`
+	Aliases     []string `json:"aliases,omitempty"`     // extra DNS names on the network
+	IPv4Address string   `json:"ipv4Address,omitempty"` // a static address in the network's subnet
+	IPv6Address string   `json:"ipv6Address,omitempty"`
+}
+
+// PortCheck compares the ports a compose container publishes with those its
+// service declares
+type PortCheck struct {
+	Service    string         `json:"service"`
+	Container  string         `json:"container"`
+	Declared   []DeclaredPort `json:"declared"`
+	Published  []Port         `json:"published"`
+	Missing    []DeclaredPort `json:"missing,omitempty"`    // declared but not published
+	Unexpected []Port         `json:"unexpected,omitempty"` // published but not declared
+	Mismatch   bool           `json:"mismatch"`
+}
+
+// DeclaredPort is a port mapping as a compose file declares it. HostPort
+// may be a range, or empty for a port the engine picks.
+type DeclaredPort struct {
+	HostIP        string `json:"hostIp,omitempty"`
+	HostPort      string `json:"hostPort,omitempty"`
+	ContainerPort string `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+}
+
+// Port is a published container port
+type Port struct {
+	HostIP        string `json:"hostIp"`
+	HostPort      string `json:"hostPort"`
+	ContainerPort string `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+	URL           string `json:"url,omitempty"`
+}
+
+// UpOptions are the flags of docker compose up
+type UpOptions struct {
+	Pull          string `json:"pull,omitempty"` // "always", "missing", "never" or "build"
+	Build         bool   `json:"build,omitempty"`
+	ForceRecreate bool   `json:"forceRecreate,omitempty"`
+	Timeout       *int   `json:"timeout,omitempty"` // seconds to wait for containers to stop
+}
+
+// Operation is a compose operation started in the background
+type Operation struct {
+	ProjectID string `json:"projectId"`
+	Operation string `json:"operation"`
+	Status    string `json:"status"`
+}
+
+// LogOptions selects container log lines
+type LogOptions struct {
+	// Tail is how many of the latest lines to return: a number or "all".
+	// The server defaults to 100, or all lines when Since is set.
+	Tail string
+
+	// Since only returns lines after this time. Set it to the timestamp of
+	// the last line received to resume where a stream ended.
+	Since time.Time
+
+	// ANSI is what to do with escape sequences of colorized output: "keep",
+	// "strip" (the server's default) or "spans"
+	ANSI string
+}
+
+func (o LogOptions) query(follow bool) url.Values {
+	q := url.Values{}
+	if o.Tail != "" {
+		q.Set("tail", o.Tail)
+	}
+	if !o.Since.IsZero() {
+		q.Set("since", o.Since.Format(time.RFC3339Nano))
+	}
+	if o.ANSI != "" {
+		q.Set("ansi", o.ANSI)
+	}
+	if follow {
+		q.Set("follow", strconv.FormatBool(follow))
+	}
+	return q
+}
+
+// LogLine is a line of container output
+type LogLine struct {
+	Timestamp time.Time `json:"timestamp"`
+	Stream    string    `json:"stream"` // stdout or stderr
+	Message   string    `json:"message"`
+}
+
+// Version describes a gosei instance
+type Version struct {
+	Version     string         `json:"version"`
+	Commit      string         `json:"commit"`
+	BuildDate   string         `json:"buildDate"`
+	APIVersions []int          `json:"apiVersions"`
+	GoVersion   string         `json:"goVersion"`
+	OS          string         `json:"os"`
+	Arch        string         `json:"arch"`
+	Compose     ComposeInfo    `json:"compose"`
+	Docker      *DockerVersion `json:"docker,omitempty"`
+	DockerError string         `json:"dockerError,omitempty"` // why Docker is nil
+}
+
+// ComposeInfo is the compose implementation the instance runs
+type ComposeInfo struct {
+	Command string `json:"command"`
+	Version string `json:"version"`
+	Plugin  bool   `json:"plugin"`
+	Error   string `json:"error,omitempty"`
+}
+
+// DockerVersion identifies the Docker daemon the instance manages
+type DockerVersion struct {
+	Version          string `json:"version"`
+	APIVersion       string `json:"apiVersion"`
+	ClientAPIVersion string `json:"clientApiVersion"`
+	GitCommit        string `json:"gitCommit,omitempty"`
+	GoVersion        string `json:"goVersion,omitempty"`
+	OS               string `json:"os"`
+	Arch             string `json:"arch"`
+	KernelVersion    string `json:"kernelVersion,omitempty"`
+}
+
+// Event types, to pass to StreamEvents to receive only those
+const (
+	EventContainerStatus = "container:status"
+	EventContainerStats  = "container:stats"
+	EventProjectStatus   = "project:status"
+	EventComposeOutput   = "compose:output"
+	EventComposeProgress = "compose:progress"
+	EventComposeComplete = "compose:complete"
+	EventImageProgress   = "image:progress"
+	EventImageComplete   = "image:complete"
+	EventAlert           = "alert"
+)
+
+// ContainerStatusEvent is the data of a container:status event
+type ContainerStatusEvent struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Status  string    `json:"status"`
+	State   string    `json:"state"`
+	Health  string    `json:"health"`
+	Project string    `json:"project"`
+	Service string    `json:"service"`
+	Time    time.Time `json:"time"`
+}
+
+// ProjectStatusEvent is the data of a project:status event
+type ProjectStatusEvent struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Running int    `json:"running"`
+	Total   int    `json:"total"`
+}
+
+// ComposeOutputEvent is the data of a compose:output event, a line of
+// output of a compose operation
+type ComposeOutputEvent struct {
+	ProjectID string `json:"projectId"`
+	Operation string `json:"operation"`
+	Line      string `json:"line"`
+	Stream    string `json:"stream"`
+}
+
+// ComposeCompleteEvent is the data of a compose:complete event, sent when a
+// compose operation finishes
+type ComposeCompleteEvent struct {
+	ProjectID string `json:"projectId"`
+	Operation string `json:"operation"`
+	Success   bool   `json:"success"`
+	Status    string `json:"status"` // "success", "failed", "cancelled" or "timeout"
+	Message   string `json:"message"`
+}
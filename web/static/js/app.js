@@ -103,7 +103,7 @@
                 this.source.close();
             }
 
-            this.source = new EventSource('/api/events');
+            this.source = new EventSource('/api/v1/events');
 
             this.source.onopen = () => {
                 console.log('SSE connected');
@@ -138,11 +138,43 @@
                 this.handleComposeOutput(data);
             });
 
+            this.source.addEventListener('compose:progress', (e) => {
+                const data = JSON.parse(e.data);
+                this.handleComposeProgress(data);
+            });
+
             this.source.addEventListener('compose:complete', (e) => {
                 const data = JSON.parse(e.data);
                 this.handleComposeComplete(data);
             });
 
+            this.source.addEventListener('autoupdate:complete', (e) => {
+                const data = JSON.parse(e.data);
+                if (!data.success) {
+                    Toast.error(`Auto-update of ${data.projectId} failed: ${data.message}`);
+                } else if (data.updated && data.updated.length > 0) {
+                    Toast.success(`Auto-update of ${data.projectId}: ${data.message}`);
+                }
+            });
+
+            this.source.addEventListener('alert', (e) => {
+                const data = JSON.parse(e.data);
+                if (data.resolved) {
+                    Toast.show(`Resolved: ${data.message}`, 'info');
+                } else {
+                    Toast.show(`Alert: ${data.message}`, 'error', 10000);
+                }
+            });
+
+            this.source.addEventListener('image:scan', (e) => {
+                const data = JSON.parse(e.data);
+                this.handleImageScan(data);
+            });
+
+            this.source.addEventListener('container:stats', (e) => {
+                Stats.receive(JSON.parse(e.data));
+            });
+
             this.source.addEventListener('log', (e) => {
                 const data = JSON.parse(e.data);
                 this.handleLogLine(data);
@@ -213,6 +245,23 @@
                 }, 500);
             }
 
+            // Refresh the standalone page containers section (debounced)
+            if (document.querySelector('.standalone-page') && !data.project) {
+                debounce('standalone-containers-refresh', () => {
+                    const section = document.getElementById('containers-section');
+                    if (section) {
+                        fetch('/partials/standalone/containers')
+                            .then(r => r.text())
+                            .then(html => {
+                                section.outerHTML = html;
+                                const newSection = document.getElementById('containers-section');
+                                if (newSection) htmx.process(newSection);
+                            })
+                            .catch(err => console.error('Failed to refresh containers:', err));
+                    }
+                }, 500);
+            }
+
             // Refresh container detail page (debounced)
             const containerPage = document.querySelector('.container-page');
             if (containerPage) {
@@ -276,14 +325,25 @@
             }
         },
 
-        handleComposeOutput(data) {
+        openOutputModal(projectId) {
             const modal = document.getElementById('output-modal');
-            const outputLog = document.getElementById('output-log');
-
+            const cancel = document.getElementById('output-cancel');
+            if (modal && cancel && ComposeOps.activeOperations.has(projectId)) {
+                modal.dataset.projectId = projectId;
+                cancel.style.display = '';
+            }
             if (modal && modal.style.display === 'none') {
+                const outputLog = document.getElementById('output-log');
+                const progress = document.getElementById('output-progress');
                 if (outputLog) outputLog.innerHTML = '';
+                if (progress) progress.innerHTML = '';
                 modal.style.display = 'flex';
             }
+        },
+
+        handleComposeOutput(data) {
+            const outputLog = document.getElementById('output-log');
+            this.openOutputModal(data.projectId);
 
             if (outputLog) {
                 const line = document.createElement('div');
@@ -294,11 +354,46 @@
             }
         },
 
+        handleComposeProgress(data) {
+            this.openOutputModal(data.projectId);
+
+            const container = document.getElementById('output-progress');
+            if (!container) return;
+
+            let row = Array.from(container.children).find(el => el.dataset.service === data.service);
+            if (!row) {
+                row = document.createElement('div');
+                row.className = 'progress-row';
+                row.dataset.service = data.service;
+                row.innerHTML = `
+                    <span class="progress-service"></span>
+                    <div class="progress-bar"><div class="progress-fill"></div></div>
+                    <span class="progress-stage"></span>
+                `;
+                row.querySelector('.progress-service').textContent = data.service;
+                container.appendChild(row);
+            }
+
+            const percent = Math.round(data.percent);
+            row.querySelector('.progress-fill').style.width = `${percent}%`;
+            row.querySelector('.progress-stage').textContent =
+                data.stage === 'pulled' || data.stage === 'skipped' ? data.stage : `${data.stage} ${percent}%`;
+            row.classList.toggle('progress-error', data.stage === 'error');
+        },
+
         handleComposeComplete(data) {
             ComposeOps.endOperation(data.projectId);
 
+            const modal = document.getElementById('output-modal');
+            const cancel = document.getElementById('output-cancel');
+            if (modal && cancel && modal.dataset.projectId === data.projectId) {
+                cancel.style.display = 'none';
+            }
+
             if (data.success) {
                 Toast.success(`${data.operation} completed successfully`);
+            } else if (data.status === 'cancelled' || data.status === 'timeout') {
+                Toast.show(`${data.operation}: ${data.message}`);
             } else {
                 Toast.error(`${data.operation} failed: ${data.message}`);
             }
@@ -322,6 +417,22 @@
             }
         },
 
+        handleImageScan(data) {
+            const slots = document.querySelectorAll(`.vuln-slot[data-image-id="${data.image}"]`);
+            if (slots.length === 0) return;
+
+            if (data.status === 'failed') {
+                Toast.error(`Image scan failed: ${data.error}`);
+            } else {
+                const c = data.counts;
+                Toast.success(`Scan complete: ${c.critical} critical, ${c.high} high`);
+            }
+
+            fetch(`/partials/images/${data.image}/vuln-badge`)
+                .then(r => r.text())
+                .then(html => slots.forEach(slot => { slot.outerHTML = html; }));
+        },
+
         handleLogLine(data) {
             const logsContent = document.querySelector('.logs-content');
             if (logsContent) {
@@ -331,10 +442,8 @@
                 const timestamp = new Date(data.timestamp);
                 const timeStr = timestamp.toTimeString().split(' ')[0];
 
-                line.innerHTML = `
-                    <span class="log-timestamp">${timeStr}</span>
-                    <span class="log-message">${this.escapeHtml(data.line)}</span>
-                `;
+                line.innerHTML = `<span class="log-timestamp">${timeStr}</span>`;
+                line.appendChild(renderLogMessage(data));
 
                 logsContent.appendChild(line);
                 logsContent.scrollTop = logsContent.scrollHeight;
@@ -364,11 +473,40 @@
         }
     };
 
+    // Builds the message of a log line, styled by its spans when the logs
+    // were requested with ?ansi=spans
+    function renderLogMessage(data) {
+        const message = document.createElement('span');
+        message.className = 'log-message';
+        if (!data.spans) {
+            message.textContent = data.line;
+            return message;
+        }
+
+        for (const span of data.spans) {
+            const el = document.createElement('span');
+            el.textContent = span.text;
+            const classes = [];
+            if (span.fg && !span.fg.startsWith('#')) classes.push('ansi-' + span.fg);
+            if (span.bg && !span.bg.startsWith('#')) classes.push('ansi-bg-' + span.bg);
+            if (span.bold) classes.push('ansi-bold');
+            if (span.dim) classes.push('ansi-dim');
+            if (span.italic) classes.push('ansi-italic');
+            if (span.underline) classes.push('ansi-underline');
+            el.className = classes.join(' ');
+            if (span.fg && span.fg.startsWith('#')) el.style.color = span.fg;
+            if (span.bg && span.bg.startsWith('#')) el.style.backgroundColor = span.bg;
+            message.appendChild(el);
+        }
+        return message;
+    }
+
     // ============================================
     // Stats Polling
     // ============================================
     const Stats = {
         interval: null,
+        pushedAt: 0, // last container:stats event from the server's sampler
 
         start() {
             // Poll stats every 5 seconds for visible containers
@@ -384,23 +522,62 @@
         },
 
         async poll() {
+            this.pollHost();
+
+            // The server pushes stats when its sampler is enabled
+            if (Date.now() - this.pushedAt < 15000) return;
+
             const statsCells = document.querySelectorAll('[data-stats-id]');
             if (statsCells.length === 0) return;
 
-            for (const cell of statsCells) {
-                const containerId = cell.dataset.statsId;
-                try {
-                    const response = await fetch(`/api/containers/${containerId}/stats`);
-                    if (response.ok) {
-                        const stats = await response.json();
-                        cell.innerHTML = `${this.formatPercent(stats.cpuPercent)} / ${this.formatBytes(stats.memoryUsage)}`;
-                    }
-                } catch (e) {
-                    // Ignore errors, container might be stopped
+            // One request for all visible containers; stopped ones are omitted
+            const params = new URLSearchParams();
+            statsCells.forEach(cell => params.append('id', cell.dataset.statsId));
+            try {
+                const response = await fetch(`/api/v1/containers/stats?${params}`);
+                if (!response.ok) return;
+                this.render(await response.json());
+            } catch (e) {
+                // Ignore errors, the next poll retries
+            }
+        },
+
+        receive(stats) {
+            this.pushedAt = Date.now();
+            this.render(stats);
+        },
+
+        render(stats) {
+            const byName = new Map(stats.map(s => [s.name, s]));
+            for (const cell of document.querySelectorAll('[data-stats-id]')) {
+                const s = byName.get(cell.dataset.statsId);
+                if (s) {
+                    cell.innerHTML = `${this.formatPercent(s.cpuPercent)} / ${this.formatBytes(s.memoryUsage)}`;
                 }
             }
         },
 
+        async pollHost() {
+            const el = document.getElementById('host-stats');
+            if (!el) return;
+
+            try {
+                const response = await fetch('/api/v1/system/stats');
+                if (!response.ok) return;
+                const stats = await response.json();
+                const host = stats.host;
+                const cpu = host.cpus > 0 ? stats.containers.cpuPercent / host.cpus : 0;
+                const parts = [
+                    `CPU ${this.formatPercent(cpu)} of ${host.cpus} cores`,
+                    `Mem ${this.formatBytes(host.memoryUsed || stats.containers.memoryUsage)} / ${this.formatBytes(host.memoryTotal)}`
+                ];
+                if (host.load) parts.push(`Load ${host.load[0].toFixed(2)}`);
+                el.textContent = parts.join(' · ');
+            } catch (e) {
+                // Ignore errors, the next poll retries
+            }
+        },
+
         formatPercent(percent) {
             if (percent < 10) return percent.toFixed(1) + '%';
             return Math.round(percent) + '%';
@@ -437,7 +614,7 @@
         const url = event.detail.pathInfo?.requestPath || '';
 
         // Check if this is a compose operation
-        const match = url.match(/\/api\/projects\/([^/]+)\/(up|down|restart|pull|update)$/);
+        const match = url.match(/\/api\/v1\/projects\/([^/]+)\/(up|down|start|stop|restart|pull|update)$/);
         if (match) {
             const projectId = match[1];
             ComposeOps.startOperation(projectId, button);
@@ -447,7 +624,7 @@
     // Handle request errors for compose operations
     document.body.addEventListener('htmx:sendError', function(event) {
         const url = event.detail.pathInfo?.requestPath || '';
-        const match = url.match(/\/api\/projects\/([^/]+)\/(up|down|restart|pull|update)$/);
+        const match = url.match(/\/api\/v1\/projects\/([^/]+)\/(up|down|start|stop|restart|pull|update)$/);
         if (match) {
             ComposeOps.endOperation(match[1]);
         }
@@ -463,7 +640,24 @@
         }
     }
 
+    // cancelOperation stops the compose operation shown in the output modal
+    function cancelOperation() {
+        const modal = document.getElementById('output-modal');
+        const projectId = modal && modal.dataset.projectId;
+        if (!projectId) return;
+
+        const token = document.querySelector('meta[name="csrf-token"]');
+        fetch(`/api/v1/projects/${projectId}/operations/cancel`, {
+            method: 'POST',
+            headers: token ? { 'X-CSRF-Token': token.content } : {}
+        }).then(r => {
+            if (!r.ok) r.json().then(body => Toast.error(body.error || 'Failed to cancel operation'));
+        });
+    }
+
+    window.renderLogMessage = renderLogMessage;
     window.closeOutputModal = closeOutputModal;
+    window.cancelOperation = cancelOperation;
 
     // ============================================
     // Initialize
@@ -472,7 +666,7 @@
         Toast.init();
         SSE.connect();
 
-        if (document.querySelector('[data-stats-id]')) {
+        if (document.querySelector('[data-stats-id], #host-stats')) {
             Stats.start();
         }
 